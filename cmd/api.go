@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/lmorchard/feedspool-go/internal/api"
+	"github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/server"
+	"github.com/lmorchard/feedspool-go/internal/subscription"
+	"github.com/sirupsen/logrus"
+)
+
+// apiDefaultHost is where the server listens when it serves the API and no
+// host is configured.
+const apiDefaultHost = "127.0.0.1"
+
+// feedListSubscriber edits the configured default feed list for the API.
+type feedListSubscriber struct {
+	manager          *subscription.Manager
+	format, filename string
+}
+
+func (s *feedListSubscriber) Subscribe(url string) (bool, error) {
+	result, err := s.manager.Subscribe(s.format, s.filename, []string{url})
+	if err != nil {
+		return false, err
+	}
+	return result.AddedCount > 0, nil
+}
+
+func (s *feedListSubscriber) Unsubscribe(url string) (bool, error) {
+	result, err := s.manager.Unsubscribe(s.format, s.filename, url)
+	if err != nil {
+		return false, err
+	}
+	return result.Found, nil
+}
+
+// newAPIHandler opens the database and creates the JSON API handler.
+// Subscribing through the API needs a default feed list in the config. The
+// caller must close the returned database.
+func newAPIHandler(cfg *config.Config) (*api.Handler, *database.DB, error) {
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if err := db.IsInitialized(); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	var subscriber api.Subscriber
+	if cfg.HasDefaultFeedList() {
+		format, filename := cfg.GetDefaultFeedList()
+		subscriber = &feedListSubscriber{manager: subscription.New(cfg), format: format, filename: filename}
+	}
+
+	handler := api.NewHandler(db, subscriber)
+	handler.SetToken(cfg.Serve.APIToken)
+	return handler, db, nil
+}
+
+// mountAPIHandler adds the JSON API to a server config. The API can change
+// state, so unless a host is configured the server only listens locally.
+func mountAPIHandler(serveConfig *server.Config, handler *api.Handler) {
+	if serveConfig.Host == "" {
+		serveConfig.Host = apiDefaultHost
+		logrus.Infof("Serving the API on %s only; set serve.host to listen elsewhere", apiDefaultHost)
+	}
+	if serveConfig.Handlers == nil {
+		serveConfig.Handlers = make(map[string]http.Handler)
+	}
+	serveConfig.Handlers[api.PathPrefix] = handler
+}
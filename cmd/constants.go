@@ -12,4 +12,7 @@ const (
 	// Output format constants.
 	formatJSON  = "json"
 	formatTable = "table"
+
+	// Search constants.
+	defaultSearchLimit = 50
 )
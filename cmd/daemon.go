@@ -0,0 +1,335 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/api"
+	"github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/daemon"
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/fetcher"
+	"github.com/lmorchard/feedspool-go/internal/media"
+	"github.com/lmorchard/feedspool-go/internal/renderer"
+	"github.com/lmorchard/feedspool-go/internal/server"
+	"github.com/lmorchard/feedspool-go/internal/websub"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	daemonFetchInterval time.Duration
+	daemonPurgeInterval time.Duration
+	daemonNoServe       bool
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run fetch, render, purge and serve in a single long-running process",
+	Long: `Run feedspool as a long-running service.
+
+The daemon replaces an external cron loop of 'purge && fetch && render'. It
+runs the following jobs in-process, one at a time:
+
+  purge   Delete archived items older than purge.max_age (every --purge-interval)
+  fetch   Fetch all feeds, then render the static site (every --fetch-interval)
+
+Both jobs run once at startup. Feeds are read from the configured default feed
+list when one is set, otherwise from the database.
+
+The static site is also served over HTTP from the same process, using the
+serve.port and serve.dir settings (PORT env var overrides the port). Use
+--no-serve to disable the server when the site is published some other way.
+With serve.api set in the config, the server also serves the JSON reader API,
+listening on 127.0.0.1 unless serve.host is set, and with serve.metrics,
+Prometheus metrics under /metrics. After every job, metrics are also written
+next to metrics.textfile and pushed to metrics.push_url, under the name
+"daemon", when those are set.
+
+With websub.enabled and websub.callback_url set, a websub job also subscribes
+to the hubs feeds advertise (every 10m), and the server accepts their pushes.
+
+An interval of 0 disables the corresponding job. SIGINT/SIGTERM stop the
+daemon gracefully after the running job notices cancellation.
+
+Examples:
+  feedspool daemon                          # Fetch every 30m, purge daily, serve on 8889
+  feedspool daemon --fetch-interval 15m     # Fetch more often
+  feedspool daemon --purge-interval 0       # Never purge
+  feedspool daemon --no-serve               # Scheduled jobs only`,
+	RunE: runDaemon,
+}
+
+func init() {
+	daemonCmd.Flags().DurationVar(&daemonFetchInterval, "fetch-interval", config.DefaultFetchInterval,
+		"Interval between fetch and render runs (0 to disable)")
+	daemonCmd.Flags().DurationVar(&daemonPurgeInterval, "purge-interval", config.DefaultPurgeInterval,
+		"Interval between purge runs (0 to disable)")
+	daemonCmd.Flags().BoolVar(&daemonNoServe, "no-serve", false, "Do not run the HTTP server")
+
+	// Bind flags to viper for config file support
+	_ = viper.BindPFlag("daemon.fetch_interval", daemonCmd.Flags().Lookup("fetch-interval"))
+	_ = viper.BindPFlag("daemon.purge_interval", daemonCmd.Flags().Lookup("purge-interval"))
+	_ = viper.BindPFlag("daemon.no_serve", daemonCmd.Flags().Lookup("no-serve"))
+
+	rootCmd.AddCommand(daemonCmd)
+}
+
+func runDaemon(_ *cobra.Command, _ []string) error {
+	cfg := GetConfig()
+
+	// Fail fast on an uninitialized database rather than on every scheduled run
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	err = db.IsInitialized()
+	db.Close()
+	if err != nil {
+		return err
+	}
+	if _, err := loadRules(cfg); err != nil {
+		return err
+	}
+	if _, err := loadHTTPOverrides(cfg); err != nil {
+		return err
+	}
+
+	ctx, cancel := setupGracefulShutdown()
+	defer cancel()
+
+	jobs := []daemon.Job{
+		{
+			Name:     "purge",
+			Interval: cfg.Daemon.PurgeInterval,
+			Run: instrumentJob(cfg, "purge", func(context.Context) error {
+				return runScheduledPurge(cfg)
+			}),
+		},
+		{
+			Name:     "fetch",
+			Interval: cfg.Daemon.FetchInterval,
+			Run: instrumentJob(cfg, "fetch", func(ctx context.Context) error {
+				return runScheduledFetch(ctx, cfg)
+			}),
+		},
+	}
+
+	// Hubs can only push to the callback endpoint while the server runs
+	var manager *websub.Manager
+	if cfg.WebSub.Enabled && cfg.Daemon.NoServe {
+		logrus.Warn("WebSub needs the HTTP server; ignoring websub.enabled with --no-serve")
+	} else if cfg.WebSub.Enabled {
+		var websubDB *database.DB
+		manager, websubDB, err = newWebSubManager(cfg)
+		if err != nil {
+			return err
+		}
+		defer websubDB.Close()
+
+		jobs = append(jobs, daemon.Job{
+			Name:     "websub",
+			Interval: websub.DefaultCheckInterval,
+			Run:      manager.Sync,
+		})
+	}
+
+	var srv *server.Server
+	if !cfg.Daemon.NoServe {
+		var apiHandler *api.Handler
+		if cfg.Serve.API {
+			var apiDB *database.DB
+			apiHandler, apiDB, err = newAPIHandler(cfg)
+			if err != nil {
+				return err
+			}
+			defer apiDB.Close()
+		}
+
+		srv, err = startDaemonServer(cfg, manager, apiHandler, cancel)
+		if err != nil {
+			return err
+		}
+	}
+
+	scheduler := daemon.NewScheduler(jobs...)
+
+	logrus.Infof("Daemon started (fetch every %v, purge every %v)",
+		cfg.Daemon.FetchInterval, cfg.Daemon.PurgeInterval)
+
+	if err := scheduler.Run(ctx); err != nil {
+		return err
+	}
+
+	if srv != nil {
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout*time.Second)
+		defer shutdownCancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+
+	return nil
+}
+
+// startDaemonServer starts the static file server in the background, along
+// with the WebSub callback endpoint when manager is set, the JSON API when
+// apiHandler is and the metrics endpoint when serve.metrics is. A server
+// failure cancels the daemon context so the process exits instead of running
+// headless.
+func startDaemonServer(
+	cfg *config.Config, manager *websub.Manager, apiHandler *api.Handler, cancel context.CancelFunc,
+) (*server.Server, error) {
+	serveConfig := buildServeConfig(cfg)
+	if manager != nil {
+		mountWebSubHandler(serveConfig, manager)
+	}
+	if apiHandler != nil {
+		mountAPIHandler(serveConfig, apiHandler)
+	}
+	if cfg.Serve.Metrics {
+		mountMetricsHandler(cfg, serveConfig)
+	}
+
+	// The first render may not have happened yet, but the server requires
+	// its directory to exist
+	if err := os.MkdirAll(serveConfig.Dir, config.DefaultDirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create serve directory: %w", err)
+	}
+
+	srv := server.NewServer(serveConfig)
+	go func() {
+		if err := srv.Start(); err != nil {
+			logrus.WithError(err).Error("HTTP server stopped")
+			cancel()
+		}
+	}()
+
+	return srv, nil
+}
+
+// runScheduledFetch fetches all feeds and then re-renders the site.
+func runScheduledFetch(ctx context.Context, cfg *config.Config) error {
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	engine, err := loadRules(cfg)
+	if err != nil {
+		return err
+	}
+	overrides, err := loadHTTPOverrides(cfg)
+	if err != nil {
+		return err
+	}
+
+	orchestrator := fetcher.NewOrchestrator(db, cfg)
+	orchestrator.SetRules(engine)
+	orchestrator.SetOverrides(overrides)
+	opts := fetcher.FetchOptions{
+		Timeout:     cfg.Timeout,
+		MaxItems:    cfg.Fetch.MaxItems,
+		Force:       false,
+		Concurrency: cfg.Fetch.Concurrency,
+		WithUnfurl:  cfg.Fetch.WithUnfurl,
+	}
+
+	var results []*fetcher.FetchResult
+	mode := "database"
+	if cfg.HasDefaultFeedList() {
+		mode = "file"
+		format, filename := cfg.GetDefaultFeedList()
+		feedFormat, err := fetcher.FormatValidation{}.ValidateFormat(format)
+		if err != nil {
+			return err
+		}
+		results, err = orchestrator.FetchFromFile(ctx, feedFormat, filename, opts)
+		if err != nil {
+			return err
+		}
+	} else {
+		results, err = orchestrator.FetchFromDatabase(ctx, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	summary := fetcher.ProcessResults(results)
+	summary.Mode = mode
+	summary.Print(cfg)
+	recordFetchSummary(summary)
+
+	// Don't render a half-finished fetch while shutting down
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	// Release the database before the renderer opens its own connection
+	db.Close()
+
+	started := time.Now()
+	err = renderer.ExecuteWorkflow(buildRenderConfig(cfg))
+	recordRun("render", started, err)
+	return err
+}
+
+// runScheduledPurge deletes archived items older than the configured max age
+// and cleans up orphaned metadata, old fetch log entries and cached media.
+func runScheduledPurge(cfg *config.Config) error {
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	ageStr := cfg.Purge.MaxAge
+	if ageStr == "" {
+		ageStr = "30d"
+	}
+
+	duration, err := database.ParseDuration(ageStr)
+	if err != nil {
+		return fmt.Errorf("invalid purge.max_age: %w", err)
+	}
+
+	cutoffTime := time.Now().Add(-duration)
+	deleted, err := deleteArchivedItems(db, cutoffTime, cfg.Purge.MinItemsKeep)
+	if err != nil {
+		return err
+	}
+
+	metadataDeleted, err := db.DeleteOrphanedMetadata()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to clean up orphaned metadata")
+	}
+
+	logrus.Infof("Purged %d archived items older than %s (%d orphaned metadata entries)",
+		deleted, cutoffTime.Format("2006-01-02"), metadataDeleted)
+
+	if logCutoff, ok, err := fetchLogCutoff(cfg.Purge.FetchLogAge); err != nil {
+		logrus.WithError(err).Warn("Failed to clean up the fetch log")
+	} else if ok {
+		if logDeleted, err := db.DeleteFetchLogBefore(logCutoff); err != nil {
+			logrus.WithError(err).Warn("Failed to clean up the fetch log")
+		} else if logDeleted > 0 {
+			logrus.Infof("Deleted %d fetch log entries older than %s", logDeleted, logCutoff.Format("2006-01-02"))
+		}
+	}
+
+	if mediaDeleted, err := media.New(cfg.Media, db, cfg.Timeout).CollectGarbage(); err != nil {
+		logrus.WithError(err).Warn("Failed to clean up cached media")
+	} else if mediaDeleted > 0 {
+		logrus.Infof("Deleted %d unreferenced cached media files", mediaDeleted)
+	}
+
+	if !cfg.Purge.SkipVacuum {
+		if err := db.Vacuum(); err != nil {
+			logrus.WithError(err).Warn("Failed to vacuum database")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/lmorchard/feedspool-go/internal/dedupe"
+	"github.com/spf13/cobra"
+)
+
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "Regroup items syndicated through more than one feed",
+	Long: `Rebuild the groups of duplicate items from scratch.
+
+When a story shows up in several feeds, fetch groups the copies under the
+first one seen, and render shows it once with "also in" links to the other
+feeds. Items are duplicates when they share:
+
+  - a link, once scheme, "www.", fragments, tracking parameters (utm_*,
+    fbclid, ...) and trailing slashes are dropped
+  - the canonical URL (og:url) unfurled from their page
+  - a title fingerprint: the same significant words, in any order and case
+    (unless dedupe.titles is false)
+
+With dedupe.enabled, fetch groups new items as they arrive, and groups the
+items it unfurled again once their canonical URLs are known. Run dedupe after
+unfurling separately, or after changing the dedupe config, to regroup every
+item already stored.
+
+Example config:
+  dedupe:
+    enabled: true   # Opt in to grouping on fetch and collapsing in render
+    titles: true    # Also match title fingerprints
+
+Examples:
+  feedspool dedupe`,
+	RunE: runDedupe,
+}
+
+func init() {
+	rootCmd.AddCommand(dedupeCmd)
+}
+
+func runDedupe(_ *cobra.Command, _ []string) error {
+	cfg := GetConfig()
+
+	db, err := openFeedsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	stats, err := dedupe.New(db, cfg.Dedupe.Titles).Rebuild()
+	if err != nil {
+		return fmt.Errorf("failed to regroup duplicates: %w", err)
+	}
+
+	fmt.Printf("Checked %d items: %d duplicates in %d groups\n", stats.Items, stats.Duplicates, stats.Groups)
+	return nil
+}
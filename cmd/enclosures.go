@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lmorchard/feedspool-go/internal/enclosure"
+	"github.com/spf13/cobra"
+)
+
+var (
+	enclosuresDir     string
+	enclosuresMaxSize int64
+	enclosuresKeep    int
+	enclosuresTypes   []string
+)
+
+var enclosuresCmd = &cobra.Command{
+	Use:   "enclosures",
+	Short: "Work with podcast episodes and other item enclosures",
+	Long: `Commands for the media files items carry as enclosures, such as podcast
+episodes. Enclosures are recorded as feeds are fetched, shown by 'feedspool
+show --format json' and played from rendered pages.
+
+Examples:
+  feedspool enclosures download                  # Download enclosures from every feed
+  feedspool enclosures download --keep 5         # Keep the 5 newest per feed
+  feedspool enclosures download <feed-url>       # Only this feed's enclosures`,
+}
+
+var enclosuresDownloadCmd = &cobra.Command{
+	Use:   "download [feed-url...]",
+	Short: "Download enclosures for offline listening",
+	Long: `Downloads the enclosures of items into the enclosures directory, with a
+subdirectory per feed and files named for each item's date and title.
+
+Only enclosures of the configured types are downloaded, audio and video by
+default. Files larger than --max-size are skipped. Interrupted downloads are
+resumed on the next run when the server supports it.
+
+With --keep, only the enclosures of each feed's newest items are kept:
+older downloads are deleted, and older enclosures aren't downloaded. A run
+over every feed also deletes files no enclosure refers to any more, such as
+those of purged items, so the directory should hold nothing else.`,
+	RunE: runEnclosuresDownload,
+}
+
+func init() {
+	enclosuresDownloadCmd.Flags().StringVar(&enclosuresDir, "dir", "",
+		"Directory to download into (default from config: ./enclosures)")
+	enclosuresDownloadCmd.Flags().Int64Var(&enclosuresMaxSize, "max-size", -1,
+		"Largest file to download, in bytes (-1 = use config default, 0 = no limit)")
+	enclosuresDownloadCmd.Flags().IntVar(&enclosuresKeep, "keep", -1,
+		"Downloads to keep per feed, newest first (-1 = use config default, 0 = keep all)")
+	enclosuresDownloadCmd.Flags().StringSliceVar(&enclosuresTypes, "type", nil,
+		"Type prefixes to download, e.g. audio/ (default from config: audio/, video/)")
+
+	enclosuresCmd.AddCommand(enclosuresDownloadCmd)
+	rootCmd.AddCommand(enclosuresCmd)
+}
+
+func runEnclosuresDownload(_ *cobra.Command, args []string) error {
+	cfg := GetConfig()
+
+	db, err := openFeedsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	settings := cfg.Enclosures
+	if enclosuresDir != "" {
+		settings.Dir = enclosuresDir
+	}
+	if enclosuresMaxSize >= 0 {
+		settings.MaxSize = enclosuresMaxSize
+	}
+	if enclosuresKeep >= 0 {
+		settings.Keep = enclosuresKeep
+	}
+	if len(enclosuresTypes) > 0 {
+		settings.Types = enclosuresTypes
+	}
+
+	result, err := enclosure.New(settings, db).Run(args)
+	if err != nil {
+		return fmt.Errorf("failed to download enclosures: %w", err)
+	}
+
+	if cfg.JSON {
+		jsonData, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to encode result: %w", err)
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	fmt.Printf("Downloaded %d enclosures (%d bytes), %d failed, %d old downloads deleted\n",
+		result.Downloaded, result.Bytes, result.Failed, result.Deleted)
+	return nil
+}
@@ -11,25 +11,37 @@ import (
 var (
 	exportFormat   string
 	exportFilename string
+	exportFrom     string
 )
 
 var exportCmd = &cobra.Command{
-	Use:   "export --format [opml|text] [filename]",
+	Use:   "export --format [opml|text|yaml|json] [filename]",
 	Short: "Export database feeds to a feed list file",
-	Long: `Export all feeds from the database to a feed list file (OPML or text format).
+	Long: `Export all feeds from the database to a feed list file (OPML, text, YAML or
+JSON format).
 
-For OPML format, includes feed metadata (title, description) when available.
+For OPML format, feeds are grouped into folders by their tags. A feed with
+several tags is listed in the folder of each, so fetching from the exported
+file restores every tag.
 For text format, creates a simple list of URLs with header comments.
+YAML and JSON lists keep each feed's tags and per-feed settings with its
+entry. OPML keeps the settings in feedspool attributes on each outline.
+
+With --from, converts another feed list file instead of reading the database,
+detecting its format from the file extension. Converting between OPML, YAML
+and JSON keeps every tag and setting.
 
 Examples:
   feedspool export --format opml feeds.opml
-  feedspool export --format text feeds.txt`,
+  feedspool export --format text feeds.txt
+  feedspool export --format yaml --from feeds.opml feeds.yaml`,
 	Args: cobra.ExactArgs(1),
 	RunE: runExport,
 }
 
 func init() {
-	exportCmd.Flags().StringVar(&exportFormat, "format", "", "Feed list format (opml or text) - REQUIRED")
+	exportCmd.Flags().StringVar(&exportFormat, "format", "", "Feed list format (opml, text, yaml or json) - REQUIRED")
+	exportCmd.Flags().StringVar(&exportFrom, "from", "", "Feed list file to convert instead of the database")
 	_ = exportCmd.MarkFlagRequired("format")
 	rootCmd.AddCommand(exportCmd)
 }
@@ -45,8 +57,16 @@ func runExport(_ *cobra.Command, args []string) error {
 		feedFormat = feedlist.FormatOPML
 	case string(feedlist.FormatText):
 		feedFormat = feedlist.FormatText
+	case string(feedlist.FormatYAML):
+		feedFormat = feedlist.FormatYAML
+	case string(feedlist.FormatJSON):
+		feedFormat = feedlist.FormatJSON
 	default:
-		return fmt.Errorf("unsupported format: %s (must be 'opml' or 'text')", exportFormat)
+		return fmt.Errorf("unsupported format: %s (must be 'opml', 'text', 'yaml' or 'json')", exportFormat)
+	}
+
+	if exportFrom != "" {
+		return runExportConversion(feedFormat)
 	}
 
 	// Connect to database
@@ -71,19 +91,23 @@ func runExport(_ *cobra.Command, args []string) error {
 		return nil
 	}
 
+	feedTags, err := db.GetAllFeedTags()
+	if err != nil {
+		return fmt.Errorf("failed to get feed tags from database: %w", err)
+	}
+
 	// Create new feed list of specified format
 	list := feedlist.NewFeedList(feedFormat)
 
-	// Add all feed URLs to the list
+	// Add all feeds to the list, in folders by tag and with their settings
+	// where supported
 	for _, feed := range feeds {
-		if err := list.AddURL(feed.URL); err != nil {
+		entry := feedlist.Entry{URL: feed.URL, Tags: feedTags[feed.URL], Settings: feed.FeedSettings}
+		if err := list.AddEntry(entry); err != nil {
 			return fmt.Errorf("failed to add URL %s to feed list: %w", feed.URL, err)
 		}
 	}
 
-	// For OPML format, we could enhance with feed metadata in the future
-	// For now, both formats just export URLs
-
 	// Save to specified filename
 	if err := list.Save(exportFilename); err != nil {
 		return fmt.Errorf("failed to save feed list: %w", err)
@@ -93,3 +117,25 @@ func runExport(_ *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// runExportConversion converts the --from feed list into the given format.
+func runExportConversion(feedFormat feedlist.Format) error {
+	source, err := feedlist.LoadFeedList(feedlist.DetectFormat(exportFrom), exportFrom)
+	if err != nil {
+		return fmt.Errorf("failed to load feed list: %w", err)
+	}
+
+	list, err := feedlist.Convert(source, feedFormat)
+	if err != nil {
+		return err
+	}
+
+	if err := list.Save(exportFilename); err != nil {
+		return fmt.Errorf("failed to save feed list: %w", err)
+	}
+
+	fmt.Printf("Converted %d feeds from %s to %s (%s format)\n",
+		len(list.GetURLs()), exportFrom, exportFilename, exportFormat)
+
+	return nil
+}
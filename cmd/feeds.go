@@ -0,0 +1,396 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/fetcher"
+	"github.com/spf13/cobra"
+)
+
+var (
+	feedsFormat      string
+	feedsDueOnly     bool
+	feedsEnableAll   bool
+	feedsFullTextOff bool
+)
+
+var feedsCmd = &cobra.Command{
+	Use:   "feeds",
+	Short: "Inspect feed status in the database",
+	Long: `Commands for inspecting the state of feeds stored in the database.
+
+Examples:
+  feedspool feeds schedule           # When each feed will next be fetched
+  feedspool feeds schedule --due     # Only feeds due for a fetch now
+  feedspool feeds errors             # Failing and disabled feeds
+  feedspool feeds enable <url>       # Re-enable a disabled feed
+  feedspool feeds fulltext <url>     # Extract article text for a feed's items`,
+}
+
+var feedsScheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Show the adaptive fetch schedule for each feed",
+	Long: `Lists feeds in the order they will next be fetched.
+
+Each successful fetch schedules the feed's next fetch from its publish history
+and HTTP caching hints, bounded by fetch.min_interval and fetch.max_interval.
+Feeds whose list sets a fixed interval are scheduled that far ahead instead.
+Feeds that have never been scheduled are always due. Feeds parked by a
+server's 429 or 503 response aren't due until their Retry-After time.`,
+	Args: cobra.NoArgs,
+	RunE: runFeedsSchedule,
+}
+
+var feedsErrorsCmd = &cobra.Command{
+	Use:   "errors",
+	Short: "List failing and disabled feeds",
+	Long: `Lists feeds whose last fetch failed or that have been disabled, with the
+last error, the last successful fetch and a suggested action.
+
+Failing feeds are retried with exponential backoff. After fetch.disable_after
+consecutive errors a feed is disabled and skipped by batch fetches until it
+is re-enabled with 'feedspool feeds enable'.`,
+	Args: cobra.NoArgs,
+	RunE: runFeedsErrors,
+}
+
+var feedsEnableCmd = &cobra.Command{
+	Use:   "enable [URL...]",
+	Short: "Re-enable disabled feeds",
+	Long: `Re-enables feeds disabled after repeated errors. Their error count is
+reset and they are fetched on the next run.
+
+Examples:
+  feedspool feeds enable https://example.com/feed.xml
+  feedspool feeds enable --all`,
+	RunE: runFeedsEnable,
+}
+
+var feedsFullTextCmd = &cobra.Command{
+	Use:   "fulltext URL...",
+	Short: "Extract the full article text of feeds' items",
+	Long: `Marks feeds whose items should have their article text extracted, for
+feeds that only publish a summary or a single line.
+
+When the items of these feeds are unfurled, during 'feedspool fetch
+--with-unfurl' or by 'feedspool unfurl', the linked page's main text is
+extracted and stored. Rendered pages show it when the feed's own content is
+missing.
+
+Examples:
+  feedspool feeds fulltext https://example.com/feed.xml
+  feedspool feeds fulltext --off https://example.com/feed.xml`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runFeedsFullText,
+}
+
+// FeedSchedule is the JSON representation of a feed's fetch schedule.
+type FeedSchedule struct {
+	URL           string     `json:"url"`
+	Title         string     `json:"title"`
+	LastFetchTime *time.Time `json:"lastFetchTime,omitempty"`
+	NextFetchAt   *time.Time `json:"nextFetchAt,omitempty"`
+	ParkedUntil   *time.Time `json:"parkedUntil,omitempty"`
+	Due           bool       `json:"due"`
+	Disabled      bool       `json:"disabled"`
+}
+
+// FeedError is the JSON representation of a failing feed.
+type FeedError struct {
+	URL                 string     `json:"url"`
+	Title               string     `json:"title"`
+	Disabled            bool       `json:"disabled"`
+	ErrorCount          int        `json:"errorCount"`
+	LastError           string     `json:"lastError"`
+	LastSuccessfulFetch *time.Time `json:"lastSuccessfulFetch,omitempty"`
+	NextFetchAt         *time.Time `json:"nextFetchAt,omitempty"`
+	SuggestedAction     string     `json:"suggestedAction"`
+}
+
+func init() {
+	feedsScheduleCmd.Flags().StringVar(&feedsFormat, "format", formatTable, "Output format (table|json)")
+	feedsScheduleCmd.Flags().BoolVar(&feedsDueOnly, "due", false, "Only show feeds that are due now")
+	feedsErrorsCmd.Flags().StringVar(&feedsFormat, "format", formatTable, "Output format (table|json)")
+	feedsEnableCmd.Flags().BoolVar(&feedsEnableAll, "all", false, "Re-enable every disabled feed")
+	feedsFullTextCmd.Flags().BoolVar(&feedsFullTextOff, "off", false, "Stop extracting article text")
+
+	feedsCmd.AddCommand(feedsScheduleCmd)
+	feedsCmd.AddCommand(feedsErrorsCmd)
+	feedsCmd.AddCommand(feedsEnableCmd)
+	feedsCmd.AddCommand(feedsFullTextCmd)
+	rootCmd.AddCommand(feedsCmd)
+}
+
+// openFeedsDB opens the configured database and checks it is initialized.
+func openFeedsDB() (*database.DB, error) {
+	db, err := database.New(GetConfig().Database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	if err := db.IsInitialized(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// feedsOutputFormat resolves the --format flag, honoring --json.
+func feedsOutputFormat() string {
+	if feedsFormat == formatTable && GetConfig().JSON {
+		return formatJSON
+	}
+	return feedsFormat
+}
+
+func runFeedsSchedule(_ *cobra.Command, _ []string) error {
+	db, err := openFeedsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	feeds, err := db.GetAllFeeds()
+	if err != nil {
+		return fmt.Errorf("failed to get feeds: %w", err)
+	}
+
+	now := time.Now()
+	schedules := make([]FeedSchedule, 0, len(feeds))
+	for _, feed := range feeds {
+		schedule := FeedSchedule{
+			URL:      feed.URL,
+			Title:    feed.Title,
+			Due:      !feed.Disabled && (!feed.NextFetchAt.Valid || !now.Before(feed.NextFetchAt.Time)),
+			Disabled: feed.Disabled,
+		}
+		if !feed.LastFetchTime.IsZero() {
+			lastFetch := feed.LastFetchTime
+			schedule.LastFetchTime = &lastFetch
+		}
+		if feed.NextFetchAt.Valid {
+			nextFetch := feed.NextFetchAt.Time
+			schedule.NextFetchAt = &nextFetch
+		}
+		if feed.ParkedUntil.Valid && now.Before(feed.ParkedUntil.Time) {
+			parkedUntil := feed.ParkedUntil.Time
+			schedule.ParkedUntil = &parkedUntil
+			schedule.Due = false
+		}
+		if feedsDueOnly && !schedule.Due {
+			continue
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	// Unscheduled feeds first, then soonest next fetch
+	sort.SliceStable(schedules, func(i, j int) bool {
+		a, b := schedules[i].NextFetchAt, schedules[j].NextFetchAt
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+		return a.Before(*b)
+	})
+
+	switch feedsOutputFormat() {
+	case formatJSON:
+		return outputFeedsJSON(schedules)
+	case formatTable:
+		return outputScheduleTable(schedules, now)
+	default:
+		return fmt.Errorf("unknown format: %s", feedsFormat)
+	}
+}
+
+func outputScheduleTable(schedules []FeedSchedule, now time.Time) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NEXT FETCH\tLAST FETCH\tFEED")
+	fmt.Fprintln(w, "----------\t----------\t----")
+
+	for i := range schedules {
+		next := "due"
+		if schedules[i].Disabled {
+			next = "disabled"
+		} else if schedules[i].ParkedUntil != nil {
+			next = "parked " + schedules[i].ParkedUntil.Sub(now).Round(time.Minute).String()
+		} else if !schedules[i].Due {
+			next = "in " + schedules[i].NextFetchAt.Sub(now).Round(time.Minute).String()
+		}
+		last := "never"
+		if schedules[i].LastFetchTime != nil {
+			last = schedules[i].LastFetchTime.Format("2006-01-02 15:04")
+		}
+		name := schedules[i].Title
+		if name == "" {
+			name = schedules[i].URL
+		}
+		if len(name) > 60 {
+			name = name[:57] + "..."
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", next, last, name)
+	}
+
+	return w.Flush()
+}
+
+func runFeedsErrors(_ *cobra.Command, _ []string) error {
+	db, err := openFeedsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	feeds, err := db.GetFailingFeeds()
+	if err != nil {
+		return err
+	}
+
+	feedErrors := make([]FeedError, 0, len(feeds))
+	for _, feed := range feeds {
+		feedError := FeedError{
+			URL:             feed.URL,
+			Title:           feed.Title,
+			Disabled:        feed.Disabled,
+			ErrorCount:      feed.ErrorCount,
+			LastError:       feed.LastError,
+			SuggestedAction: fetcher.SuggestAction(feed),
+		}
+		if !feed.LastSuccessfulFetch.IsZero() {
+			lastSuccess := feed.LastSuccessfulFetch
+			feedError.LastSuccessfulFetch = &lastSuccess
+		}
+		if feed.NextFetchAt.Valid && !feed.Disabled {
+			nextFetch := feed.NextFetchAt.Time
+			feedError.NextFetchAt = &nextFetch
+		}
+		feedErrors = append(feedErrors, feedError)
+	}
+
+	switch feedsOutputFormat() {
+	case formatJSON:
+		return outputFeedsJSON(feedErrors)
+	case formatTable:
+		outputFeedErrors(feedErrors)
+		return nil
+	default:
+		return fmt.Errorf("unknown format: %s", feedsFormat)
+	}
+}
+
+func outputFeedErrors(feedErrors []FeedError) {
+	if len(feedErrors) == 0 {
+		fmt.Println("No failing feeds")
+		return
+	}
+
+	for i := range feedErrors {
+		feedError := &feedErrors[i]
+
+		status := "FAILING"
+		if feedError.Disabled {
+			status = "DISABLED"
+		}
+		fmt.Printf("%s  %s (%d consecutive errors)\n", status, feedError.URL, feedError.ErrorCount)
+		if feedError.Title != "" {
+			fmt.Printf("  Title:        %s\n", feedError.Title)
+		}
+		fmt.Printf("  Last error:   %s\n", feedError.LastError)
+
+		lastSuccess := "never"
+		if feedError.LastSuccessfulFetch != nil {
+			lastSuccess = feedError.LastSuccessfulFetch.Format("2006-01-02 15:04")
+		}
+		fmt.Printf("  Last success: %s\n", lastSuccess)
+
+		if feedError.NextFetchAt != nil {
+			fmt.Printf("  Next retry:   %s\n", feedError.NextFetchAt.Format("2006-01-02 15:04"))
+		}
+		fmt.Printf("  Action:       %s\n\n", feedError.SuggestedAction)
+	}
+}
+
+func runFeedsEnable(_ *cobra.Command, args []string) error {
+	if feedsEnableAll == (len(args) > 0) {
+		return errors.New("specify feed URLs or --all")
+	}
+
+	db, err := openFeedsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	urls := args
+	if feedsEnableAll {
+		feeds, err := db.GetFailingFeeds()
+		if err != nil {
+			return err
+		}
+		for _, feed := range feeds {
+			if feed.Disabled {
+				urls = append(urls, feed.URL)
+			}
+		}
+	}
+
+	enabled := 0
+	for _, url := range urls {
+		found, err := db.EnableFeed(url)
+		if err != nil {
+			return err
+		}
+		if !found {
+			fmt.Printf("Feed not found: %s\n", url)
+			continue
+		}
+		fmt.Printf("Enabled: %s\n", url)
+		enabled++
+	}
+
+	fmt.Printf("Enabled %d feed(s)\n", enabled)
+	return nil
+}
+
+func runFeedsFullText(_ *cobra.Command, args []string) error {
+	db, err := openFeedsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	state := "on"
+	if feedsFullTextOff {
+		state = "off"
+	}
+
+	changed := 0
+	for _, url := range args {
+		found, err := db.SetFeedFullText(url, !feedsFullTextOff)
+		if err != nil {
+			return err
+		}
+		if !found {
+			fmt.Printf("Feed not found: %s\n", url)
+			continue
+		}
+		fmt.Printf("Full text %s: %s\n", state, url)
+		changed++
+	}
+
+	fmt.Printf("Updated %d feed(s)\n", changed)
+	return nil
+}
+
+func outputFeedsJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
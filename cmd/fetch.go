@@ -11,6 +11,7 @@ import (
 	"github.com/lmorchard/feedspool-go/internal/config"
 	"github.com/lmorchard/feedspool-go/internal/database"
 	"github.com/lmorchard/feedspool-go/internal/fetcher"
+	"github.com/lmorchard/feedspool-go/internal/httpoverride"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -19,6 +20,7 @@ var (
 	fetchTimeout       time.Duration
 	fetchMaxItems      int
 	fetchForce         bool
+	fetchIgnoreSched   bool
 	fetchConcurrency   int
 	fetchMaxAge        time.Duration
 	fetchRemoveMissing bool
@@ -45,6 +47,13 @@ From database:
 The command supports all options from the former 'update' command including concurrency
 control, age filtering, and database cleanup based on feed lists.
 
+Adaptive schedule:
+  Each fetch schedules the feed's next fetch from how often it publishes and
+  any Cache-Control/Expires hints, bounded by fetch.min_interval and
+  fetch.max_interval. File and database modes skip feeds that aren't due yet;
+  use --ignore-schedule (or --force) to fetch them anyway. A single URL is
+  always fetched. See 'feedspool feeds schedule' for the current schedule.
+
 Parallel Unfurl:
   feedspool fetch --with-unfurl                          # Fetch feeds and unfurl metadata in parallel
   
@@ -52,17 +61,19 @@ The --with-unfurl flag enables parallel metadata extraction for new feed items.
 unfurl operations concurrently with feed fetching for improved performance. Only new items
 without existing metadata will be processed.`,
 	Args: cobra.MaximumNArgs(1),
-	RunE: runFetch,
+	RunE: instrumentCommand("fetch", runFetch),
 }
 
 func init() {
 	fetchCmd.Flags().DurationVar(&fetchTimeout, "timeout", config.DefaultTimeout, "Feed fetch timeout")
 	fetchCmd.Flags().IntVar(&fetchMaxItems, "max-items", config.DefaultMaxItems, "Maximum items to keep per feed")
-	fetchCmd.Flags().BoolVar(&fetchForce, "force", false, "Ignore cache headers and fetch anyway")
+	fetchCmd.Flags().BoolVar(&fetchForce, "force", false, "Ignore cache headers and schedule and fetch anyway")
+	fetchCmd.Flags().BoolVar(&fetchIgnoreSched, "ignore-schedule", false,
+		"Fetch feeds even if their next scheduled fetch hasn't arrived")
 	fetchCmd.Flags().IntVar(&fetchConcurrency, "concurrency", config.DefaultConcurrency, "Maximum concurrent fetches")
 	fetchCmd.Flags().DurationVar(&fetchMaxAge, "max-age", 0, "Skip feeds fetched within this duration")
 	fetchCmd.Flags().BoolVar(&fetchRemoveMissing, "remove-missing", false, "Delete feeds not in list (file mode only)")
-	fetchCmd.Flags().StringVar(&fetchFormat, "format", "", "Feed list format (opml or text)")
+	fetchCmd.Flags().StringVar(&fetchFormat, "format", "", "Feed list format (opml, text, yaml or json)")
 	fetchCmd.Flags().StringVar(&fetchFilename, "filename", "", "Feed list filename")
 	fetchCmd.Flags().BoolVar(&fetchWithUnfurl, "with-unfurl", false,
 		"Run unfurl operations in parallel with feed fetching")
@@ -85,6 +96,16 @@ func setupGracefulShutdown() (context.Context, context.CancelFunc) {
 	return ctx, cancel
 }
 
+// loadHTTPOverrides resolves the http_overrides from the config file,
+// reading any secrets they refer to.
+func loadHTTPOverrides(cfg *config.Config) (*httpoverride.Set, error) {
+	overrides, err := httpoverride.New(cfg.HTTPOverrides)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load http overrides: %w", err)
+	}
+	return overrides, nil
+}
+
 func runFetch(_ *cobra.Command, args []string) error {
 	cfg := GetConfig()
 
@@ -101,8 +122,19 @@ func runFetch(_ *cobra.Command, args []string) error {
 		return err
 	}
 
+	engine, err := loadRules(cfg)
+	if err != nil {
+		return err
+	}
+	overrides, err := loadHTTPOverrides(cfg)
+	if err != nil {
+		return err
+	}
+
 	// Create orchestrator
 	orchestrator := fetcher.NewOrchestrator(db, cfg)
+	orchestrator.SetRules(engine)
+	orchestrator.SetOverrides(overrides)
 
 	// Set up graceful shutdown
 	ctx, cancel := setupGracefulShutdown()
@@ -121,13 +153,14 @@ func runFetch(_ *cobra.Command, args []string) error {
 
 	// Create fetch options
 	opts := fetcher.FetchOptions{
-		Timeout:       fetchTimeout,
-		MaxItems:      maxItems,
-		MaxAge:        fetchMaxAge,
-		Force:         fetchForce,
-		Concurrency:   concurrency,
-		WithUnfurl:    withUnfurl,
-		RemoveMissing: fetchRemoveMissing,
+		Timeout:        fetchTimeout,
+		MaxItems:       maxItems,
+		MaxAge:         fetchMaxAge,
+		Force:          fetchForce,
+		IgnoreSchedule: fetchIgnoreSched,
+		Concurrency:    concurrency,
+		WithUnfurl:     withUnfurl,
+		RemoveMissing:  fetchRemoveMissing,
 	}
 
 	// Determine fetch mode and execute
@@ -148,6 +181,7 @@ func runSingleURLFetch(
 	opts fetcher.FetchOptions, cfg *config.Config,
 ) error {
 	result, err := orchestrator.FetchSingle(ctx, feedURL, opts)
+	recordFetchSummary(fetcher.ProcessResults([]*fetcher.FetchResult{result}))
 	if err != nil {
 		return fmt.Errorf("failed to fetch feed: %w", err)
 	}
@@ -181,6 +215,7 @@ func runFileFetch(
 	summary := fetcher.ProcessResults(results)
 	summary.Mode = "file"
 	summary.Print(cfg)
+	recordFetchSummary(summary)
 
 	return nil
 }
@@ -198,6 +233,7 @@ func runDatabaseFetch(
 	summary := fetcher.ProcessResults(results)
 	summary.Mode = "database"
 	summary.Print(cfg)
+	recordFetchSummary(summary)
 
 	return nil
 }
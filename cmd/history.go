@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/spf13/cobra"
+)
+
+const defaultHistoryLimit = 20
+
+var (
+	historyFormat     string
+	historyLimit      int
+	historySince      string
+	historyErrorsOnly bool
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [URL]",
+	Short: "Show the fetch log for a feed or for every feed",
+	Long: `Lists recorded fetch attempts, newest first: when each started, how long it
+took, the HTTP status and response size, whether the server answered 304 Not
+Modified, how many items were new, updated or archived, and any error.
+
+Every fetch of a feed is recorded, including failures and fetches skipped
+because the feed's host is rate limiting requests. Feeds skipped because they
+are disabled or not yet due are not fetched, so they aren't recorded. purge
+deletes attempts older than purge.fetch_log_max_age (default: 90d).
+
+Examples:
+  feedspool history                                   # Latest attempts for every feed
+  feedspool history https://example.com/feed.xml      # Latest attempts for one feed
+  feedspool history --since 7d --errors               # Failures in the past week
+  feedspool history --limit 0 --format json <url>     # Every attempt, as JSON`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runHistory,
+}
+
+// FetchAttempt is the JSON representation of a fetch log entry.
+type FetchAttempt struct {
+	URL           string    `json:"url"`
+	StartedAt     time.Time `json:"startedAt"`
+	DurationMS    int64     `json:"durationMs"`
+	StatusCode    int       `json:"statusCode,omitempty"`
+	Bytes         int64     `json:"bytes"`
+	Cached        bool      `json:"cached"`
+	NewItems      int       `json:"newItems"`
+	UpdatedItems  int       `json:"updatedItems"`
+	ArchivedItems int       `json:"archivedItems"`
+	Error         string    `json:"error,omitempty"`
+}
+
+func init() {
+	historyCmd.Flags().StringVar(&historyFormat, "format", formatTable, "Output format (table|json)")
+	historyCmd.Flags().IntVar(&historyLimit, "limit", defaultHistoryLimit, "Maximum attempts to show (0 for all)")
+	historyCmd.Flags().StringVar(&historySince, "since", "", "Only show attempts within this long (e.g., 7d, 48h)")
+	historyCmd.Flags().BoolVar(&historyErrorsOnly, "errors", false, "Only show failed attempts")
+	rootCmd.AddCommand(historyCmd)
+}
+
+func runHistory(_ *cobra.Command, args []string) error {
+	var feedURL string
+	if len(args) > 0 {
+		feedURL = args[0]
+	}
+
+	var since time.Time
+	if historySince != "" {
+		duration, err := database.ParseDuration(historySince)
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		since = time.Now().Add(-duration)
+	}
+
+	db, err := openFeedsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Failures are filtered here, so the limit counts only what is shown
+	limit := historyLimit
+	if historyErrorsOnly {
+		limit = 0
+	}
+	entries, err := db.GetFetchLog(feedURL, since, limit)
+	if err != nil {
+		return err
+	}
+
+	attempts := make([]FetchAttempt, 0, len(entries))
+	for _, entry := range entries {
+		if historyErrorsOnly && entry.Error == "" {
+			continue
+		}
+		if historyLimit > 0 && len(attempts) >= historyLimit {
+			break
+		}
+		attempts = append(attempts, FetchAttempt{
+			URL:           entry.FeedURL,
+			StartedAt:     entry.StartedAt,
+			DurationMS:    entry.Duration.Milliseconds(),
+			StatusCode:    entry.StatusCode,
+			Bytes:         entry.Bytes,
+			Cached:        entry.Cached,
+			NewItems:      entry.NewItems,
+			UpdatedItems:  entry.UpdatedItems,
+			ArchivedItems: entry.ArchivedItems,
+			Error:         entry.Error,
+		})
+	}
+
+	format := historyFormat
+	if format == formatTable && GetConfig().JSON {
+		format = formatJSON
+	}
+	switch format {
+	case formatJSON:
+		return outputFeedsJSON(attempts)
+	case formatTable:
+		return outputHistoryTable(attempts, feedURL == "")
+	default:
+		return fmt.Errorf("unknown format: %s", historyFormat)
+	}
+}
+
+func outputHistoryTable(attempts []FetchAttempt, showFeed bool) error {
+	if len(attempts) == 0 {
+		fmt.Println("No fetch attempts recorded")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := "STARTED\tSTATUS\tTIME\tBYTES\tNEW\tUPD\tARCH\tERROR"
+	if showFeed {
+		header += "\tFEED"
+	}
+	fmt.Fprintln(w, header)
+
+	for i := range attempts {
+		attempt := &attempts[i]
+		status := "-"
+		if attempt.StatusCode != 0 {
+			status = strconv.Itoa(attempt.StatusCode)
+		}
+		message := attempt.Error
+		if len(message) > 60 {
+			message = message[:57] + "..."
+		}
+		line := fmt.Sprintf("%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s",
+			attempt.StartedAt.Local().Format("2006-01-02 15:04:05"), status,
+			(time.Duration(attempt.DurationMS) * time.Millisecond).String(), attempt.Bytes,
+			attempt.NewItems, attempt.UpdatedItems, attempt.ArchivedItems, message)
+		if showFeed {
+			line += "\t" + attempt.URL
+		}
+		fmt.Fprintln(w, line)
+	}
+
+	return w.Flush()
+}
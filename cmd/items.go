@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/spf13/cobra"
+)
+
+var (
+	itemsFormat      string
+	itemsUnread      bool
+	itemsStarred     bool
+	itemsHighlighted bool
+	itemsTag         string
+	itemsFeed        string
+	itemsBefore      string
+	itemsLimit       int
+	itemsAll         bool
+)
+
+var itemsCmd = &cobra.Command{
+	Use:   "items",
+	Short: "List items and mark them read or starred",
+	Long: `Commands for the read and starred state of items.
+
+New items start unread, unless a mark-read rule matches them (see
+'feedspool rules'). Marking items selects them by ID (as shown by
+'feedspool items list'), by --feed, by --before a date, or all of them with
+--all. Selectors combine: --feed with --before marks that feed's older items.
+
+Examples:
+  feedspool items list --unread                       # Unread items, newest first
+  feedspool items list --starred --format json        # Starred items as JSON
+  feedspool items list --tag kubernetes               # Items a rule tagged kubernetes
+  feedspool items read 42 43                          # Mark items 42 and 43 read
+  feedspool items read --feed https://example.com/feed.xml
+  feedspool items read --before 2024-06-01T00:00:00Z  # Catch up on old items
+  feedspool items star 42                             # Star item 42`,
+}
+
+var itemsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List items with their read and starred state",
+	Args:  cobra.NoArgs,
+	RunE:  runItemsList,
+}
+
+var itemsReadCmd = newItemsMarkCmd("read [ID...]", "Mark items read",
+	func(db *database.DB, filter *database.ItemFilter) (int64, error) {
+		return db.SetItemsRead(filter, true)
+	},
+	"Marked %d item(s) read\n")
+
+var itemsUnreadCmd = newItemsMarkCmd("unread [ID...]", "Mark items unread",
+	func(db *database.DB, filter *database.ItemFilter) (int64, error) {
+		return db.SetItemsRead(filter, false)
+	},
+	"Marked %d item(s) unread\n")
+
+var itemsStarCmd = newItemsMarkCmd("star [ID...]", "Star items",
+	func(db *database.DB, filter *database.ItemFilter) (int64, error) {
+		return db.SetItemsStarred(filter, true)
+	},
+	"Starred %d item(s)\n")
+
+var itemsUnstarCmd = newItemsMarkCmd("unstar [ID...]", "Unstar items",
+	func(db *database.DB, filter *database.ItemFilter) (int64, error) {
+		return db.SetItemsStarred(filter, false)
+	},
+	"Unstarred %d item(s)\n")
+
+func init() {
+	itemsListCmd.Flags().StringVar(&itemsFormat, "format", formatTable, "Output format (table|json|csv)")
+	itemsListCmd.Flags().BoolVar(&itemsUnread, "unread", false, "Only list unread items")
+	itemsListCmd.Flags().BoolVar(&itemsStarred, "starred", false, "Only list starred items")
+	itemsListCmd.Flags().BoolVar(&itemsHighlighted, "highlighted", false, "Only list items highlighted by a rule")
+	itemsListCmd.Flags().StringVar(&itemsTag, "tag", "", "Only list items tagged by a rule with this tag")
+	itemsListCmd.Flags().StringVar(&itemsFeed, "feed", "", "Only list items from this feed URL")
+	itemsListCmd.Flags().StringVar(&itemsBefore, "before", "", "Only list items published before this date (RFC3339)")
+	itemsListCmd.Flags().IntVar(&itemsLimit, "limit", defaultSearchLimit, "Maximum items to return (0 for all)")
+	itemsCmd.AddCommand(itemsListCmd)
+
+	for _, cmd := range []*cobra.Command{itemsReadCmd, itemsUnreadCmd, itemsStarCmd, itemsUnstarCmd} {
+		cmd.Flags().StringVar(&itemsFeed, "feed", "", "Select items from this feed URL")
+		cmd.Flags().StringVar(&itemsBefore, "before", "", "Select items published before this date (RFC3339)")
+		cmd.Flags().BoolVar(&itemsAll, "all", false, "Select every item")
+		itemsCmd.AddCommand(cmd)
+	}
+
+	rootCmd.AddCommand(itemsCmd)
+}
+
+// newItemsMarkCmd creates a command that changes the state of the selected items.
+func newItemsMarkCmd(
+	use, short string, mark func(*database.DB, *database.ItemFilter) (int64, error), message string,
+) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		RunE: func(_ *cobra.Command, args []string) error {
+			filter, err := itemsFilterFromFlags(args)
+			if err != nil {
+				return err
+			}
+
+			db, err := openFeedsDB()
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			changed, err := mark(db, filter)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf(message, changed)
+			return nil
+		},
+	}
+}
+
+// itemsFilterFromFlags builds the item selection for a mark command. Marking
+// every item takes an explicit --all.
+func itemsFilterFromFlags(args []string) (*database.ItemFilter, error) {
+	filter := &database.ItemFilter{FeedURL: itemsFeed}
+
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid item ID: %s", arg)
+		}
+		filter.IDs = append(filter.IDs, id)
+	}
+
+	if itemsBefore != "" {
+		before, err := time.Parse(time.RFC3339, itemsBefore)
+		if err != nil {
+			return nil, fmt.Errorf("invalid before date: %w", err)
+		}
+		filter.Before = before
+	}
+
+	selected := len(filter.IDs) > 0 || filter.FeedURL != "" || !filter.Before.IsZero()
+	if selected == itemsAll {
+		return nil, errors.New("specify item IDs, --feed or --before, or --all")
+	}
+
+	return filter, nil
+}
+
+func runItemsList(_ *cobra.Command, _ []string) error {
+	filter := &database.ItemFilter{
+		FeedURL:     itemsFeed,
+		Unread:      itemsUnread,
+		Starred:     itemsStarred,
+		Highlighted: itemsHighlighted,
+		Tag:         itemsTag,
+		Limit:       itemsLimit,
+	}
+	if itemsBefore != "" {
+		before, err := time.Parse(time.RFC3339, itemsBefore)
+		if err != nil {
+			return fmt.Errorf("invalid before date: %w", err)
+		}
+		filter.Before = before
+	}
+
+	db, err := openFeedsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	items, err := db.ListItems(filter)
+	if err != nil {
+		return err
+	}
+
+	switch format := determineOutputFormat(GetConfig(), itemsFormat); format {
+	case formatJSON:
+		return outputFeedsJSON(items)
+	case "csv":
+		return outputItemsCSV(items)
+	case formatTable:
+		return outputItemsTable(items)
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// itemState summarizes an item's read and starred flags for table output.
+func itemState(item *database.Item) string {
+	state := "unread"
+	if item.Read {
+		state = "read"
+	}
+	if item.Starred {
+		state += " ★"
+	}
+	if item.Highlighted {
+		state += " !"
+	}
+	return state
+}
+
+func outputItemsTable(items []*database.Item) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDATE\tSTATE\tTITLE\tLINK")
+	fmt.Fprintln(w, "--\t----\t-----\t-----\t----")
+
+	for _, item := range items {
+		title := item.Title
+		if len(title) > 60 {
+			title = title[:57] + "..."
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
+			item.ID, item.PublishedDate.Format("2006-01-02 15:04"), itemState(item), title, item.Link)
+	}
+
+	return w.Flush()
+}
+
+func outputItemsCSV(items []*database.Item) error {
+	w := csv.NewWriter(os.Stdout)
+
+	if err := w.Write([]string{"ID", "Date", "Read", "Starred", "Highlighted", "Title", "Link", "Feed"}); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		record := []string{
+			strconv.FormatInt(item.ID, 10),
+			item.PublishedDate.Format(time.RFC3339),
+			strconv.FormatBool(item.Read),
+			strconv.FormatBool(item.Starred),
+			strconv.FormatBool(item.Highlighted),
+			item.Title,
+			item.Link,
+			item.FeedURL,
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/fetcher"
+	"github.com/lmorchard/feedspool-go/internal/metrics"
+	"github.com/lmorchard/feedspool-go/internal/server"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// metricsPath is where the server exposes metrics for Prometheus to scrape.
+const metricsPath = "/metrics"
+
+// daemonExportName is what the daemon exports its jobs' metrics under, all
+// of them being recorded in the one process.
+const daemonExportName = "daemon"
+
+// instrumentCommand wraps a command so its run is recorded in the metrics,
+// which are then exported as configured.
+func instrumentCommand(
+	name string, run func(*cobra.Command, []string) error,
+) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		started := time.Now()
+		err := run(cmd, args)
+		recordRun(name, started, err)
+		exportMetrics(GetConfig(), name)
+		return err
+	}
+}
+
+// instrumentJob wraps a daemon job the same way, exporting the metrics of
+// every job together.
+func instrumentJob(
+	cfg *config.Config, name string, run func(context.Context) error,
+) func(context.Context) error {
+	return func(ctx context.Context) error {
+		started := time.Now()
+		err := run(ctx)
+		recordRun(name, started, err)
+		exportMetrics(cfg, daemonExportName)
+		return err
+	}
+}
+
+// recordRun records how long a run took and whether it succeeded.
+func recordRun(command string, started time.Time, err error) {
+	labels := metrics.Labels{"command": command}
+	metrics.Default.Set("feedspool_run_duration_seconds", "How long the last run of each command took.",
+		labels, time.Since(started).Seconds())
+
+	result := "success"
+	if err != nil {
+		result = "error"
+	} else {
+		metrics.Default.Set("feedspool_run_last_success_timestamp_seconds",
+			"When each command last finished without an error.", labels, float64(time.Now().Unix()))
+	}
+	metrics.Default.Add("feedspool_runs_total", "Runs of each command, by result.",
+		metrics.Labels{"command": command, "result": result}, 1)
+}
+
+// recordFetchSummary counts the feeds and items of a fetch run.
+func recordFetchSummary(summary fetcher.FetchSummary) {
+	feeds := map[string]int{
+		"success":  summary.Successful,
+		"cached":   summary.Cached,
+		"error":    summary.Errors,
+		"disabled": summary.Disabled,
+		"parked":   summary.Parked,
+	}
+	for result, count := range feeds {
+		metrics.Default.Add("feedspool_fetch_feeds_total", "Feeds considered for fetching, by result.",
+			metrics.Labels{"result": result}, float64(count))
+	}
+	metrics.Default.Add("feedspool_fetch_items_total", "Items stored by fetches, new or not.",
+		nil, float64(summary.TotalItems))
+	metrics.Default.Add("feedspool_fetch_new_items_total", "Items fetched for the first time.",
+		nil, float64(summary.NewItems))
+}
+
+// registerDatabaseMetrics adds the database size, read whenever metrics are
+// written.
+func registerDatabaseMetrics(cfg *config.Config) {
+	metrics.Default.GaugeFunc("feedspool_database_size_bytes", "Size of the database and its write-ahead log.",
+		func() float64 {
+			var size int64
+			for _, path := range []string{cfg.Database, cfg.Database + "-wal"} {
+				if info, err := os.Stat(path); err == nil {
+					size += info.Size()
+				}
+			}
+			return float64(size)
+		})
+}
+
+// exportMetrics writes the metrics to a textfile next to metrics.textfile and
+// pushes them to metrics.push_url, when configured, both under the name of the
+// exporting process. Each process only holds its own metrics, so exporting
+// them all to one place would have every run replace the last one's.
+// Failures are logged, not returned, so they never fail the run being
+// measured.
+func exportMetrics(cfg *config.Config, name string) {
+	if cfg.Metrics.Textfile == "" && cfg.Metrics.PushURL == "" {
+		return
+	}
+	registerDatabaseMetrics(cfg)
+
+	if cfg.Metrics.Textfile != "" {
+		path := metricsTextfile(cfg.Metrics.Textfile, name)
+		if err := metrics.Default.WriteFile(path, metrics.Labels{"command": name}); err != nil {
+			logrus.WithError(err).Warn("Failed to export metrics")
+		}
+	}
+	if cfg.Metrics.PushURL != "" {
+		if err := metrics.Default.Push(metricsPushURL(cfg.Metrics.PushURL, name), cfg.Timeout); err != nil {
+			logrus.WithError(err).Warn("Failed to export metrics")
+		}
+	}
+}
+
+// metricsTextfile returns the textfile a process exports to: metrics.textfile
+// with the process name before its .prom extension, which node_exporter
+// requires.
+func metricsTextfile(textfile, name string) string {
+	return strings.TrimSuffix(textfile, ".prom") + "." + name + ".prom"
+}
+
+// metricsPushURL returns the Pushgateway URL a process pushes to, grouped by
+// the process name.
+func metricsPushURL(pushURL, name string) string {
+	return strings.TrimSuffix(pushURL, "/") + "/command/" + url.PathEscape(name)
+}
+
+// mountMetricsHandler adds the metrics endpoint to a server config.
+func mountMetricsHandler(cfg *config.Config, serveConfig *server.Config) {
+	registerDatabaseMetrics(cfg)
+	if serveConfig.Handlers == nil {
+		serveConfig.Handlers = make(map[string]http.Handler)
+	}
+	serveConfig.Handlers[metricsPath] = metrics.Default.Handler()
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/lmorchard/feedspool-go/internal/config"
 	"github.com/lmorchard/feedspool-go/internal/database"
 	"github.com/lmorchard/feedspool-go/internal/feedlist"
+	"github.com/lmorchard/feedspool-go/internal/media"
 	"github.com/spf13/cobra"
 )
 
@@ -18,16 +19,18 @@ var (
 	purgeFilename string
 	purgeNoVacuum bool
 	purgeMinItems int
+	purgeLogAge   string
 )
 
 var purgeCmd = &cobra.Command{
 	Use:   "purge",
 	Short: "Purge archived items and cleanup unsubscribed feeds",
-	Long: `Purge command performs two cleanup operations:
+	Long: `Purge command performs these cleanup operations:
 
 Age-based purging:
   Deletes archived items from the database that are older than the specified age.
-  Uses --age flag or purge.max_age from config (default: 30d).
+  Uses --age flag or purge.max_age from config (default: 30d). Feeds with a
+  retention setting in a YAML, JSON or OPML feed list keep their items that long instead.
 
   Minimum items protection:
   Use --min-items to ensure at least N items remain per feed regardless of age.
@@ -37,6 +40,14 @@ Feed list cleanup (optional):
   When --format and filename are specified (or configured), removes any feeds
   (and their items) from the database that are NOT in the specified feed list.
 
+Fetch log cleanup:
+  Deletes recorded fetch attempts older than --fetch-log-age or
+  purge.fetch_log_max_age (default: 90d). Use 0 to keep them forever.
+
+Cached media cleanup:
+  When the media cache is enabled, deletes cached images that no remaining
+  item or unfurled page references.
+
 Examples:
   feedspool purge                             # Delete old items using config max_age
   feedspool purge --age 30d                   # Delete items older than 30 days
@@ -51,8 +62,10 @@ func init() {
 	purgeCmd.Flags().IntVar(&purgeMinItems, "min-items", -1,
 		"Minimum items to keep per feed regardless of age (-1 = use config default, 0 = no minimum)")
 	purgeCmd.Flags().BoolVar(&purgeDryRun, "dry-run", false, "Preview what would be deleted without actually deleting")
-	purgeCmd.Flags().StringVar(&purgeFormat, "format", "", "Feed list format for cleanup (opml or text)")
+	purgeCmd.Flags().StringVar(&purgeFormat, "format", "", "Feed list format for cleanup (opml, text, yaml or json)")
 	purgeCmd.Flags().StringVar(&purgeFilename, "filename", "", "Feed list filename for cleanup")
+	purgeCmd.Flags().StringVar(&purgeLogAge, "fetch-log-age", "",
+		"Delete fetch log entries older than this (e.g., 90d; 0 = keep forever)")
 	purgeCmd.Flags().BoolVar(&purgeNoVacuum, "no-vacuum", false, "Skip running VACUUM on the database")
 	rootCmd.AddCommand(purgeCmd)
 }
@@ -88,6 +101,19 @@ func runPurge(_ *cobra.Command, _ []string) error {
 		return err
 	}
 
+	logAge := purgeLogAge
+	if logAge == "" {
+		logAge = cfg.Purge.FetchLogAge
+	}
+	if err := runFetchLogPurge(cfg, db, logAge); err != nil {
+		return err
+	}
+
+	// Delete cached images nothing references any more
+	if !purgeDryRun {
+		runMediaPurge(cfg, db)
+	}
+
 	// Run VACUUM unless skipped via flag or config
 	shouldSkipVacuum := purgeNoVacuum || cfg.Purge.SkipVacuum
 	if !shouldSkipVacuum && !purgeDryRun {
@@ -255,6 +281,82 @@ func executeFeedDeletion(cfg *config.Config, db *database.DB, feedsToDelete []st
 	return nil
 }
 
+// runMediaPurge deletes cached images no longer referenced by any item or
+// metadata, when the media cache is enabled.
+func runMediaPurge(cfg *config.Config, db *database.DB) {
+	cache := media.New(cfg.Media, db, cfg.Timeout)
+	if cache == nil {
+		return
+	}
+
+	deleted, err := cache.CollectGarbage()
+	if err != nil {
+		fmt.Printf("Warning: Failed to clean up cached media: %v\n", err)
+		return
+	}
+
+	if cfg.JSON {
+		result := map[string]interface{}{
+			"mode":    "media",
+			"dryRun":  false,
+			"deleted": deleted,
+		}
+		jsonData, _ := json.Marshal(result)
+		fmt.Println(string(jsonData))
+	} else if deleted > 0 {
+		fmt.Printf("Deleted %d unreferenced cached media files\n", deleted)
+	}
+}
+
+// fetchLogCutoff returns the time before which fetch log entries are
+// deleted, or false when the log is kept forever.
+func fetchLogCutoff(ageStr string) (time.Time, bool, error) {
+	if ageStr == "" || ageStr == "0" {
+		return time.Time{}, false, nil
+	}
+	duration, err := database.ParseDuration(ageStr)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid fetch log age: %w", err)
+	}
+	return time.Now().Add(-duration), true, nil
+}
+
+// runFetchLogPurge deletes fetch attempts older than the given age.
+func runFetchLogPurge(cfg *config.Config, db *database.DB, ageStr string) error {
+	cutoffTime, ok, err := fetchLogCutoff(ageStr)
+	if err != nil || !ok {
+		return err
+	}
+
+	var deleted int64
+	if purgeDryRun {
+		deleted, err = db.CountFetchLogBefore(cutoffTime)
+	} else {
+		deleted, err = db.DeleteFetchLogBefore(cutoffTime)
+	}
+	if err != nil {
+		return err
+	}
+
+	if cfg.JSON {
+		result := map[string]interface{}{
+			"mode":       "fetchlog",
+			"dryRun":     purgeDryRun,
+			"cutoffDate": cutoffTime.Format(time.RFC3339),
+			"deleted":    deleted,
+		}
+		jsonData, _ := json.Marshal(result)
+		fmt.Println(string(jsonData))
+	} else if purgeDryRun {
+		fmt.Printf("Dry run mode - would delete %d fetch log entries older than %s\n",
+			deleted, cutoffTime.Format("2006-01-02"))
+	} else {
+		fmt.Printf("Deleted %d fetch log entries older than %s\n", deleted, cutoffTime.Format("2006-01-02"))
+	}
+
+	return nil
+}
+
 func runAgePurge(cfg *config.Config, db *database.DB, minItems int) error {
 	// Use --age flag if provided, otherwise use config max_age, fallback to 30d
 	ageStr := purgeAge
@@ -293,14 +395,9 @@ func runAgePurge(cfg *config.Config, db *database.DB, minItems int) error {
 		return nil
 	}
 
-	var deleted int64
-	if minItems > 0 {
-		deleted, err = db.DeleteArchivedItemsWithMinimum(cutoffTime, minItems)
-	} else {
-		deleted, err = db.DeleteArchivedItems(cutoffTime)
-	}
+	deleted, err := deleteArchivedItems(db, cutoffTime, minItems)
 	if err != nil {
-		return fmt.Errorf("failed to delete archived items: %w", err)
+		return err
 	}
 
 	// Clean up orphaned metadata after deleting items
@@ -332,6 +429,22 @@ func runAgePurge(cfg *config.Config, db *database.DB, minItems int) error {
 	return nil
 }
 
+// deleteArchivedItems deletes archived items older than the cutoff, keeping at
+// least minItems per feed when minItems is positive.
+func deleteArchivedItems(db *database.DB, cutoffTime time.Time, minItems int) (int64, error) {
+	var deleted int64
+	var err error
+	if minItems > 0 {
+		deleted, err = db.DeleteArchivedItemsWithMinimum(cutoffTime, minItems)
+	} else {
+		deleted, err = db.DeleteArchivedItems(cutoffTime)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete archived items: %w", err)
+	}
+	return deleted, nil
+}
+
 func determinePurgeFormatAndFilename(
 	cfg *config.Config, format, filename string,
 ) (resultFormat, resultFilename string, err error) {
@@ -357,7 +470,11 @@ func validatePurgeFormat(format string) (feedlist.Format, error) {
 		return feedlist.FormatOPML, nil
 	case string(feedlist.FormatText):
 		return feedlist.FormatText, nil
+	case string(feedlist.FormatYAML):
+		return feedlist.FormatYAML, nil
+	case string(feedlist.FormatJSON):
+		return feedlist.FormatJSON, nil
 	default:
-		return "", fmt.Errorf("unsupported format: %s (must be 'opml' or 'text')", format)
+		return "", fmt.Errorf("unsupported format: %s (must be 'opml', 'text', 'yaml' or 'json')", format)
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	"github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/feedlist"
 	"github.com/lmorchard/feedspool-go/internal/renderer"
 	"github.com/spf13/cobra"
 )
@@ -23,6 +24,12 @@ var (
 	renderMinItemsPerFeed int
 	renderMaxItemsPerFeed int
 	renderFeedsPerPage    int
+	renderBaseURL         string
+	renderTags            []string
+	renderRiver           bool
+	renderRiverPaging     string
+	renderItemsPerPage    int
+	renderNoCollapse      bool
 )
 
 var renderCmd = &cobra.Command{
@@ -44,18 +51,45 @@ Item count per feed:
 Feed filtering:
   --feeds feeds.txt --format text   # Use feeds from text file
   --feeds feeds.opml --format opml  # Use feeds from OPML file
+  --tag golang --tag security       # Only feeds tagged golang or security
+                                    # (tags come from OPML folders, see 'subscribe --tag')
+
+River view:
+  --river                           # List items across feeds, newest first, under day headings
+  --river-paging day                # One page per day (default: items)
+  --items-per-page 50               # River items per page (default: 100, 0 = no pagination)
+
+Duplicates:
+  --no-collapse                     # Show every copy of items syndicated through several feeds
 
 Customization:
   --templates ./custom-templates    # Use custom templates directory
   --assets ./custom-assets          # Use custom static assets directory
   --output ./site                   # Output directory (default: ./build)
   --clean                          # Remove output directory before building
+  --base-url https://feeds.example.com  # Public site URL, for absolute links in feeds
 
 The command generates an index.html file with all matching feeds and their items
 grouped underneath. Static assets (CSS, JS) are copied to the output directory.
 
+The same items are also written as feeds for other readers: feed.atom, feed.rss
+and feed.json aggregate every rendered feed, and feeds/<id>.atom, .rss and .json
+hold each source feed, next to feeds/<id>.html.
+
+With --river, each index instead lists the items of every rendered feed in
+time order, attributed to their feeds, and loads its pages from river/page-N.html.
+
+Items grouped as duplicates by fetch or 'feedspool dedupe' are shown once, under
+the feed that published them first, with "also in" links to the other copies.
+This needs dedupe.enabled in the config; --no-collapse turns it off. Feeds keep
+copies when needed to show --min-items-per-feed items.
+
+Feeds with tags also get a section per tag in tags/<slug>/: an index.html with
+its own pagination and unread counts, and the tag's river as feed.atom, feed.rss
+and feed.json. Every index links to the other sections.
+
 Use 'feedspool init --extract-templates' to extract default templates for customization.`,
-	RunE: runRender,
+	RunE: instrumentCommand("render", runRender),
 }
 
 func init() {
@@ -72,8 +106,17 @@ func init() {
 	renderCmd.Flags().StringVar(&renderTemplates, "templates", "", "Custom templates directory")
 	renderCmd.Flags().StringVar(&renderAssets, "assets", "", "Custom assets directory")
 	renderCmd.Flags().StringVar(&renderFeeds, "feeds", "", "Feed list file")
-	renderCmd.Flags().StringVar(&renderFormat, "format", defaultFormat, "Feed list format (opml or text)")
+	renderCmd.Flags().StringVar(&renderFormat, "format", defaultFormat, "Feed list format (opml, text, yaml or json)")
 	renderCmd.Flags().BoolVar(&renderClean, "clean", false, "Remove output directory before building")
+	renderCmd.Flags().StringVar(&renderBaseURL, "base-url", "", "Public URL of the site, for self links in generated feeds")
+	renderCmd.Flags().StringArrayVar(&renderTags, "tag", nil, "Only render feeds with this tag; repeatable")
+	renderCmd.Flags().BoolVar(&renderRiver, "river", false,
+		"Render a river of items in time order instead of grouping them by feed")
+	renderCmd.Flags().StringVar(&renderRiverPaging, "river-paging", "", "River pagination: items or day")
+	renderCmd.Flags().IntVar(&renderItemsPerPage, "items-per-page", -1,
+		"River items per page (-1 = use config default, 0 = disable pagination)")
+	renderCmd.Flags().BoolVar(&renderNoCollapse, "no-collapse", false,
+		"Show every copy of duplicate items instead of collapsing them")
 
 	// Note: Config file values are loaded through the Config struct, not viper bindings
 
@@ -111,6 +154,14 @@ func buildRenderConfig(cfg *config.Config) *renderer.WorkflowConfig {
 		Format:          cfg.FeedList.Format,
 		Database:        cfg.Database,
 		Clean:           cfg.Render.DefaultClean,
+		BaseURL:         cfg.Render.BaseURL,
+		River:           cfg.Render.River,
+		RiverPaging:     cfg.Render.RiverPaging,
+		ItemsPerPage:    cfg.Render.ItemsPerPage,
+		Collapse:        cfg.Dedupe.Enabled,
+	}
+	if cfg.Media.Enabled {
+		config.MediaDir = cfg.Media.Dir
 	}
 
 	// Override with command line flags if provided
@@ -150,11 +201,37 @@ func buildRenderConfig(cfg *config.Config) *renderer.WorkflowConfig {
 	if renderClean {
 		config.Clean = renderClean
 	}
+	if renderBaseURL != "" {
+		config.BaseURL = renderBaseURL
+	}
+	if len(renderTags) > 0 {
+		config.Tags = renderTags
+	}
+	if renderRiver {
+		config.River = renderRiver
+	}
+	if renderRiverPaging != "" {
+		config.RiverPaging = renderRiverPaging
+	}
+	if config.RiverPaging == "" {
+		config.RiverPaging = renderer.RiverPagingItems
+	}
+	if renderItemsPerPage >= 0 {
+		config.ItemsPerPage = renderItemsPerPage
+	}
+	if renderNoCollapse {
+		config.Collapse = false
+	}
 
 	return config
 }
 
 func validateRenderConfig(config *renderer.WorkflowConfig) error {
+	if config.RiverPaging != renderer.RiverPagingItems && config.RiverPaging != renderer.RiverPagingDay {
+		return fmt.Errorf("unsupported river paging: %s (must be '%s' or '%s')",
+			config.RiverPaging, renderer.RiverPagingItems, renderer.RiverPagingDay)
+	}
+
 	return validateRenderParams(config.MaxAge, config.Start, config.End,
 		config.OutputDir, config.TemplatesDir, config.AssetsDir,
 		config.FeedsFile, config.Format)
@@ -187,8 +264,10 @@ func validateRenderParams(maxAge, start, end, outputDir, templatesDir, assetsDir
 		}
 
 		// Validate format
-		if format != "opml" && format != "text" {
-			return fmt.Errorf("unsupported format: %s (must be 'opml' or 'text')", format)
+		switch feedlist.Format(format) {
+		case feedlist.FormatOPML, feedlist.FormatText, feedlist.FormatYAML, feedlist.FormatJSON:
+		default:
+			return fmt.Errorf("unsupported format: %s (must be 'opml', 'text', 'yaml' or 'json')", format)
 		}
 	}
 
@@ -24,15 +24,18 @@ var rootCmd = &cobra.Command{
 Features:
 • Unified feed fetching from single URLs, OPML files, or text lists
 • Subscribe/unsubscribe commands with RSS/Atom autodiscovery
-• Export database feeds to OPML or text formats
+• Export database feeds to OPML, text, YAML or JSON formats
 • Feed list cleanup and age-based purging
 • Concurrent fetching with HTTP caching
 • Configurable defaults for streamlined workflows
 
 Use 'feedspool <command> --help' for detailed command information.`,
-	PersistentPreRun: func(_ *cobra.Command, _ []string) {
-		initConfig()
+	PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+		if err := initConfig(); err != nil {
+			return err
+		}
 		setupLogging()
+		return nil
 	},
 }
 
@@ -56,7 +59,7 @@ func init() {
 	_ = viper.BindPFlag("json", rootCmd.PersistentFlags().Lookup("json"))
 }
 
-func initConfig() {
+func initConfig() error {
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
 	} else {
@@ -80,6 +83,10 @@ func initConfig() {
 	viper.SetDefault("serve.port", defaultPort)
 	viper.SetDefault("serve.dir", defaultOutputDir)
 
+	// Daemon command defaults
+	viper.SetDefault("daemon.fetch_interval", config.DefaultFetchInterval)
+	viper.SetDefault("daemon.purge_interval", config.DefaultPurgeInterval)
+
 	// Init command defaults
 	viper.SetDefault("init.templates_dir", "./templates")
 	viper.SetDefault("init.assets_dir", "./assets")
@@ -101,7 +108,12 @@ func initConfig() {
 		}
 	}
 
-	cfg = config.LoadConfig()
+	loaded, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg = loaded
+	return nil
 }
 
 func setupLogging() {
@@ -124,7 +136,11 @@ func setupLogging() {
 
 func GetConfig() *config.Config {
 	if cfg == nil {
-		cfg = config.LoadConfig()
+		loaded, err := config.LoadConfig()
+		if err != nil {
+			logrus.Fatalf("Failed to load config: %v", err)
+		}
+		cfg = loaded
 	}
 	return cfg
 }
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/rules"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rulesFormat string
+	rulesFeed   string
+)
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Check the item rules from the config file",
+	Long: `Commands for the rules list in the config file.
+
+Rules run on every item as it is fetched, before it is stored. A rule matches
+items from its feed (every feed if unset) whose title, content, author and
+link all match the patterns it sets; invert: true makes it act on the items
+that don't match instead. Actions:
+
+  skip        Don't store the item
+  tag         Add the rule's tag to the item
+  mark-read   Store new items as already read
+  highlight   Flag the item as highlighted
+
+Example config:
+  rules:
+    - name: no-sponsored
+      feed: https://example.com/feed.xml
+      title: /sponsored/i
+      action: skip
+    - name: kubernetes-only
+      feed: https://news.example.com/rss
+      content: /kubernetes/i
+      invert: true
+      action: skip
+
+Examples:
+  feedspool rules test                   # Items each rule would match
+  feedspool rules test no-sponsored      # Items one rule would match
+  feedspool rules test --feed https://example.com/feed.xml`,
+}
+
+var rulesTestCmd = &cobra.Command{
+	Use:   "test [NAME...]",
+	Short: "Show which stored items the rules would match",
+	Long: `Check the rules against the items already in the database, without
+changing them. Lists each matching item with the rule and its action. Give
+rule names to check only those rules.`,
+	RunE: runRulesTest,
+}
+
+func init() {
+	rulesTestCmd.Flags().StringVar(&rulesFormat, "format", formatTable, "Output format (table|json)")
+	rulesTestCmd.Flags().StringVar(&rulesFeed, "feed", "", "Only check items from this feed URL")
+	rulesCmd.AddCommand(rulesTestCmd)
+	rootCmd.AddCommand(rulesCmd)
+}
+
+// loadRules compiles the rules from the config file.
+func loadRules(cfg *config.Config) (*rules.Engine, error) {
+	engine, err := rules.New(cfg.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rules: %w", err)
+	}
+	return engine, nil
+}
+
+// ruleMatch is an item matched by a rule, for rules test output.
+type ruleMatch struct {
+	Rule    string `json:"rule"`
+	Action  string `json:"action"`
+	Tag     string `json:"tag,omitempty"`
+	ItemID  int64  `json:"item_id"`
+	FeedURL string `json:"feed_url"`
+	Title   string `json:"title"`
+	Link    string `json:"link"`
+}
+
+func runRulesTest(_ *cobra.Command, args []string) error {
+	engine, err := loadRules(GetConfig())
+	if err != nil {
+		return err
+	}
+
+	selected, err := selectRules(engine, args)
+	if err != nil {
+		return err
+	}
+	if len(selected) == 0 {
+		fmt.Println("No rules configured")
+		return nil
+	}
+
+	db, err := openFeedsDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	items, err := db.ListItems(&database.ItemFilter{FeedURL: rulesFeed})
+	if err != nil {
+		return err
+	}
+
+	matches := []ruleMatch{}
+	for _, rule := range selected {
+		for _, item := range items {
+			if rule.Matches(item) {
+				match := ruleMatch{
+					Rule:    rule.Name,
+					Action:  rule.Action,
+					ItemID:  item.ID,
+					FeedURL: item.FeedURL,
+					Title:   item.Title,
+					Link:    item.Link,
+				}
+				if rule.Action == rules.ActionTag {
+					match.Tag = rule.Tag
+				}
+				matches = append(matches, match)
+			}
+		}
+	}
+
+	switch format := determineOutputFormat(GetConfig(), rulesFormat); format {
+	case formatJSON:
+		return outputFeedsJSON(matches)
+	case formatTable:
+		return outputRuleMatchesTable(matches, len(selected), len(items))
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// selectRules returns the named rules, or every rule when no names are given.
+func selectRules(engine *rules.Engine, names []string) ([]*rules.Rule, error) {
+	if len(names) == 0 {
+		return engine.Rules(), nil
+	}
+
+	byName := make(map[string]*rules.Rule)
+	for _, rule := range engine.Rules() {
+		byName[rule.Name] = rule
+	}
+
+	selected := []*rules.Rule{}
+	for _, name := range names {
+		rule, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("no rule named %s", name)
+		}
+		selected = append(selected, rule)
+	}
+	return selected, nil
+}
+
+func outputRuleMatchesTable(matches []ruleMatch, ruleCount, itemCount int) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RULE\tACTION\tID\tTITLE\tFEED")
+	fmt.Fprintln(w, "----\t------\t--\t-----\t----")
+
+	for _, match := range matches {
+		action := match.Action
+		if match.Tag != "" {
+			action += " " + match.Tag
+		}
+		title := match.Title
+		if len(title) > 60 {
+			title = title[:57] + "..."
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", match.Rule, action, match.ItemID, title, match.FeedURL)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%d match(es) from %d rule(s) against %d item(s)\n", len(matches), ruleCount, itemCount)
+	return nil
+}
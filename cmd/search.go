@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchFormat string
+	searchFeed   string
+	searchLimit  int
+	searchSince  string
+	searchUntil  string
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search QUERY",
+	Short: "Search items across all feeds",
+	Long: `Search the titles, summaries and content of stored items, including archived ones.
+
+When feedspool is built with FTS5 (go build -tags sqlite_fts5, as the Makefile
+does), QUERY uses SQLite FTS5 syntax and results are ranked by relevance:
+
+  feedspool search golang                 # Items mentioning golang
+  feedspool search '"static site"'        # Exact phrase
+  feedspool search 'sqlite OR postgres'   # Either word
+  feedspool search 'feed*'                # Prefix match
+
+Without FTS5, every word of QUERY must appear somewhere in the item and
+results are listed newest first.
+
+Examples:
+  feedspool search rust --feed https://example.com/feed.xml
+  feedspool search release --since 2024-01-01T00:00:00Z --format json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSearch,
+}
+
+func init() {
+	searchCmd.Flags().StringVar(&searchFormat, "format", formatTable, "Output format (table|json|csv)")
+	searchCmd.Flags().StringVar(&searchFeed, "feed", "", "Only search items from this feed URL")
+	searchCmd.Flags().IntVar(&searchLimit, "limit", defaultSearchLimit, "Maximum items to return (0 for all)")
+	searchCmd.Flags().StringVar(&searchSince, "since", "", "Filter items since date (RFC3339)")
+	searchCmd.Flags().StringVar(&searchUntil, "until", "", "Filter items until date (RFC3339)")
+	rootCmd.AddCommand(searchCmd)
+}
+
+func runSearch(_ *cobra.Command, args []string) error {
+	cfg := GetConfig()
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.IsInitialized(); err != nil {
+		return err
+	}
+
+	since, until, err := parseDateFilters(searchSince, searchUntil)
+	if err != nil {
+		return err
+	}
+
+	if !db.SearchAvailable() {
+		logrus.Debug("SQLite built without FTS5, searching by substring")
+	}
+
+	items, err := db.SearchItems(&database.SearchOptions{
+		Query:   args[0],
+		FeedURL: searchFeed,
+		Since:   since,
+		Until:   until,
+		Limit:   searchLimit,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch format := determineOutputFormat(cfg, searchFormat); format {
+	case formatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(items)
+	case "csv":
+		return outputCSV(items)
+	case formatTable:
+		return outputTable(items)
+	default:
+		return fmt.Errorf("unknown format: %s", format)
+	}
+}
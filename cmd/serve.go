@@ -10,13 +10,19 @@ import (
 
 	"github.com/lmorchard/feedspool-go/internal/config"
 	"github.com/lmorchard/feedspool-go/internal/server"
+	"github.com/lmorchard/feedspool-go/internal/websub"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	servePort int
-	serveDir  string
+	serveHost              string
+	servePort              int
+	serveDir               string
+	serveAPI               bool
+	serveMetrics           bool
+	serveWebSub            bool
+	serveWebSubCallbackURL string
 )
 
 var serveCmd = &cobra.Command{
@@ -30,6 +36,8 @@ The server serves files from the specified directory (default: ./build) and prov
 - Basic error pages (404)
 - Graceful shutdown on SIGINT/SIGTERM
 - Request logging (when verbose mode is enabled)
+- With --api, a JSON API under /api/ for reading and marking items
+- With --metrics, Prometheus metrics under /metrics
 
 Examples:
   feedspool serve                    # Serve from ./build on port 8889
@@ -38,18 +46,52 @@ Examples:
   feedspool serve -v                 # Enable request logging
   PORT=9000 feedspool serve          # Serve on port 9000 (via env var)
 
+With --api, the server opens the database and serves a JSON API under /api/:
+listing feeds and items, marking items read or starred, and subscribing to or
+unsubscribing from the default feed list. The generated site uses it when
+present, so opening an item marks it read without re-rendering:
+  feedspool serve --api
+
+The API only accepts JSON bodies and same-origin browser requests. With it,
+the server listens on 127.0.0.1 unless --host (serve.host) says otherwise;
+set serve.api_token to require a bearer token before exposing it further:
+  feedspool serve --api --host 0.0.0.0
+
+With --metrics, the server exposes Prometheus metrics under /metrics. They
+are only the serving process's own, such as the database size; fetch, unfurl
+and render runs and their HTTP requests only show up when the server runs
+under the daemon (serve.metrics), since other processes export their own:
+  feedspool serve --metrics
+
+With --websub, the server also exposes a WebSub callback endpoint and
+subscribes to the hubs feeds advertise, so hubs can push new items instead of
+waiting for the next fetch. The callback URL must be reachable by the hubs:
+  feedspool serve --websub --websub-callback-url https://feeds.example.com/websub/
+
 This server is intended for development and testing. For production use,
 consider using a dedicated web server like nginx or Apache.`,
 	RunE: runServe,
 }
 
 func init() {
+	serveCmd.Flags().StringVar(&serveHost, "host", "",
+		"Address to listen on (default all interfaces, or 127.0.0.1 with --api)")
 	serveCmd.Flags().IntVar(&servePort, "port", defaultPort, "HTTP server port")
 	serveCmd.Flags().StringVar(&serveDir, "dir", defaultOutputDir, "Directory to serve")
+	serveCmd.Flags().BoolVar(&serveAPI, "api", false, "Serve the JSON reader API under /api/")
+	serveCmd.Flags().BoolVar(&serveMetrics, "metrics", false, "Serve Prometheus metrics under /metrics")
+	serveCmd.Flags().BoolVar(&serveWebSub, "websub", false, "Subscribe to WebSub hubs and accept pushed content")
+	serveCmd.Flags().StringVar(&serveWebSubCallbackURL, "websub-callback-url", "",
+		"Public URL of the WebSub callback endpoint")
 
 	// Bind flags to viper for config file support
+	_ = viper.BindPFlag("serve.host", serveCmd.Flags().Lookup("host"))
 	_ = viper.BindPFlag("serve.port", serveCmd.Flags().Lookup("port"))
 	_ = viper.BindPFlag("serve.dir", serveCmd.Flags().Lookup("dir"))
+	_ = viper.BindPFlag("serve.api", serveCmd.Flags().Lookup("api"))
+	_ = viper.BindPFlag("serve.metrics", serveCmd.Flags().Lookup("metrics"))
+	_ = viper.BindPFlag("websub.enabled", serveCmd.Flags().Lookup("websub"))
+	_ = viper.BindPFlag("websub.callback_url", serveCmd.Flags().Lookup("websub-callback-url"))
 
 	rootCmd.AddCommand(serveCmd)
 }
@@ -60,6 +102,34 @@ func runServe(_ *cobra.Command, _ []string) error {
 	// Build configuration from flags and config file
 	config := buildServeConfig(cfg)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if cfg.Serve.API {
+		handler, db, err := newAPIHandler(cfg)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		mountAPIHandler(config, handler)
+	}
+
+	if cfg.Serve.Metrics {
+		mountMetricsHandler(cfg, config)
+	}
+
+	if cfg.WebSub.Enabled {
+		manager, db, err := newWebSubManager(cfg)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		mountWebSubHandler(config, manager)
+		go manager.Run(ctx, websub.DefaultCheckInterval)
+	}
+
 	// Create and start server
 	srv := server.NewServer(config)
 
@@ -76,15 +146,17 @@ func runServe(_ *cobra.Command, _ []string) error {
 	<-quit
 
 	// Graceful shutdown with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout*time.Second)
-	defer cancel()
+	cancel()
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout*time.Second)
+	defer shutdownCancel()
 
-	return srv.Shutdown(ctx)
+	return srv.Shutdown(shutdownCtx)
 }
 
 func buildServeConfig(cfg *config.Config) *server.Config {
 	// Start with viper values (includes config file values)
 	config := &server.Config{
+		Host:    viper.GetString("serve.host"),
 		Port:    viper.GetInt("serve.port"),
 		Dir:     viper.GetString("serve.dir"),
 		Verbose: cfg.Verbose,
@@ -15,7 +15,13 @@ import (
 
 type FeedWithItems struct {
 	*database.Feed
-	Items []*database.Item `json:"Items"`
+	Items []*ItemWithEnclosures `json:"Items"`
+}
+
+// ItemWithEnclosures is an item in JSON output, with its attached media files.
+type ItemWithEnclosures struct {
+	*database.Item
+	Enclosures []database.Enclosure `json:"Enclosures,omitempty"`
 }
 
 var (
@@ -57,7 +63,7 @@ func runShow(_ *cobra.Command, args []string) error {
 		return err
 	}
 
-	since, until, err := parseDateFilters()
+	since, until, err := parseDateFilters(showSince, showUntil)
 	if err != nil {
 		return err
 	}
@@ -71,21 +77,26 @@ func runShow(_ *cobra.Command, args []string) error {
 		reverseItems(items)
 	}
 
-	format := determineOutputFormat(cfg)
-	return outputInFormat(format, feed, items)
+	enclosures, err := db.GetItemEnclosures([]string{feedURL})
+	if err != nil {
+		return err
+	}
+
+	format := determineOutputFormat(cfg, showFormat)
+	return outputInFormat(format, feed, items, enclosures)
 }
 
-func parseDateFilters() (since, until time.Time, err error) {
-	if showSince != "" {
-		since, err = time.Parse(time.RFC3339, showSince)
+func parseDateFilters(sinceStr, untilStr string) (since, until time.Time, err error) {
+	if sinceStr != "" {
+		since, err = time.Parse(time.RFC3339, sinceStr)
 		if err != nil {
 			err = fmt.Errorf("invalid since date: %w", err)
 			return
 		}
 	}
 
-	if showUntil != "" {
-		until, err = time.Parse(time.RFC3339, showUntil)
+	if untilStr != "" {
+		until, err = time.Parse(time.RFC3339, untilStr)
 		if err != nil {
 			err = fmt.Errorf("invalid until date: %w", err)
 			return
@@ -117,18 +128,19 @@ func reverseItems(items []*database.Item) {
 	}
 }
 
-func determineOutputFormat(cfg *config.Config) string {
-	format := showFormat
+func determineOutputFormat(cfg *config.Config, format string) string {
 	if format == formatTable && cfg.JSON {
 		format = formatJSON
 	}
 	return format
 }
 
-func outputInFormat(format string, feed *database.Feed, items []*database.Item) error {
+func outputInFormat(
+	format string, feed *database.Feed, items []*database.Item, enclosures []*database.ItemEnclosure,
+) error {
 	switch format {
 	case formatJSON:
-		return outputJSON(feed, items)
+		return outputJSON(feed, items, enclosures)
 	case "csv":
 		return outputCSV(items)
 	case formatTable:
@@ -155,10 +167,18 @@ func outputTable(items []*database.Item) error {
 	return w.Flush()
 }
 
-func outputJSON(feed *database.Feed, items []*database.Item) error {
+func outputJSON(feed *database.Feed, items []*database.Item, enclosures []*database.ItemEnclosure) error {
+	byItem := make(map[int64][]database.Enclosure)
+	for _, enclosure := range enclosures {
+		byItem[enclosure.ItemID] = append(byItem[enclosure.ItemID], enclosure.Enclosure)
+	}
+
 	feedWithItems := &FeedWithItems{
 		Feed:  feed,
-		Items: items,
+		Items: make([]*ItemWithEnclosures, len(items)),
+	}
+	for i, item := range items {
+		feedWithItems.Items[i] = &ItemWithEnclosures{Item: item, Enclosures: byItem[item.ID]}
 	}
 	encoder := json.NewEncoder(os.Stdout)
 	encoder.SetIndent("", "  ")
@@ -11,27 +11,37 @@ var (
 	subscribeFormat   string
 	subscribeFilename string
 	subscribeDiscover bool
+	subscribeTags     []string
 )
 
 var subscribeCmd = &cobra.Command{
 	Use:   "subscribe [URL]",
 	Short: "Subscribe to a feed by adding it to a feed list",
-	Long: `Subscribe to a feed by adding its URL to a feed list (OPML or text format).
+	Long: `Subscribe to a feed by adding its URL to a feed list (OPML, text, YAML or JSON format).
 
 If --discover is specified, the URL will be treated as a webpage and parsed for RSS/Atom autodiscovery links.
 
+Use --tag to file the feed in an OPML folder; with several tags it is filed in
+each tag's folder, and a "/" in a tag nests folders. The next fetch from the
+list saves the tags to the database. YAML and JSON lists keep tags with
+the feed's entry, where per-feed settings can be added by hand. Text lists
+ignore tags.
+
 Examples:
   feedspool subscribe https://example.com/feed.xml
   feedspool subscribe --discover https://example.com/blog
-  feedspool subscribe --format text --filename feeds.txt https://example.com/feed.xml`,
+  feedspool subscribe --tag golang --tag news https://example.com/feed.xml
+  feedspool subscribe --format text --filename feeds.txt https://example.com/feed.xml
+  feedspool subscribe --format yaml --filename feeds.yaml https://example.com/feed.xml`,
 	Args: cobra.ExactArgs(1),
 	RunE: runSubscribe,
 }
 
 func init() {
-	subscribeCmd.Flags().StringVar(&subscribeFormat, "format", "", "Feed list format (opml or text)")
+	subscribeCmd.Flags().StringVar(&subscribeFormat, "format", "", "Feed list format (opml, text, yaml or json)")
 	subscribeCmd.Flags().StringVar(&subscribeFilename, "filename", "", "Feed list filename")
 	subscribeCmd.Flags().BoolVar(&subscribeDiscover, "discover", false, "Discover RSS/Atom feeds from HTML page")
+	subscribeCmd.Flags().StringArrayVar(&subscribeTags, "tag", nil, "Tag (OPML folder) for the feed; repeatable")
 	rootCmd.AddCommand(subscribeCmd)
 }
 
@@ -55,7 +65,7 @@ func runSubscribe(_ *cobra.Command, args []string) error {
 		return nil
 	}
 
-	result, err := manager.Subscribe(format, filename, urlsToAdd)
+	result, err := manager.Subscribe(format, filename, urlsToAdd, subscribeTags...)
 	if err != nil {
 		return err
 	}
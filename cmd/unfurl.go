@@ -7,6 +7,7 @@ import (
 	"github.com/lmorchard/feedspool-go/internal/config"
 	"github.com/lmorchard/feedspool-go/internal/database"
 	"github.com/lmorchard/feedspool-go/internal/httpclient"
+	"github.com/lmorchard/feedspool-go/internal/media"
 	"github.com/lmorchard/feedspool-go/internal/unfurl"
 	"github.com/spf13/cobra"
 )
@@ -18,6 +19,7 @@ var (
 	unfurlRetryAfter     time.Duration
 	unfurlRetryImmediate bool
 	unfurlSkipRobots     bool
+	unfurlFullText       bool
 )
 
 var unfurlCmd = &cobra.Command{
@@ -28,6 +30,7 @@ var unfurlCmd = &cobra.Command{
 Single URL:
   feedspool unfurl <url>                     # Fetch metadata for specific URL
   feedspool unfurl <url> --format json      # Output as JSON to stdout
+  feedspool unfurl <url> --full-text         # Also extract the article text
 
 Batch mode:
   feedspool unfurl                           # Process all item URLs without metadata
@@ -36,9 +39,13 @@ Batch mode:
   feedspool unfurl --skip-robots             # Skip robots.txt checking
 
 The command extracts OpenGraph metadata, Twitter Cards, and favicons from web pages.
-By default it respects robots.txt, but this can be disabled with --skip-robots.`,
+By default it respects robots.txt, but this can be disabled with --skip-robots.
+
+In batch mode, the article text of items in feeds marked with
+'feedspool feeds fulltext' is extracted as well, for use when the feed only
+publishes a summary.`,
 	Args: cobra.MaximumNArgs(1),
-	RunE: runUnfurl,
+	RunE: instrumentCommand("unfurl", runUnfurl),
 }
 
 func init() {
@@ -51,6 +58,8 @@ func init() {
 		"Retry failed URLs immediately, ignoring retry delay")
 	unfurlCmd.Flags().BoolVar(&unfurlSkipRobots, "skip-robots", false,
 		"Skip robots.txt checking when fetching URLs")
+	unfurlCmd.Flags().BoolVar(&unfurlFullText, "full-text", false,
+		"Extract the article text of a single URL")
 	rootCmd.AddCommand(unfurlCmd)
 }
 
@@ -85,17 +94,20 @@ func runUnfurl(_ *cobra.Command, args []string) error {
 
 func runSingleURLUnfurl(db *database.DB, httpClient *httpclient.Client, targetURL string, cfg *config.Config) error {
 	service := unfurl.NewService(db, httpClient)
+	service.SetMediaCache(media.New(cfg.Media, db, cfg.Timeout))
 	// Use CLI flag if set, otherwise fall back to config
 	skipRobots := unfurlSkipRobots || cfg.Unfurl.SkipRobots
 	retryAfter := unfurlRetryAfter
 	if retryAfter == 1*time.Hour && cfg.Unfurl.RetryAfter > 0 {
 		retryAfter = cfg.Unfurl.RetryAfter
 	}
-	return service.ProcessSingleURL(targetURL, unfurlFormat, retryAfter, unfurlRetryImmediate, skipRobots)
+	return service.ProcessSingleURL(targetURL, unfurlFormat, retryAfter, unfurlRetryImmediate, skipRobots,
+		unfurlFullText)
 }
 
 func runBatchUnfurl(db *database.DB, httpClient *httpclient.Client, cfg *config.Config) error {
 	service := unfurl.NewService(db, httpClient)
+	service.SetMediaCache(media.New(cfg.Media, db, cfg.Timeout))
 	// Use CLI flag if set, otherwise fall back to config
 	skipRobots := unfurlSkipRobots || cfg.Unfurl.SkipRobots
 	retryAfter := unfurlRetryAfter
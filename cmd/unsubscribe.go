@@ -15,7 +15,7 @@ var (
 var unsubscribeCmd = &cobra.Command{
 	Use:   "unsubscribe [URL]",
 	Short: "Unsubscribe from a feed by removing it from a feed list",
-	Long: `Unsubscribe from a feed by removing its URL from a feed list (OPML or text format).
+	Long: `Unsubscribe from a feed by removing its URL from a feed list (OPML, text, YAML or JSON format).
 
 Examples:
   feedspool unsubscribe https://example.com/feed.xml
@@ -25,7 +25,7 @@ Examples:
 }
 
 func init() {
-	unsubscribeCmd.Flags().StringVar(&unsubscribeFormat, "format", "", "Feed list format (opml or text)")
+	unsubscribeCmd.Flags().StringVar(&unsubscribeFormat, "format", "", "Feed list format (opml, text, yaml or json)")
 	unsubscribeCmd.Flags().StringVar(&unsubscribeFilename, "filename", "", "Feed list filename")
 	rootCmd.AddCommand(unsubscribeCmd)
 }
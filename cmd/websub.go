@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/dedupe"
+	"github.com/lmorchard/feedspool-go/internal/fetcher"
+	"github.com/lmorchard/feedspool-go/internal/httpclient"
+	"github.com/lmorchard/feedspool-go/internal/media"
+	"github.com/lmorchard/feedspool-go/internal/server"
+	"github.com/lmorchard/feedspool-go/internal/urlcanon"
+	"github.com/lmorchard/feedspool-go/internal/websub"
+)
+
+// newWebSubManager opens the database and creates a WebSub subscription
+// manager that ingests pushed content the same way fetch does. The caller
+// must close the returned database.
+func newWebSubManager(cfg *config.Config) (*websub.Manager, *database.DB, error) {
+	if cfg.WebSub.CallbackURL == "" {
+		return nil, nil, fmt.Errorf("websub.callback_url is required when WebSub is enabled")
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if err := db.IsInitialized(); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	engine, err := loadRules(cfg)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	pushFetcher := fetcher.NewFetcher(db, cfg.Timeout, cfg.Fetch.MaxItems, false)
+	pushFetcher.SetScheduleBounds(cfg.Fetch.MinInterval, cfg.Fetch.MaxInterval)
+	pushFetcher.SetRules(engine)
+	if cfg.Dedupe.Enabled {
+		pushFetcher.SetDetector(dedupe.New(db, cfg.Dedupe.Titles))
+	}
+
+	client := httpclient.NewClient(&httpclient.Config{
+		Timeout:   cfg.Timeout,
+		UserAgent: httpclient.DefaultUserAgent,
+	})
+	pushFetcher.SetCanonicalizer(urlcanon.New(cfg.Links))
+	pushFetcher.SetMediaCache(media.New(cfg.Media, db, cfg.Timeout))
+
+	manager, err := websub.NewManager(db, client, &websub.Config{
+		CallbackURL:   cfg.WebSub.CallbackURL,
+		LeaseDuration: cfg.WebSub.LeaseDuration,
+	}, pushFetcher.IngestPush)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	return manager, db, nil
+}
+
+// mountWebSubHandler adds the manager's callback endpoint to a server config.
+func mountWebSubHandler(serveConfig *server.Config, manager *websub.Manager) {
+	if serveConfig.Handlers == nil {
+		serveConfig.Handlers = make(map[string]http.Handler)
+	}
+	serveConfig.Handlers[manager.CallbackPath()] = manager.Handler()
+}
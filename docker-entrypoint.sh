@@ -2,49 +2,16 @@
 set -e
 
 # Docker entrypoint script for feedspool container
-# Manages both crond (for scheduled feed updates) and feedspool serve
+# Prepares /data and then runs feedspool daemon, which schedules fetch,
+# render and purge in-process and serves the site as a single process
 
 echo "Starting feedspool container..."
 
-# If the command is not 'serve', just run it directly without setting up cron
-if [ "$1" != "serve" ]; then
+# If the command is not 'daemon', just run it directly
+if [ "$1" != "daemon" ]; then
     exec /usr/local/bin/feedspool "$@"
 fi
 
-# Set up cron job for feed updates
-# CRON_SCHEDULE env var allows customization, defaults to every 30 minutes
-CRON_SCHEDULE="${CRON_SCHEDULE:-*/30 * * * *}"
-echo "Setting up cron job for feed updates (schedule: $CRON_SCHEDULE)..."
-# Disable email notifications and redirect output to Docker logs
-cat > /etc/crontabs/root << EOF
-# Disable email notifications
-MAILTO=""
-# Run fetch and render on schedule, output to Docker logs
-$CRON_SCHEDULE (cd /data && /usr/local/bin/feedspool purge && /usr/local/bin/feedspool fetch && /usr/local/bin/feedspool render) > /proc/1/fd/1 2> /proc/1/fd/2
-EOF
-
-# Start crond in foreground mode in background to capture PID correctly
-echo "Starting cron daemon..."
-crond -f &
-CRON_PID=$!
-
-# Show the crontab for debugging
-echo "Cron jobs registered:"
-crontab -l
-
-# Function to handle shutdown signals
-shutdown_handler() {
-    echo "Received shutdown signal, stopping services..."
-    kill $CRON_PID 2>/dev/null || true
-    kill $SERVER_PID 2>/dev/null || true
-    wait $CRON_PID 2>/dev/null || true
-    wait $SERVER_PID 2>/dev/null || true
-    exit 0
-}
-
-# Set up signal traps for graceful shutdown
-trap 'shutdown_handler' SIGTERM SIGINT
-
 # Create a default configuration file if it doesn't exist
 if [ ! -f "/data/feedspool.yaml" ]; then
     echo "Creating default feedspool.yaml configuration..."
@@ -84,6 +51,11 @@ serve:
   port: 8889
   dir: "/data/build"
 
+# Daemon schedule (FETCH_INTERVAL / PURGE_INTERVAL env vars override)
+daemon:
+  fetch_interval: "30m"
+  purge_interval: "24h"
+
 # Fetch settings
 fetch:
   with_unfurl: true       # Enable metadata extraction
@@ -105,40 +77,12 @@ if [ ! -f "/data/feeds.db" ]; then
     /usr/local/bin/feedspool init || echo "Database initialization failed - continuing anyway"
 fi
 
-# Check if feed file exists before running fetch
-if [ -f "/data/feeds.txt" ] || [ -f "/data/feeds.opml" ]; then
-    # Run initial fetch and render in background to populate content
-    echo "Starting initial fetch and render in background..."
-    (
-        /usr/local/bin/feedspool fetch || echo "Initial fetch failed - continuing anyway"
-        /usr/local/bin/feedspool render || echo "Initial render failed - continuing anyway"
-        echo "Initial fetch and render completed"
-    ) &
-    FETCH_PID=$!
-    
-    # Wait for build directory to exist before starting server
-    echo "Waiting for build directory to be created..."
-    timeout=60  # Maximum wait time in seconds
-    elapsed=0
-    while [ ! -d "/data/build" ] && [ $elapsed -lt $timeout ]; do
-        sleep 1
-        elapsed=$((elapsed + 1))
-    done
-    
-    if [ -d "/data/build" ]; then
-        echo "Build directory ready"
-    else
-        echo "Warning: Build directory not created within $timeout seconds, starting server anyway"
-    fi
-    
-    # Don't wait for the fetch process to complete, let it run in background
-    echo "Initial content loading in progress (PID: $FETCH_PID)"
-else
+if [ ! -f "/data/feeds.txt" ] && [ ! -f "/data/feeds.opml" ]; then
     echo "==========================================================================="
     echo "WARNING: No feed file found!"
     echo ""
     echo "Please create one of the following files in your mounted volume:"
-    echo "  - feeds.txt   (one URL per line)"  
+    echo "  - feeds.txt   (one URL per line)"
     echo "  - feeds.opml  (OPML format)"
     echo ""
     echo "Example feeds.txt:"
@@ -147,47 +91,17 @@ else
     echo ""
     echo "The container will continue running but won't have any feeds to display."
     echo "==========================================================================="
-    
-    # Create empty build directory so server can start
-    mkdir -p "/data/build"
 fi
 
-# Function to start the server with monitoring
-start_server() {
-    echo "Starting feedspool serve..."
-    /usr/local/bin/feedspool "$@" &
-    SERVER_PID=$!
-    echo "Server started with PID: $SERVER_PID"
-}
-
-# Function to monitor and restart the server
-monitor_server() {
-    restart_count=0
-    max_restarts=5
-    restart_delay=5
-    
-    while true; do
-        if ! kill -0 $SERVER_PID 2>/dev/null; then
-            echo "Server process $SERVER_PID has died!"
-            
-            if [ $restart_count -ge $max_restarts ]; then
-                echo "Maximum restart attempts ($max_restarts) reached. Giving up."
-                exit 1
-            fi
-            
-            restart_count=$((restart_count + 1))
-            echo "Restarting server (attempt $restart_count/$max_restarts) in $restart_delay seconds..."
-            sleep $restart_delay
-            
-            start_server "$@"
-            # Exponential backoff for restart delay
-            restart_delay=$((restart_delay * 2))
-        fi
-        
-        sleep 5
-    done
-}
+# Optional schedule overrides from the environment
+if [ -n "$FETCH_INTERVAL" ]; then
+    set -- "$@" --fetch-interval "$FETCH_INTERVAL"
+fi
+if [ -n "$PURGE_INTERVAL" ]; then
+    set -- "$@" --purge-interval "$PURGE_INTERVAL"
+fi
 
-# Start the server and monitor it
-start_server "$@"
-monitor_server "$@"
\ No newline at end of file
+# Replace the shell so feedspool receives SIGTERM directly and shuts down
+# gracefully; the daemon performs the initial fetch and render itself
+echo "Starting feedspool $*..."
+exec /usr/local/bin/feedspool "$@"
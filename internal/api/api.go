@@ -0,0 +1,468 @@
+// Package api serves a JSON API over the feed database, so a reader can
+// browse items and mark them read or starred without re-rendering the site.
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// PathPrefix is where the API is mounted.
+	PathPrefix = "/api/"
+
+	// DefaultPageSize is the number of items listed when no limit is given.
+	DefaultPageSize = 50
+	// MaxPageSize caps the limit a client may request.
+	MaxPageSize = 500
+
+	// maxBodySize caps request bodies, which are only ever small JSON objects.
+	maxBodySize = 64 * 1024
+)
+
+// Subscriber edits the feed list that fetch reads, so subscriptions made
+// through the API are picked up by the next fetch.
+type Subscriber interface {
+	Subscribe(url string) (added bool, err error)
+	Unsubscribe(url string) (found bool, err error)
+}
+
+// Handler serves the API. It is safe for concurrent use.
+type Handler struct {
+	db         *database.DB
+	subscriber Subscriber
+	token      string
+}
+
+// NewHandler creates an API handler. When subscriber is nil the subscribe
+// and unsubscribe endpoints respond 501 Not Implemented.
+func NewHandler(db *database.DB, subscriber Subscriber) *Handler {
+	return &Handler{db: db, subscriber: subscriber}
+}
+
+// SetToken requires requests to carry the token as a bearer token in their
+// Authorization header. An empty token requires none.
+func (h *Handler) SetToken(token string) {
+	h.token = token
+}
+
+// ServeHTTP routes requests below PathPrefix.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, PathPrefix), "/")
+	segments := strings.Split(path, "/")
+
+	switch {
+	case path == "":
+		h.allow(w, r, map[string]http.HandlerFunc{http.MethodGet: h.handleStatus})
+	case path == "feeds":
+		h.allow(w, r, map[string]http.HandlerFunc{
+			http.MethodGet:    h.handleListFeeds,
+			http.MethodPost:   h.handleSubscribe,
+			http.MethodDelete: h.handleUnsubscribe,
+		})
+	case path == "items":
+		h.allow(w, r, map[string]http.HandlerFunc{http.MethodGet: h.handleListItems})
+	case path == "items/mark":
+		h.allow(w, r, map[string]http.HandlerFunc{http.MethodPost: h.handleMarkItems})
+	case len(segments) == 2 && segments[0] == "items":
+		id, err := strconv.ParseInt(segments[1], 10, 64)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		h.allow(w, r, map[string]http.HandlerFunc{
+			http.MethodGet:   func(w http.ResponseWriter, _ *http.Request) { h.handleGetItem(w, id) },
+			http.MethodPatch: func(w http.ResponseWriter, r *http.Request) { h.handleUpdateItem(w, r, id) },
+		})
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// authorize answers 403 for requests from pages on other sites, which
+// browsers mark with their Origin, and 401 for requests without the token
+// when one is set. Returns false if the request was answered.
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+			writeError(w, http.StatusForbidden, "cross-origin requests are not allowed")
+			return false
+		}
+	}
+
+	if h.token != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="feedspool"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+			return false
+		}
+	}
+
+	return true
+}
+
+// allow dispatches on the request method, answering 405 for others.
+func (h *Handler) allow(w http.ResponseWriter, r *http.Request, handlers map[string]http.HandlerFunc) {
+	if handler, ok := handlers[r.Method]; ok {
+		handler(w, r)
+		return
+	}
+
+	methods := make([]string, 0, len(handlers))
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete} {
+		if _, ok := handlers[method]; ok {
+			methods = append(methods, method)
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+func (h *Handler) handleStatus(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"ok":        true,
+		"subscribe": h.subscriber != nil,
+		"search":    h.db.SearchAvailable(),
+	})
+}
+
+func (h *Handler) handleListFeeds(w http.ResponseWriter, _ *http.Request) {
+	feeds, err := h.db.GetAllFeeds()
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	counts, err := h.db.GetUnreadCounts()
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	tags, err := h.db.GetAllFeedTags()
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	views := make([]*Feed, len(feeds))
+	for i, feed := range feeds {
+		views[i] = newFeed(feed, counts[feed.URL], tags[feed.URL])
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"feeds": views})
+}
+
+// subscribeRequest is the body of POST /api/feeds.
+type subscribeRequest struct {
+	URL string `json:"url"`
+}
+
+func (h *Handler) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+	if h.subscriber == nil {
+		writeError(w, http.StatusNotImplemented, "no default feed list configured")
+		return
+	}
+
+	var req subscribeRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.URL == "" {
+		writeError(w, http.StatusBadRequest, "url is required")
+		return
+	}
+
+	added, err := h.subscriber.Subscribe(req.URL)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	status := http.StatusOK
+	if added {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, map[string]interface{}{"url": req.URL, "added": added})
+}
+
+func (h *Handler) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	if h.subscriber == nil {
+		writeError(w, http.StatusNotImplemented, "no default feed list configured")
+		return
+	}
+
+	feedURL := r.URL.Query().Get("url")
+	if feedURL == "" {
+		writeError(w, http.StatusBadRequest, "url is required")
+		return
+	}
+
+	found, err := h.subscriber.Unsubscribe(feedURL)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "feed not in feed list")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// itemPage is the response of GET /api/items.
+type itemPage struct {
+	Items   []*Item `json:"items"`
+	Limit   int     `json:"limit"`
+	Offset  int     `json:"offset"`
+	HasMore bool    `json:"has_more"`
+}
+
+func (h *Handler) handleListItems(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseItemFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Ask for one more than the page to learn whether another page follows
+	limit := filter.Limit
+	filter.Limit++
+	items, err := h.db.ListItems(filter)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	page := &itemPage{Items: []*Item{}, Limit: limit, Offset: filter.Offset}
+	if len(items) > limit {
+		items = items[:limit]
+		page.HasMore = true
+	}
+	for _, item := range items {
+		page.Items = append(page.Items, newItem(item))
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// parseItemFilter reads the feed, unread, starred, before, limit and offset
+// query parameters of GET /api/items.
+func parseItemFilter(r *http.Request) (*database.ItemFilter, error) {
+	query := r.URL.Query()
+	filter := &database.ItemFilter{
+		FeedURL: query.Get("feed"),
+		Unread:  query.Get("unread") == "true",
+		Starred: query.Get("starred") == "true",
+		Limit:   DefaultPageSize,
+	}
+
+	if before := query.Get("before"); before != "" {
+		t, err := time.Parse(time.RFC3339, before)
+		if err != nil {
+			return nil, errors.New("before must be an RFC3339 timestamp")
+		}
+		filter.Before = t
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return nil, errors.New("limit must be a positive integer")
+		}
+		if n > MaxPageSize {
+			n = MaxPageSize
+		}
+		filter.Limit = n
+	}
+
+	if offset := query.Get("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return nil, errors.New("offset must be a non-negative integer")
+		}
+		filter.Offset = n
+	}
+
+	return filter, nil
+}
+
+func (h *Handler) handleGetItem(w http.ResponseWriter, id int64) {
+	item, err := h.getItem(id)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+	if item == nil {
+		writeError(w, http.StatusNotFound, "item not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newItem(item))
+}
+
+// stateRequest is the body of PATCH /api/items/{id}. Omitted fields are
+// left unchanged.
+type stateRequest struct {
+	Read    *bool `json:"read"`
+	Starred *bool `json:"starred"`
+}
+
+func (h *Handler) handleUpdateItem(w http.ResponseWriter, r *http.Request, id int64) {
+	var req stateRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	item, err := h.getItem(id)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+	if item == nil {
+		writeError(w, http.StatusNotFound, "item not found")
+		return
+	}
+
+	filter := &database.ItemFilter{IDs: []int64{id}}
+	if _, err := h.setState(filter, &req); err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	if req.Read != nil {
+		item.Read = *req.Read
+	}
+	if req.Starred != nil {
+		item.Starred = *req.Starred
+	}
+	writeJSON(w, http.StatusOK, newItem(item))
+}
+
+// markRequest is the body of POST /api/items/mark. Like the items CLI,
+// selecting every item takes an explicit "all".
+type markRequest struct {
+	stateRequest
+	IDs    []int64 `json:"ids"`
+	Feed   string  `json:"feed"`
+	Before string  `json:"before"`
+	All    bool    `json:"all"`
+}
+
+func (h *Handler) handleMarkItems(w http.ResponseWriter, r *http.Request) {
+	var req markRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	filter := &database.ItemFilter{IDs: req.IDs, FeedURL: req.Feed}
+	if req.Before != "" {
+		before, err := time.Parse(time.RFC3339, req.Before)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "before must be an RFC3339 timestamp")
+			return
+		}
+		filter.Before = before
+	}
+
+	selected := len(filter.IDs) > 0 || filter.FeedURL != "" || !filter.Before.IsZero()
+	if selected == req.All {
+		writeError(w, http.StatusBadRequest, "specify ids, feed or before, or all")
+		return
+	}
+	if req.Read == nil && req.Starred == nil {
+		writeError(w, http.StatusBadRequest, "specify read or starred")
+		return
+	}
+
+	changed, err := h.setState(filter, &req.stateRequest)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"changed": changed})
+}
+
+// getItem returns the item with id, or nil if there is none.
+func (h *Handler) getItem(id int64) (*database.Item, error) {
+	items, err := h.db.ListItems(&database.ItemFilter{IDs: []int64{id}})
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return items[0], nil
+}
+
+// setState applies the requested read and starred changes to the items
+// matching filter, returning the number of changes made.
+func (h *Handler) setState(filter *database.ItemFilter, req *stateRequest) (int64, error) {
+	var changed int64
+	if req.Read != nil {
+		n, err := h.db.SetItemsRead(filter, *req.Read)
+		if err != nil {
+			return changed, err
+		}
+		changed += n
+	}
+	if req.Starred != nil {
+		n, err := h.db.SetItemsStarred(filter, *req.Starred)
+		if err != nil {
+			return changed, err
+		}
+		changed += n
+	}
+	return changed, nil
+}
+
+func (h *Handler) internalError(w http.ResponseWriter, err error) {
+	logrus.WithError(err).Warn("API request failed")
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
+
+// readJSON decodes the request body into v, answering 415 and returning
+// false if it is not sent as JSON, or 400 if it is not valid JSON. Requiring
+// the JSON content type keeps pages on other sites from sending bodies with
+// plain form or text posts, which browsers send without asking first.
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "request body must be application/json")
+		return false
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.WithError(err).Debug("Failed to write API response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
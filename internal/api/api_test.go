@@ -0,0 +1,234 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/database/databasetest"
+)
+
+func setupTestDatabase(t *testing.T) *database.DB {
+	t.Helper()
+
+	db := databasetest.New(t)
+	feed := &database.Feed{URL: "https://example.com/feed", Title: "Example", FeedJSON: database.JSON(`{}`)}
+	if err := db.UpsertFeed(feed); err != nil {
+		t.Fatal(err)
+	}
+	for i, guid := range []string{"one", "two", "three"} {
+		err := db.UpsertItem(&database.Item{
+			FeedURL: "https://example.com/feed", GUID: guid, Title: "Item " + guid,
+			PublishedDate: time.Date(2024, 1, i+1, 0, 0, 0, 0, time.UTC), ItemJSON: database.JSON(`{}`),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return db
+}
+
+// fakeSubscriber records subscriptions in memory.
+type fakeSubscriber struct {
+	urls map[string]bool
+}
+
+func (f *fakeSubscriber) Subscribe(url string) (bool, error) {
+	added := !f.urls[url]
+	f.urls[url] = true
+	return added, nil
+}
+
+func (f *fakeSubscriber) Unsubscribe(url string) (bool, error) {
+	found := f.urls[url]
+	delete(f.urls, url)
+	return found, nil
+}
+
+func request(t *testing.T, h http.Handler, method, target, body string, v interface{}) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s: invalid JSON %q: %v", method, target, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestListAndMarkItems(t *testing.T) {
+	h := NewHandler(setupTestDatabase(t), nil)
+
+	var page itemPage
+	if code := request(t, h, http.MethodGet, "/api/items?limit=2", "", &page); code != http.StatusOK {
+		t.Fatalf("GET /api/items status = %d", code)
+	}
+	if len(page.Items) != 2 || !page.HasMore || page.Items[0].GUID != "three" {
+		t.Fatalf("first page = %+v, want two newest items and more to come", page)
+	}
+
+	if code := request(t, h, http.MethodGet, "/api/items?limit=2&offset=2", "", &page); code != http.StatusOK {
+		t.Fatalf("GET /api/items offset status = %d", code)
+	}
+	if len(page.Items) != 1 || page.HasMore || page.Items[0].GUID != "one" {
+		t.Fatalf("second page = %+v, want the oldest item only", page)
+	}
+
+	// Mark one item read and starred
+	id := page.Items[0].ID
+	var item Item
+	target := "/api/items/" + strconv.FormatInt(id, 10)
+	if code := request(t, h, http.MethodPatch, target, `{"read": true, "starred": true}`, &item); code != http.StatusOK {
+		t.Fatalf("PATCH %s status = %d", target, code)
+	}
+	if !item.Read || !item.Starred {
+		t.Errorf("PATCH %s = %+v, want read and starred", target, item)
+	}
+
+	if code := request(t, h, http.MethodGet, "/api/items?unread=true", "", &page); code != http.StatusOK || len(page.Items) != 2 {
+		t.Errorf("unread items = %d (status %d), want 2", len(page.Items), code)
+	}
+
+	// Mark the rest of the feed read
+	var marked struct{ Changed int }
+	code := request(t, h, http.MethodPost, "/api/items/mark", `{"feed": "https://example.com/feed", "read": true}`, &marked)
+	if code != http.StatusOK || marked.Changed != 2 {
+		t.Errorf("POST /api/items/mark = %d changed (status %d), want 2", marked.Changed, code)
+	}
+
+	var feeds struct{ Feeds []*Feed }
+	if code := request(t, h, http.MethodGet, "/api/feeds", "", &feeds); code != http.StatusOK {
+		t.Fatalf("GET /api/feeds status = %d", code)
+	}
+	if len(feeds.Feeds) != 1 || feeds.Feeds[0].UnreadCount != 0 {
+		t.Errorf("feeds = %+v, want one feed with nothing unread", feeds.Feeds)
+	}
+}
+
+func TestItemErrors(t *testing.T) {
+	h := NewHandler(setupTestDatabase(t), nil)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   int
+	}{
+		{"missing item", http.MethodGet, "/api/items/999", "", http.StatusNotFound},
+		{"bad item ID", http.MethodGet, "/api/items/abc", "", http.StatusNotFound},
+		{"bad limit", http.MethodGet, "/api/items?limit=0", "", http.StatusBadRequest},
+		{"bad before", http.MethodGet, "/api/items?before=yesterday", "", http.StatusBadRequest},
+		{"unknown field", http.MethodPatch, "/api/items/1", `{"archived": true}`, http.StatusBadRequest},
+		{"mark without selector", http.MethodPost, "/api/items/mark", `{"read": true}`, http.StatusBadRequest},
+		{"mark without state", http.MethodPost, "/api/items/mark", `{"all": true}`, http.StatusBadRequest},
+		{"wrong method", http.MethodDelete, "/api/items", "", http.StatusMethodNotAllowed},
+		{"subscribe without feed list", http.MethodPost, "/api/feeds", `{"url": "https://a.example/"}`, http.StatusNotImplemented},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]string
+			if code := request(t, h, tt.method, tt.target, tt.body, &body); code != tt.want {
+				t.Errorf("%s %s status = %d, want %d", tt.method, tt.target, code, tt.want)
+			}
+			if body["error"] == "" {
+				t.Errorf("%s %s: no error message in response", tt.method, tt.target)
+			}
+		})
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	subscriber := &fakeSubscriber{urls: map[string]bool{}}
+	h := NewHandler(setupTestDatabase(t), subscriber)
+
+	body := `{"url": "https://new.example.com/feed"}`
+	if code := request(t, h, http.MethodPost, "/api/feeds", body, nil); code != http.StatusCreated {
+		t.Errorf("first subscribe status = %d, want 201", code)
+	}
+	if code := request(t, h, http.MethodPost, "/api/feeds", body, nil); code != http.StatusOK {
+		t.Errorf("repeat subscribe status = %d, want 200", code)
+	}
+
+	target := "/api/feeds?url=https%3A%2F%2Fnew.example.com%2Ffeed"
+	if code := request(t, h, http.MethodDelete, target, "", nil); code != http.StatusNoContent {
+		t.Errorf("unsubscribe status = %d, want 204", code)
+	}
+	if code := request(t, h, http.MethodDelete, target, "", nil); code != http.StatusNotFound {
+		t.Errorf("repeat unsubscribe status = %d, want 404", code)
+	}
+}
+
+func TestRequestChecks(t *testing.T) {
+	h := NewHandler(setupTestDatabase(t), nil)
+	body := `{"all": true, "read": true}`
+
+	tests := []struct {
+		name        string
+		contentType string
+		origin      string
+		want        int
+	}{
+		{"JSON", "application/json", "", http.StatusOK},
+		{"JSON with charset", "application/json; charset=utf-8", "", http.StatusOK},
+		{"same origin", "application/json", "http://example.com", http.StatusOK},
+		{"plain text", "text/plain", "", http.StatusUnsupportedMediaType},
+		{"form", "application/x-www-form-urlencoded", "", http.StatusUnsupportedMediaType},
+		{"other origin", "application/json", "https://evil.example", http.StatusForbidden},
+		{"opaque origin", "application/json", "null", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/items/mark", strings.NewReader(body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("POST /api/items/mark status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestToken(t *testing.T) {
+	h := NewHandler(setupTestDatabase(t), nil)
+	h.SetToken("secret")
+
+	for _, tt := range []struct {
+		authorization string
+		want          int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"secret", http.StatusUnauthorized},
+		{"Bearer secret", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+		if tt.authorization != "" {
+			req.Header.Set("Authorization", tt.authorization)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("GET /api/items with Authorization %q status = %d, want %d", tt.authorization, rec.Code, tt.want)
+		}
+	}
+}
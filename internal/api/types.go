@@ -0,0 +1,83 @@
+package api
+
+import (
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+)
+
+// Feed is the API representation of a feed.
+type Feed struct {
+	URL                 string     `json:"url"`
+	Title               string     `json:"title"`
+	Description         string     `json:"description,omitempty"`
+	LastSuccessfulFetch *time.Time `json:"last_successful_fetch,omitempty"`
+	LatestItemDate      *time.Time `json:"latest_item_date,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	Disabled            bool       `json:"disabled"`
+	FullText            bool       `json:"full_text,omitempty"`
+	UnreadCount         int        `json:"unread_count"`
+	Tags                []string   `json:"tags,omitempty"`
+}
+
+// Item is the API representation of an item.
+type Item struct {
+	ID            int64      `json:"id"`
+	FeedURL       string     `json:"feed_url"`
+	GUID          string     `json:"guid"`
+	Title         string     `json:"title"`
+	Link          string     `json:"link"`
+	PublishedDate time.Time  `json:"published_date"`
+	FirstSeen     *time.Time `json:"first_seen,omitempty"`
+	Content       string     `json:"content,omitempty"`
+	Summary       string     `json:"summary,omitempty"`
+	Archived      bool       `json:"archived"`
+	Read          bool       `json:"read"`
+	Starred       bool       `json:"starred"`
+	Highlighted   bool       `json:"highlighted"`
+	DuplicateOf   int64      `json:"duplicate_of,omitempty"` // First item of the item's duplicate group
+}
+
+func newFeed(feed *database.Feed, unread int, tags []string) *Feed {
+	view := &Feed{
+		URL:         feed.URL,
+		Title:       feed.Title,
+		Description: feed.Description,
+		LastError:   feed.LastError,
+		Disabled:    feed.Disabled,
+		FullText:    feed.FullText,
+		UnreadCount: unread,
+		Tags:        tags,
+	}
+	if !feed.LastSuccessfulFetch.IsZero() {
+		view.LastSuccessfulFetch = &feed.LastSuccessfulFetch
+	}
+	if feed.LatestItemDate.Valid {
+		view.LatestItemDate = &feed.LatestItemDate.Time
+	}
+	return view
+}
+
+func newItem(item *database.Item) *Item {
+	view := &Item{
+		ID:            item.ID,
+		FeedURL:       item.FeedURL,
+		GUID:          item.GUID,
+		Title:         item.Title,
+		Link:          item.Link,
+		PublishedDate: item.PublishedDate,
+		Content:       item.Content,
+		Summary:       item.Summary,
+		Archived:      item.Archived,
+		Read:          item.Read,
+		Starred:       item.Starred,
+		Highlighted:   item.Highlighted,
+	}
+	if item.FirstSeen.Valid {
+		view.FirstSeen = &item.FirstSeen.Time
+	}
+	if item.DuplicateOf.Valid {
+		view.DuplicateOf = item.DuplicateOf.Int64
+	}
+	return view
+}
@@ -1,11 +1,20 @@
 package config
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
+// getBoolWithDefault returns the viper bool value or default if not set.
+func getBoolWithDefault(key string, defaultValue bool) bool {
+	if viper.IsSet(key) {
+		return viper.GetBool(key)
+	}
+	return defaultValue
+}
+
 // getIntWithDefault returns the viper int value or default if not set.
 func getIntWithDefault(key string, defaultValue int) int {
 	if viper.IsSet(key) {
@@ -14,32 +23,100 @@ func getIntWithDefault(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getInt64WithDefault returns the viper int64 value or default if not set.
+func getInt64WithDefault(key string, defaultValue int64) int64 {
+	if viper.IsSet(key) {
+		return viper.GetInt64(key)
+	}
+	return defaultValue
+}
+
+// getStringWithDefault returns the viper string value or default if not set.
+func getStringWithDefault(key, defaultValue string) string {
+	if viper.IsSet(key) {
+		return viper.GetString(key)
+	}
+	return defaultValue
+}
+
+// getStringSliceWithDefault returns the viper string slice value or default if not set.
+func getStringSliceWithDefault(key string, defaultValue []string) []string {
+	if viper.IsSet(key) {
+		return viper.GetStringSlice(key)
+	}
+	return defaultValue
+}
+
+// getFloat64WithDefault returns the viper float value or default if not set.
+func getFloat64WithDefault(key string, defaultValue float64) float64 {
+	if viper.IsSet(key) {
+		return viper.GetFloat64(key)
+	}
+	return defaultValue
+}
+
+// getDurationWithDefault returns the viper duration value or default if not set.
+func getDurationWithDefault(key string, defaultValue time.Duration) time.Duration {
+	if viper.IsSet(key) {
+		return viper.GetDuration(key)
+	}
+	return defaultValue
+}
+
 const (
-	defaultPort              = 8080
-	defaultOutputDir         = "./build"
-	DefaultTimeout           = 30 * time.Second
-	DefaultConcurrency       = 32
-	DefaultMaxItems          = 100
-	DefaultDirPerm           = 0o755
-	DefaultMinItemsPerFeed   = 5  // Render: minimum items to show per feed
-	DefaultMaxItemsPerFeed   = 50 // Render: maximum items to show per feed
-	DefaultMinItemsKeepPurge = 10 // Purge: minimum items to keep per feed
-	DefaultFeedsPerPage      = 25 // Render: feeds per page for pagination
+	defaultPort               = 8080
+	defaultOutputDir          = "./build"
+	DefaultMediaDir           = "./media"
+	DefaultEnclosuresDir      = "./enclosures"
+	DefaultTimeout            = 30 * time.Second
+	DefaultConcurrency        = 32
+	DefaultMaxItems           = 100
+	DefaultDirPerm            = 0o755
+	DefaultMinItemsPerFeed    = 5     // Render: minimum items to show per feed
+	DefaultMaxItemsPerFeed    = 50    // Render: maximum items to show per feed
+	DefaultMinItemsKeepPurge  = 10    // Purge: minimum items to keep per feed
+	DefaultFetchLogMaxAge     = "90d" // Purge: how long fetch attempts are kept in the fetch log
+	DefaultFeedsPerPage       = 25    // Render: feeds per page for pagination
+	DefaultItemsPerPage       = 100   // Render: river items per page for pagination
+	DefaultFetchInterval      = 30 * time.Minute
+	DefaultPurgeInterval      = 24 * time.Hour
+	DefaultMinFetchInterval   = 15 * time.Minute  // Fetch: shortest adaptive polling interval
+	DefaultMaxFetchInterval   = 24 * time.Hour    // Fetch: longest adaptive polling interval
+	DefaultDisableAfter       = 10                // Fetch: consecutive errors before a feed is disabled
+	DefaultPerHostConcurrency = 4                 // Fetch: concurrent requests per host
+	DefaultPerHostRate        = 2.0               // Fetch: new requests per second per host
+	DefaultWebSubLease        = 168 * time.Hour   // WebSub: subscription lease requested from hubs
+	DefaultMediaMaxSize       = 5 * 1024 * 1024   // Media: largest image downloaded into the cache
+	DefaultEnclosuresMaxSize  = 500 * 1024 * 1024 // Enclosures: largest file downloaded
+	DefaultEnclosuresTimeout  = time.Hour         // Enclosures: longest a single download may take
 )
 
+// DefaultEnclosureTypes are the enclosure types downloaded unless configured
+// otherwise: podcast episodes, not the images many blogs attach.
+var DefaultEnclosureTypes = []string{"audio/", "video/"}
+
 type Config struct {
-	Database string
-	Verbose  bool
-	Debug    bool
-	JSON     bool
-	Timeout  time.Duration
-	FeedList FeedListConfig
-	Fetch    FetchConfig
-	Render   RenderConfig
-	Serve    ServeConfig
-	Init     InitConfig
-	Unfurl   UnfurlConfig
-	Purge    PurgeConfig
+	Database      string
+	Verbose       bool
+	Debug         bool
+	JSON          bool
+	Timeout       time.Duration
+	FeedList      FeedListConfig
+	Fetch         FetchConfig
+	Render        RenderConfig
+	Serve         ServeConfig
+	Init          InitConfig
+	Unfurl        UnfurlConfig
+	Purge         PurgeConfig
+	Daemon        DaemonConfig
+	WebSub        WebSubConfig
+	Dedupe        DedupeConfig
+	Links         LinksConfig
+	Media         MediaConfig
+	Enclosures    EnclosuresConfig
+	Metrics       MetricsConfig
+	Rules         []RuleConfig
+	HTTPOverrides []HTTPOverrideConfig
 }
 
 type FeedListConfig struct {
@@ -48,9 +125,14 @@ type FeedListConfig struct {
 }
 
 type FetchConfig struct {
-	WithUnfurl  bool `mapstructure:"with_unfurl"`
-	Concurrency int  `mapstructure:"concurrency"`
-	MaxItems    int  `mapstructure:"max_items"`
+	WithUnfurl         bool          `mapstructure:"with_unfurl"`
+	Concurrency        int           `mapstructure:"concurrency"`
+	MaxItems           int           `mapstructure:"max_items"`
+	MinInterval        time.Duration `mapstructure:"min_interval"`
+	MaxInterval        time.Duration `mapstructure:"max_interval"`
+	DisableAfter       int           `mapstructure:"disable_after"`
+	PerHostConcurrency int           `mapstructure:"per_host_concurrency"`
+	PerHostRate        float64       `mapstructure:"per_host_rate"`
 }
 
 type RenderConfig struct {
@@ -58,15 +140,23 @@ type RenderConfig struct {
 	TemplatesDir           string
 	AssetsDir              string
 	DefaultMaxAge          string
-	DefaultClean           bool `mapstructure:"default_clean"`
-	DefaultMinItemsPerFeed int  `mapstructure:"default_min_items_per_feed"`
-	DefaultMaxItemsPerFeed int  `mapstructure:"default_max_items_per_feed"`
-	FeedsPerPage           int  `mapstructure:"feeds_per_page"`
+	DefaultClean           bool   `mapstructure:"default_clean"`
+	DefaultMinItemsPerFeed int    `mapstructure:"default_min_items_per_feed"`
+	DefaultMaxItemsPerFeed int    `mapstructure:"default_max_items_per_feed"`
+	FeedsPerPage           int    `mapstructure:"feeds_per_page"`
+	BaseURL                string `mapstructure:"base_url"`
+	River                  bool   `mapstructure:"river"`
+	RiverPaging            string `mapstructure:"river_paging"`
+	ItemsPerPage           int    `mapstructure:"items_per_page"`
 }
 
 type ServeConfig struct {
-	Port int
-	Dir  string
+	Host     string
+	Port     int
+	Dir      string
+	API      bool   `mapstructure:"api"`
+	APIToken string `mapstructure:"api_token"`
+	Metrics  bool   `mapstructure:"metrics"`
 }
 
 type InitConfig struct {
@@ -84,15 +174,108 @@ type PurgeConfig struct {
 	MaxAge       string `mapstructure:"max_age"`
 	SkipVacuum   bool   `mapstructure:"skip_vacuum"`
 	MinItemsKeep int    `mapstructure:"min_items_keep"`
+	FetchLogAge  string `mapstructure:"fetch_log_max_age"`
+}
+
+type DaemonConfig struct {
+	FetchInterval time.Duration `mapstructure:"fetch_interval"`
+	PurgeInterval time.Duration `mapstructure:"purge_interval"`
+	NoServe       bool          `mapstructure:"no_serve"`
+}
+
+type WebSubConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	CallbackURL   string        `mapstructure:"callback_url"`
+	LeaseDuration time.Duration `mapstructure:"lease_duration"`
+}
+
+type DedupeConfig struct {
+	Enabled bool `mapstructure:"enabled"` // Group items syndicated through several feeds and collapse them in render
+	Titles  bool `mapstructure:"titles"`  // Also group items by title fingerprint, not just by link
 }
 
-func LoadConfig() *Config {
+// LinksConfig controls how item links are canonicalized as they are fetched.
+type LinksConfig struct {
+	Canonicalize     bool     `mapstructure:"canonicalize"`      // Drop fragments and tracking parameters from links
+	StripParams      []string `mapstructure:"strip_params"`      // Query parameters to drop; "utm_*" drops a prefix
+	ResolveRedirects bool     `mapstructure:"resolve_redirects"` // Follow links on redirector hosts to their target
+	Redirectors      []string `mapstructure:"redirectors"`       // Redirector hosts to resolve
+}
+
+// MediaConfig controls the local cache of images referenced by items.
+type MediaConfig struct {
+	Enabled bool   `mapstructure:"enabled"`  // Download images during fetch and unfurl, and render local copies
+	Dir     string `mapstructure:"dir"`      // Directory holding the cached files
+	MaxSize int64  `mapstructure:"max_size"` // Largest image to download, in bytes
+}
+
+// EnclosuresConfig controls 'feedspool enclosures download'.
+type EnclosuresConfig struct {
+	Dir     string        `mapstructure:"dir"`      // Directory downloads are saved in, one subdirectory per feed
+	MaxSize int64         `mapstructure:"max_size"` // Largest file to download, in bytes; 0 for no limit
+	Keep    int           `mapstructure:"keep"`     // Downloads kept per feed, newest first; 0 keeps them all
+	Types   []string      `mapstructure:"types"`    // Type prefixes to download, e.g. "audio/"
+	Timeout time.Duration `mapstructure:"timeout"`  // Longest a single download may take
+}
+
+// MetricsConfig controls where run metrics are exported after fetch, unfurl
+// and render runs. Both are optional; serve --metrics exposes them instead.
+type MetricsConfig struct {
+	Textfile string `mapstructure:"textfile"` // Base name of the files written for node_exporter, one per command
+	PushURL  string `mapstructure:"push_url"` // Pushgateway URL, e.g. http://pushgateway:9091/metrics/job/feedspool
+}
+
+// RuleConfig is an entry in the rules list, applied to items as they are
+// fetched. Patterns are regular expressions, or /pattern/i for a
+// case-insensitive match.
+type RuleConfig struct {
+	Name    string `mapstructure:"name"`
+	Feed    string `mapstructure:"feed"`    // Only items from this feed URL (empty = every feed)
+	Title   string `mapstructure:"title"`   // Pattern for the item title
+	Content string `mapstructure:"content"` // Pattern for the item content or summary
+	Author  string `mapstructure:"author"`  // Pattern for an author name or email
+	Link    string `mapstructure:"link"`    // Pattern for the item link
+	Invert  bool   `mapstructure:"invert"`  // Act on items that do not match instead
+	Action  string `mapstructure:"action"`  // skip, tag, mark-read or highlight
+	Tag     string `mapstructure:"tag"`     // Tag to add, for the tag action
+}
+
+// HTTPOverrideConfig is an entry in the http_overrides list, changing how
+// matching feeds are requested. Credentials, cookies, header values and the
+// proxy may be given as env:NAME or file:PATH to read them from an environment
+// variable or a file.
+type HTTPOverrideConfig struct {
+	Feed               string            `mapstructure:"feed"`                 // Only this feed URL
+	Host               string            `mapstructure:"host"`                 // Only feeds on hosts matching this pattern
+	UserAgent          string            `mapstructure:"user_agent"`           // User-Agent sent instead of the default
+	Headers            map[string]string `mapstructure:"headers"`              // Extra request headers
+	Username           string            `mapstructure:"username"`             // HTTP basic auth user
+	Password           string            `mapstructure:"password"`             // HTTP basic auth password
+	Token              string            `mapstructure:"token"`                // Bearer token
+	Cookie             string            `mapstructure:"cookie"`               // Cookie header value
+	Proxy              string            `mapstructure:"proxy"`                // http, https or socks5 proxy URL
+	CAFile             string            `mapstructure:"ca_file"`              // Extra PEM CA certificates to trust
+	InsecureSkipVerify bool              `mapstructure:"insecure_skip_verify"` // Accept any TLS certificate
+}
+
+// LoadConfig builds the config from viper. Returns an error when a list in the
+// config file cannot be decoded.
+func LoadConfig() (*Config, error) {
 	timeoutStr := viper.GetString("timeout")
 	timeout, err := time.ParseDuration(timeoutStr)
 	if err != nil {
 		timeout = DefaultTimeout
 	}
 
+	rules, err := getRules()
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := getHTTPOverrides()
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		Database: viper.GetString("database"),
 		Verbose:  viper.GetBool("verbose"),
@@ -104,9 +287,14 @@ func LoadConfig() *Config {
 			Filename: viper.GetString("feedlist.filename"),
 		},
 		Fetch: FetchConfig{
-			WithUnfurl:  viper.GetBool("fetch.with_unfurl"),
-			Concurrency: getIntWithDefault("fetch.concurrency", DefaultConcurrency),
-			MaxItems:    getIntWithDefault("fetch.max_items", DefaultMaxItems),
+			WithUnfurl:         viper.GetBool("fetch.with_unfurl"),
+			Concurrency:        getIntWithDefault("fetch.concurrency", DefaultConcurrency),
+			MaxItems:           getIntWithDefault("fetch.max_items", DefaultMaxItems),
+			MinInterval:        getDurationWithDefault("fetch.min_interval", DefaultMinFetchInterval),
+			MaxInterval:        getDurationWithDefault("fetch.max_interval", DefaultMaxFetchInterval),
+			DisableAfter:       getIntWithDefault("fetch.disable_after", DefaultDisableAfter),
+			PerHostConcurrency: getIntWithDefault("fetch.per_host_concurrency", DefaultPerHostConcurrency),
+			PerHostRate:        getFloat64WithDefault("fetch.per_host_rate", DefaultPerHostRate),
 		},
 		Render: RenderConfig{
 			OutputDir:              viper.GetString("render.output_dir"),
@@ -117,10 +305,18 @@ func LoadConfig() *Config {
 			DefaultMinItemsPerFeed: getIntWithDefault("render.default_min_items_per_feed", DefaultMinItemsPerFeed),
 			DefaultMaxItemsPerFeed: getIntWithDefault("render.default_max_items_per_feed", DefaultMaxItemsPerFeed),
 			FeedsPerPage:           getIntWithDefault("render.feeds_per_page", DefaultFeedsPerPage),
+			BaseURL:                viper.GetString("render.base_url"),
+			River:                  viper.GetBool("render.river"),
+			RiverPaging:            viper.GetString("render.river_paging"),
+			ItemsPerPage:           getIntWithDefault("render.items_per_page", DefaultItemsPerPage),
 		},
 		Serve: ServeConfig{
-			Port: viper.GetInt("serve.port"),
-			Dir:  viper.GetString("serve.dir"),
+			Host:     viper.GetString("serve.host"),
+			Port:     viper.GetInt("serve.port"),
+			Dir:      viper.GetString("serve.dir"),
+			API:      viper.GetBool("serve.api"),
+			APIToken: viper.GetString("serve.api_token"),
+			Metrics:  viper.GetBool("serve.metrics"),
 		},
 		Init: InitConfig{
 			TemplatesDir: viper.GetString("init.templates_dir"),
@@ -135,8 +331,65 @@ func LoadConfig() *Config {
 			MaxAge:       viper.GetString("purge.max_age"),
 			SkipVacuum:   viper.GetBool("purge.skip_vacuum"),
 			MinItemsKeep: getIntWithDefault("purge.min_items_keep", 0),
+			FetchLogAge:  getStringWithDefault("purge.fetch_log_max_age", DefaultFetchLogMaxAge),
+		},
+		Daemon: DaemonConfig{
+			FetchInterval: viper.GetDuration("daemon.fetch_interval"),
+			PurgeInterval: viper.GetDuration("daemon.purge_interval"),
+			NoServe:       viper.GetBool("daemon.no_serve"),
 		},
+		WebSub: WebSubConfig{
+			Enabled:       viper.GetBool("websub.enabled"),
+			CallbackURL:   viper.GetString("websub.callback_url"),
+			LeaseDuration: getDurationWithDefault("websub.lease_duration", DefaultWebSubLease),
+		},
+		Dedupe: DedupeConfig{
+			Enabled: viper.GetBool("dedupe.enabled"),
+			Titles:  getBoolWithDefault("dedupe.titles", true),
+		},
+		Links: LinksConfig{
+			Canonicalize:     getBoolWithDefault("links.canonicalize", true),
+			StripParams:      viper.GetStringSlice("links.strip_params"),
+			ResolveRedirects: viper.GetBool("links.resolve_redirects"),
+			Redirectors:      viper.GetStringSlice("links.redirectors"),
+		},
+		Media: MediaConfig{
+			Enabled: viper.GetBool("media.enabled"),
+			Dir:     getStringWithDefault("media.dir", DefaultMediaDir),
+			MaxSize: getInt64WithDefault("media.max_size", DefaultMediaMaxSize),
+		},
+		Enclosures: EnclosuresConfig{
+			Dir:     getStringWithDefault("enclosures.dir", DefaultEnclosuresDir),
+			MaxSize: getInt64WithDefault("enclosures.max_size", DefaultEnclosuresMaxSize),
+			Keep:    viper.GetInt("enclosures.keep"),
+			Types:   getStringSliceWithDefault("enclosures.types", DefaultEnclosureTypes),
+			Timeout: getDurationWithDefault("enclosures.timeout", DefaultEnclosuresTimeout),
+		},
+		Metrics: MetricsConfig{
+			Textfile: viper.GetString("metrics.textfile"),
+			PushURL:  viper.GetString("metrics.push_url"),
+		},
+		Rules:         rules,
+		HTTPOverrides: overrides,
+	}, nil
+}
+
+// getRules returns the rules list.
+func getRules() ([]RuleConfig, error) {
+	var rules []RuleConfig
+	if err := viper.UnmarshalKey("rules", &rules); err != nil {
+		return nil, fmt.Errorf("invalid rules config: %w", err)
+	}
+	return rules, nil
+}
+
+// getHTTPOverrides returns the http_overrides list.
+func getHTTPOverrides() ([]HTTPOverrideConfig, error) {
+	var overrides []HTTPOverrideConfig
+	if err := viper.UnmarshalKey("http_overrides", &overrides); err != nil {
+		return nil, fmt.Errorf("invalid http_overrides config: %w", err)
 	}
+	return overrides, nil
 }
 
 func GetDefault() *Config {
@@ -148,9 +401,14 @@ func GetDefault() *Config {
 			Filename: "",
 		},
 		Fetch: FetchConfig{
-			WithUnfurl:  false, // Default to false
-			Concurrency: DefaultConcurrency,
-			MaxItems:    DefaultMaxItems,
+			WithUnfurl:         false, // Default to false
+			Concurrency:        DefaultConcurrency,
+			MaxItems:           DefaultMaxItems,
+			MinInterval:        DefaultMinFetchInterval,
+			MaxInterval:        DefaultMaxFetchInterval,
+			DisableAfter:       DefaultDisableAfter,
+			PerHostConcurrency: DefaultPerHostConcurrency,
+			PerHostRate:        DefaultPerHostRate,
 		},
 		Render: RenderConfig{
 			OutputDir:              "./build",
@@ -160,6 +418,8 @@ func GetDefault() *Config {
 			DefaultMinItemsPerFeed: DefaultMinItemsPerFeed,
 			DefaultMaxItemsPerFeed: DefaultMaxItemsPerFeed,
 			FeedsPerPage:           DefaultFeedsPerPage,
+			RiverPaging:            "items",
+			ItemsPerPage:           DefaultItemsPerPage,
 		},
 		Serve: ServeConfig{
 			Port: defaultPort,
@@ -177,6 +437,30 @@ func GetDefault() *Config {
 		Purge: PurgeConfig{
 			MaxAge:       "30d",
 			MinItemsKeep: DefaultMinItemsKeepPurge,
+			FetchLogAge:  DefaultFetchLogMaxAge,
+		},
+		Daemon: DaemonConfig{
+			FetchInterval: DefaultFetchInterval,
+			PurgeInterval: DefaultPurgeInterval,
+		},
+		WebSub: WebSubConfig{
+			LeaseDuration: DefaultWebSubLease,
+		},
+		Dedupe: DedupeConfig{
+			Titles: true,
+		},
+		Links: LinksConfig{
+			Canonicalize: true,
+		},
+		Media: MediaConfig{
+			Dir:     DefaultMediaDir,
+			MaxSize: DefaultMediaMaxSize,
+		},
+		Enclosures: EnclosuresConfig{
+			Dir:     DefaultEnclosuresDir,
+			MaxSize: DefaultEnclosuresMaxSize,
+			Types:   DefaultEnclosureTypes,
+			Timeout: DefaultEnclosuresTimeout,
 		},
 	}
 }
@@ -3,6 +3,8 @@ package config
 import (
 	"testing"
 	"time"
+
+	"github.com/spf13/viper"
 )
 
 func TestGetDefault(t *testing.T) {
@@ -17,11 +19,27 @@ func TestGetDefault(t *testing.T) {
 		{"Timeout", cfg.Timeout, 30 * time.Second},
 		{"Fetch.Concurrency", cfg.Fetch.Concurrency, 32},
 		{"Fetch.MaxItems", cfg.Fetch.MaxItems, 100},
+		{"Fetch.MinInterval", cfg.Fetch.MinInterval, 15 * time.Minute},
+		{"Fetch.MaxInterval", cfg.Fetch.MaxInterval, 24 * time.Hour},
+		{"Fetch.DisableAfter", cfg.Fetch.DisableAfter, 10},
+		{"Fetch.PerHostConcurrency", cfg.Fetch.PerHostConcurrency, 4},
 		{"Verbose", cfg.Verbose, false},
 		{"Debug", cfg.Debug, false},
 		{"JSON", cfg.JSON, false},
 		{"FeedList.Format", cfg.FeedList.Format, ""},
 		{"FeedList.Filename", cfg.FeedList.Filename, ""},
+		{"Daemon.FetchInterval", cfg.Daemon.FetchInterval, 30 * time.Minute},
+		{"Daemon.PurgeInterval", cfg.Daemon.PurgeInterval, 24 * time.Hour},
+		{"Dedupe.Enabled", cfg.Dedupe.Enabled, false},
+		{"Dedupe.Titles", cfg.Dedupe.Titles, true},
+		{"Links.Canonicalize", cfg.Links.Canonicalize, true},
+		{"Links.ResolveRedirects", cfg.Links.ResolveRedirects, false},
+		{"Media.Enabled", cfg.Media.Enabled, false},
+		{"Media.Dir", cfg.Media.Dir, "./media"},
+		{"Media.MaxSize", cfg.Media.MaxSize, int64(5 * 1024 * 1024)},
+		{"Enclosures.Dir", cfg.Enclosures.Dir, "./enclosures"},
+		{"Enclosures.Keep", cfg.Enclosures.Keep, 0},
+		{"Enclosures.Timeout", cfg.Enclosures.Timeout, time.Hour},
 	}
 
 	for _, tt := range tests {
@@ -97,3 +115,33 @@ func TestGetDefaultFeedList(t *testing.T) {
 		t.Errorf("GetDefaultFeedList() filename = %v, want %v", filename, "my-feeds.opml")
 	}
 }
+
+func TestLoadConfigInvalidRules(t *testing.T) {
+	t.Cleanup(viper.Reset)
+
+	viper.Set("rules", []interface{}{map[string]interface{}{"title": "golang", "action": "skip"}})
+	cfg, err := LoadConfig()
+	if err != nil || len(cfg.Rules) != 1 {
+		t.Fatalf("LoadConfig() = %v, %v; want one rule", cfg, err)
+	}
+
+	viper.Set("rules", []interface{}{"not a rule"})
+	if _, err := LoadConfig(); err == nil {
+		t.Error("LoadConfig() with invalid rules succeeded, want error")
+	}
+}
+
+func TestLoadConfigInvalidHTTPOverrides(t *testing.T) {
+	t.Cleanup(viper.Reset)
+
+	viper.Set("http_overrides", []interface{}{map[string]interface{}{"host": "example.com", "cookie": "a=b"}})
+	cfg, err := LoadConfig()
+	if err != nil || len(cfg.HTTPOverrides) != 1 {
+		t.Fatalf("LoadConfig() = %v, %v; want one override", cfg, err)
+	}
+
+	viper.Set("http_overrides", []interface{}{map[string]interface{}{"headers": "not a map"}})
+	if _, err := LoadConfig(); err == nil {
+		t.Error("LoadConfig() with invalid http_overrides succeeded, want error")
+	}
+}
@@ -0,0 +1,100 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Job is a named unit of recurring work run by the Scheduler.
+type Job struct {
+	Name     string
+	Interval time.Duration // Jobs with an interval <= 0 are disabled
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs jobs on fixed intervals within a single process.
+//
+// Jobs run serially, never concurrently with one another, so a fetch never
+// overlaps a purge. Other writers in the process, such as HTTP handlers, are
+// not serialized with them: they use their own database connections and wait
+// on SQLite's busy timeout for the write lock. Every enabled job runs once at
+// startup, in the order given, and is then rescheduled Interval after it
+// finishes.
+type Scheduler struct {
+	jobs []Job
+}
+
+// NewScheduler creates a scheduler for the given jobs.
+func NewScheduler(jobs ...Job) *Scheduler {
+	return &Scheduler{jobs: jobs}
+}
+
+// Run executes jobs until the context is canceled. A failing job is logged
+// and rescheduled as usual; it does not stop the scheduler.
+func (s *Scheduler) Run(ctx context.Context) error {
+	enabled := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		if job.Interval > 0 && job.Run != nil {
+			enabled = append(enabled, job)
+		} else {
+			logrus.Infof("Scheduled job %s is disabled", job.Name)
+		}
+	}
+
+	if len(enabled) == 0 {
+		<-ctx.Done()
+		return nil
+	}
+
+	// Zero time means "due now", so every job runs once at startup
+	nextRun := make([]time.Time, len(enabled))
+
+	for {
+		idx := nextDue(nextRun)
+
+		if wait := time.Until(nextRun[idx]); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-timer.C:
+			}
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		job := enabled[idx]
+		runJob(ctx, job)
+		nextRun[idx] = time.Now().Add(job.Interval)
+		logrus.Infof("Next %s run at %s", job.Name, nextRun[idx].Format(time.RFC3339))
+	}
+}
+
+// nextDue returns the index of the job that should run next. Ties go to the
+// job listed first.
+func nextDue(nextRun []time.Time) int {
+	idx := 0
+	for i := 1; i < len(nextRun); i++ {
+		if nextRun[i].Before(nextRun[idx]) {
+			idx = i
+		}
+	}
+	return idx
+}
+
+func runJob(ctx context.Context, job Job) {
+	logrus.Infof("Starting scheduled %s", job.Name)
+	start := time.Now()
+
+	if err := job.Run(ctx); err != nil {
+		logrus.WithError(err).Errorf("Scheduled %s failed after %v", job.Name, time.Since(start).Round(time.Millisecond))
+		return
+	}
+
+	logrus.Infof("Finished scheduled %s in %v", job.Name, time.Since(start).Round(time.Millisecond))
+}
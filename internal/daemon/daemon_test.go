@@ -0,0 +1,114 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSchedulerRunsJobsAtStartupInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+
+	record := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	scheduler := NewScheduler(
+		Job{Name: "purge", Interval: time.Hour, Run: record("purge")},
+		Job{Name: "fetch", Interval: time.Hour, Run: record("fetch")},
+	)
+	if err := scheduler.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(order) != 2 || order[0] != "purge" || order[1] != "fetch" {
+		t.Errorf("Run() order = %v, want [purge fetch]", order)
+	}
+}
+
+func TestSchedulerRepeatsOnInterval(t *testing.T) {
+	var mu sync.Mutex
+	runs := 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+	defer cancel()
+
+	scheduler := NewScheduler(Job{
+		Name:     "fetch",
+		Interval: 20 * time.Millisecond,
+		Run: func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			runs++
+			return nil
+		},
+	})
+	if err := scheduler.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if runs < 3 {
+		t.Errorf("Run() ran job %d times, want at least 3", runs)
+	}
+}
+
+func TestSchedulerContinuesAfterJobError(t *testing.T) {
+	runs := 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Millisecond)
+	defer cancel()
+
+	scheduler := NewScheduler(Job{
+		Name:     "render",
+		Interval: 10 * time.Millisecond,
+		Run: func(context.Context) error {
+			runs++
+			return errors.New("boom")
+		},
+	})
+	if err := scheduler.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if runs < 2 {
+		t.Errorf("Run() ran failing job %d times, want at least 2", runs)
+	}
+}
+
+func TestSchedulerSkipsDisabledJobs(t *testing.T) {
+	ran := false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	scheduler := NewScheduler(Job{
+		Name:     "purge",
+		Interval: 0,
+		Run: func(context.Context) error {
+			ran = true
+			return nil
+		},
+	})
+	if err := scheduler.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if ran {
+		t.Error("Run() should not run a job with a zero interval")
+	}
+}
@@ -0,0 +1,28 @@
+// Package databasetest provides databases for the tests of packages that
+// store feeds and items.
+package databasetest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+)
+
+// New returns a database with the current schema in a temporary directory,
+// closed when the test finishes.
+func New(t testing.TB) *database.DB {
+	t.Helper()
+
+	db, err := database.New(filepath.Join(t.TempDir(), "feedspool_test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.InitSchema(); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"database/sql"
 	_ "embed"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -68,6 +69,10 @@ func (db *DB) InitSchema() error {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	if err := db.ensureSearchIndex(); err != nil {
+		return err
+	}
+
 	logrus.Debug("Database schema initialized")
 	return nil
 }
@@ -95,6 +100,10 @@ func (db *DB) IsInitialized() error {
 		// Don't fail here - the database is still usable even if migrations fail
 	}
 
+	if err := db.ensureSearchIndex(); err != nil {
+		logrus.Warnf("Failed to update search index: %v", err)
+	}
+
 	return nil
 }
 
@@ -126,7 +135,7 @@ func (db *DB) ApplyMigration(version int, migrationSQL string) error {
 		return err
 	}
 	defer func() {
-		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
 			// Only log rollback errors if they're not transaction already committed
 			logrus.WithError(rollbackErr).Warn("Failed to rollback transaction")
 		}
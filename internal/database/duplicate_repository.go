@@ -0,0 +1,221 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DuplicateCandidate is the part of an item used to find its duplicates.
+type DuplicateCandidate struct {
+	ID       int64
+	FeedURL  string
+	Title    string
+	Link     string
+	Metadata JSON // Unfurled metadata of the item link, if any
+}
+
+// GroupItem records the duplicate keys of the item with the given feed and
+// GUID, and joins it to the earliest group of items from other feeds sharing
+// any of those keys. A group never holds two items from the same feed.
+// Returns the ID of the group's first item, or 0 if the item has no duplicate.
+func (db *DB) GroupItem(feedURL, guid string, keys []string) (int64, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			logrus.Warnf("Failed to rollback transaction: %v", rollbackErr)
+		}
+	}()
+
+	var itemID int64
+	var duplicateOf sql.NullInt64
+	err = tx.QueryRow("SELECT id, duplicate_of FROM items WHERE feed_url = ? AND guid = ?", feedURL, guid).
+		Scan(&itemID, &duplicateOf)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get item: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM item_keys WHERE item_id = ?", itemID); err != nil {
+		return 0, fmt.Errorf("failed to clear item keys: %w", err)
+	}
+	for _, key := range keys {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO item_keys (item_id, key) VALUES (?, ?)", itemID, key); err != nil {
+			return 0, fmt.Errorf("failed to add item key: %w", err)
+		}
+	}
+
+	if !duplicateOf.Valid && len(keys) > 0 {
+		duplicateOf, err = findDuplicateGroup(tx, itemID, feedURL, keys)
+		if err != nil {
+			return 0, err
+		}
+		if duplicateOf.Valid {
+			if _, err := tx.Exec("UPDATE items SET duplicate_of = ? WHERE id = ?", duplicateOf, itemID); err != nil {
+				return 0, fmt.Errorf("failed to group item: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit item keys: %w", err)
+	}
+
+	if duplicateOf.Valid {
+		logrus.Debugf("Item %s from %s is a duplicate of item %d", guid, feedURL, duplicateOf.Int64)
+	}
+	return duplicateOf.Int64, nil
+}
+
+// findDuplicateGroup returns the first item of the earliest group, without
+// items from feedURL, that shares a key with the item. Items that already head
+// a group of their own stay where they are, so groups are never nested.
+func findDuplicateGroup(tx *sql.Tx, itemID int64, feedURL string, keys []string) (sql.NullInt64, error) {
+	placeholders := strings.Repeat(",?", len(keys))[1:]
+	args := make([]interface{}, 0, len(keys)+3)
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	args = append(args, feedURL, feedURL, itemID, itemID)
+
+	var group sql.NullInt64
+	err := tx.QueryRow(`
+		SELECT COALESCE(items.duplicate_of, items.id) FROM item_keys
+		JOIN items ON items.id = item_keys.item_id
+		WHERE item_keys.key IN (`+placeholders+`)
+			AND items.feed_url != ?
+			AND COALESCE(items.duplicate_of, items.id) NOT IN (
+				SELECT COALESCE(duplicate_of, id) FROM items WHERE feed_url = ? AND id != ?)
+			AND NOT EXISTS (SELECT 1 FROM items WHERE duplicate_of = ?)
+		ORDER BY COALESCE(items.first_seen, items.published_date), items.id
+		LIMIT 1`, args...).Scan(&group)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sql.NullInt64{}, nil
+	}
+	if err != nil {
+		return sql.NullInt64{}, fmt.Errorf("failed to find duplicate items: %w", err)
+	}
+	return group, nil
+}
+
+// GetDuplicateCandidates retrieves every item with the metadata of its link,
+// in the order the items were first seen.
+func (db *DB) GetDuplicateCandidates() ([]DuplicateCandidate, error) {
+	rows, err := db.conn.Query(`
+		SELECT items.id, items.feed_url, items.title, items.link, url_metadata.metadata
+		FROM items
+		LEFT JOIN url_metadata ON url_metadata.url = items.link
+		ORDER BY COALESCE(items.first_seen, items.published_date), items.id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get items: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := []DuplicateCandidate{}
+	for rows.Next() {
+		var candidate DuplicateCandidate
+		if err := rows.Scan(&candidate.ID, &candidate.FeedURL, &candidate.Title, &candidate.Link,
+			&candidate.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over items: %w", err)
+	}
+
+	return candidates, nil
+}
+
+// GetItemsWithLinks retrieves the items linking to any of links, in the order
+// they were first seen.
+func (db *DB) GetItemsWithLinks(links []string) ([]*Item, error) {
+	items := []*Item{}
+	for start := 0; start < len(links); start += maxQueryParams {
+		chunk := links[start:min(start+maxQueryParams, len(links))]
+		args := make([]interface{}, len(chunk))
+		for i, link := range chunk {
+			args[i] = link
+		}
+
+		rows, err := db.conn.Query(`SELECT `+itemColumns+` FROM items
+			WHERE link IN (`+strings.Repeat(",?", len(chunk))[1:]+`)
+			ORDER BY COALESCE(first_seen, published_date), id`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get items: %w", err)
+		}
+		for rows.Next() {
+			item := &Item{}
+			if err := scanItem(rows, item); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan item: %w", err)
+			}
+			items = append(items, item)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error iterating over items: %w", err)
+		}
+	}
+
+	return items, nil
+}
+
+// ReplaceDuplicateGroups replaces every item's duplicate keys and group in
+// one transaction. duplicateOf maps the ID of each duplicate item to the first
+// item of its group.
+func (db *DB) ReplaceDuplicateGroups(keys map[int64][]string, duplicateOf map[int64]int64) error {
+	start := time.Now()
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			logrus.Warnf("Failed to rollback transaction: %v", rollbackErr)
+		}
+	}()
+
+	if _, err := tx.Exec("DELETE FROM item_keys"); err != nil {
+		return fmt.Errorf("failed to clear item keys: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE items SET duplicate_of = NULL WHERE duplicate_of IS NOT NULL"); err != nil {
+		return fmt.Errorf("failed to clear duplicate groups: %w", err)
+	}
+
+	insertKey, err := tx.Prepare("INSERT OR IGNORE INTO item_keys (item_id, key) VALUES (?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare item key insert: %w", err)
+	}
+	defer insertKey.Close()
+
+	for itemID, itemKeys := range keys {
+		for _, key := range itemKeys {
+			if _, err := insertKey.Exec(itemID, key); err != nil {
+				return fmt.Errorf("failed to add item key: %w", err)
+			}
+		}
+	}
+
+	for itemID, groupID := range duplicateOf {
+		if _, err := tx.Exec("UPDATE items SET duplicate_of = ? WHERE id = ?", groupID, itemID); err != nil {
+			return fmt.Errorf("failed to group item: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit duplicate groups: %w", err)
+	}
+
+	logrus.Debugf("Replaced duplicate groups of %d items in %v", len(keys), time.Since(start))
+	return nil
+}
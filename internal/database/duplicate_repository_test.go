@@ -0,0 +1,99 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDuplicateGroups(t *testing.T) {
+	db := setupTestDB(t)
+
+	ids := make(map[string]int64)
+	for _, entry := range []struct{ feedURL, guid string }{
+		{"https://a.example/feed", "a1"},
+		{"https://b.example/feed", "b1"},
+		{"https://a.example/feed", "a2"},
+	} {
+		if err := db.UpsertFeed(&Feed{URL: entry.feedURL}); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.UpsertItem(&Item{FeedURL: entry.feedURL, GUID: entry.guid}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.GroupItem(entry.feedURL, entry.guid, []string{"link:example.com/story"}); err != nil {
+			t.Fatalf("GroupItem(%s) error = %v", entry.guid, err)
+		}
+		items, err := db.GetItemsForFeed(entry.feedURL, 0, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range items {
+			ids[item.GUID] = item.ID
+		}
+	}
+
+	candidates, err := db.GetDuplicateCandidates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 3 {
+		t.Fatalf("GetDuplicateCandidates() = %d items, want 3", len(candidates))
+	}
+
+	duplicateOf := func(guid string) int64 {
+		items, err := db.ListItems(&ItemFilter{IDs: []int64{ids[guid]}})
+		if err != nil || len(items) != 1 {
+			t.Fatalf("ListItems(%s) = %v, %v", guid, items, err)
+		}
+		return items[0].DuplicateOf.Int64
+	}
+
+	// b1 joins a1's group; a2 shares the key but not across feeds
+	if got := duplicateOf("b1"); got != ids["a1"] {
+		t.Errorf("b1 duplicate_of = %d, want %d", got, ids["a1"])
+	}
+	if got := duplicateOf("a2"); got != 0 {
+		t.Errorf("a2 duplicate_of = %d, want none", got)
+	}
+
+	// a1 heads a group, so it stays put when regrouped with c1's key
+	if err := db.UpsertFeed(&Feed{URL: "https://c.example/feed"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpsertItem(&Item{FeedURL: "https://c.example/feed", GUID: "c1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GroupItem("https://c.example/feed", "c1", []string{"link:example.com/canonical"}); err != nil {
+		t.Fatal(err)
+	}
+	group, err := db.GroupItem("https://a.example/feed", "a1",
+		[]string{"link:example.com/story", "link:example.com/canonical"})
+	if err != nil || group != 0 {
+		t.Errorf("GroupItem(a1) = %d, %v; want 0 for a group's first item", group, err)
+	}
+
+	// None of these items has a link
+	items, err := db.GetItemsWithLinks([]string{""})
+	if err != nil || len(items) != 4 {
+		t.Errorf("GetItemsWithLinks() = %d items, %v; want 4", len(items), err)
+	}
+
+	// Replacing the groups drops the old ones
+	err = db.ReplaceDuplicateGroups(
+		map[int64][]string{ids["a2"]: {"title:abc"}, ids["b1"]: {"title:abc"}},
+		map[int64]int64{ids["b1"]: ids["a2"]})
+	if err != nil {
+		t.Fatalf("ReplaceDuplicateGroups() error = %v", err)
+	}
+	if got := duplicateOf("b1"); got != ids["a2"] {
+		t.Errorf("b1 duplicate_of = %d, want %d", got, ids["a2"])
+	}
+
+	// Deleting the first item of a group ungroups its duplicates
+	if _, err := db.conn.Exec("DELETE FROM items WHERE id = ?", ids["a2"]); err != nil {
+		t.Fatal(err)
+	}
+	if got := duplicateOf("b1"); got != 0 {
+		t.Errorf("b1 duplicate_of = %d after deleting its group, want none", got)
+	}
+}
@@ -0,0 +1,224 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const enclosureColumns = `e.item_id, e.url, e.type, e.length, e.duration,
+	e.local_path, e.downloaded_at, e.download_error`
+
+// ItemEnclosure is an enclosure along with the item and feed it belongs to.
+type ItemEnclosure struct {
+	Enclosure
+	FeedURL       string
+	FeedTitle     string
+	ItemTitle     string
+	PublishedDate time.Time
+}
+
+func scanEnclosure(scanner interface{ Scan(...interface{}) error }, dest ...interface{}) (*Enclosure, error) {
+	var enclosure Enclosure
+	fields := []interface{}{
+		&enclosure.ItemID, &enclosure.URL, &enclosure.Type, &enclosure.Length, &enclosure.Duration,
+		&enclosure.LocalPath, &enclosure.DownloadedAt, &enclosure.DownloadError,
+	}
+	if err := scanner.Scan(append(fields, dest...)...); err != nil {
+		return nil, err
+	}
+	return &enclosure, nil
+}
+
+// SetItemEnclosures replaces the enclosures of the item with the given feed
+// and GUID. Enclosures the item still has keep their download state.
+func (db *DB) SetItemEnclosures(feedURL, guid string, enclosures []*Enclosure) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			logrus.Warnf("Failed to rollback transaction: %v", rollbackErr)
+		}
+	}()
+
+	var itemID int64
+	err = tx.QueryRow("SELECT id FROM items WHERE feed_url = ? AND guid = ?", feedURL, guid).Scan(&itemID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find item: %w", err)
+	}
+
+	if err := replaceEnclosures(tx, itemID, enclosures); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit enclosures: %w", err)
+	}
+	return nil
+}
+
+// replaceEnclosures replaces the enclosures of an item, keeping the download
+// state of those it still has.
+func replaceEnclosures(conn execer, itemID int64, enclosures []*Enclosure) error {
+	query := "DELETE FROM enclosures WHERE item_id = ?"
+	args := []interface{}{itemID}
+	if len(enclosures) > 0 {
+		query += " AND url NOT IN (" + strings.Repeat(",?", len(enclosures))[1:] + ")"
+		for _, enclosure := range enclosures {
+			args = append(args, enclosure.URL)
+		}
+	}
+	if _, err := conn.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to remove old enclosures: %w", err)
+	}
+
+	for _, enclosure := range enclosures {
+		_, err := conn.Exec(`
+			INSERT INTO enclosures (item_id, url, type, length, duration) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(item_id, url) DO UPDATE SET
+				type = excluded.type,
+				length = excluded.length,
+				duration = excluded.duration`,
+			itemID, enclosure.URL, enclosure.Type, enclosure.Length, enclosure.Duration)
+		if err != nil {
+			return fmt.Errorf("failed to save enclosure: %w", err)
+		}
+		enclosure.ItemID = itemID
+	}
+	return nil
+}
+
+// GetAllEnclosures retrieves the enclosures of every item, keyed by item ID,
+// in the order the feed listed them.
+func (db *DB) GetAllEnclosures() (map[int64][]Enclosure, error) {
+	rows, err := db.conn.Query("SELECT " + enclosureColumns + " FROM enclosures e ORDER BY e.item_id, e.rowid")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get enclosures: %w", err)
+	}
+	defer rows.Close()
+
+	enclosures := make(map[int64][]Enclosure)
+	for rows.Next() {
+		enclosure, err := scanEnclosure(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan enclosure: %w", err)
+		}
+		enclosures[enclosure.ItemID] = append(enclosures[enclosure.ItemID], *enclosure)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over enclosures: %w", err)
+	}
+
+	return enclosures, nil
+}
+
+// GetItemEnclosures retrieves the enclosures of the given feeds' items, or of
+// every feed's when none are given, newest item first within each feed.
+func (db *DB) GetItemEnclosures(feedURLs []string) ([]*ItemEnclosure, error) {
+	query := `
+		SELECT ` + enclosureColumns + `, i.feed_url, COALESCE(f.title, ''), i.title, i.published_date
+		FROM enclosures e
+		JOIN items i ON i.id = e.item_id
+		JOIN feeds f ON f.url = i.feed_url`
+	args := []interface{}{}
+	if len(feedURLs) > 0 {
+		query += " WHERE i.feed_url IN (" + strings.Repeat(",?", len(feedURLs))[1:] + ")"
+		for _, feedURL := range feedURLs {
+			args = append(args, feedURL)
+		}
+	}
+	query += " ORDER BY i.feed_url, i.published_date DESC, i.id DESC, e.rowid"
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item enclosures: %w", err)
+	}
+	defer rows.Close()
+
+	enclosures := []*ItemEnclosure{}
+	for rows.Next() {
+		var ie ItemEnclosure
+		enclosure, err := scanEnclosure(rows, &ie.FeedURL, &ie.FeedTitle, &ie.ItemTitle, &ie.PublishedDate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan item enclosure: %w", err)
+		}
+		ie.Enclosure = *enclosure
+		enclosures = append(enclosures, &ie)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over item enclosures: %w", err)
+	}
+
+	return enclosures, nil
+}
+
+// RecordEnclosureDownload records that an enclosure was downloaded to a path
+// relative to the enclosures directory.
+func (db *DB) RecordEnclosureDownload(itemID int64, url, localPath string) error {
+	_, err := db.conn.Exec(`
+		UPDATE enclosures SET local_path = ?, downloaded_at = ?, download_error = NULL
+		WHERE item_id = ? AND url = ?`,
+		localPath, time.Now(), itemID, url)
+	if err != nil {
+		return fmt.Errorf("failed to record enclosure download: %w", err)
+	}
+	return nil
+}
+
+// RecordEnclosureError records why an enclosure failed to download.
+func (db *DB) RecordEnclosureError(itemID int64, url, message string) error {
+	_, err := db.conn.Exec("UPDATE enclosures SET download_error = ? WHERE item_id = ? AND url = ?",
+		message, itemID, url)
+	if err != nil {
+		return fmt.Errorf("failed to record enclosure error: %w", err)
+	}
+	return nil
+}
+
+// ClearEnclosureDownload forgets the downloaded file of an enclosure, once it
+// has been deleted.
+func (db *DB) ClearEnclosureDownload(itemID int64, url string) error {
+	_, err := db.conn.Exec(`
+		UPDATE enclosures SET local_path = NULL, downloaded_at = NULL
+		WHERE item_id = ? AND url = ?`,
+		itemID, url)
+	if err != nil {
+		return fmt.Errorf("failed to clear enclosure download: %w", err)
+	}
+	return nil
+}
+
+// GetEnclosurePaths retrieves the paths of every downloaded enclosure.
+func (db *DB) GetEnclosurePaths() (map[string]bool, error) {
+	rows, err := db.conn.Query("SELECT local_path FROM enclosures WHERE local_path IS NOT NULL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get enclosure paths: %w", err)
+	}
+	defer rows.Close()
+
+	paths := make(map[string]bool)
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("failed to scan enclosure path: %w", err)
+		}
+		paths[path] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over enclosure paths: %w", err)
+	}
+
+	return paths, nil
+}
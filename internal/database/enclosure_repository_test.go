@@ -0,0 +1,93 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestItemEnclosures(t *testing.T) {
+	db := setupTestDB(t)
+	feedURL := "https://example.com/podcast.xml"
+
+	if err := db.UpsertFeed(&Feed{URL: feedURL, Title: "Podcast"}); err != nil {
+		t.Fatal(err)
+	}
+	published := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, guid := range []string{"episode-1", "episode-2"} {
+		item := &Item{FeedURL: feedURL, GUID: guid, Title: guid, PublishedDate: published.AddDate(0, 0, i)}
+		if err := db.UpsertItem(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first := []*Enclosure{
+		{URL: "https://cdn.example.com/1.mp3", Type: "audio/mpeg", Length: 100},
+		{URL: "https://cdn.example.com/1.jpg", Type: "image/jpeg"},
+	}
+	if err := db.SetItemEnclosures(feedURL, "episode-1", first); err != nil {
+		t.Fatalf("SetItemEnclosures() error = %v", err)
+	}
+	second := []*Enclosure{{URL: "https://cdn.example.com/2.mp3", Type: "audio/mpeg", Duration: "30:00"}}
+	if err := db.SetItemEnclosures(feedURL, "episode-2", second); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetItemEnclosures(feedURL, "unknown", first); err != nil {
+		t.Errorf("SetItemEnclosures(unknown item) error = %v, want nil", err)
+	}
+
+	enclosures, err := db.GetItemEnclosures(nil)
+	if err != nil {
+		t.Fatalf("GetItemEnclosures() error = %v", err)
+	}
+	if len(enclosures) != 3 || enclosures[0].URL != second[0].URL || enclosures[1].URL != first[0].URL {
+		t.Fatalf("GetItemEnclosures() = %+v, want the newest item's first", enclosures)
+	}
+	if enclosures[0].FeedTitle != "Podcast" || enclosures[0].ItemTitle != "episode-2" || enclosures[0].Duration != "30:00" {
+		t.Errorf("GetItemEnclosures()[0] = %+v, want its feed and item", enclosures[0])
+	}
+
+	// Downloads survive a refetch listing the same enclosure; dropped ones go
+	audio := enclosures[1]
+	if err := db.RecordEnclosureDownload(audio.ItemID, audio.URL, "podcast/1.mp3"); err != nil {
+		t.Fatalf("RecordEnclosureDownload() error = %v", err)
+	}
+	if err := db.SetItemEnclosures(feedURL, "episode-1", first[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := db.GetAllEnclosures()
+	if err != nil {
+		t.Fatalf("GetAllEnclosures() error = %v", err)
+	}
+	kept := all[audio.ItemID]
+	if len(kept) != 1 || kept[0].LocalPath.String != "podcast/1.mp3" || !kept[0].DownloadedAt.Valid {
+		t.Errorf("enclosures after refetch = %+v, want the downloaded audio only", kept)
+	}
+
+	paths, err := db.GetEnclosurePaths()
+	if err != nil || len(paths) != 1 || !paths["podcast/1.mp3"] {
+		t.Errorf("GetEnclosurePaths() = %v, %v", paths, err)
+	}
+
+	if err := db.RecordEnclosureError(audio.ItemID, audio.URL, "HTTP 404"); err != nil {
+		t.Fatalf("RecordEnclosureError() error = %v", err)
+	}
+	if err := db.ClearEnclosureDownload(audio.ItemID, audio.URL); err != nil {
+		t.Fatalf("ClearEnclosureDownload() error = %v", err)
+	}
+	enclosures, err = db.GetItemEnclosures([]string{feedURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := enclosures[1]; got.LocalPath.Valid || got.DownloadError.String != "HTTP 404" {
+		t.Errorf("enclosure after clearing = %+v, want no download and the error", got)
+	}
+
+	// Enclosures go with their feed's items
+	if err := db.DeleteFeed(feedURL); err != nil {
+		t.Fatal(err)
+	}
+	if all, err := db.GetAllEnclosures(); err != nil || len(all) != 0 {
+		t.Errorf("GetAllEnclosures() after deleting the feed = %v, %v; want none", all, err)
+	}
+}
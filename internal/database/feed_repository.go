@@ -2,6 +2,7 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -9,12 +10,47 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// UpsertFeed inserts or updates a feed record in the database.
+// feedColumns lists the feeds columns in the order scanFeed expects them.
+const feedColumns = `url, title, description, last_updated, etag, last_modified,
+	last_fetch_time, last_successful_fetch, error_count, last_error, latest_item_date, feed_json,
+	next_fetch_at, disabled, parked_until, full_text,
+	title_override, fetch_interval, max_items, hidden, retention`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// scanFeed scans a row selected with feedColumns into feed. Setting durations
+// are stored in seconds.
+func scanFeed(row rowScanner, feed *Feed) error {
+	var fetchInterval, retention int64
+	err := row.Scan(
+		&feed.URL, &feed.Title, &feed.Description, &feed.LastUpdated, &feed.ETag,
+		&feed.LastModified, &feed.LastFetchTime, &feed.LastSuccessfulFetch,
+		&feed.ErrorCount, &feed.LastError, &feed.LatestItemDate, &feed.FeedJSON,
+		&feed.NextFetchAt, &feed.Disabled, &feed.ParkedUntil, &feed.FullText,
+		&feed.TitleOverride, &fetchInterval, &feed.MaxItems, &feed.Hidden, &retention)
+	if err != nil {
+		return err
+	}
+	feed.FetchInterval = time.Duration(fetchInterval) * time.Second
+	feed.Retention = time.Duration(retention) * time.Second
+	return nil
+}
+
+// UpsertFeed inserts or updates a feed record in the database. The disabled
+// and full_text flags and the feed's settings are only set on insert; use
+// DisableFeed, EnableFeed, SetFeedFullText and SetFeedSettings to change them.
 func (db *DB) UpsertFeed(feed *Feed) error {
 	query := `
-		INSERT INTO feeds (url, title, description, last_updated, etag, last_modified,
-			last_fetch_time, last_successful_fetch, error_count, last_error, latest_item_date, feed_json)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO feeds (` + feedColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(url) DO UPDATE SET
 			title = excluded.title,
 			description = excluded.description,
@@ -26,13 +62,18 @@ func (db *DB) UpsertFeed(feed *Feed) error {
 			error_count = excluded.error_count,
 			last_error = excluded.last_error,
 			latest_item_date = COALESCE(excluded.latest_item_date, feeds.latest_item_date),
-			feed_json = excluded.feed_json
+			feed_json = excluded.feed_json,
+			next_fetch_at = excluded.next_fetch_at,
+			parked_until = excluded.parked_until
 	`
 
 	_, err := db.conn.Exec(query,
 		feed.URL, feed.Title, feed.Description, feed.LastUpdated, feed.ETag,
 		feed.LastModified, feed.LastFetchTime, feed.LastSuccessfulFetch,
-		feed.ErrorCount, feed.LastError, feed.LatestItemDate, feed.FeedJSON)
+		feed.ErrorCount, feed.LastError, feed.LatestItemDate, feed.FeedJSON,
+		feed.NextFetchAt, feed.Disabled, feed.ParkedUntil, feed.FullText,
+		feed.TitleOverride, int64(feed.FetchInterval/time.Second), feed.MaxItems, feed.Hidden,
+		int64(feed.Retention/time.Second))
 	if err != nil {
 		return fmt.Errorf("failed to upsert feed: %w", err)
 	}
@@ -43,17 +84,10 @@ func (db *DB) UpsertFeed(feed *Feed) error {
 
 // GetFeed retrieves a feed by URL from the database.
 func (db *DB) GetFeed(url string) (*Feed, error) {
-	query := `
-		SELECT url, title, description, last_updated, etag, last_modified,
-			last_fetch_time, last_successful_fetch, error_count, last_error, latest_item_date, feed_json
-		FROM feeds WHERE url = ?
-	`
+	query := `SELECT ` + feedColumns + ` FROM feeds WHERE url = ?`
 
 	feed := &Feed{}
-	err := db.conn.QueryRow(query, url).Scan(
-		&feed.URL, &feed.Title, &feed.Description, &feed.LastUpdated, &feed.ETag,
-		&feed.LastModified, &feed.LastFetchTime, &feed.LastSuccessfulFetch,
-		&feed.ErrorCount, &feed.LastError, &feed.LatestItemDate, &feed.FeedJSON)
+	err := scanFeed(db.conn.QueryRow(query, url), feed)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -67,11 +101,7 @@ func (db *DB) GetFeed(url string) (*Feed, error) {
 
 // GetAllFeeds retrieves all feeds from the database, ordered by URL.
 func (db *DB) GetAllFeeds() ([]*Feed, error) {
-	query := `
-		SELECT url, title, description, last_updated, etag, last_modified,
-			last_fetch_time, last_successful_fetch, error_count, last_error, latest_item_date, feed_json
-		FROM feeds ORDER BY url
-	`
+	query := `SELECT ` + feedColumns + ` FROM feeds ORDER BY url`
 
 	rows, err := db.conn.Query(query)
 	if err != nil {
@@ -82,10 +112,7 @@ func (db *DB) GetAllFeeds() ([]*Feed, error) {
 	feeds := []*Feed{}
 	for rows.Next() {
 		feed := &Feed{}
-		err := rows.Scan(
-			&feed.URL, &feed.Title, &feed.Description, &feed.LastUpdated, &feed.ETag,
-			&feed.LastModified, &feed.LastFetchTime, &feed.LastSuccessfulFetch,
-			&feed.ErrorCount, &feed.LastError, &feed.LatestItemDate, &feed.FeedJSON)
+		err := scanFeed(rows, feed)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan feed: %w", err)
 		}
@@ -99,6 +126,177 @@ func (db *DB) GetAllFeeds() ([]*Feed, error) {
 	return feeds, nil
 }
 
+// DisableFeed marks a feed as disabled so batch fetches skip it.
+func (db *DB) DisableFeed(url string) error {
+	_, err := db.conn.Exec("UPDATE feeds SET disabled = 1 WHERE url = ?", url)
+	if err != nil {
+		return fmt.Errorf("failed to disable feed: %w", err)
+	}
+
+	logrus.Debugf("Disabled feed: %s", url)
+	return nil
+}
+
+// EnableFeed re-enables a feed, clearing its error count and making it due
+// for the next fetch. Returns false if the feed does not exist.
+func (db *DB) EnableFeed(url string) (bool, error) {
+	result, err := db.conn.Exec(`
+		UPDATE feeds SET disabled = 0, error_count = 0, next_fetch_at = NULL
+		WHERE url = ?
+	`, url)
+	if err != nil {
+		return false, fmt.Errorf("failed to enable feed: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	logrus.Debugf("Enabled feed: %s", url)
+	return affected > 0, nil
+}
+
+// SetFeedFullText turns extracting the article text of a feed's items on or
+// off. Returns false if the feed does not exist.
+func (db *DB) SetFeedFullText(url string, fullText bool) (bool, error) {
+	result, err := db.conn.Exec("UPDATE feeds SET full_text = ? WHERE url = ?", fullText, url)
+	if err != nil {
+		return false, fmt.Errorf("failed to set feed full text: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	logrus.Debugf("Set full text %v for feed: %s", fullText, url)
+	return affected > 0, nil
+}
+
+// SetFeedSettings replaces the per-feed settings of a feed. Returns false if
+// the feed does not exist.
+func (db *DB) SetFeedSettings(url string, settings FeedSettings) (bool, error) {
+	result, err := db.conn.Exec(`
+		UPDATE feeds SET title_override = ?, fetch_interval = ?, max_items = ?, hidden = ?, retention = ?
+		WHERE url = ?`,
+		settings.TitleOverride, int64(settings.FetchInterval/time.Second), settings.MaxItems,
+		settings.Hidden, int64(settings.Retention/time.Second), url)
+	if err != nil {
+		return false, fmt.Errorf("failed to set feed settings: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	logrus.Debugf("Set settings %+v for feed: %s", settings, url)
+	return affected > 0, nil
+}
+
+// GetFeedSettings retrieves the per-feed settings of a feed, all zero if the
+// feed does not exist.
+func (db *DB) GetFeedSettings(url string) (FeedSettings, error) {
+	var settings FeedSettings
+	var fetchInterval, retention int64
+	err := db.conn.QueryRow(`
+		SELECT title_override, fetch_interval, max_items, hidden, retention FROM feeds WHERE url = ?`, url,
+	).Scan(&settings.TitleOverride, &fetchInterval, &settings.MaxItems, &settings.Hidden, &retention)
+	if errors.Is(err, sql.ErrNoRows) {
+		return FeedSettings{}, nil
+	}
+	if err != nil {
+		return FeedSettings{}, fmt.Errorf("failed to get feed settings: %w", err)
+	}
+
+	settings.FetchInterval = time.Duration(fetchInterval) * time.Second
+	settings.Retention = time.Duration(retention) * time.Second
+	return settings, nil
+}
+
+// GetFailingFeeds retrieves feeds that are disabled or whose last fetch failed,
+// disabled feeds first, then by consecutive error count.
+func (db *DB) GetFailingFeeds() ([]*Feed, error) {
+	query := `SELECT ` + feedColumns + ` FROM feeds
+		WHERE disabled = 1 OR error_count > 0
+		ORDER BY disabled DESC, error_count DESC, url`
+
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failing feeds: %w", err)
+	}
+	defer rows.Close()
+
+	feeds := []*Feed{}
+	for rows.Next() {
+		feed := &Feed{}
+		if err := scanFeed(rows, feed); err != nil {
+			return nil, fmt.Errorf("failed to scan feed: %w", err)
+		}
+		feeds = append(feeds, feed)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over feeds: %w", err)
+	}
+
+	return feeds, nil
+}
+
+// MigrateFeedURL moves a feed to a new URL, taking its items, tags, fetch log,
+// WebSub subscription and any url_metadata stored for the old URL with it. If a feed already exists at
+// newURL it is kept, and items it already has are dropped from the old feed.
+func (db *DB) MigrateFeedURL(oldURL, newURL string) error {
+	if oldURL == newURL {
+		return nil
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			logrus.Warnf("Failed to rollback transaction: %v", rollbackErr)
+		}
+	}()
+
+	statements := []struct {
+		query string
+		desc  string
+	}{
+		{`INSERT OR IGNORE INTO feeds (` + feedColumns + `)
+			SELECT ?, title, description, last_updated, etag, last_modified,
+				last_fetch_time, last_successful_fetch, error_count, last_error,
+				latest_item_date, feed_json, next_fetch_at, disabled, parked_until, full_text,
+				title_override, fetch_interval, max_items, hidden, retention
+			FROM feeds WHERE url = ?`, "copy feed"},
+		{`UPDATE OR IGNORE items SET feed_url = ? WHERE feed_url = ?`, "move items"},
+		{`UPDATE OR IGNORE url_metadata SET url = ? WHERE url = ?`, "move url metadata"},
+		{`UPDATE OR IGNORE feed_tags SET feed_url = ? WHERE feed_url = ?`, "move tags"},
+		{`UPDATE fetch_log SET feed_url = ? WHERE feed_url = ?`, "move fetch log"},
+		{`UPDATE OR IGNORE websub_subscriptions SET feed_url = ? WHERE feed_url = ?`, "move websub subscription"},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt.query, newURL, oldURL); err != nil {
+			return fmt.Errorf("failed to %s: %w", stmt.desc, err)
+		}
+	}
+
+	// Deleting the old feed cascades to any items the new feed already had
+	if _, err := tx.Exec("DELETE FROM feeds WHERE url = ?", oldURL); err != nil {
+		return fmt.Errorf("failed to delete old feed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit feed migration: %w", err)
+	}
+
+	logrus.Debugf("Migrated feed %s to %s", oldURL, newURL)
+	return nil
+}
+
 // DeleteFeed deletes a feed and all its associated items from the database.
 func (db *DB) DeleteFeed(url string) error {
 	_, err := db.conn.Exec("DELETE FROM feeds WHERE url = ?", url)
@@ -139,8 +337,7 @@ func (db *DB) GetFeedsWithItemsByTimeRange(start, end time.Time, feedURLs []stri
 	// Build feeds query
 	// Use latest_item_date to determine if feed has recent items, falling back to last_updated
 	feedsQuery := `
-		SELECT f.url, f.title, f.description, f.last_updated, f.etag, f.last_modified,
-			f.last_fetch_time, f.last_successful_fetch, f.error_count, f.last_error, f.latest_item_date, f.feed_json
+		SELECT ` + feedColumns + `
 		FROM feeds f
 		WHERE COALESCE(f.latest_item_date, f.last_updated) >= ?
 			AND COALESCE(f.latest_item_date, f.last_updated) <= ?
@@ -176,10 +373,7 @@ func (db *DB) GetFeedsWithItemsByTimeRange(start, end time.Time, feedURLs []stri
 
 	for rows.Next() {
 		feed := Feed{}
-		err := rows.Scan(
-			&feed.URL, &feed.Title, &feed.Description, &feed.LastUpdated, &feed.ETag,
-			&feed.LastModified, &feed.LastFetchTime, &feed.LastSuccessfulFetch,
-			&feed.ErrorCount, &feed.LastError, &feed.LatestItemDate, &feed.FeedJSON)
+		err := scanFeed(rows, &feed)
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to scan feed: %w", err)
 		}
@@ -260,11 +454,7 @@ func (db *DB) GetFeedsWithItemsMinimum(
 
 // getFeedsFiltered gets all feeds, optionally filtered by a list of URLs.
 func (db *DB) getFeedsFiltered(feedURLs []string) ([]Feed, error) {
-	query := `
-		SELECT url, title, description, last_updated, etag, last_modified,
-			last_fetch_time, last_successful_fetch, error_count, last_error, latest_item_date, feed_json
-		FROM feeds
-	`
+	query := `SELECT ` + feedColumns + ` FROM feeds`
 	args := []interface{}{}
 
 	if len(feedURLs) > 0 {
@@ -291,10 +481,7 @@ func (db *DB) getFeedsFiltered(feedURLs []string) ([]Feed, error) {
 	feeds := []Feed{}
 	for rows.Next() {
 		feed := Feed{}
-		err := rows.Scan(
-			&feed.URL, &feed.Title, &feed.Description, &feed.LastUpdated, &feed.ETag,
-			&feed.LastModified, &feed.LastFetchTime, &feed.LastSuccessfulFetch,
-			&feed.ErrorCount, &feed.LastError, &feed.LatestItemDate, &feed.FeedJSON)
+		err := scanFeed(rows, &feed)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan feed: %w", err)
 		}
@@ -314,8 +501,7 @@ func (db *DB) getFeedsFiltered(feedURLs []string) ([]Feed, error) {
 func (db *DB) getItemsForFeedWithMinimum(feedURL string, start, end time.Time, minItems int) ([]Item, error) {
 	// First, get items within the timespan
 	timespanQuery := `
-		SELECT id, feed_url, guid, title, link, published_date,
-			content, summary, archived, item_json
+		SELECT ` + itemColumns + `
 		FROM items
 		WHERE feed_url = ?
 			AND published_date >= ? AND published_date <= ?
@@ -331,11 +517,7 @@ func (db *DB) getItemsForFeedWithMinimum(feedURL string, start, end time.Time, m
 	items := []Item{}
 	for rows.Next() {
 		item := Item{}
-		err := rows.Scan(
-			&item.ID, &item.FeedURL, &item.GUID, &item.Title, &item.Link,
-			&item.PublishedDate, &item.Content, &item.Summary, &item.Archived,
-			&item.ItemJSON)
-		if err != nil {
+		if err := scanItem(rows, &item); err != nil {
 			return nil, fmt.Errorf("failed to scan timespan item: %w", err)
 		}
 		items = append(items, item)
@@ -353,8 +535,7 @@ func (db *DB) getItemsForFeedWithMinimum(feedURL string, start, end time.Time, m
 	// Otherwise, get additional recent items to reach minItems
 	// Query for recent items that we might not have already
 	recentQuery := `
-		SELECT id, feed_url, guid, title, link, published_date,
-			content, summary, archived, item_json
+		SELECT ` + itemColumns + `
 		FROM items
 		WHERE feed_url = ?
 		ORDER BY published_date DESC
@@ -377,11 +558,7 @@ func (db *DB) getItemsForFeedWithMinimum(feedURL string, start, end time.Time, m
 	needed := minItems - len(items)
 	for rows2.Next() && needed > 0 {
 		item := Item{}
-		err := rows2.Scan(
-			&item.ID, &item.FeedURL, &item.GUID, &item.Title, &item.Link,
-			&item.PublishedDate, &item.Content, &item.Summary, &item.Archived,
-			&item.ItemJSON)
-		if err != nil {
+		if err := scanItem(rows2, &item); err != nil {
 			return nil, fmt.Errorf("failed to scan recent item: %w", err)
 		}
 		if !existingGUIDs[item.GUID] {
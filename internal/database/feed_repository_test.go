@@ -1,6 +1,7 @@
 package database
 
 import (
+	"database/sql"
 	"testing"
 	"time"
 )
@@ -99,3 +100,206 @@ func TestGetAllFeeds(t *testing.T) {
 		t.Errorf("First feed URL = %v, want %v", retrieved[0].URL, "https://example1.com/feed.xml")
 	}
 }
+
+func TestDisableAndEnableFeed(t *testing.T) {
+	db := setupTestDB(t)
+
+	feed := &Feed{
+		URL:         "https://example.com/broken.xml",
+		Title:       "Broken Feed",
+		ErrorCount:  12,
+		LastError:   "HTTP 404",
+		FeedJSON:    JSON(`{}`),
+		NextFetchAt: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+	if err := db.UpsertFeed(feed); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.DisableFeed(feed.URL); err != nil {
+		t.Fatalf("DisableFeed() error = %v", err)
+	}
+
+	// Upserting must not silently re-enable the feed
+	if err := db.UpsertFeed(feed); err != nil {
+		t.Fatal(err)
+	}
+
+	failing, err := db.GetFailingFeeds()
+	if err != nil {
+		t.Fatalf("GetFailingFeeds() error = %v", err)
+	}
+	if len(failing) != 1 || !failing[0].Disabled {
+		t.Fatalf("GetFailingFeeds() = %+v, want one disabled feed", failing)
+	}
+
+	found, err := db.EnableFeed(feed.URL)
+	if err != nil {
+		t.Fatalf("EnableFeed() error = %v", err)
+	}
+	if !found {
+		t.Error("EnableFeed() found = false, want true")
+	}
+
+	retrieved, err := db.GetFeed(feed.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retrieved.Disabled || retrieved.ErrorCount != 0 || retrieved.NextFetchAt.Valid {
+		t.Errorf("EnableFeed() left disabled=%v errorCount=%d nextFetchAt=%v",
+			retrieved.Disabled, retrieved.ErrorCount, retrieved.NextFetchAt)
+	}
+
+	found, err = db.EnableFeed("https://example.com/missing.xml")
+	if err != nil {
+		t.Fatalf("EnableFeed() error = %v", err)
+	}
+	if found {
+		t.Error("EnableFeed() found = true for missing feed")
+	}
+}
+
+func TestMigrateFeedURL(t *testing.T) {
+	db := setupTestDB(t)
+
+	oldURL := "http://example.com/feed.xml"
+	newURL := "https://example.com/feed.xml"
+
+	if err := db.UpsertFeed(&Feed{URL: oldURL, Title: "Moved Feed", FeedJSON: JSON(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	for _, guid := range []string{"item-1", "item-2"} {
+		item := &Item{FeedURL: oldURL, GUID: guid, Link: "https://example.com/" + guid, ItemJSON: JSON(`{}`)}
+		if err := db.UpsertItem(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.UpsertMetadata(&URLMetadata{URL: oldURL, Metadata: JSON(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpsertWebSubHub(oldURL, "https://hub.example.com/", oldURL); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.MigrateFeedURL(oldURL, newURL); err != nil {
+		t.Fatalf("MigrateFeedURL() error = %v", err)
+	}
+
+	if feed, err := db.GetFeed(oldURL); err != nil || feed != nil {
+		t.Errorf("GetFeed(old) = %v, %v; want nil", feed, err)
+	}
+	feed, err := db.GetFeed(newURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed == nil || feed.Title != "Moved Feed" {
+		t.Fatalf("GetFeed(new) = %+v, want the moved feed", feed)
+	}
+
+	items, err := db.GetItemsForFeed(newURL, 0, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Errorf("GetItemsForFeed(new) returned %d items, want 2", len(items))
+	}
+
+	if metadata, err := db.GetMetadata(newURL); err != nil || metadata == nil {
+		t.Errorf("GetMetadata(new) = %v, %v; want moved metadata", metadata, err)
+	}
+
+	// The subscription moves rather than being deleted with the old feed
+	subs, err := db.GetWebSubSubscriptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 1 || subs[0].FeedURL != newURL {
+		t.Errorf("GetWebSubSubscriptions() = %+v, want one for %s", subs, newURL)
+	}
+}
+
+func TestMigrateFeedURLMergesIntoExistingFeed(t *testing.T) {
+	db := setupTestDB(t)
+
+	oldURL := "http://example.com/feed.xml"
+	newURL := "https://example.com/feed.xml"
+
+	for _, url := range []string{oldURL, newURL} {
+		if err := db.UpsertFeed(&Feed{URL: url, Title: url, FeedJSON: JSON(`{}`)}); err != nil {
+			t.Fatal(err)
+		}
+		item := &Item{FeedURL: url, GUID: "shared", ItemJSON: JSON(`{}`)}
+		if err := db.UpsertItem(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.UpsertItem(&Item{FeedURL: oldURL, GUID: "old-only", ItemJSON: JSON(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.MigrateFeedURL(oldURL, newURL); err != nil {
+		t.Fatalf("MigrateFeedURL() error = %v", err)
+	}
+
+	feed, err := db.GetFeed(newURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != newURL {
+		t.Errorf("existing feed title = %q, want it kept", feed.Title)
+	}
+
+	items, err := db.GetItemsForFeed(newURL, 0, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Errorf("GetItemsForFeed(new) returned %d items, want 2", len(items))
+	}
+}
+
+func TestSetFeedSettings(t *testing.T) {
+	db := setupTestDB(t)
+
+	feed := &Feed{URL: "https://example.com/feed.xml", Title: "Feed", FeedJSON: JSON(`{}`)}
+	if err := db.UpsertFeed(feed); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := FeedSettings{
+		TitleOverride: "My Feed",
+		FetchInterval: 6 * time.Hour,
+		MaxItems:      20,
+		Hidden:        true,
+		Retention:     90 * 24 * time.Hour,
+	}
+	found, err := db.SetFeedSettings(feed.URL, settings)
+	if err != nil {
+		t.Fatalf("SetFeedSettings() error = %v", err)
+	}
+	if !found {
+		t.Error("SetFeedSettings() found = false, want true")
+	}
+
+	// Upserting after a fetch must keep the settings
+	if err := db.UpsertFeed(feed); err != nil {
+		t.Fatal(err)
+	}
+	retrieved, err := db.GetFeed(feed.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retrieved.FeedSettings != settings {
+		t.Errorf("GetFeed() settings = %+v, want %+v", retrieved.FeedSettings, settings)
+	}
+	if got, err := db.GetFeedSettings(feed.URL); err != nil || got != settings {
+		t.Errorf("GetFeedSettings() = %+v, %v; want %+v", got, err, settings)
+	}
+
+	if got, err := db.GetFeedSettings("https://example.com/missing.xml"); err != nil || got != (FeedSettings{}) {
+		t.Errorf("GetFeedSettings(missing) = %+v, %v; want zero settings", got, err)
+	}
+	if found, err := db.SetFeedSettings("https://example.com/missing.xml", settings); err != nil || found {
+		t.Errorf("SetFeedSettings(missing) = %v, %v; want false", found, err)
+	}
+}
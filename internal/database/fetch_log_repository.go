@@ -0,0 +1,94 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const fetchLogColumns = `id, feed_url, started_at, duration_ms, status_code, bytes, cached,
+	new_items, updated_items, archived_items, error`
+
+// RecordFetch adds a fetch attempt to the fetch log.
+func (db *DB) RecordFetch(entry *FetchLogEntry) error {
+	result, err := db.conn.Exec(`
+		INSERT INTO fetch_log (feed_url, started_at, duration_ms, status_code, bytes, cached,
+			new_items, updated_items, archived_items, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.FeedURL, entry.StartedAt, entry.Duration.Milliseconds(), entry.StatusCode, entry.Bytes,
+		entry.Cached, entry.NewItems, entry.UpdatedItems, entry.ArchivedItems, entry.Error)
+	if err != nil {
+		return fmt.Errorf("failed to record fetch: %w", err)
+	}
+	entry.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// GetFetchLog retrieves fetch attempts, newest first, for one feed or for
+// every feed when feedURL is empty. Attempts started before since are left
+// out unless since is zero, and limit caps the number returned when positive.
+func (db *DB) GetFetchLog(feedURL string, since time.Time, limit int) ([]*FetchLogEntry, error) {
+	query := `SELECT ` + fetchLogColumns + ` FROM fetch_log WHERE 1 = 1`
+	args := []interface{}{}
+	if feedURL != "" {
+		query += " AND feed_url = ?"
+		args = append(args, feedURL)
+	}
+	if !since.IsZero() {
+		query += " AND started_at >= ?"
+		args = append(args, since)
+	}
+	query += " ORDER BY started_at DESC, id DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fetch log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*FetchLogEntry{}
+	for rows.Next() {
+		var entry FetchLogEntry
+		var durationMS int64
+		err := rows.Scan(&entry.ID, &entry.FeedURL, &entry.StartedAt, &durationMS, &entry.StatusCode,
+			&entry.Bytes, &entry.Cached, &entry.NewItems, &entry.UpdatedItems, &entry.ArchivedItems, &entry.Error)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan fetch log entry: %w", err)
+		}
+		entry.Duration = time.Duration(durationMS) * time.Millisecond
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over fetch log: %w", err)
+	}
+
+	return entries, nil
+}
+
+// CountFetchLogBefore counts the fetch attempts started before the given time.
+func (db *DB) CountFetchLogBefore(before time.Time) (int64, error) {
+	var count int64
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM fetch_log WHERE started_at < ?", before).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count fetch log entries: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteFetchLogBefore deletes the fetch attempts started before the given
+// time.
+func (db *DB) DeleteFetchLogBefore(before time.Time) (int64, error) {
+	result, err := db.conn.Exec("DELETE FROM fetch_log WHERE started_at < ?", before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete fetch log entries: %w", err)
+	}
+
+	deleted, _ := result.RowsAffected()
+	logrus.Debugf("Deleted %d fetch log entries", deleted)
+	return deleted, nil
+}
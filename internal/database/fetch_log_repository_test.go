@@ -0,0 +1,100 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFetchLog(t *testing.T) {
+	db := setupTestDB(t)
+	feedURL := "https://example.com/feed.xml"
+	otherURL := "https://example.org/feed.xml"
+	now := time.Now().Truncate(time.Second)
+
+	entries := []*FetchLogEntry{
+		{FeedURL: feedURL, StartedAt: now.Add(-100 * 24 * time.Hour), StatusCode: 200, Bytes: 1000, NewItems: 5},
+		{FeedURL: feedURL, StartedAt: now.Add(-2 * time.Hour), StatusCode: 304, Cached: true},
+		{FeedURL: otherURL, StartedAt: now.Add(-time.Hour), Error: "HTTP 500: Internal Server Error", StatusCode: 500},
+		{
+			FeedURL: feedURL, StartedAt: now, Duration: 1500 * time.Millisecond, StatusCode: 200, Bytes: 2048,
+			NewItems: 1, UpdatedItems: 2, ArchivedItems: 3,
+		},
+	}
+	for _, entry := range entries {
+		if err := db.RecordFetch(entry); err != nil {
+			t.Fatalf("RecordFetch() error = %v", err)
+		}
+		if entry.ID == 0 {
+			t.Errorf("RecordFetch() left ID unset")
+		}
+	}
+
+	all, err := db.GetFetchLog("", time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("GetFetchLog() error = %v", err)
+	}
+	if len(all) != 4 || all[0].ID != entries[3].ID || all[3].ID != entries[0].ID {
+		t.Fatalf("GetFetchLog() = %+v, want all four, newest first", all)
+	}
+	latest := all[0]
+	if latest.Duration != 1500*time.Millisecond || latest.Bytes != 2048 ||
+		latest.NewItems != 1 || latest.UpdatedItems != 2 || latest.ArchivedItems != 3 {
+		t.Errorf("GetFetchLog()[0] = %+v, want the recorded attempt", latest)
+	}
+	if all[1].Error == "" || !all[2].Cached {
+		t.Errorf("GetFetchLog() = %+v, want the error and cached flag kept", all)
+	}
+
+	feedOnly, err := db.GetFetchLog(feedURL, now.Add(-24*time.Hour), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feedOnly) != 2 || feedOnly[0].FeedURL != feedURL || feedOnly[1].FeedURL != feedURL {
+		t.Errorf("GetFetchLog(feed, since) = %+v, want the feed's two recent attempts", feedOnly)
+	}
+	if limited, _ := db.GetFetchLog(feedURL, time.Time{}, 1); len(limited) != 1 || limited[0].ID != entries[3].ID {
+		t.Errorf("GetFetchLog(limit 1) = %+v, want only the newest", limited)
+	}
+
+	cutoff := now.Add(-90 * 24 * time.Hour)
+	if count, err := db.CountFetchLogBefore(cutoff); err != nil || count != 1 {
+		t.Errorf("CountFetchLogBefore() = %d, %v; want 1", count, err)
+	}
+	if deleted, err := db.DeleteFetchLogBefore(cutoff); err != nil || deleted != 1 {
+		t.Errorf("DeleteFetchLogBefore() = %d, %v; want 1", deleted, err)
+	}
+	if remaining, _ := db.GetFetchLog("", time.Time{}, 0); len(remaining) != 3 {
+		t.Errorf("GetFetchLog() after delete = %d entries, want 3", len(remaining))
+	}
+}
+
+func TestFetchLogFollowsMovedFeed(t *testing.T) {
+	db := setupTestDB(t)
+	oldURL := "http://example.com/feed.xml"
+	newURL := "https://example.com/feed.xml"
+
+	if err := db.UpsertFeed(&Feed{URL: oldURL, FeedJSON: JSON(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RecordFetch(&FetchLogEntry{FeedURL: oldURL, StartedAt: time.Now(), StatusCode: 200}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.MigrateFeedURL(oldURL, newURL); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries, _ := db.GetFetchLog(oldURL, time.Time{}, 0); len(entries) != 0 {
+		t.Errorf("GetFetchLog(old) = %+v, want none", entries)
+	}
+	if entries, _ := db.GetFetchLog(newURL, time.Time{}, 0); len(entries) != 1 {
+		t.Errorf("GetFetchLog(new) = %+v, want the moved attempt", entries)
+	}
+
+	// The log outlives the feed, so history is kept for unsubscribed feeds
+	if err := db.DeleteFeed(newURL); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := db.GetFetchLog(newURL, time.Time{}, 0); len(entries) != 1 {
+		t.Errorf("GetFetchLog() after DeleteFeed = %+v, want the attempt kept", entries)
+	}
+}
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -8,40 +10,171 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// UpsertItem inserts or updates an item record in the database.
+// maxQueryParams is the most values bound to one IN (...) list; longer lists
+// are queried in chunks.
+const maxQueryParams = 500
+
+// itemColumns lists the items columns in the order scanItem expects them. They
+// are qualified so queries can join other tables with the same column names.
+const itemColumns = `items.id, items.feed_url, items.guid, items.title, items.link,
+	items.published_date, items.first_seen, items.content, items.summary, items.archived,
+	items.item_json, items.read, items.starred, items.highlighted, items.duplicate_of`
+
+// scanItem scans a row selected with itemColumns into item.
+func scanItem(row rowScanner, item *Item) error {
+	return row.Scan(
+		&item.ID, &item.FeedURL, &item.GUID, &item.Title, &item.Link,
+		&item.PublishedDate, &item.FirstSeen, &item.Content, &item.Summary, &item.Archived,
+		&item.ItemJSON, &item.Read, &item.Starred, &item.Highlighted, &item.DuplicateOf)
+}
+
+// UpsertItem inserts or updates an item record in the database and sets its
+// ID. The read state is only set for new items; existing items keep theirs.
 func (db *DB) UpsertItem(item *Item) error {
-	query := `
-		INSERT INTO items (feed_url, guid, title, link, published_date, first_seen,
-			content, summary, archived, item_json)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT(feed_url, guid) DO UPDATE SET
-			title = excluded.title,
-			link = excluded.link,
-			content = excluded.content,
-			summary = excluded.summary,
-			archived = excluded.archived,
-			item_json = excluded.item_json
-	`
+	if err := db.conn.QueryRow(upsertItemQuery, upsertItemArgs(item)...).Scan(&item.ID); err != nil {
+		return fmt.Errorf("failed to upsert item: %w", err)
+	}
+
+	logrus.Debugf("Upserted item: %s - %s", item.FeedURL, item.GUID)
+	return nil
+}
 
-	_, err := db.conn.Exec(query,
+// upsertItemQuery inserts or updates an item, returning its ID.
+const upsertItemQuery = `
+	INSERT INTO items (feed_url, guid, title, link, published_date, first_seen,
+		content, summary, archived, item_json, read, highlighted)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(feed_url, guid) DO UPDATE SET
+		title = excluded.title,
+		link = excluded.link,
+		content = excluded.content,
+		summary = excluded.summary,
+		archived = excluded.archived,
+		item_json = excluded.item_json,
+		highlighted = excluded.highlighted
+	RETURNING id`
+
+func upsertItemArgs(item *Item) []interface{} {
+	return []interface{}{
 		item.FeedURL, item.GUID, item.Title, item.Link, item.PublishedDate, item.FirstSeen,
-		item.Content, item.Summary, item.Archived, item.ItemJSON)
+		item.Content, item.Summary, item.Archived, item.ItemJSON, item.Read, item.Highlighted,
+	}
+}
+
+// StoredItem is what ingesting a feed needs to know about an item already in
+// the database.
+type StoredItem struct {
+	FirstSeen sql.NullTime
+	Link      string
+	Title     string
+	Content   string
+	Summary   string
+}
+
+// GetStoredItems retrieves the first_seen timestamp, link and content of the
+// stored items of a feed with the given GUIDs, keyed by GUID. GUIDs without a
+// stored item are left out.
+func (db *DB) GetStoredItems(feedURL string, guids []string) (map[string]*StoredItem, error) {
+	stored := make(map[string]*StoredItem)
+	for start := 0; start < len(guids); start += maxQueryParams {
+		chunk := guids[start:min(start+maxQueryParams, len(guids))]
+		if err := db.getStoredItems(feedURL, chunk, stored); err != nil {
+			return nil, err
+		}
+	}
+	return stored, nil
+}
+
+// getStoredItems adds the stored items of a feed with the given GUIDs to
+// stored.
+func (db *DB) getStoredItems(feedURL string, guids []string, stored map[string]*StoredItem) error {
+	args := make([]interface{}, 0, len(guids)+1)
+	args = append(args, feedURL)
+	for _, guid := range guids {
+		args = append(args, guid)
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT guid, first_seen, COALESCE(link, ''), COALESCE(title, ''),
+			COALESCE(content, ''), COALESCE(summary, '')
+		FROM items WHERE feed_url = ? AND guid IN (`+strings.Repeat(",?", len(guids))[1:]+`)`, args...)
 	if err != nil {
-		return fmt.Errorf("failed to upsert item: %w", err)
+		return fmt.Errorf("failed to get stored items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var guid string
+		var item StoredItem
+		if err := rows.Scan(&guid, &item.FirstSeen, &item.Link, &item.Title, &item.Content, &item.Summary); err != nil {
+			return fmt.Errorf("failed to scan stored item: %w", err)
+		}
+		stored[guid] = &item
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over stored items: %w", err)
 	}
 
-	logrus.Debugf("Upserted item: %s - %s", item.FeedURL, item.GUID)
 	return nil
 }
 
+// IngestItem is an item to save along with its enclosures.
+type IngestItem struct {
+	Item       *Item
+	Enclosures []*Enclosure
+}
+
+// SaveFeedItems saves a feed's items and replaces their enclosures in a single
+// transaction, setting each item's ID. With archiveMissing, the feed's other
+// items are archived in the same transaction. Nothing is saved if any of it
+// fails. Returns the number of items newly archived.
+func (db *DB) SaveFeedItems(feedURL string, items []*IngestItem, archiveMissing bool) (int64, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			logrus.Warnf("Failed to rollback transaction: %v", rollbackErr)
+		}
+	}()
+
+	stmt, err := tx.Prepare(upsertItemQuery)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare item upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	activeGUIDs := make([]string, 0, len(items))
+	for _, ingest := range items {
+		if err := stmt.QueryRow(upsertItemArgs(ingest.Item)...).Scan(&ingest.Item.ID); err != nil {
+			return 0, fmt.Errorf("failed to upsert item: %w", err)
+		}
+		if err := replaceEnclosures(tx, ingest.Item.ID, ingest.Enclosures); err != nil {
+			return 0, err
+		}
+		activeGUIDs = append(activeGUIDs, ingest.Item.GUID)
+	}
+
+	var archived int64
+	if archiveMissing {
+		if archived, err = markItemsArchived(tx, feedURL, activeGUIDs); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit items: %w", err)
+	}
+
+	logrus.Debugf("Saved %d items for feed: %s", len(items), feedURL)
+	return archived, nil
+}
+
 // GetItemsForFeed retrieves items for a specific feed with optional filtering by time range and limit.
 func (db *DB) GetItemsForFeed(feedURL string, limit int, since, until time.Time) ([]*Item, error) {
-	query := `
-		SELECT id, feed_url, guid, title, link, published_date, first_seen,
-			content, summary, archived, item_json
-		FROM items
-		WHERE feed_url = ?
-	`
+	query := `SELECT ` + itemColumns + ` FROM items WHERE feed_url = ?`
 	args := []interface{}{feedURL}
 
 	if !since.IsZero() {
@@ -70,11 +203,7 @@ func (db *DB) GetItemsForFeed(feedURL string, limit int, since, until time.Time)
 	items := []*Item{}
 	for rows.Next() {
 		item := &Item{}
-		err := rows.Scan(
-			&item.ID, &item.FeedURL, &item.GUID, &item.Title, &item.Link,
-			&item.PublishedDate, &item.FirstSeen, &item.Content, &item.Summary, &item.Archived,
-			&item.ItemJSON)
-		if err != nil {
+		if err := scanItem(rows, item); err != nil {
 			return nil, fmt.Errorf("failed to scan item: %w", err)
 		}
 		items = append(items, item)
@@ -88,14 +217,23 @@ func (db *DB) GetItemsForFeed(feedURL string, limit int, since, until time.Time)
 }
 
 // MarkItemsArchived marks items as archived for a specific feed, except for the provided active GUIDs.
-func (db *DB) MarkItemsArchived(feedURL string, activeGUIDs []string) error {
+// Returns the number of items newly archived.
+func (db *DB) MarkItemsArchived(feedURL string, activeGUIDs []string) (int64, error) {
+	return markItemsArchived(db.conn, feedURL, activeGUIDs)
+}
+
+// markItemsArchived is MarkItemsArchived on a connection or transaction. The
+// unary + keeps SQLite from picking the archived index, which covers every
+// unarchived item of every feed, over the feed's own.
+func markItemsArchived(conn execer, feedURL string, activeGUIDs []string) (int64, error) {
 	if len(activeGUIDs) == 0 {
-		_, err := db.conn.Exec("UPDATE items SET archived = 1 WHERE feed_url = ?", feedURL)
+		result, err := conn.Exec("UPDATE items SET archived = 1 WHERE feed_url = ? AND +archived = 0", feedURL)
 		if err != nil {
-			return fmt.Errorf("failed to archive all items: %w", err)
+			return 0, fmt.Errorf("failed to archive all items: %w", err)
 		}
 		logrus.Debugf("Archived all items for feed: %s", feedURL)
-		return nil
+		rowsAffected, _ := result.RowsAffected()
+		return rowsAffected, nil
 	}
 
 	placeholders := make([]string, len(activeGUIDs))
@@ -108,12 +246,12 @@ func (db *DB) MarkItemsArchived(feedURL string, activeGUIDs []string) error {
 
 	//nolint:gosec // Safe: only formatting placeholder count, not user input
 	query := fmt.Sprintf(
-		"UPDATE items SET archived = 1 WHERE feed_url = ? AND guid NOT IN (%s)",
+		"UPDATE items SET archived = 1 WHERE feed_url = ? AND +archived = 0 AND guid NOT IN (%s)",
 		strings.Join(placeholders, ","))
 
-	result, err := db.conn.Exec(query, args...)
+	result, err := conn.Exec(query, args...)
 	if err != nil {
-		return fmt.Errorf("failed to archive items: %w", err)
+		return 0, fmt.Errorf("failed to archive items: %w", err)
 	}
 
 	rowsAffected, _ := result.RowsAffected()
@@ -121,24 +259,69 @@ func (db *DB) MarkItemsArchived(feedURL string, activeGUIDs []string) error {
 		logrus.Debugf("Archived %d items for feed: %s", rowsAffected, feedURL)
 	}
 
-	return nil
+	return rowsAffected, nil
 }
 
-// DeleteArchivedItems deletes archived items older than the specified time.
+// DeleteArchivedItems deletes archived items older than the specified time,
+// or than their feed's own retention period when it has one. Starred items
+// are always kept.
 func (db *DB) DeleteArchivedItems(olderThan time.Time) (int64, error) {
-	query := "DELETE FROM items WHERE archived = 1 AND published_date < ?"
+	query := `DELETE FROM items WHERE archived = 1 AND starred = 0 AND published_date < ?
+		AND feed_url NOT IN (SELECT url FROM feeds WHERE retention > 0)`
 	result, err := db.conn.Exec(query, olderThan)
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete archived items: %w", err)
 	}
-
 	rowsAffected, _ := result.RowsAffected()
+
+	retentions, err := db.getFeedRetentions()
+	if err != nil {
+		return rowsAffected, err
+	}
+	for feedURL, retention := range retentions {
+		result, err := db.conn.Exec(
+			"DELETE FROM items WHERE feed_url = ? AND archived = 1 AND starred = 0 AND published_date < ?",
+			feedURL, time.Now().Add(-retention))
+		if err != nil {
+			return rowsAffected, fmt.Errorf("failed to delete archived items: %w", err)
+		}
+		deleted, _ := result.RowsAffected()
+		rowsAffected += deleted
+	}
+
 	logrus.Debugf("Deleted %d archived items", rowsAffected)
 	return rowsAffected, nil
 }
 
+// getFeedRetentions retrieves the retention period of every feed that has
+// one, keyed by feed URL.
+func (db *DB) getFeedRetentions() (map[string]time.Duration, error) {
+	rows, err := db.conn.Query("SELECT url, retention FROM feeds WHERE retention > 0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed retentions: %w", err)
+	}
+	defer rows.Close()
+
+	retentions := make(map[string]time.Duration)
+	for rows.Next() {
+		var url string
+		var seconds int64
+		if err := rows.Scan(&url, &seconds); err != nil {
+			return nil, fmt.Errorf("failed to scan feed retention: %w", err)
+		}
+		retentions[url] = time.Duration(seconds) * time.Second
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over feed retentions: %w", err)
+	}
+
+	return retentions, nil
+}
+
 // DeleteArchivedItemsWithMinimum deletes archived items older than the specified time,
-// but ensures at least minItemsPerFeed items remain for each feed.
+// or than their feed's own retention period, but ensures at least minItemsPerFeed
+// items remain for each feed. Starred items are always kept.
 func (db *DB) DeleteArchivedItemsWithMinimum(olderThan time.Time, minItemsPerFeed int) (int64, error) {
 	if minItemsPerFeed <= 0 {
 		return db.DeleteArchivedItems(olderThan)
@@ -154,7 +337,11 @@ func (db *DB) DeleteArchivedItemsWithMinimum(olderThan time.Time, minItemsPerFee
 
 	// Process each feed individually
 	for _, feed := range feeds {
-		deleted, err := db.deleteArchivedItemsForFeed(feed.URL, olderThan, minItemsPerFeed)
+		cutoff := olderThan
+		if feed.Retention > 0 {
+			cutoff = time.Now().Add(-feed.Retention)
+		}
+		deleted, err := db.deleteArchivedItemsForFeed(feed.URL, cutoff, minItemsPerFeed)
 		if err != nil {
 			logrus.Warnf("Failed to delete items for feed %s: %v", feed.URL, err)
 			continue
@@ -213,6 +400,7 @@ func (db *DB) deleteArchivedItemsForFeed(feedURL string, olderThan time.Time, mi
 		DELETE FROM items
 		WHERE feed_url = ?
 		  AND archived = 1
+		  AND starred = 0
 		  AND published_date < ?
 		  AND id NOT IN (%s)
 	`, strings.Join(placeholders, ","))
@@ -249,8 +437,7 @@ func (db *DB) getItemsForFeeds(feedURLMap map[string]bool, start, end time.Time)
 
 	//nolint:gosec // Safe: only formatting placeholder count, not user input
 	query := fmt.Sprintf(`
-		SELECT id, feed_url, guid, title, link, published_date, first_seen,
-			content, summary, archived, item_json
+		SELECT `+itemColumns+`
 		FROM items
 		WHERE feed_url IN (%s)
 			AND published_date >= ? AND published_date <= ?
@@ -266,11 +453,7 @@ func (db *DB) getItemsForFeeds(feedURLMap map[string]bool, start, end time.Time)
 	items := make(map[string][]Item)
 	for rows.Next() {
 		item := Item{}
-		err := rows.Scan(
-			&item.ID, &item.FeedURL, &item.GUID, &item.Title, &item.Link,
-			&item.PublishedDate, &item.FirstSeen, &item.Content, &item.Summary, &item.Archived,
-			&item.ItemJSON)
-		if err != nil {
+		if err := scanItem(rows, &item); err != nil {
 			return nil, fmt.Errorf("failed to scan item: %w", err)
 		}
 		items[item.FeedURL] = append(items[item.FeedURL], item)
@@ -282,3 +465,36 @@ func (db *DB) getItemsForFeeds(feedURLMap map[string]bool, start, end time.Time)
 
 	return items, nil
 }
+
+// GetRecentItemDates returns the published and first-seen dates of a feed's most
+// recent items, newest first. Only PublishedDate and FirstSeen are populated.
+func (db *DB) GetRecentItemDates(feedURL string, limit int) ([]Item, error) {
+	query := `
+		SELECT published_date, first_seen
+		FROM items
+		WHERE feed_url = ?
+		ORDER BY COALESCE(first_seen, published_date) DESC
+		LIMIT ?
+	`
+
+	rows, err := db.conn.Query(query, feedURL, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item dates: %w", err)
+	}
+	defer rows.Close()
+
+	items := []Item{}
+	for rows.Next() {
+		item := Item{}
+		if err := rows.Scan(&item.PublishedDate, &item.FirstSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan item dates: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over item dates: %w", err)
+	}
+
+	return items, nil
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"database/sql"
 	"testing"
 	"time"
 )
@@ -56,6 +57,44 @@ func TestUpsertAndGetItem(t *testing.T) {
 	}
 }
 
+func TestUpsertItemRuleState(t *testing.T) {
+	db := setupTestDB(t)
+
+	feedURL := "https://example.com/feed.xml"
+	if err := db.UpsertFeed(&Feed{URL: feedURL}); err != nil {
+		t.Fatal(err)
+	}
+
+	// New items take their read and highlighted state from the upsert
+	item := &Item{FeedURL: feedURL, GUID: "guid", Title: "Item", Read: true, Highlighted: true}
+	if err := db.UpsertItem(item); err != nil {
+		t.Fatal(err)
+	}
+	items, err := db.GetItemsForFeed(feedURL, 0, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !items[0].Read || !items[0].Highlighted {
+		t.Fatalf("new item read = %v, highlighted = %v, want both true", items[0].Read, items[0].Highlighted)
+	}
+
+	// Existing items keep their read state but follow the highlight
+	if _, err := db.SetItemsRead(&ItemFilter{IDs: []int64{items[0].ID}}, false); err != nil {
+		t.Fatal(err)
+	}
+	item.Highlighted = false
+	if err := db.UpsertItem(item); err != nil {
+		t.Fatal(err)
+	}
+	items, err = db.GetItemsForFeed(feedURL, 0, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items[0].Read || items[0].Highlighted {
+		t.Errorf("updated item read = %v, highlighted = %v, want both false", items[0].Read, items[0].Highlighted)
+	}
+}
+
 func TestUpsertItemDateStability(t *testing.T) {
 	const updatedTitle = "Updated Title"
 
@@ -249,10 +288,18 @@ func TestMarkItemsArchived(t *testing.T) {
 
 	// Mark item2 and item3 as not archived (active), item1 should be archived
 	activeGUIDs := []string{"item2", "item3"}
-	err = db.MarkItemsArchived(feed.URL, activeGUIDs)
+	archived, err := db.MarkItemsArchived(feed.URL, activeGUIDs)
 	if err != nil {
 		t.Errorf("db.MarkItemsArchived() error = %v", err)
 	}
+	if archived != 1 {
+		t.Errorf("db.MarkItemsArchived() archived %d items, want 1", archived)
+	}
+
+	// Items that were already archived aren't counted again
+	if archived, _ := db.MarkItemsArchived(feed.URL, activeGUIDs); archived != 0 {
+		t.Errorf("db.MarkItemsArchived() again archived %d items, want 0", archived)
+	}
 
 	// Get all items (including archived)
 	conn := db.GetConnection()
@@ -290,6 +337,91 @@ func TestMarkItemsArchived(t *testing.T) {
 	}
 }
 
+func TestSaveFeedItems(t *testing.T) {
+	db := setupTestDB(t)
+	feedURL := "https://example.com/feed.xml"
+
+	if err := db.UpsertFeed(&Feed{URL: feedURL, Title: "Test Feed"}); err != nil {
+		t.Fatal(err)
+	}
+	gone := &Item{FeedURL: feedURL, GUID: "gone", Title: "Gone", PublishedDate: time.Now()}
+	if err := db.UpsertItem(gone); err != nil {
+		t.Fatal(err)
+	}
+
+	firstSeen := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	batch := []*IngestItem{
+		{
+			Item: &Item{
+				FeedURL: feedURL, GUID: "item1", Title: "Item 1", Link: "https://example.com/1",
+				FirstSeen: sql.NullTime{Time: firstSeen, Valid: true},
+			},
+			Enclosures: []*Enclosure{{URL: "https://cdn.example.com/1.mp3", Type: "audio/mpeg"}},
+		},
+		{Item: &Item{FeedURL: feedURL, GUID: "item2", Title: "Item 2", Content: "Content"}},
+	}
+	archived, err := db.SaveFeedItems(feedURL, batch, true)
+	if err != nil {
+		t.Fatalf("SaveFeedItems() error = %v", err)
+	}
+	if archived != 1 {
+		t.Errorf("SaveFeedItems() archived %d items, want 1", archived)
+	}
+	if batch[0].Item.ID == 0 || batch[1].Item.ID == 0 || batch[0].Item.ID == batch[1].Item.ID {
+		t.Errorf("SaveFeedItems() set IDs %d and %d, want distinct IDs", batch[0].Item.ID, batch[1].Item.ID)
+	}
+	if batch[0].Enclosures[0].ItemID != batch[0].Item.ID {
+		t.Errorf("enclosure ItemID = %d, want %d", batch[0].Enclosures[0].ItemID, batch[0].Item.ID)
+	}
+
+	// Only the requested GUIDs are loaded
+	stored, err := db.GetStoredItems(feedURL, []string{"item1", "item2", "missing"})
+	if err != nil {
+		t.Fatalf("GetStoredItems() error = %v", err)
+	}
+	if len(stored) != 2 {
+		t.Fatalf("GetStoredItems() returned %d items, want 2", len(stored))
+	}
+	if item := stored["item1"]; !item.FirstSeen.Valid || !item.FirstSeen.Time.Equal(firstSeen) ||
+		item.Link != "https://example.com/1" || item.Title != "Item 1" {
+		t.Errorf("GetStoredItems()[item1] = %+v", item)
+	}
+	if item := stored["item2"]; item.Content != "Content" || item.Summary != "" {
+		t.Errorf("GetStoredItems()[item2] = %+v", item)
+	}
+
+	// Pushed content doesn't archive what it leaves out
+	pushed := []*IngestItem{{Item: &Item{FeedURL: feedURL, GUID: "item3", Title: "Item 3"}}}
+	if archived, err := db.SaveFeedItems(feedURL, pushed, false); err != nil || archived != 0 {
+		t.Errorf("SaveFeedItems(archiveMissing = false) = %d, %v, want 0, nil", archived, err)
+	}
+
+	// A failure saves nothing, not even the items before it
+	failing := []*IngestItem{
+		{Item: &Item{FeedURL: feedURL, GUID: "item4", Title: "Item 4"}},
+		{Item: &Item{FeedURL: "https://example.com/unknown.xml", GUID: "item5"}},
+	}
+	if _, err := db.SaveFeedItems(feedURL, failing, true); err == nil {
+		t.Error("SaveFeedItems() error = nil, want the unknown feed rejected")
+	}
+	stored, err = db.GetStoredItems(feedURL, []string{"gone", "item1", "item2", "item3", "item4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stored["item4"]; ok || len(stored) != 4 {
+		t.Errorf("GetStoredItems() after a failed save returned %d items, want the 4 saved before", len(stored))
+	}
+	items, err := db.GetItemsForFeed(feedURL, 0, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		if item.Archived != (item.GUID == "gone") {
+			t.Errorf("item %s archived = %v after a failed save", item.GUID, item.Archived)
+		}
+	}
+}
+
 func TestDeleteArchivedItems(t *testing.T) {
 	db := setupTestDB(t)
 
@@ -375,3 +507,131 @@ func TestDeleteArchivedItems(t *testing.T) {
 		t.Errorf("Total items in DB = %d, want 2", totalCount)
 	}
 }
+
+func TestDeleteArchivedItemsKeepsStarred(t *testing.T) {
+	db := setupTestDB(t)
+	feedURL := "https://example.com/feed.xml"
+
+	if err := db.UpsertFeed(&Feed{URL: feedURL, FeedJSON: JSON(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	live := &Item{FeedURL: feedURL, GUID: "live", PublishedDate: now, ItemJSON: JSON(`{}`)}
+	starred := &Item{FeedURL: feedURL, GUID: "starred", PublishedDate: now.Add(-72 * time.Hour), Archived: true}
+	old := &Item{FeedURL: feedURL, GUID: "old", PublishedDate: now.Add(-48 * time.Hour), Archived: true}
+	for _, item := range []*Item{live, starred, old} {
+		if err := db.UpsertItem(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.SetItemsStarred(&ItemFilter{IDs: []int64{starred.ID}}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	cutoff := now.Add(-time.Hour)
+	purges := []struct {
+		name  string
+		purge func() (int64, error)
+	}{
+		{"DeleteArchivedItems", func() (int64, error) { return db.DeleteArchivedItems(cutoff) }},
+		{"DeleteArchivedItemsWithMinimum", func() (int64, error) { return db.DeleteArchivedItemsWithMinimum(cutoff, 1) }},
+	}
+	for _, tt := range purges {
+		deleted, err := tt.purge()
+		if err != nil {
+			t.Fatalf("%s() error = %v", tt.name, err)
+		}
+		if deleted != 1 {
+			t.Errorf("%s() deleted %d items, want only the unstarred one", tt.name, deleted)
+		}
+		remaining, err := db.GetItemsForFeed(feedURL, 0, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(remaining) != 2 {
+			t.Errorf("%s() left %d items, want the live and starred ones", tt.name, len(remaining))
+		}
+
+		// Put the unstarred item back for the next purge
+		if err := db.UpsertItem(old); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDeleteArchivedItemsFeedRetention(t *testing.T) {
+	db := setupTestDB(t)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, url := range []string{"https://example.com/default.xml", "https://example.com/kept.xml"} {
+		if err := db.UpsertFeed(&Feed{URL: url, FeedJSON: JSON(`{}`)}); err != nil {
+			t.Fatal(err)
+		}
+		for _, age := range []time.Duration{2 * time.Hour, 48 * time.Hour} {
+			item := &Item{
+				FeedURL:       url,
+				GUID:          age.String(),
+				PublishedDate: now.Add(-age),
+				Archived:      true,
+				ItemJSON:      JSON(`{}`),
+			}
+			if err := db.UpsertItem(item); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if _, err := db.SetFeedSettings("https://example.com/kept.xml", FeedSettings{Retention: 72 * time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The feed with its own retention keeps both items despite the 1h cutoff
+	deleted, err := db.DeleteArchivedItems(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteArchivedItems() error = %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("DeleteArchivedItems() deleted %d items, want 2", deleted)
+	}
+	kept, err := db.GetItemsForFeed("https://example.com/kept.xml", 0, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 2 {
+		t.Errorf("feed with 72h retention has %d items, want 2", len(kept))
+	}
+
+	// A shorter retention than the cutoff still purges the feed's old items
+	if _, err := db.SetFeedSettings("https://example.com/kept.xml", FeedSettings{Retention: 24 * time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err = db.DeleteArchivedItems(now.Add(-100 * time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteArchivedItems() error = %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("DeleteArchivedItems() deleted %d items, want 1", deleted)
+	}
+
+	// Starred items outlive the feed's retention too
+	starred := &Item{
+		FeedURL:       "https://example.com/kept.xml",
+		GUID:          "starred",
+		PublishedDate: now.Add(-48 * time.Hour),
+		Archived:      true,
+		ItemJSON:      JSON(`{}`),
+	}
+	if err := db.UpsertItem(starred); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.SetItemsStarred(&ItemFilter{IDs: []int64{starred.ID}}, true); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err = db.DeleteArchivedItems(now.Add(-100 * time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteArchivedItems() error = %v", err)
+	}
+	if deleted != 0 {
+		t.Errorf("DeleteArchivedItems() deleted %d items, want the starred item kept", deleted)
+	}
+}
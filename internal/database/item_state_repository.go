@@ -0,0 +1,149 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ItemFilter selects items to list or to change the read and starred state
+// of. Set fields are combined with AND; an empty filter selects every item.
+type ItemFilter struct {
+	IDs         []int64
+	FeedURL     string
+	Before      time.Time // Only items published before this time, if set
+	Unread      bool      // Only unread items
+	Starred     bool      // Only starred items
+	Highlighted bool      // Only items highlighted by a rule
+	Tag         string    // Only items with this tag
+	Limit       int       // Maximum items to list, 0 for all; ignored when changing state
+	Offset      int       // Items to skip when listing; ignored when changing state
+}
+
+// where returns the SQL conditions and arguments for the filter.
+func (f *ItemFilter) where() (string, []interface{}) {
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+
+	if len(f.IDs) > 0 {
+		placeholders := strings.Repeat(",?", len(f.IDs))[1:]
+		conditions = append(conditions, "id IN ("+placeholders+")")
+		for _, id := range f.IDs {
+			args = append(args, id)
+		}
+	}
+	if f.FeedURL != "" {
+		conditions = append(conditions, "feed_url = ?")
+		args = append(args, f.FeedURL)
+	}
+	if !f.Before.IsZero() {
+		conditions = append(conditions, "published_date < ?")
+		args = append(args, f.Before)
+	}
+	if f.Unread {
+		conditions = append(conditions, "read = 0")
+	}
+	if f.Starred {
+		conditions = append(conditions, "starred = 1")
+	}
+	if f.Highlighted {
+		conditions = append(conditions, "highlighted = 1")
+	}
+	if f.Tag != "" {
+		conditions = append(conditions, "id IN (SELECT item_id FROM item_tags WHERE tag = ?)")
+		args = append(args, f.Tag)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// ListItems retrieves the items matching filter, newest first.
+func (db *DB) ListItems(filter *ItemFilter) ([]*Item, error) {
+	where, args := filter.where()
+	query := `SELECT ` + itemColumns + ` FROM items WHERE ` + where + ` ORDER BY published_date DESC, id DESC`
+	if filter.Limit > 0 || filter.Offset > 0 {
+		limit := filter.Limit
+		if limit <= 0 {
+			limit = -1 // SQLite's "no limit"
+		}
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, filter.Offset)
+	}
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []*Item{}
+	for rows.Next() {
+		item := &Item{}
+		if err := scanItem(rows, item); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over items: %w", err)
+	}
+
+	return items, nil
+}
+
+// SetItemsRead marks the items matching filter as read or unread, returning
+// the number of items changed.
+func (db *DB) SetItemsRead(filter *ItemFilter, read bool) (int64, error) {
+	return db.setItemState("read", filter, read)
+}
+
+// SetItemsStarred stars or unstars the items matching filter, returning the
+// number of items changed.
+func (db *DB) SetItemsStarred(filter *ItemFilter, starred bool) (int64, error) {
+	return db.setItemState("starred", filter, starred)
+}
+
+// setItemState sets a boolean state column on the items matching filter.
+func (db *DB) setItemState(column string, filter *ItemFilter, value bool) (int64, error) {
+	where, args := filter.where()
+
+	//nolint:gosec // Safe: column is one of our own constants, not user input
+	query := fmt.Sprintf("UPDATE items SET %s = ? WHERE %s AND %s != ?", column, where, column)
+	args = append([]interface{}{value}, args...)
+	args = append(args, value)
+
+	result, err := db.conn.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update %s state: %w", column, err)
+	}
+
+	changed, _ := result.RowsAffected()
+	return changed, nil
+}
+
+// GetUnreadCounts returns the number of unread items still in each feed, for
+// feeds that have any. Archived items are left out.
+func (db *DB) GetUnreadCounts() (map[string]int, error) {
+	rows, err := db.conn.Query(`SELECT feed_url, COUNT(*) FROM items WHERE read = 0 AND archived = 0 GROUP BY feed_url`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unread items: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var feedURL string
+		var count int
+		if err := rows.Scan(&feedURL, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan unread count: %w", err)
+		}
+		counts[feedURL] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over unread counts: %w", err)
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestItemReadAndStarredState(t *testing.T) {
+	db := setupTestDB(t)
+	setupSearchTestItems(t, db)
+
+	counts, err := db.GetUnreadCounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts["https://go.example.com/feed"] != 2 || counts["https://rust.example.com/feed"] != 1 {
+		t.Fatalf("GetUnreadCounts() = %v, want 2 and 1 for new items", counts)
+	}
+
+	// Mark everything published before February read
+	changed, err := db.SetItemsRead(&ItemFilter{Before: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}, true)
+	if err != nil || changed != 1 {
+		t.Fatalf("SetItemsRead(before) = %d, %v; want 1 item", changed, err)
+	}
+
+	// Marking again changes nothing
+	changed, err = db.SetItemsRead(&ItemFilter{FeedURL: "https://go.example.com/feed"}, true)
+	if err != nil || changed != 1 {
+		t.Fatalf("SetItemsRead(feed) = %d, %v; want only the 1 unread item", changed, err)
+	}
+
+	unread, err := db.ListItems(&ItemFilter{Unread: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unread) != 1 || unread[0].GUID != "ownership" || unread[0].Read {
+		t.Fatalf("ListItems(unread) = %+v, want only the rust item", unread)
+	}
+
+	changed, err = db.SetItemsStarred(&ItemFilter{IDs: []int64{unread[0].ID}}, true)
+	if err != nil || changed != 1 {
+		t.Fatalf("SetItemsStarred(id) = %d, %v; want 1 item", changed, err)
+	}
+
+	starred, err := db.ListItems(&ItemFilter{Starred: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(starred) != 1 || !starred[0].Starred {
+		t.Fatalf("ListItems(starred) = %+v, want the starred item", starred)
+	}
+
+	// Refetching an item keeps its state
+	err = db.UpsertItem(&Item{
+		FeedURL: "https://rust.example.com/feed", GUID: "ownership", Title: "Ownership, revised", ItemJSON: JSON(`{}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := db.GetItemsForFeed("https://rust.example.com/feed", 0, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !items[0].Starred || items[0].Read {
+		t.Errorf("item after refetch: starred=%v read=%v, want starred and unread", items[0].Starred, items[0].Read)
+	}
+
+	counts, err = db.GetUnreadCounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := counts["https://go.example.com/feed"]; ok || counts["https://rust.example.com/feed"] != 1 {
+		t.Errorf("GetUnreadCounts() = %v, want only the rust feed", counts)
+	}
+
+	// Unread items that dropped out of their feed aren't counted
+	if _, err := db.MarkItemsArchived("https://rust.example.com/feed", nil); err != nil {
+		t.Fatal(err)
+	}
+	counts, err = db.GetUnreadCounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 0 {
+		t.Errorf("GetUnreadCounts() = %v after archiving, want none", counts)
+	}
+}
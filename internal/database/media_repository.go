@@ -0,0 +1,127 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+const mediaColumns = `url, hash, content_type, size, fetched_at, fetch_error`
+
+func scanMedia(scanner interface{ Scan(...interface{}) error }) (*Media, error) {
+	var media Media
+	err := scanner.Scan(&media.URL, &media.Hash, &media.ContentType, &media.Size, &media.FetchedAt,
+		&media.FetchError)
+	if err != nil {
+		return nil, err
+	}
+	return &media, nil
+}
+
+// UpsertMedia records the outcome of downloading a media URL.
+func (db *DB) UpsertMedia(media *Media) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO media (`+mediaColumns+`) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(url) DO UPDATE SET
+			hash = excluded.hash,
+			content_type = excluded.content_type,
+			size = excluded.size,
+			fetched_at = excluded.fetched_at,
+			fetch_error = excluded.fetch_error`,
+		media.URL, media.Hash, media.ContentType, media.Size, media.FetchedAt, media.FetchError)
+	if err != nil {
+		return fmt.Errorf("failed to upsert media: %w", err)
+	}
+	return nil
+}
+
+// GetMedia retrieves the media cache entry for a URL, or nil if it was never
+// downloaded.
+func (db *DB) GetMedia(url string) (*Media, error) {
+	media, err := scanMedia(db.conn.QueryRow("SELECT "+mediaColumns+" FROM media WHERE url = ?", url))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get media: %w", err)
+	}
+	return media, nil
+}
+
+// GetCachedMedia retrieves every successfully downloaded media entry, keyed
+// by URL.
+func (db *DB) GetCachedMedia() (map[string]*Media, error) {
+	rows, err := db.conn.Query("SELECT " + mediaColumns + " FROM media WHERE hash IS NOT NULL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached media: %w", err)
+	}
+	defer rows.Close()
+
+	cached := make(map[string]*Media)
+	for rows.Next() {
+		media, err := scanMedia(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan media: %w", err)
+		}
+		cached[media.URL] = media
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over media: %w", err)
+	}
+
+	return cached, nil
+}
+
+// AddItemMedia records that the item with the given feed and GUID references
+// the media URLs, so they are kept as long as the item is.
+func (db *DB) AddItemMedia(feedURL, guid string, urls []string) error {
+	for _, url := range urls {
+		_, err := db.conn.Exec(`
+			INSERT OR IGNORE INTO item_media (item_id, url)
+			SELECT id, ? FROM items WHERE feed_url = ? AND guid = ?`,
+			url, feedURL, guid)
+		if err != nil {
+			return fmt.Errorf("failed to add item media: %w", err)
+		}
+	}
+	return nil
+}
+
+// DeleteUnreferencedMedia removes media entries no longer referenced by an
+// item or by the image or favicon of unfurled metadata.
+func (db *DB) DeleteUnreferencedMedia() (int64, error) {
+	result, err := db.conn.Exec(`
+		DELETE FROM media
+		WHERE url NOT IN (SELECT url FROM item_media)
+			AND url NOT IN (SELECT image_url FROM url_metadata WHERE image_url IS NOT NULL)
+			AND url NOT IN (SELECT favicon_url FROM url_metadata WHERE favicon_url IS NOT NULL)`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete unreferenced media: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetMediaHashes retrieves the content hashes of every cached file.
+func (db *DB) GetMediaHashes() (map[string]bool, error) {
+	rows, err := db.conn.Query("SELECT DISTINCT hash FROM media WHERE hash IS NOT NULL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get media hashes: %w", err)
+	}
+	defer rows.Close()
+
+	hashes := make(map[string]bool)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("failed to scan media hash: %w", err)
+		}
+		hashes[hash] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over media hashes: %w", err)
+	}
+
+	return hashes, nil
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestMediaCache(t *testing.T) {
+	db := setupTestDB(t)
+	feedURL := "https://example.com/feed"
+	fetched := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := db.UpsertFeed(&Feed{URL: feedURL}); err != nil {
+		t.Fatal(err)
+	}
+	for _, guid := range []string{"kept", "deleted"} {
+		if err := db.UpsertItem(&Item{FeedURL: feedURL, GUID: guid, Title: guid}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.UpsertMetadata(&URLMetadata{
+		URL:        "https://example.com/post",
+		ImageURL:   sql.NullString{String: "https://cdn.example.com/thumb.jpg", Valid: true},
+		FaviconURL: sql.NullString{String: "https://example.com/favicon.ico", Valid: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []*Media{
+		{URL: "https://cdn.example.com/a.png", Hash: sql.NullString{String: "aaaa", Valid: true}},
+		{URL: "https://cdn.example.com/b.png", Hash: sql.NullString{String: "bbbb", Valid: true}},
+		{URL: "https://cdn.example.com/thumb.jpg", Hash: sql.NullString{String: "cccc", Valid: true}},
+		{URL: "https://example.com/favicon.ico", Hash: sql.NullString{String: "aaaa", Valid: true}},
+		{URL: "https://cdn.example.com/missing.png", FetchError: sql.NullString{String: "HTTP 404", Valid: true}},
+	}
+	for _, media := range entries {
+		media.ContentType = "image/png"
+		media.FetchedAt = fetched
+		if err := db.UpsertMedia(media); err != nil {
+			t.Fatalf("UpsertMedia() error = %v", err)
+		}
+	}
+
+	if err := db.AddItemMedia(feedURL, "kept", []string{"https://cdn.example.com/a.png"}); err != nil {
+		t.Fatalf("AddItemMedia() error = %v", err)
+	}
+	if err := db.AddItemMedia(feedURL, "deleted", []string{"https://cdn.example.com/b.png"}); err != nil {
+		t.Fatal(err)
+	}
+
+	media, err := db.GetMedia("https://cdn.example.com/a.png")
+	if err != nil || media == nil || media.Hash.String != "aaaa" || !media.FetchedAt.Equal(fetched) {
+		t.Errorf("GetMedia() = %+v, %v; want hash aaaa fetched at %v", media, err, fetched)
+	}
+	if media, err := db.GetMedia("https://cdn.example.com/unknown.png"); err != nil || media != nil {
+		t.Errorf("GetMedia(unknown) = %+v, %v; want nil", media, err)
+	}
+
+	cached, err := db.GetCachedMedia()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached) != 4 || cached["https://cdn.example.com/missing.png"] != nil {
+		t.Errorf("GetCachedMedia() has %d entries, want the 4 downloaded", len(cached))
+	}
+
+	// Deleting an item releases its media, but not media shared with metadata
+	if _, err := db.conn.Exec("DELETE FROM items WHERE guid = 'deleted'"); err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := db.DeleteUnreferencedMedia()
+	if err != nil {
+		t.Fatalf("DeleteUnreferencedMedia() error = %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("DeleteUnreferencedMedia() = %d, want b.png and missing.png deleted", deleted)
+	}
+
+	hashes, err := db.GetMediaHashes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashes) != 2 || !hashes["aaaa"] || !hashes["cccc"] {
+		t.Errorf("GetMediaHashes() = %v, want aaaa and cccc", hashes)
+	}
+}
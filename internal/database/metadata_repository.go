@@ -12,7 +12,7 @@ func (db *DB) GetMetadata(url string) (*URLMetadata, error) {
 	var metadata URLMetadata
 	query := `
 		SELECT url, title, description, image_url, favicon_url, metadata,
-		       last_fetch_at, fetch_status_code, fetch_error, created_at, updated_at
+		       last_fetch_at, fetch_status_code, fetch_error, content, created_at, updated_at
 		FROM url_metadata
 		WHERE url = ?
 	`
@@ -27,6 +27,7 @@ func (db *DB) GetMetadata(url string) (*URLMetadata, error) {
 		&metadata.LastFetchAt,
 		&metadata.FetchStatusCode,
 		&metadata.FetchError,
+		&metadata.Content,
 		&metadata.CreatedAt,
 		&metadata.UpdatedAt,
 	)
@@ -41,13 +42,14 @@ func (db *DB) GetMetadata(url string) (*URLMetadata, error) {
 	return &metadata, nil
 }
 
-// UpsertMetadata inserts or updates URL metadata.
+// UpsertMetadata inserts or updates URL metadata. Extracted content is kept
+// when the update has none, so refreshing metadata doesn't lose it.
 func (db *DB) UpsertMetadata(metadata *URLMetadata) error {
 	query := `
 		INSERT INTO url_metadata (
 			url, title, description, image_url, favicon_url, metadata,
-			last_fetch_at, fetch_status_code, fetch_error, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			last_fetch_at, fetch_status_code, fetch_error, content, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 		ON CONFLICT(url) DO UPDATE SET
 			title = excluded.title,
 			description = excluded.description,
@@ -57,6 +59,7 @@ func (db *DB) UpsertMetadata(metadata *URLMetadata) error {
 			last_fetch_at = excluded.last_fetch_at,
 			fetch_status_code = excluded.fetch_status_code,
 			fetch_error = excluded.fetch_error,
+			content = COALESCE(excluded.content, url_metadata.content),
 			updated_at = CURRENT_TIMESTAMP
 	`
 
@@ -70,6 +73,7 @@ func (db *DB) UpsertMetadata(metadata *URLMetadata) error {
 		metadata.LastFetchAt,
 		metadata.FetchStatusCode,
 		metadata.FetchError,
+		metadata.Content,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to upsert metadata: %w", err)
@@ -78,13 +82,21 @@ func (db *DB) UpsertMetadata(metadata *URLMetadata) error {
 	return nil
 }
 
-// GetURLsNeedingFetch finds item URLs without metadata or due for retry.
-func (db *DB) GetURLsNeedingFetch(limit int, retryAfter time.Duration) ([]string, error) {
+// UnfurlTarget is an item URL to unfurl.
+type UnfurlTarget struct {
+	URL      string
+	FullText bool // An item with this link is in a feed wanting full text
+}
+
+// GetURLsNeedingFetch finds item URLs without metadata or due for retry, and
+// URLs of items in full-text feeds whose article text was never extracted.
+func (db *DB) GetURLsNeedingFetch(limit int, retryAfter time.Duration) ([]UnfurlTarget, error) {
 	retryTime := time.Now().Add(-retryAfter)
 
 	query := `
-		SELECT DISTINCT i.link
+		SELECT i.link, MAX(f.full_text)
 		FROM items i
+		JOIN feeds f ON f.url = i.feed_url
 		LEFT JOIN url_metadata um ON i.link = um.url
 		WHERE i.link != '' 
 		AND i.archived = 0
@@ -94,8 +106,12 @@ func (db *DB) GetURLsNeedingFetch(limit int, retryAfter time.Duration) ([]string
 				(um.fetch_status_code IS NULL OR um.fetch_status_code NOT BETWEEN 200 AND 299)  -- Failed fetch or no status
 				AND um.last_fetch_at < ?  -- And enough time has passed
 			)
+			OR (  -- Full text wanted but never extracted from a successful fetch
+				f.full_text = 1 AND um.content IS NULL AND um.fetch_status_code BETWEEN 200 AND 299
+			)
 		)
-		ORDER BY i.published_date DESC
+		GROUP BY i.link
+		ORDER BY MAX(i.published_date) DESC
 	`
 
 	if limit > 0 {
@@ -108,16 +124,16 @@ func (db *DB) GetURLsNeedingFetch(limit int, retryAfter time.Duration) ([]string
 	}
 	defer rows.Close()
 
-	var urls []string
+	var targets []UnfurlTarget
 	for rows.Next() {
-		var url string
-		if err := rows.Scan(&url); err != nil {
+		var target UnfurlTarget
+		if err := rows.Scan(&target.URL, &target.FullText); err != nil {
 			return nil, fmt.Errorf("failed to scan URL: %w", err)
 		}
-		urls = append(urls, url)
+		targets = append(targets, target)
 	}
 
-	return urls, rows.Err()
+	return targets, rows.Err()
 }
 
 // DeleteOrphanedMetadata removes metadata for URLs with no item references.
@@ -142,7 +158,7 @@ func (db *DB) GetMetadataForItems(feedURL string) (map[string]*URLMetadata, erro
 	query := `
 		SELECT um.url, um.title, um.description, um.image_url, um.favicon_url, 
 		       um.metadata, um.last_fetch_at, um.fetch_status_code, um.fetch_error,
-		       um.created_at, um.updated_at
+		       um.content, um.created_at, um.updated_at
 		FROM url_metadata um
 		INNER JOIN items i ON i.link = um.url
 		WHERE i.feed_url = ? AND i.archived = 0
@@ -167,6 +183,7 @@ func (db *DB) GetMetadataForItems(feedURL string) (map[string]*URLMetadata, erro
 			&metadata.LastFetchAt,
 			&metadata.FetchStatusCode,
 			&metadata.FetchError,
+			&metadata.Content,
 			&metadata.CreatedAt,
 			&metadata.UpdatedAt,
 		)
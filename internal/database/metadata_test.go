@@ -137,3 +137,81 @@ func TestHasUnfurlMetadataBatch_Empty(t *testing.T) {
 		t.Errorf("Expected empty result map, got %d entries", len(results))
 	}
 }
+
+func TestGetURLsNeedingFetchFullText(t *testing.T) {
+	db, err := New(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.InitSchema(); err != nil {
+		t.Fatalf("Failed to initialize test database: %v", err)
+	}
+
+	for _, feedURL := range []string{"https://a.example/feed", "https://b.example/feed"} {
+		if err := db.UpsertFeed(&Feed{URL: feedURL}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if found, err := db.SetFeedFullText("https://b.example/feed", true); err != nil || !found {
+		t.Fatalf("SetFeedFullText() = %v, %v", found, err)
+	}
+	for _, item := range []*Item{
+		{FeedURL: "https://a.example/feed", GUID: "a1", Link: "https://example.com/summary-only"},
+		{FeedURL: "https://b.example/feed", GUID: "b1", Link: "https://example.com/full-text"},
+	} {
+		if err := db.UpsertItem(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Both pages were unfurled before full text was wanted
+	for _, url := range []string{"https://example.com/summary-only", "https://example.com/full-text"} {
+		if err := db.UpsertMetadata(&URLMetadata{
+			URL:             url,
+			LastFetchAt:     sql.NullTime{Time: time.Now(), Valid: true},
+			FetchStatusCode: sql.NullInt64{Int64: 200, Valid: true},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	targets, err := db.GetURLsNeedingFetch(0, time.Hour)
+	if err != nil {
+		t.Fatalf("GetURLsNeedingFetch() error = %v", err)
+	}
+	if len(targets) != 1 || targets[0].URL != "https://example.com/full-text" || !targets[0].FullText {
+		t.Errorf("GetURLsNeedingFetch() = %+v, want only the full-text page", targets)
+	}
+
+	// Once extracted, content survives metadata refreshes without it
+	if err := db.UpsertMetadata(&URLMetadata{
+		URL:             "https://example.com/full-text",
+		FetchStatusCode: sql.NullInt64{Int64: 200, Valid: true},
+		Content:         sql.NullString{String: "<p>Article</p>", Valid: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpsertMetadata(&URLMetadata{
+		URL:             "https://example.com/full-text",
+		FetchStatusCode: sql.NullInt64{Int64: 200, Valid: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := db.GetMetadata("https://example.com/full-text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Content.String != "<p>Article</p>" {
+		t.Errorf("Content = %q, want the extracted article", metadata.Content.String)
+	}
+
+	targets, err = db.GetURLsNeedingFetch(0, time.Hour)
+	if err != nil {
+		t.Fatalf("GetURLsNeedingFetch() error = %v", err)
+	}
+	if len(targets) != 0 {
+		t.Errorf("GetURLsNeedingFetch() after extraction = %+v, want none", targets)
+	}
+}
@@ -8,11 +8,29 @@ import (
 
 const (
 	// Migration version constants.
-	migrationVersion1   = 1 // Initial schema (handled by InitSchema)
-	migrationVersion2   = 2 // Add latest_item_date column to feeds
-	migrationVersion3   = 3 // Add url_metadata table
-	migrationVersion4   = 4 // Add first_seen column to items
-	maxMigrationVersion = migrationVersion4
+	migrationVersion1   = 1  // Initial schema (handled by InitSchema)
+	migrationVersion2   = 2  // Add latest_item_date column to feeds
+	migrationVersion3   = 3  // Add url_metadata table
+	migrationVersion4   = 4  // Add first_seen column to items
+	migrationVersion5   = 5  // Add next_fetch_at column to feeds
+	migrationVersion6   = 6  // Add disabled column to feeds
+	migrationVersion7   = 7  // Add parked_until column to feeds
+	migrationVersion8   = 8  // Add websub_subscriptions table
+	migrationVersion9   = 9  // Add items_fts full-text search index
+	migrationVersion10  = 10 // Add read column to items
+	migrationVersion11  = 11 // Add starred column to items
+	migrationVersion12  = 12 // Add feed_tags table
+	migrationVersion13  = 13 // Add highlighted column to items
+	migrationVersion14  = 14 // Add item_tags table
+	migrationVersion15  = 15 // Add duplicate_of column to items
+	migrationVersion16  = 16 // Add item_keys table
+	migrationVersion17  = 17 // Add full_text column to feeds
+	migrationVersion18  = 18 // Add content column to url_metadata
+	migrationVersion19  = 19 // Add media and item_media tables
+	migrationVersion20  = 20 // Add enclosures table
+	migrationVersion21  = 21 // Add per-feed settings columns to feeds
+	migrationVersion22  = 22 // Add fetch_log table
+	maxMigrationVersion = migrationVersion22
 )
 
 // getMigrations returns the database migration scripts.
@@ -40,6 +58,99 @@ func getMigrations() map[int]string {
 			UPDATE url_metadata SET updated_at = CURRENT_TIMESTAMP WHERE url = NEW.url;
 		END;`,
 		migrationVersion4: `ALTER TABLE items ADD COLUMN first_seen DATETIME;`,
+		migrationVersion5: `ALTER TABLE feeds ADD COLUMN next_fetch_at DATETIME;`,
+		migrationVersion6: `ALTER TABLE feeds ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT 0;`,
+		migrationVersion7: `ALTER TABLE feeds ADD COLUMN parked_until DATETIME;`,
+		migrationVersion8: `CREATE TABLE IF NOT EXISTS websub_subscriptions (
+			feed_url TEXT PRIMARY KEY,
+			hub_url TEXT NOT NULL,
+			topic_url TEXT NOT NULL,
+			callback_id TEXT NOT NULL DEFAULT '',
+			secret TEXT NOT NULL DEFAULT '',
+			state TEXT NOT NULL DEFAULT 'discovered',
+			lease_expires_at DATETIME,
+			last_error TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME,
+			FOREIGN KEY (feed_url) REFERENCES feeds(url) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_websub_subscriptions_callback_id ON websub_subscriptions(callback_id);`,
+		migrationVersion9:  searchIndexSQL,
+		migrationVersion10: `ALTER TABLE items ADD COLUMN read BOOLEAN NOT NULL DEFAULT 0;`,
+		migrationVersion11: `ALTER TABLE items ADD COLUMN starred BOOLEAN NOT NULL DEFAULT 0;`,
+		migrationVersion12: `CREATE TABLE IF NOT EXISTS feed_tags (
+			feed_url TEXT NOT NULL,
+			tag TEXT NOT NULL,
+			PRIMARY KEY (feed_url, tag),
+			FOREIGN KEY (feed_url) REFERENCES feeds(url) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_feed_tags_tag ON feed_tags(tag);`,
+		migrationVersion13: `ALTER TABLE items ADD COLUMN highlighted BOOLEAN NOT NULL DEFAULT 0;`,
+		migrationVersion14: `CREATE TABLE IF NOT EXISTS item_tags (
+			item_id INTEGER NOT NULL,
+			tag TEXT NOT NULL,
+			PRIMARY KEY (item_id, tag),
+			FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_item_tags_tag ON item_tags(tag);`,
+		migrationVersion15: `ALTER TABLE items ADD COLUMN duplicate_of INTEGER REFERENCES items(id) ON DELETE SET NULL;`,
+		migrationVersion16: `CREATE TABLE IF NOT EXISTS item_keys (
+			item_id INTEGER NOT NULL,
+			key TEXT NOT NULL,
+			PRIMARY KEY (item_id, key),
+			FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_item_keys_key ON item_keys(key);
+		CREATE INDEX IF NOT EXISTS idx_items_duplicate_of ON items(duplicate_of);`,
+		migrationVersion17: `ALTER TABLE feeds ADD COLUMN full_text BOOLEAN NOT NULL DEFAULT 0;`,
+		migrationVersion18: `ALTER TABLE url_metadata ADD COLUMN content TEXT;`,
+		migrationVersion19: `CREATE TABLE IF NOT EXISTS media (
+			url TEXT PRIMARY KEY,
+			hash TEXT,
+			content_type TEXT NOT NULL DEFAULT '',
+			size INTEGER NOT NULL DEFAULT 0,
+			fetched_at DATETIME NOT NULL,
+			fetch_error TEXT
+		);
+		CREATE INDEX IF NOT EXISTS idx_media_hash ON media(hash);
+		CREATE TABLE IF NOT EXISTS item_media (
+			item_id INTEGER NOT NULL,
+			url TEXT NOT NULL,
+			PRIMARY KEY (item_id, url),
+			FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE
+		);
+		CREATE INDEX IF NOT EXISTS idx_item_media_url ON item_media(url);`,
+		migrationVersion20: `CREATE TABLE IF NOT EXISTS enclosures (
+			item_id INTEGER NOT NULL,
+			url TEXT NOT NULL,
+			type TEXT NOT NULL DEFAULT '',
+			length INTEGER NOT NULL DEFAULT 0,
+			duration TEXT NOT NULL DEFAULT '',
+			local_path TEXT,
+			downloaded_at DATETIME,
+			download_error TEXT,
+			PRIMARY KEY (item_id, url),
+			FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE
+		);`,
+		migrationVersion21: `ALTER TABLE feeds ADD COLUMN title_override TEXT NOT NULL DEFAULT '';
+		ALTER TABLE feeds ADD COLUMN fetch_interval INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE feeds ADD COLUMN max_items INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE feeds ADD COLUMN hidden BOOLEAN NOT NULL DEFAULT 0;
+		ALTER TABLE feeds ADD COLUMN retention INTEGER NOT NULL DEFAULT 0;`,
+		migrationVersion22: `CREATE TABLE IF NOT EXISTS fetch_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			feed_url TEXT NOT NULL,
+			started_at DATETIME NOT NULL,
+			duration_ms INTEGER NOT NULL DEFAULT 0,
+			status_code INTEGER NOT NULL DEFAULT 0,
+			bytes INTEGER NOT NULL DEFAULT 0,
+			cached BOOLEAN NOT NULL DEFAULT 0,
+			new_items INTEGER NOT NULL DEFAULT 0,
+			updated_items INTEGER NOT NULL DEFAULT 0,
+			archived_items INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT ''
+		);
+		CREATE INDEX IF NOT EXISTS idx_fetch_log_feed_started ON fetch_log(feed_url, started_at);
+		CREATE INDEX IF NOT EXISTS idx_fetch_log_started ON fetch_log(started_at);`,
 	}
 }
 
@@ -88,7 +199,7 @@ func (db *DB) RunMigrations() error {
 	appliedCount := 0
 	for version := currentVersion + 1; version <= maxMigrationVersion; version++ {
 		if _, exists := migrations[version]; exists {
-			logrus.Infof("Applying migration %d: Adding latest_item_date column", version)
+			logrus.Infof("Applying migration %d", version)
 			if err := db.applySpecificMigration(version); err != nil {
 				return err
 			}
@@ -115,6 +226,26 @@ func (db *DB) applySpecificMigration(version int) error {
 		return db.applyMigration3()
 	case migrationVersion4:
 		return db.applyMigration4()
+	case migrationVersion5:
+		return db.applyColumnMigration(migrationVersion5, "feeds", "next_fetch_at")
+	case migrationVersion6:
+		return db.applyColumnMigration(migrationVersion6, "feeds", "disabled")
+	case migrationVersion7:
+		return db.applyColumnMigration(migrationVersion7, "feeds", "parked_until")
+	case migrationVersion9:
+		return db.applyMigration9()
+	case migrationVersion10:
+		return db.applyColumnMigration(migrationVersion10, "items", "read")
+	case migrationVersion11:
+		return db.applyColumnMigration(migrationVersion11, "items", "starred")
+	case migrationVersion13:
+		return db.applyColumnMigration(migrationVersion13, "items", "highlighted")
+	case migrationVersion15:
+		return db.applyColumnMigration(migrationVersion15, "items", "duplicate_of")
+	case migrationVersion17:
+		return db.applyColumnMigration(migrationVersion17, "feeds", "full_text")
+	case migrationVersion18:
+		return db.applyColumnMigration(migrationVersion18, "url_metadata", "content")
 	default:
 		// For any new migrations, just apply them directly
 		migrations := getMigrations()
@@ -255,3 +386,42 @@ func (db *DB) applyMigration4WithBackfill() error {
 	committed = true
 	return nil
 }
+
+// applyColumnMigration applies a migration that adds a single column, or just
+// records it when the column already exists.
+func (db *DB) applyColumnMigration(version int, table, column string) error {
+	var colCount int
+	err := db.conn.QueryRow(`
+		SELECT COUNT(*) FROM pragma_table_info(?)
+		WHERE name = ?
+	`, table, column).Scan(&colCount)
+	if err != nil {
+		return fmt.Errorf("failed to check column existence: %w", err)
+	}
+
+	if colCount == 0 {
+		migrations := getMigrations()
+		return db.ApplyMigration(version, migrations[version])
+	}
+
+	_, err = db.conn.Exec("INSERT INTO schema_migrations (version) VALUES (?)", version)
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", version, err)
+	}
+	return nil
+}
+
+// applyMigration9 adds the items_fts search index when this build of SQLite
+// supports FTS5. Without it the migration is only recorded, and the index is
+// created the first time a build with FTS5 opens the database.
+func (db *DB) applyMigration9() error {
+	if err := db.ensureSearchIndex(); err != nil {
+		return err
+	}
+
+	_, err := db.conn.Exec("INSERT INTO schema_migrations (version) VALUES (?)", migrationVersion9)
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", migrationVersion9, err)
+	}
+	return nil
+}
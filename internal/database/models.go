@@ -7,8 +7,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"html"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/lmorchard/feedspool-go/internal/sanitize"
 	"github.com/mmcdole/gofeed"
 )
 
@@ -37,20 +41,60 @@ type Feed struct {
 	LastError           string       `db:"last_error"`
 	LatestItemDate      sql.NullTime `db:"latest_item_date"`
 	FeedJSON            JSON         `db:"feed_json"`
+	NextFetchAt         sql.NullTime `db:"next_fetch_at"`
+	Disabled            bool         `db:"disabled"`
+	ParkedUntil         sql.NullTime `db:"parked_until"`
+	FullText            bool         `db:"full_text"` // Extract the article text of items when unfurling
+	FeedSettings
+}
+
+// FeedSettings are the per-feed options a structured feed list can set.
+// Zero values fall back to the config.
+type FeedSettings struct {
+	TitleOverride string        `db:"title_override"` // Shown instead of the feed's own title
+	FetchInterval time.Duration `db:"fetch_interval"` // Fixed time between fetches instead of the adaptive schedule
+	MaxItems      int           `db:"max_items"`      // Items stored per fetch instead of the fetch default
+	Hidden        bool          `db:"hidden"`         // Left out of rendered pages
+	Retention     time.Duration `db:"retention"`      // How long archived items are kept instead of the purge age
 }
 
 type Item struct {
-	ID            int64        `db:"id"`
-	FeedURL       string       `db:"feed_url"`
-	GUID          string       `db:"guid"`
-	Title         string       `db:"title"`
-	Link          string       `db:"link"`
-	PublishedDate time.Time    `db:"published_date"`
-	FirstSeen     sql.NullTime `db:"first_seen"`
-	Content       string       `db:"content"`
-	Summary       string       `db:"summary"`
-	Archived      bool         `db:"archived"`
-	ItemJSON      JSON         `db:"item_json"`
+	ID            int64         `db:"id"`
+	FeedURL       string        `db:"feed_url"`
+	GUID          string        `db:"guid"`
+	Title         string        `db:"title"`
+	Link          string        `db:"link"`
+	PublishedDate time.Time     `db:"published_date"`
+	FirstSeen     sql.NullTime  `db:"first_seen"`
+	Content       string        `db:"content"`
+	Summary       string        `db:"summary"`
+	Archived      bool          `db:"archived"`
+	ItemJSON      JSON          `db:"item_json"`
+	Read          bool          `db:"read"`
+	Starred       bool          `db:"starred"`
+	Highlighted   bool          `db:"highlighted"`
+	DuplicateOf   sql.NullInt64 `db:"duplicate_of"` // First item of the duplicate group this item belongs to
+}
+
+// WebSub subscription states.
+const (
+	WebSubDiscovered = "discovered" // Hub found during fetch, not yet subscribed
+	WebSubPending    = "pending"    // Subscription requested, awaiting the hub's verification
+	WebSubActive     = "active"     // Verified by the hub, lease running
+	WebSubDenied     = "denied"     // Hub refused the subscription
+)
+
+// WebSubSubscription tracks a feed's WebSub hub and our subscription to it.
+type WebSubSubscription struct {
+	FeedURL        string       `db:"feed_url"`
+	HubURL         string       `db:"hub_url"`
+	TopicURL       string       `db:"topic_url"`
+	CallbackID     string       `db:"callback_id"`
+	Secret         string       `db:"secret"`
+	State          string       `db:"state"`
+	LeaseExpiresAt sql.NullTime `db:"lease_expires_at"`
+	LastError      string       `db:"last_error"`
+	UpdatedAt      sql.NullTime `db:"updated_at"`
 }
 
 type URLMetadata struct {
@@ -63,10 +107,52 @@ type URLMetadata struct {
 	LastFetchAt     sql.NullTime   `db:"last_fetch_at" json:"last_fetch_at,omitempty"`
 	FetchStatusCode sql.NullInt64  `db:"fetch_status_code" json:"fetch_status_code,omitempty"`
 	FetchError      sql.NullString `db:"fetch_error" json:"fetch_error,omitempty"`
+	Content         sql.NullString `db:"content" json:"content,omitempty"` // Extracted article HTML, NULL if never tried
 	CreatedAt       time.Time      `db:"created_at" json:"created_at"`
 	UpdatedAt       time.Time      `db:"updated_at" json:"updated_at"`
 }
 
+// Media is an image referenced by items or their metadata, downloaded into
+// the media cache. Files are stored under the hash of their content, so URLs
+// serving the same image share a file.
+type Media struct {
+	URL         string         `db:"url"`
+	Hash        sql.NullString `db:"hash"` // SHA-256 of the content, NULL if the download failed
+	ContentType string         `db:"content_type"`
+	Size        int64          `db:"size"`
+	FetchedAt   time.Time      `db:"fetched_at"`
+	FetchError  sql.NullString `db:"fetch_error"`
+}
+
+// Enclosure is a media file attached to an item, such as a podcast episode,
+// along with the state of its download by 'feedspool enclosures download'.
+type Enclosure struct {
+	ItemID        int64          `db:"item_id"`
+	URL           string         `db:"url"`
+	Type          string         `db:"type"`
+	Length        int64          `db:"length"`     // Size in bytes, as declared by the feed
+	Duration      string         `db:"duration"`   // iTunes duration, e.g. "1:02:03" or seconds
+	LocalPath     sql.NullString `db:"local_path"` // Downloaded file, relative to the enclosures directory
+	DownloadedAt  sql.NullTime   `db:"downloaded_at"`
+	DownloadError sql.NullString `db:"download_error"`
+}
+
+// FetchLogEntry records a single fetch attempt of a feed, for diagnosing
+// feeds that stop updating.
+type FetchLogEntry struct {
+	ID            int64         `db:"id"`
+	FeedURL       string        `db:"feed_url"`
+	StartedAt     time.Time     `db:"started_at"`
+	Duration      time.Duration `db:"duration_ms"` // Stored in milliseconds
+	StatusCode    int           `db:"status_code"` // 0 when no response was received
+	Bytes         int64         `db:"bytes"`       // Size of the response body
+	Cached        bool          `db:"cached"`      // 304 Not Modified
+	NewItems      int           `db:"new_items"`
+	UpdatedItems  int           `db:"updated_items"` // Stored items whose content changed
+	ArchivedItems int           `db:"archived_items"`
+	Error         string        `db:"error"`
+}
+
 type JSON json.RawMessage
 
 func (j JSON) Value() (driver.Value, error) {
@@ -129,7 +215,12 @@ func FeedFromGofeed(gf *gofeed.Feed, url string) (*Feed, error) {
 	return feed, nil
 }
 
-func ItemFromGofeed(gi *gofeed.Item, feedURL string) (*Item, error) {
+// ItemFromGofeed converts a parsed feed item. canonicalize, if not nil,
+// rewrites the item link after the GUID is derived from the original link, so
+// GUIDs stay stable when canonicalization changes. Content and summary are
+// sanitized, with relative URLs resolved against the item link; item_json
+// keeps them as published.
+func ItemFromGofeed(gi *gofeed.Item, feedURL string, canonicalize func(string) string) (*Item, error) {
 	itemJSON, err := json.Marshal(gi)
 	if err != nil {
 		return nil, err
@@ -159,9 +250,55 @@ func ItemFromGofeed(gi *gofeed.Item, feedURL string) (*Item, error) {
 		item.PublishedDate = time.Now().UTC()
 	}
 
+	if canonicalize != nil {
+		item.Link = canonicalize(item.Link)
+	}
+
+	item.Content = sanitize.HTML(item.Content, item.Link)
+	item.Summary = sanitize.HTML(item.Summary, item.Link)
+
 	return item, nil
 }
 
+// EnclosuresFromGofeed converts the enclosures of a gofeed item. Relative URLs
+// are resolved against the item link, and anything but http(s) is dropped.
+func EnclosuresFromGofeed(gi *gofeed.Item, link string) []*Enclosure {
+	base, _ := url.Parse(link)
+
+	duration := ""
+	if gi.ITunesExt != nil {
+		duration = strings.TrimSpace(gi.ITunesExt.Duration)
+	}
+
+	enclosures := []*Enclosure{}
+	seen := make(map[string]bool)
+	for _, ge := range gi.Enclosures {
+		if ge == nil {
+			continue
+		}
+		ref, err := url.Parse(strings.TrimSpace(ge.URL))
+		if err != nil || ref.String() == "" {
+			continue
+		}
+		if base != nil {
+			ref = base.ResolveReference(ref)
+		}
+		if (ref.Scheme != "http" && ref.Scheme != "https") || seen[ref.String()] {
+			continue
+		}
+		seen[ref.String()] = true
+
+		length, _ := strconv.ParseInt(strings.TrimSpace(ge.Length), 10, 64)
+		enclosures = append(enclosures, &Enclosure{
+			URL:      ref.String(),
+			Type:     strings.TrimSpace(ge.Type),
+			Length:   max(length, 0),
+			Duration: duration,
+		})
+	}
+	return enclosures
+}
+
 func generateGUID(link, title string) string {
 	h := sha256.New()
 	h.Write([]byte(link + title))
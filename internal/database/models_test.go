@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
 )
 
 const (
@@ -323,7 +324,7 @@ func TestItemFromGofeed(t *testing.T) {
 		PublishedParsed: &now,
 	}
 
-	item, err := ItemFromGofeed(gofeedItem, testItemURL)
+	item, err := ItemFromGofeed(gofeedItem, testItemURL, nil)
 	if err != nil {
 		t.Errorf("ItemFromGofeed() error = %v", err)
 		return
@@ -352,7 +353,7 @@ func TestItemFromGofeedNoGUID(t *testing.T) {
 		Link:  "https://example.com/item",
 	}
 
-	item, err := ItemFromGofeed(gofeedItem, "https://example.com/feed.xml")
+	item, err := ItemFromGofeed(gofeedItem, "https://example.com/feed.xml", nil)
 	if err != nil {
 		t.Errorf("ItemFromGofeed() error = %v", err)
 		return
@@ -364,12 +365,83 @@ func TestItemFromGofeedNoGUID(t *testing.T) {
 	}
 
 	// Should be consistent
-	item2, _ := ItemFromGofeed(gofeedItem, "https://example.com/feed.xml")
+	item2, _ := ItemFromGofeed(gofeedItem, "https://example.com/feed.xml", nil)
 	if item.GUID != item2.GUID {
 		t.Errorf("Generated GUID should be consistent: %v != %v", item.GUID, item2.GUID)
 	}
 }
 
+func TestItemFromGofeedCanonicalize(t *testing.T) {
+	gofeedItem := &gofeed.Item{
+		Title: testItemTitle,
+		Link:  "https://example.com/item?utm_source=rss",
+	}
+	canonicalize := func(string) string { return "https://example.com/item" }
+
+	item, err := ItemFromGofeed(gofeedItem, "https://example.com/feed.xml", canonicalize)
+	if err != nil {
+		t.Fatalf("ItemFromGofeed() error = %v", err)
+	}
+	if item.Link != "https://example.com/item" {
+		t.Errorf("Item.Link = %v, want the canonical link", item.Link)
+	}
+
+	// GUIDs generated before canonicalization existed must not change
+	if item.GUID != generateGUID(gofeedItem.Link, testItemTitle) {
+		t.Errorf("Item.GUID should be generated from the original link")
+	}
+}
+
+func TestItemFromGofeedSanitizes(t *testing.T) {
+	gofeedItem := &gofeed.Item{
+		Title:       testItemTitle,
+		Link:        "https://example.com/posts/item",
+		Content:     `<p onclick="steal()">Body <img src="images/a.png"></p><script>alert(1)</script>`,
+		Description: `&lt;b&gt;Summary&lt;/b&gt;&lt;script&gt;alert(1)&lt;/script&gt;`,
+	}
+
+	item, err := ItemFromGofeed(gofeedItem, "https://example.com/feed.xml", nil)
+	if err != nil {
+		t.Fatalf("ItemFromGofeed() error = %v", err)
+	}
+	if item.Content != `<p>Body <img src="https://example.com/posts/images/a.png"/></p>` {
+		t.Errorf("Item.Content = %q, want sanitized content", item.Content)
+	}
+	// Entity-encoded markup is decoded, then sanitized
+	if item.Summary != "<b>Summary</b>" {
+		t.Errorf("Item.Summary = %q, want sanitized summary", item.Summary)
+	}
+}
+
+func TestEnclosuresFromGofeed(t *testing.T) {
+	gofeedItem := &gofeed.Item{
+		Enclosures: []*gofeed.Enclosure{
+			{URL: "episodes/1.mp3", Length: "12345", Type: "audio/mpeg"},
+			{URL: "https://cdn.example.com/1.mp4", Length: "not a number", Type: "video/mp4"},
+			{URL: "https://example.com/posts/episodes/1.mp3", Type: "audio/mpeg"},
+			{URL: "ftp://example.com/1.mp3", Type: "audio/mpeg"},
+			{URL: ""},
+		},
+		ITunesExt: &ext.ITunesItemExtension{Duration: " 1:02:03 "},
+	}
+
+	enclosures := EnclosuresFromGofeed(gofeedItem, "https://example.com/posts/item")
+	if len(enclosures) != 2 {
+		t.Fatalf("EnclosuresFromGofeed() = %d enclosures, want 2", len(enclosures))
+	}
+
+	audio := enclosures[0]
+	if audio.URL != "https://example.com/posts/episodes/1.mp3" || audio.Length != 12345 || audio.Type != "audio/mpeg" {
+		t.Errorf("enclosure = %+v, want the audio resolved against the item link", audio)
+	}
+	if audio.Duration != "1:02:03" {
+		t.Errorf("Duration = %q, want 1:02:03", audio.Duration)
+	}
+	if enclosures[1].Length != 0 {
+		t.Errorf("Length = %d, want 0 for an unreadable length", enclosures[1].Length)
+	}
+}
+
 func TestGenerateGUID(t *testing.T) {
 	link := "https://example.com/item"
 	title := testItemTitle
@@ -490,13 +562,13 @@ func TestItemFromGofeedWithBBCStyleGUID(t *testing.T) {
 		Link:  link,
 	}
 
-	item1, err := ItemFromGofeed(gofeedItem1, "https://feeds.bbci.co.uk/news/rss.xml")
+	item1, err := ItemFromGofeed(gofeedItem1, "https://feeds.bbci.co.uk/news/rss.xml", nil)
 	if err != nil {
 		t.Errorf("ItemFromGofeed() error = %v", err)
 		return
 	}
 
-	item2, err := ItemFromGofeed(gofeedItem2, "https://feeds.bbci.co.uk/news/rss.xml")
+	item2, err := ItemFromGofeed(gofeedItem2, "https://feeds.bbci.co.uk/news/rss.xml", nil)
 	if err != nil {
 		t.Errorf("ItemFromGofeed() error = %v", err)
 		return
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// searchIndexSQL creates the items_fts full-text index over item titles,
+// summaries and content, and the triggers that keep it in step with items.
+const searchIndexSQL = `CREATE VIRTUAL TABLE IF NOT EXISTS items_fts USING fts5(
+			title, summary, content,
+			content='items'
+		);
+		CREATE TRIGGER IF NOT EXISTS items_fts_insert AFTER INSERT ON items BEGIN
+			INSERT INTO items_fts(rowid, title, summary, content)
+			VALUES (new.rowid, new.title, new.summary, new.content);
+		END;
+		CREATE TRIGGER IF NOT EXISTS items_fts_delete AFTER DELETE ON items BEGIN
+			INSERT INTO items_fts(items_fts, rowid, title, summary, content)
+			VALUES ('delete', old.rowid, old.title, old.summary, old.content);
+		END;
+		CREATE TRIGGER IF NOT EXISTS items_fts_update AFTER UPDATE OF title, summary, content ON items BEGIN
+			INSERT INTO items_fts(items_fts, rowid, title, summary, content)
+			VALUES ('delete', old.rowid, old.title, old.summary, old.content);
+			INSERT INTO items_fts(rowid, title, summary, content)
+			VALUES (new.rowid, new.title, new.summary, new.content);
+		END;`
+
+// searchTriggers names the triggers created by searchIndexSQL.
+var searchTriggers = []string{"items_fts_insert", "items_fts_delete", "items_fts_update"}
+
+// SearchOptions filters a full-text item search.
+type SearchOptions struct {
+	Query   string
+	FeedURL string    // Only items from this feed, if set
+	Since   time.Time // Only items published at or after this time, if set
+	Until   time.Time // Only items published at or before this time, if set
+	Limit   int       // Maximum items to return, 0 for all
+}
+
+// SearchAvailable reports whether this build of SQLite supports FTS5, which
+// needs building with -tags sqlite_fts5. Without it, search falls back to
+// substring matching.
+func (db *DB) SearchAvailable() bool {
+	var used int
+	if err := db.conn.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&used); err != nil {
+		return false
+	}
+	return used == 1
+}
+
+// ensureSearchIndex keeps the items_fts index usable by this build. With FTS5
+// it creates the index and triggers when missing, rebuilding the index if the
+// triggers were gone since items may have changed without it. Without FTS5 it
+// drops the triggers, which would otherwise make every item write fail.
+func (db *DB) ensureSearchIndex() error {
+	placeholders := strings.Repeat(",?", len(searchTriggers))[1:]
+	args := make([]interface{}, len(searchTriggers))
+	for i, name := range searchTriggers {
+		args[i] = name
+	}
+
+	var triggerCount int
+	err := db.conn.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN ("+placeholders+")",
+		args...,
+	).Scan(&triggerCount)
+	if err != nil {
+		return fmt.Errorf("failed to check search index triggers: %w", err)
+	}
+
+	if !db.SearchAvailable() {
+		if triggerCount > 0 {
+			logrus.Warn("SQLite was built without FTS5; disabling the search index until a build with it runs")
+			for _, name := range searchTriggers {
+				if _, err := db.conn.Exec("DROP TRIGGER IF EXISTS " + name); err != nil {
+					return fmt.Errorf("failed to drop search index trigger: %w", err)
+				}
+			}
+		}
+		return nil
+	}
+
+	if triggerCount == len(searchTriggers) {
+		return nil
+	}
+
+	logrus.Info("Building full-text search index...")
+	if _, err := db.conn.Exec(searchIndexSQL); err != nil {
+		return fmt.Errorf("failed to create search index: %w", err)
+	}
+	if _, err := db.conn.Exec("INSERT INTO items_fts(items_fts) VALUES ('rebuild')"); err != nil {
+		return fmt.Errorf("failed to rebuild search index: %w", err)
+	}
+	return nil
+}
+
+// SearchItems finds items whose title, summary or content match the query.
+// With FTS5, the query uses FTS5 syntax (phrases, OR, NOT, prefix*) and
+// results are ordered by relevance. Otherwise every word of the query must
+// appear as a substring and results are ordered newest first.
+func (db *DB) SearchItems(opts *SearchOptions) ([]*Item, error) {
+	var query string
+	var args []interface{}
+
+	fts := db.SearchAvailable()
+	if fts {
+		query = `SELECT ` + itemColumns + `
+			FROM items_fts JOIN items ON items.rowid = items_fts.rowid
+			WHERE items_fts MATCH ?`
+		args = append(args, opts.Query)
+	} else {
+		query = `SELECT ` + itemColumns + ` FROM items WHERE 1 = 1`
+		for _, term := range strings.Fields(opts.Query) {
+			pattern := "%" + escapeLike(term) + "%"
+			query += ` AND (items.title LIKE ? ESCAPE '\' OR items.summary LIKE ? ESCAPE '\'
+				OR items.content LIKE ? ESCAPE '\')`
+			args = append(args, pattern, pattern, pattern)
+		}
+	}
+
+	if opts.FeedURL != "" {
+		query += " AND items.feed_url = ?"
+		args = append(args, opts.FeedURL)
+	}
+	if !opts.Since.IsZero() {
+		query += " AND items.published_date >= ?"
+		args = append(args, opts.Since)
+	}
+	if !opts.Until.IsZero() {
+		query += " AND items.published_date <= ?"
+		args = append(args, opts.Until)
+	}
+
+	if fts {
+		query += " ORDER BY items_fts.rank"
+	} else {
+		query += " ORDER BY items.published_date DESC"
+	}
+
+	if opts.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, opts.Limit)
+	}
+
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []*Item{}
+	for rows.Next() {
+		item := &Item{}
+		if err := scanItem(rows, item); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search items: %w", err)
+	}
+
+	return items, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s, for use with ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func setupSearchTestItems(t *testing.T, db *DB) {
+	t.Helper()
+
+	for _, feedURL := range []string{"https://go.example.com/feed", "https://rust.example.com/feed"} {
+		if err := db.UpsertFeed(&Feed{URL: feedURL, FeedJSON: JSON(`{}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items := []*Item{
+		{
+			FeedURL: "https://go.example.com/feed", GUID: "generics", Title: "Generics explained",
+			Summary: "Type parameters in practice", PublishedDate: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			FeedURL: "https://go.example.com/feed", GUID: "errors", Title: "Error wrapping",
+			Content: "<p>Use errors.Is with wrapped errors</p>", PublishedDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			FeedURL: "https://rust.example.com/feed", GUID: "ownership", Title: "Ownership and errors",
+			Summary: "Borrowing without tears", PublishedDate: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, item := range items {
+		item.ItemJSON = JSON(`{}`)
+		if err := db.UpsertItem(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func searchGUIDs(t *testing.T, db *DB, opts *SearchOptions) []string {
+	t.Helper()
+
+	items, err := db.SearchItems(opts)
+	if err != nil {
+		t.Fatalf("SearchItems(%+v) error = %v", opts, err)
+	}
+	guids := make([]string, len(items))
+	for i, item := range items {
+		guids[i] = item.GUID
+	}
+	return guids
+}
+
+func TestSearchItems(t *testing.T) {
+	db := setupTestDB(t)
+	setupSearchTestItems(t, db)
+	t.Logf("FTS5 available: %v", db.SearchAvailable())
+
+	if got := searchGUIDs(t, db, &SearchOptions{Query: "generics"}); len(got) != 1 || got[0] != "generics" {
+		t.Errorf("search for a title word = %v, want [generics]", got)
+	}
+
+	if got := searchGUIDs(t, db, &SearchOptions{Query: "borrowing"}); len(got) != 1 || got[0] != "ownership" {
+		t.Errorf("search for a summary word = %v, want [ownership]", got)
+	}
+
+	if got := searchGUIDs(t, db, &SearchOptions{Query: "errors"}); len(got) != 2 {
+		t.Errorf("search across feeds = %v, want 2 items", got)
+	}
+
+	got := searchGUIDs(t, db, &SearchOptions{Query: "errors", FeedURL: "https://rust.example.com/feed"})
+	if len(got) != 1 || got[0] != "ownership" {
+		t.Errorf("search within a feed = %v, want [ownership]", got)
+	}
+
+	got = searchGUIDs(t, db, &SearchOptions{
+		Query: "errors", Since: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+	})
+	if len(got) != 1 || got[0] != "ownership" {
+		t.Errorf("search since a date = %v, want [ownership]", got)
+	}
+
+	if got := searchGUIDs(t, db, &SearchOptions{Query: "errors", Limit: 1}); len(got) != 1 {
+		t.Errorf("search with limit 1 = %v, want 1 item", got)
+	}
+}
+
+func TestSearchItemsTracksChanges(t *testing.T) {
+	db := setupTestDB(t)
+	setupSearchTestItems(t, db)
+
+	// An updated title replaces the old one in the index
+	err := db.UpsertItem(&Item{
+		FeedURL: "https://go.example.com/feed", GUID: "generics", Title: "Iterators explained", ItemJSON: JSON(`{}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := searchGUIDs(t, db, &SearchOptions{Query: "generics"}); len(got) != 0 {
+		t.Errorf("search for the old title = %v, want none", got)
+	}
+	if got := searchGUIDs(t, db, &SearchOptions{Query: "iterators"}); len(got) != 1 {
+		t.Errorf("search for the new title = %v, want 1 item", got)
+	}
+
+	// Deleting a feed removes its items from the index
+	if err := db.DeleteFeed("https://rust.example.com/feed"); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchGUIDs(t, db, &SearchOptions{Query: "borrowing"}); len(got) != 0 {
+		t.Errorf("search for a deleted item = %v, want none", got)
+	}
+}
+
+func TestEnsureSearchIndexRebuilds(t *testing.T) {
+	db := setupTestDB(t)
+	if !db.SearchAvailable() {
+		t.Skip("SQLite built without FTS5 (use -tags sqlite_fts5)")
+	}
+
+	// Items written while the triggers were missing are indexed on rebuild
+	for _, name := range searchTriggers {
+		if _, err := db.conn.Exec("DROP TRIGGER " + name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setupSearchTestItems(t, db)
+
+	if err := db.ensureSearchIndex(); err != nil {
+		t.Fatalf("ensureSearchIndex() error = %v", err)
+	}
+	if got := searchGUIDs(t, db, &SearchOptions{Query: "generics"}); len(got) != 1 {
+		t.Errorf("search after rebuild = %v, want 1 item", got)
+	}
+}
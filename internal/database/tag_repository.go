@@ -0,0 +1,173 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SetFeedTags replaces the tags of a feed. Blank and repeated tags are
+// dropped, and an empty list clears the feed's tags.
+func (db *DB) SetFeedTags(feedURL string, tags []string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			logrus.Warnf("Failed to rollback transaction: %v", rollbackErr)
+		}
+	}()
+
+	if _, err := tx.Exec("DELETE FROM feed_tags WHERE feed_url = ?", feedURL); err != nil {
+		return fmt.Errorf("failed to clear feed tags: %w", err)
+	}
+
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if _, err := tx.Exec("INSERT OR IGNORE INTO feed_tags (feed_url, tag) VALUES (?, ?)", feedURL, tag); err != nil {
+			return fmt.Errorf("failed to add feed tag %q: %w", tag, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit feed tags: %w", err)
+	}
+
+	logrus.Debugf("Set tags for feed %s: %v", feedURL, tags)
+	return nil
+}
+
+// GetFeedTags retrieves the tags of a feed, ordered by name.
+func (db *DB) GetFeedTags(feedURL string) ([]string, error) {
+	rows, err := db.conn.Query("SELECT tag FROM feed_tags WHERE feed_url = ? ORDER BY tag", feedURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// GetAllFeedTags retrieves the tags of every tagged feed, keyed by feed URL.
+func (db *DB) GetAllFeedTags() (map[string][]string, error) {
+	rows, err := db.conn.Query("SELECT feed_url, tag FROM feed_tags ORDER BY feed_url, tag")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make(map[string][]string)
+	for rows.Next() {
+		var feedURL, tag string
+		if err := rows.Scan(&feedURL, &tag); err != nil {
+			return nil, fmt.Errorf("failed to scan feed tag: %w", err)
+		}
+		tags[feedURL] = append(tags[feedURL], tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over feed tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// GetFeedURLsByTags retrieves the URLs of feeds with any of the given tags,
+// ordered by URL.
+func (db *DB) GetFeedURLsByTags(tags []string) ([]string, error) {
+	urls := []string{}
+	if len(tags) == 0 {
+		return urls, nil
+	}
+
+	placeholders := strings.Repeat(",?", len(tags))[1:]
+	args := make([]interface{}, len(tags))
+	for i, tag := range tags {
+		args[i] = tag
+	}
+
+	rows, err := db.conn.Query(
+		"SELECT DISTINCT feed_url FROM feed_tags WHERE tag IN ("+placeholders+") ORDER BY feed_url", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feeds by tag: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, fmt.Errorf("failed to scan URL: %w", err)
+		}
+		urls = append(urls, url)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over URLs: %w", err)
+	}
+
+	return urls, nil
+}
+
+// AddItemTags adds tags to the item with the given feed and GUID, keeping the
+// tags it already has. Blank tags are dropped.
+func (db *DB) AddItemTags(feedURL, guid string, tags []string) error {
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		_, err := db.conn.Exec(`
+			INSERT OR IGNORE INTO item_tags (item_id, tag)
+			SELECT id, ? FROM items WHERE feed_url = ? AND guid = ?`,
+			tag, feedURL, guid)
+		if err != nil {
+			return fmt.Errorf("failed to add item tag %q: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+// GetAllItemTags retrieves the tags of every tagged item, keyed by item ID.
+func (db *DB) GetAllItemTags() (map[int64][]string, error) {
+	rows, err := db.conn.Query("SELECT item_id, tag FROM item_tags ORDER BY item_id, tag")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make(map[int64][]string)
+	for rows.Next() {
+		var itemID int64
+		var tag string
+		if err := rows.Scan(&itemID, &tag); err != nil {
+			return nil, fmt.Errorf("failed to scan item tag: %w", err)
+		}
+		tags[itemID] = append(tags[itemID], tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over item tags: %w", err)
+	}
+
+	return tags, nil
+}
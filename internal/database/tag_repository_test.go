@@ -0,0 +1,127 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFeedTags(t *testing.T) {
+	db := setupTestDB(t)
+
+	goURL := "https://go.example.com/feed"
+	rustURL := "https://rust.example.com/feed"
+	for _, url := range []string{goURL, rustURL} {
+		if err := db.UpsertFeed(&Feed{URL: url}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := db.SetFeedTags(goURL, []string{"golang", " programming ", "", "golang"}); err != nil {
+		t.Fatalf("SetFeedTags() error = %v", err)
+	}
+	if err := db.SetFeedTags(rustURL, []string{"programming"}); err != nil {
+		t.Fatalf("SetFeedTags() error = %v", err)
+	}
+
+	tags, err := db.GetFeedTags(goURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(tags, ",") != "golang,programming" {
+		t.Errorf("GetFeedTags() = %v, want [golang programming]", tags)
+	}
+
+	urls, err := db.GetFeedURLsByTags([]string{"golang", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 1 || urls[0] != goURL {
+		t.Errorf("GetFeedURLsByTags(golang) = %v, want [%s]", urls, goURL)
+	}
+
+	urls, err = db.GetFeedURLsByTags([]string{"programming"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 2 {
+		t.Errorf("GetFeedURLsByTags(programming) = %v, want both feeds", urls)
+	}
+
+	// Replacing tags drops the old ones
+	if err := db.SetFeedTags(goURL, []string{"news"}); err != nil {
+		t.Fatal(err)
+	}
+	all, err := db.GetAllFeedTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(all[goURL], ",") != "news" || strings.Join(all[rustURL], ",") != "programming" {
+		t.Errorf("GetAllFeedTags() = %v, want news and programming", all)
+	}
+
+	// Tags follow the feed to its new URL and go away with it
+	movedURL := "https://go.example.com/atom"
+	if err := db.MigrateFeedURL(goURL, movedURL); err != nil {
+		t.Fatal(err)
+	}
+	tags, err = db.GetFeedTags(movedURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(tags, ",") != "news" {
+		t.Errorf("GetFeedTags() after migrate = %v, want [news]", tags)
+	}
+
+	if err := db.DeleteFeed(movedURL); err != nil {
+		t.Fatal(err)
+	}
+	all, err = db.GetAllFeedTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := all[movedURL]; ok || len(all) != 1 {
+		t.Errorf("GetAllFeedTags() after delete = %v, want only %s", all, rustURL)
+	}
+}
+
+func TestItemTags(t *testing.T) {
+	db := setupTestDB(t)
+
+	feedURL := "https://example.com/feed"
+	if err := db.UpsertFeed(&Feed{URL: feedURL}); err != nil {
+		t.Fatal(err)
+	}
+	for _, guid := range []string{"one", "two"} {
+		if err := db.UpsertItem(&Item{FeedURL: feedURL, GUID: guid, Title: guid}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := db.AddItemTags(feedURL, "one", []string{"golang", " ", "news"}); err != nil {
+		t.Fatalf("AddItemTags() error = %v", err)
+	}
+	// Adding again keeps the existing tags
+	if err := db.AddItemTags(feedURL, "one", []string{"golang"}); err != nil {
+		t.Fatal(err)
+	}
+	// Unknown items are ignored
+	if err := db.AddItemTags(feedURL, "missing", []string{"golang"}); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := db.ListItems(&ItemFilter{Tag: "golang"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].GUID != "one" {
+		t.Fatalf("ListItems(tag golang) = %v, want item one", items)
+	}
+
+	all, err := db.GetAllItemTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || strings.Join(all[items[0].ID], ",") != "golang,news" {
+		t.Errorf("GetAllItemTags() = %v, want golang and news on item one", all)
+	}
+}
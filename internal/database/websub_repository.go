@@ -0,0 +1,122 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// websubColumns lists the websub_subscriptions columns in the order scanWebSubSubscription expects them.
+const websubColumns = `feed_url, hub_url, topic_url, callback_id, secret, state,
+	lease_expires_at, last_error, updated_at`
+
+// scanWebSubSubscription scans a row selected with websubColumns into sub.
+func scanWebSubSubscription(row rowScanner, sub *WebSubSubscription) error {
+	return row.Scan(
+		&sub.FeedURL, &sub.HubURL, &sub.TopicURL, &sub.CallbackID, &sub.Secret, &sub.State,
+		&sub.LeaseExpiresAt, &sub.LastError, &sub.UpdatedAt)
+}
+
+// UpsertWebSubHub records the hub and topic a feed advertises. A new hub or
+// topic resets the subscription so it is made again; an unchanged one leaves
+// the existing subscription alone.
+func (db *DB) UpsertWebSubHub(feedURL, hubURL, topicURL string) error {
+	query := `
+		INSERT INTO websub_subscriptions (feed_url, hub_url, topic_url, state, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(feed_url) DO UPDATE SET
+			hub_url = excluded.hub_url,
+			topic_url = excluded.topic_url,
+			state = excluded.state,
+			lease_expires_at = NULL,
+			last_error = '',
+			updated_at = excluded.updated_at
+		WHERE websub_subscriptions.hub_url != excluded.hub_url
+			OR websub_subscriptions.topic_url != excluded.topic_url
+	`
+
+	_, err := db.conn.Exec(query, feedURL, hubURL, topicURL, WebSubDiscovered, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to upsert websub hub: %w", err)
+	}
+	return nil
+}
+
+// UpdateWebSubSubscription saves the subscription state, callback, secret,
+// lease and error of sub.
+func (db *DB) UpdateWebSubSubscription(sub *WebSubSubscription) error {
+	sub.UpdatedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	_, err := db.conn.Exec(`
+		UPDATE websub_subscriptions SET
+			callback_id = ?, secret = ?, state = ?, lease_expires_at = ?,
+			last_error = ?, updated_at = ?
+		WHERE feed_url = ?
+	`, sub.CallbackID, sub.Secret, sub.State, sub.LeaseExpiresAt, sub.LastError, sub.UpdatedAt, sub.FeedURL)
+	if err != nil {
+		return fmt.Errorf("failed to update websub subscription: %w", err)
+	}
+	return nil
+}
+
+// GetWebSubSubscriptions retrieves all WebSub subscriptions, ordered by feed URL.
+func (db *DB) GetWebSubSubscriptions() ([]*WebSubSubscription, error) {
+	rows, err := db.conn.Query(`SELECT ` + websubColumns + ` FROM websub_subscriptions ORDER BY feed_url`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get websub subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []*WebSubSubscription{}
+	for rows.Next() {
+		sub := &WebSubSubscription{}
+		if err := scanWebSubSubscription(rows, sub); err != nil {
+			return nil, fmt.Errorf("failed to scan websub subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over websub subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
+
+// GetWebSubSubscriptionByCallback retrieves the subscription for a callback
+// ID. Returns nil if there is none.
+func (db *DB) GetWebSubSubscriptionByCallback(callbackID string) (*WebSubSubscription, error) {
+	if callbackID == "" {
+		return nil, nil
+	}
+
+	sub := &WebSubSubscription{}
+	row := db.conn.QueryRow(`SELECT `+websubColumns+` FROM websub_subscriptions WHERE callback_id = ?`, callbackID)
+	err := scanWebSubSubscription(row, sub)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get websub subscription: %w", err)
+	}
+
+	return sub, nil
+}
+
+// HasActiveWebSubSubscription reports whether a feed has a verified WebSub
+// subscription whose lease hasn't expired.
+func (db *DB) HasActiveWebSubSubscription(feedURL string) (bool, error) {
+	var leaseExpiresAt sql.NullTime
+	err := db.conn.QueryRow(
+		`SELECT lease_expires_at FROM websub_subscriptions WHERE feed_url = ? AND state = ?`,
+		feedURL, WebSubActive,
+	).Scan(&leaseExpiresAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check websub subscription: %w", err)
+	}
+
+	return leaseExpiresAt.Valid && time.Now().Before(leaseExpiresAt.Time), nil
+}
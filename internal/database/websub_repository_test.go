@@ -0,0 +1,67 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestWebSubSubscriptionLifecycle(t *testing.T) {
+	db := setupTestDB(t)
+
+	feedURL := "https://example.com/feed.xml"
+	if err := db.UpsertFeed(&Feed{URL: feedURL, FeedJSON: JSON(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.UpsertWebSubHub(feedURL, "https://hub.example.com/", feedURL); err != nil {
+		t.Fatalf("UpsertWebSubHub() error = %v", err)
+	}
+
+	subs, err := db.GetWebSubSubscriptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 1 || subs[0].State != WebSubDiscovered {
+		t.Fatalf("GetWebSubSubscriptions() = %+v, want one discovered subscription", subs)
+	}
+
+	sub := subs[0]
+	sub.CallbackID = "abc123"
+	sub.Secret = "s3cret"
+	sub.State = WebSubActive
+	sub.LeaseExpiresAt = sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true}
+	if err := db.UpdateWebSubSubscription(sub); err != nil {
+		t.Fatalf("UpdateWebSubSubscription() error = %v", err)
+	}
+
+	active, err := db.HasActiveWebSubSubscription(feedURL)
+	if err != nil || !active {
+		t.Errorf("HasActiveWebSubSubscription() = %v, %v; want true", active, err)
+	}
+
+	// Rediscovering the same hub keeps the active subscription
+	if err := db.UpsertWebSubHub(feedURL, "https://hub.example.com/", feedURL); err != nil {
+		t.Fatal(err)
+	}
+	byCallback, err := db.GetWebSubSubscriptionByCallback("abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byCallback == nil || byCallback.State != WebSubActive || byCallback.Secret != "s3cret" {
+		t.Fatalf("GetWebSubSubscriptionByCallback() = %+v, want the active subscription", byCallback)
+	}
+
+	// A new hub resets it
+	if err := db.UpsertWebSubHub(feedURL, "https://other-hub.example.com/", feedURL); err != nil {
+		t.Fatal(err)
+	}
+	active, err = db.HasActiveWebSubSubscription(feedURL)
+	if err != nil || active {
+		t.Errorf("HasActiveWebSubSubscription() after hub change = %v, %v; want false", active, err)
+	}
+
+	if missing, err := db.GetWebSubSubscriptionByCallback("nope"); err != nil || missing != nil {
+		t.Errorf("GetWebSubSubscriptionByCallback(unknown) = %v, %v; want nil", missing, err)
+	}
+}
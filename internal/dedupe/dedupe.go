@@ -0,0 +1,249 @@
+package dedupe
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/urlcanon"
+	"github.com/sirupsen/logrus"
+)
+
+// Key prefixes, so link and title keys never collide.
+const (
+	linkKeyPrefix  = "link:"
+	titleKeyPrefix = "title:"
+)
+
+// minTitleWords is the fewest significant words a title needs for a
+// fingerprint. Shorter titles ("Weekly links", "Podcast 12") are too generic
+// to tell stories apart.
+const minTitleWords = 4
+
+// linkCanonicalizer drops fragments and the default tracking parameters from
+// links before they are compared.
+var linkCanonicalizer = urlcanon.New(config.LinksConfig{Canonicalize: true})
+
+// stopWords are left out of title fingerprints.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "in": true, "is": true, "it": true, "its": true,
+	"of": true, "on": true, "or": true, "that": true, "the": true, "this": true, "to": true,
+	"was": true, "with": true,
+}
+
+// NormalizeLink reduces a link to the form compared between items: scheme,
+// "www.", default ports, fragments, tracking parameters and trailing slashes
+// are dropped and the remaining query parameters sorted. Returns "" for
+// anything but an http(s) URL.
+func NormalizeLink(link string) string {
+	u, err := url.Parse(linkCanonicalizer.Canonicalize(link))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ""
+	}
+
+	host := strings.TrimPrefix(u.Host, "www.")
+	if port := u.Port(); port == "80" || port == "443" {
+		host = strings.TrimPrefix(u.Hostname(), "www.")
+	}
+
+	normalized := host + strings.TrimSuffix(u.EscapedPath(), "/")
+	if encoded := u.Query().Encode(); encoded != "" {
+		normalized += "?" + encoded
+	}
+	return normalized
+}
+
+// TitleFingerprint returns a hash of the significant words of a title, in
+// sorted order, so titles differing only in case, punctuation, stop words or
+// word order match. Returns "" for titles with too few words to compare.
+func TitleFingerprint(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := make(map[string]bool)
+	significant := []string{}
+	for _, word := range words {
+		if stopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		significant = append(significant, word)
+	}
+	if len(significant) < minTitleWords {
+		return ""
+	}
+
+	sort.Strings(significant)
+	hash := sha256.Sum256([]byte(strings.Join(significant, " ")))
+	return fmt.Sprintf("%x", hash)[:16]
+}
+
+// CanonicalURL returns the og:url recorded in unfurled metadata, or "".
+func CanonicalURL(metadata database.JSON) string {
+	var parsed struct {
+		URL string `json:"og:url"`
+	}
+	if len(metadata) == 0 || json.Unmarshal(metadata, &parsed) != nil {
+		return ""
+	}
+	return parsed.URL
+}
+
+// Keys returns the keys an item shares with its duplicates: its normalized
+// link, the normalized canonical URL of its page when known and, if titles is
+// set, its title fingerprint.
+func Keys(title, link, canonicalURL string, titles bool) []string {
+	keys := []string{}
+	for _, candidate := range []string{link, canonicalURL} {
+		if normalized := NormalizeLink(candidate); normalized != "" {
+			keys = appendUnique(keys, linkKeyPrefix+normalized)
+		}
+	}
+	if titles {
+		if fingerprint := TitleFingerprint(title); fingerprint != "" {
+			keys = append(keys, titleKeyPrefix+fingerprint)
+		}
+	}
+	return keys
+}
+
+// Detector groups items published by more than one feed. A nil Detector
+// groups nothing.
+type Detector struct {
+	db     *database.DB
+	titles bool
+}
+
+// New creates a detector. With titles set, items with matching title
+// fingerprints are grouped as well as items with matching links.
+func New(db *database.DB, titles bool) *Detector {
+	return &Detector{db: db, titles: titles}
+}
+
+// Group records the keys of a newly stored item and joins it to an earlier
+// group of duplicates from other feeds. Returns the ID of the group's first
+// item, or 0 if the item has no duplicate.
+func (d *Detector) Group(item *database.Item) (int64, error) {
+	if d == nil {
+		return 0, nil
+	}
+
+	canonicalURL := ""
+	if item.Link != "" {
+		if meta, err := d.db.GetMetadata(item.Link); err == nil && meta != nil {
+			canonicalURL = CanonicalURL(meta.Metadata)
+		}
+	}
+
+	return d.db.GroupItem(item.FeedURL, item.GUID, Keys(item.Title, item.Link, canonicalURL, d.titles))
+}
+
+// GroupLinks regroups the items linking to any of links, picking up the
+// canonical URLs unfurled from their pages since they were stored. Items
+// already grouped stay in their groups. Returns the number of items grouped
+// under an earlier item.
+func (d *Detector) GroupLinks(links []string) (int, error) {
+	if d == nil || len(links) == 0 {
+		return 0, nil
+	}
+
+	items, err := d.db.GetItemsWithLinks(links)
+	if err != nil {
+		return 0, err
+	}
+
+	grouped := 0
+	for _, item := range items {
+		group, err := d.Group(item)
+		if err != nil {
+			return grouped, err
+		}
+		if group != 0 {
+			grouped++
+		}
+	}
+	return grouped, nil
+}
+
+// Stats summarizes the duplicate groups found by Rebuild.
+type Stats struct {
+	Items      int // Items checked
+	Groups     int // Groups with at least one duplicate
+	Duplicates int // Items grouped under an earlier item
+}
+
+// Rebuild regroups every stored item from scratch, picking up canonical URLs
+// unfurled since the items were fetched. Each item joins the earliest group
+// sharing one of its keys that has no item from its feed yet.
+func (d *Detector) Rebuild() (Stats, error) {
+	var stats Stats
+	if d == nil {
+		return stats, nil
+	}
+
+	candidates, err := d.db.GetDuplicateCandidates()
+	if err != nil {
+		return stats, err
+	}
+
+	keys := make(map[int64][]string, len(candidates))
+	duplicateOf := make(map[int64]int64)
+	groupByKey := make(map[string]int64)
+	groupFeeds := make(map[int64]map[string]bool)
+
+	for i := range candidates {
+		candidate := &candidates[i]
+		itemKeys := Keys(candidate.Title, candidate.Link, CanonicalURL(candidate.Metadata), d.titles)
+		keys[candidate.ID] = itemKeys
+
+		group := candidate.ID
+		for _, key := range itemKeys {
+			if existing, ok := groupByKey[key]; ok && !groupFeeds[existing][candidate.FeedURL] {
+				group = existing
+				break
+			}
+		}
+
+		if group != candidate.ID {
+			duplicateOf[candidate.ID] = group
+			if len(groupFeeds[group]) == 1 {
+				stats.Groups++
+			}
+			stats.Duplicates++
+		} else {
+			groupFeeds[group] = make(map[string]bool)
+		}
+		groupFeeds[group][candidate.FeedURL] = true
+
+		for _, key := range itemKeys {
+			if _, ok := groupByKey[key]; !ok {
+				groupByKey[key] = group
+			}
+		}
+	}
+	stats.Items = len(candidates)
+
+	if err := d.db.ReplaceDuplicateGroups(keys, duplicateOf); err != nil {
+		return stats, err
+	}
+
+	logrus.Debugf("Grouped %d duplicates of %d items into %d groups", stats.Duplicates, stats.Items, stats.Groups)
+	return stats, nil
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
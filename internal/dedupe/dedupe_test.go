@@ -0,0 +1,243 @@
+package dedupe
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/database/databasetest"
+)
+
+func TestNormalizeLink(t *testing.T) {
+	tests := []struct {
+		link     string
+		expected string
+	}{
+		{"https://www.Example.com/story/", "example.com/story"},
+		{"http://example.com/story#comments", "example.com/story"},
+		{"https://example.com:443/story?utm_source=rss&utm_medium=feed", "example.com/story"},
+		{"https://example.com/story?id=2&fbclid=abc&a=1", "example.com/story?a=1&id=2"},
+		{"https://example.com:8080/story", "example.com:8080/story"},
+		{"https://example.com/", "example.com"},
+		{"mailto:someone@example.com", ""},
+		{"/relative/path", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			if got := NormalizeLink(tt.link); got != tt.expected {
+				t.Errorf("NormalizeLink(%q) = %q, want %q", tt.link, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTitleFingerprint(t *testing.T) {
+	base := TitleFingerprint("Rust 2.0 released with async traits")
+	if base == "" {
+		t.Fatal("TitleFingerprint() should fingerprint a title with enough words")
+	}
+
+	for _, title := range []string{
+		"RUST 2.0 RELEASED WITH ASYNC TRAITS",
+		"Rust 2.0 released, with async traits!",
+		"Async traits: Rust 2.0 released",
+	} {
+		if got := TitleFingerprint(title); got != base {
+			t.Errorf("TitleFingerprint(%q) = %q, want %q", title, got, base)
+		}
+	}
+
+	if TitleFingerprint("Rust 2.0 released without async traits") == base {
+		t.Error("TitleFingerprint() should differ for different words")
+	}
+	if got := TitleFingerprint("The weekly links"); got != "" {
+		t.Errorf("TitleFingerprint() of a short title = %q, want empty", got)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	keys := Keys("Rust 2.0 released with async traits", "https://example.com/a?utm_source=x",
+		"https://example.com/a", true)
+	if len(keys) != 2 || keys[0] != "link:example.com/a" || keys[1][:len(titleKeyPrefix)] != titleKeyPrefix {
+		t.Errorf("Keys() = %v, want one link key and a title key", keys)
+	}
+
+	keys = Keys("Rust 2.0 released with async traits", "https://example.com/a", "https://example.org/canonical", false)
+	if len(keys) != 2 || keys[1] != "link:example.org/canonical" {
+		t.Errorf("Keys() without titles = %v, want the link and canonical URL", keys)
+	}
+
+	if got := CanonicalURL(database.JSON(`{"og:url":"https://example.org/canonical"}`)); got != "https://example.org/canonical" {
+		t.Errorf("CanonicalURL() = %q", got)
+	}
+	if got := CanonicalURL(database.JSON("null")); got != "" {
+		t.Errorf("CanonicalURL(null) = %q, want empty", got)
+	}
+}
+
+func addItem(t *testing.T, db *database.DB, feedURL, guid, title, link string, seen time.Time) *database.Item {
+	t.Helper()
+
+	if err := db.UpsertFeed(&database.Feed{URL: feedURL}); err != nil {
+		t.Fatal(err)
+	}
+	item := &database.Item{
+		FeedURL:   feedURL,
+		GUID:      guid,
+		Title:     title,
+		Link:      link,
+		FirstSeen: sql.NullTime{Time: seen, Valid: true},
+	}
+	if err := db.UpsertItem(item); err != nil {
+		t.Fatal(err)
+	}
+	return item
+}
+
+func groupsByGUID(t *testing.T, db *database.DB) map[string]string {
+	t.Helper()
+
+	items, err := db.ListItems(&database.ItemFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	guids := make(map[int64]string)
+	for _, item := range items {
+		guids[item.ID] = item.GUID
+	}
+	groups := make(map[string]string)
+	for _, item := range items {
+		if item.DuplicateOf.Valid {
+			groups[item.GUID] = guids[item.DuplicateOf.Int64]
+		}
+	}
+	return groups
+}
+
+func TestDetectorGroup(t *testing.T) {
+	db := databasetest.New(t)
+	detector := New(db, true)
+	seen := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	original := addItem(t, db, "https://a.example/feed", "a1", "Original story", "https://news.example/story", seen)
+	if group, err := detector.Group(original); err != nil || group != 0 {
+		t.Fatalf("Group(original) = %d, %v; want 0", group, err)
+	}
+
+	// Same link with tracking parameters, from another feed
+	copied := addItem(t, db, "https://b.example/feed", "b1", "Different title",
+		"https://www.news.example/story/?utm_source=b", seen.Add(time.Hour))
+	group, err := detector.Group(copied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groups := groupsByGUID(t, db); group == 0 || groups["b1"] != "a1" {
+		t.Errorf("Group(copy) = %d, groups %v; want b1 grouped under a1", group, groups)
+	}
+
+	// The same feed never joins its own group
+	again := addItem(t, db, "https://a.example/feed", "a2", "Original story again", "https://news.example/story",
+		seen.Add(2*time.Hour))
+	if group, err := detector.Group(again); err != nil || group != 0 {
+		t.Errorf("Group(same feed) = %d, %v; want 0", group, err)
+	}
+
+	// Nil detectors group nothing
+	var none *Detector
+	if group, err := none.Group(original); err != nil || group != 0 {
+		t.Errorf("nil Group() = %d, %v; want 0", group, err)
+	}
+}
+
+func TestDetectorRebuild(t *testing.T) {
+	db := databasetest.New(t)
+	seen := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	addItem(t, db, "https://a.example/feed", "a1", "Rust 2.0 released with async traits",
+		"https://blog.example/rust-2", seen)
+	addItem(t, db, "https://b.example/feed", "b1", "Async traits: Rust 2.0 released",
+		"https://aggregator.example/item/1", seen.Add(time.Hour))
+	addItem(t, db, "https://c.example/feed", "c1", "Something else entirely, honestly",
+		"https://short.example/xyz", seen.Add(2*time.Hour))
+	addItem(t, db, "https://c.example/feed", "c2", "Unrelated post", "https://c.example/post", seen)
+
+	// c1's page unfurled to the same canonical URL as a1
+	if err := db.UpsertMetadata(&database.URLMetadata{
+		URL:      "https://short.example/xyz",
+		Metadata: database.JSON(`{"og:url":"https://blog.example/rust-2/"}`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := New(db, false).Rebuild()
+	if err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+	if groups := groupsByGUID(t, db); len(groups) != 1 || groups["c1"] != "a1" {
+		t.Errorf("Rebuild() without titles groups = %v, want only c1 under a1", groups)
+	}
+	if stats.Items != 4 || stats.Groups != 1 || stats.Duplicates != 1 {
+		t.Errorf("Rebuild() stats = %+v, want 4 items, 1 group, 1 duplicate", stats)
+	}
+
+	stats, err = New(db, true).Rebuild()
+	if err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+	groups := groupsByGUID(t, db)
+	if len(groups) != 2 || groups["b1"] != "a1" || groups["c1"] != "a1" {
+		t.Errorf("Rebuild() with titles groups = %v, want b1 and c1 under a1", groups)
+	}
+	if stats.Groups != 1 || stats.Duplicates != 2 {
+		t.Errorf("Rebuild() stats = %+v, want 1 group, 2 duplicates", stats)
+	}
+
+	// Keys are recorded for items fetched later
+	late := addItem(t, db, "https://d.example/feed", "d1", "Rust 2.0 is out", "https://blog.example/rust-2#top",
+		seen.Add(3*time.Hour))
+	if group, err := New(db, true).Group(late); err != nil || group == 0 {
+		t.Errorf("Group() after Rebuild() = %d, %v; want a1's group", group, err)
+	}
+}
+
+func TestDetectorGroupLinks(t *testing.T) {
+	db := databasetest.New(t)
+	detector := New(db, false)
+	seen := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	original := addItem(t, db, "https://a.example/feed", "a1", "Original", "https://blog.example/post", seen)
+	short := addItem(t, db, "https://b.example/feed", "b1", "Shared", "https://short.example/abc", seen.Add(time.Hour))
+	other := addItem(t, db, "https://c.example/feed", "c1", "Other", "https://short.example/def", seen.Add(time.Hour))
+	for _, item := range []*database.Item{original, short, other} {
+		if _, err := detector.Group(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Both short links unfurl to the original post, but only one was unfurled
+	// in this run
+	for _, link := range []string{"https://short.example/abc", "https://short.example/def"} {
+		if err := db.UpsertMetadata(&database.URLMetadata{
+			URL:      link,
+			Metadata: database.JSON(`{"og:url":"https://blog.example/post"}`),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	grouped, err := detector.GroupLinks([]string{"https://short.example/abc"})
+	if err != nil {
+		t.Fatalf("GroupLinks() error = %v", err)
+	}
+	if groups := groupsByGUID(t, db); grouped != 1 || len(groups) != 1 || groups["b1"] != "a1" {
+		t.Errorf("GroupLinks() = %d, groups %v; want only b1 under a1", grouped, groups)
+	}
+
+	var none *Detector
+	if grouped, err := none.GroupLinks([]string{"https://short.example/def"}); err != nil || grouped != 0 {
+		t.Errorf("nil GroupLinks() = %d, %v; want 0", grouped, err)
+	}
+}
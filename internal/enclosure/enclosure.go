@@ -0,0 +1,387 @@
+package enclosure
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/httpclient"
+	"github.com/sirupsen/logrus"
+)
+
+// partSuffix marks a download in progress, kept between runs to resume it.
+const partSuffix = ".part"
+
+// maxSlugLength caps the length of feed and item names in paths.
+const maxSlugLength = 60
+
+var extensionPattern = regexp.MustCompile(`^\.[a-z0-9]{1,5}$`)
+
+// extensions maps common enclosure types to file extensions, ahead of the
+// system's list, which offers the least common first for some.
+var extensions = map[string]string{
+	"audio/mpeg":      ".mp3",
+	"audio/mp4":       ".m4a",
+	"audio/x-m4a":     ".m4a",
+	"audio/aac":       ".aac",
+	"audio/ogg":       ".ogg",
+	"audio/opus":      ".opus",
+	"video/mp4":       ".mp4",
+	"video/webm":      ".webm",
+	"video/quicktime": ".mov",
+}
+
+// Downloader saves enclosures into a directory, one subdirectory per feed,
+// and deletes the downloads each feed no longer keeps.
+type Downloader struct {
+	db      *database.DB
+	client  *httpclient.Client
+	dir     string
+	maxSize int64
+	keep    int
+	types   []string
+}
+
+// Result summarizes a download run.
+type Result struct {
+	Downloaded int   `json:"downloaded"`
+	Failed     int   `json:"failed"`
+	Deleted    int   `json:"deleted"`
+	Bytes      int64 `json:"bytes"` // Bytes downloaded, including resumed parts
+}
+
+// New creates a downloader from the enclosures config.
+func New(cfg config.EnclosuresConfig, db *database.DB) *Downloader {
+	dir := cfg.Dir
+	if dir == "" {
+		dir = config.DefaultEnclosuresDir
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = config.DefaultEnclosuresTimeout
+	}
+
+	return &Downloader{
+		db:      db,
+		client:  httpclient.NewClient(&httpclient.Config{Timeout: timeout}),
+		dir:     dir,
+		maxSize: cfg.MaxSize,
+		keep:    cfg.Keep,
+		types:   cfg.Types,
+	}
+}
+
+// Run downloads the enclosures of the given feeds, or of every feed when
+// none are given. Each feed keeps the enclosures of its newest items, up to
+// the retention limit, and older downloads are deleted. A run over every
+// feed also deletes files no enclosure refers to any more, such as those of
+// purged items.
+func (d *Downloader) Run(feedURLs []string) (*Result, error) {
+	enclosures, err := d.db.GetItemEnclosures(feedURLs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	wanted := make(map[string]bool)
+	kept := make(map[string]int)
+	for _, enclosure := range enclosures {
+		if !d.wants(enclosure) {
+			continue
+		}
+
+		if d.keep > 0 && kept[enclosure.FeedURL] >= d.keep {
+			if enclosure.LocalPath.Valid {
+				if err := d.remove(enclosure); err != nil {
+					return result, err
+				}
+				result.Deleted++
+			}
+			continue
+		}
+		kept[enclosure.FeedURL]++
+
+		localPath := Path(enclosure)
+		if enclosure.LocalPath.Valid {
+			localPath = enclosure.LocalPath.String
+			if d.exists(localPath) {
+				wanted[localPath] = true
+				continue
+			}
+		}
+		wanted[localPath] = true
+
+		logrus.Infof("Downloading %s", enclosure.URL)
+		size, err := d.download(enclosure, localPath)
+		result.Bytes += size
+		if err != nil {
+			logrus.Warnf("Failed to download %s: %v", enclosure.URL, err)
+			result.Failed++
+			if err := d.db.RecordEnclosureError(enclosure.ItemID, enclosure.URL, err.Error()); err != nil {
+				return result, err
+			}
+			continue
+		}
+		if err := d.db.RecordEnclosureDownload(enclosure.ItemID, enclosure.URL, localPath); err != nil {
+			return result, err
+		}
+		result.Downloaded++
+	}
+
+	if len(feedURLs) == 0 {
+		deleted, err := d.removeOrphans(wanted)
+		result.Deleted += deleted
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// wants reports whether an enclosure is of a type that is downloaded.
+func (d *Downloader) wants(enclosure *database.ItemEnclosure) bool {
+	if len(d.types) == 0 {
+		return true
+	}
+	for _, prefix := range d.types {
+		if strings.HasPrefix(strings.ToLower(enclosure.Type), strings.ToLower(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Downloader) exists(localPath string) bool {
+	_, err := os.Stat(filepath.Join(d.dir, filepath.FromSlash(localPath)))
+	return err == nil
+}
+
+// download fetches an enclosure into a file at localPath, resuming a partial
+// download left by an earlier run if the server supports it. Returns the
+// number of bytes fetched.
+func (d *Downloader) download(enclosure *database.ItemEnclosure, localPath string) (int64, error) {
+	if d.maxSize > 0 && enclosure.Length > d.maxSize {
+		return 0, fmt.Errorf("declared size of %d bytes is over the %d byte limit", enclosure.Length, d.maxSize)
+	}
+
+	target := filepath.Join(d.dir, filepath.FromSlash(localPath))
+	part := target + partSuffix
+	if err := os.MkdirAll(filepath.Dir(target), config.DefaultDirPerm); err != nil {
+		return 0, fmt.Errorf("failed to create enclosure directory: %w", err)
+	}
+
+	var offset int64
+	headers := map[string]string{}
+	if info, err := os.Stat(part); err == nil && info.Size() > 0 {
+		offset = info.Size()
+		headers["Range"] = fmt.Sprintf("bytes=%d-", offset)
+	}
+
+	resp, err := d.client.GetWithHeaders(enclosure.URL, headers)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	resume := false
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0 && rangeStart(resp) == offset:
+		logrus.Debugf("Resuming %s at %d bytes", enclosure.URL, offset)
+		resume = true
+	case resp.StatusCode == http.StatusOK:
+		offset = 0
+	case resp.StatusCode == http.StatusPartialContent || resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The partial file doesn't match what the server has; start over next run
+		os.Remove(part)
+		return 0, fmt.Errorf("HTTP %d resuming at %d bytes", resp.StatusCode, offset)
+	default:
+		return 0, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	if d.maxSize > 0 && resp.ContentLength > 0 && offset+resp.ContentLength > d.maxSize {
+		return 0, fmt.Errorf("file of %d bytes is over the %d byte limit", offset+resp.ContentLength, d.maxSize)
+	}
+
+	var file *os.File
+	if resume {
+		file, err = os.OpenFile(part, os.O_APPEND|os.O_WRONLY, 0)
+	} else {
+		file, err = os.Create(part)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to create enclosure file: %w", err)
+	}
+
+	body := io.Reader(resp.Body)
+	if d.maxSize > 0 {
+		// One byte over the limit, to tell files at the limit from larger ones
+		body = io.LimitReader(resp.Body, d.maxSize-offset+1)
+	}
+	written, err := io.Copy(file, body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// Keep what was fetched, to resume from it next run
+		return written, fmt.Errorf("failed to download: %w", err)
+	}
+
+	if d.maxSize > 0 && offset+written > d.maxSize {
+		os.Remove(part)
+		return written, fmt.Errorf("file is over the %d byte limit", d.maxSize)
+	}
+
+	if err := os.Rename(part, target); err != nil {
+		return written, fmt.Errorf("failed to store enclosure file: %w", err)
+	}
+	return written, nil
+}
+
+// rangeStart returns the first byte of a partial response, or -1 if its
+// Content-Range can't be read.
+func rangeStart(resp *httpclient.Response) int64 {
+	// Content-Range: bytes 100-999/1000
+	value := strings.TrimPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	start, _, found := strings.Cut(value, "-")
+	if !found {
+		return -1
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(start), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// remove deletes the downloaded file of an enclosure and forgets it.
+func (d *Downloader) remove(enclosure *database.ItemEnclosure) error {
+	file := filepath.Join(d.dir, filepath.FromSlash(enclosure.LocalPath.String))
+	if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete enclosure file: %w", err)
+	}
+	logrus.Debugf("Deleted %s", file)
+	return d.db.ClearEnclosureDownload(enclosure.ItemID, enclosure.URL)
+}
+
+// removeOrphans deletes files no enclosure refers to, other than partial
+// downloads of wanted enclosures. Returns the number of files deleted.
+func (d *Downloader) removeOrphans(wanted map[string]bool) (int, error) {
+	paths, err := d.db.GetEnclosurePaths()
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	err = filepath.WalkDir(d.dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(d.dir, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if paths[rel] || wanted[strings.TrimSuffix(rel, partSuffix)] {
+			return nil
+		}
+
+		if err := os.Remove(file); err != nil {
+			return fmt.Errorf("failed to delete enclosure file: %w", err)
+		}
+		logrus.Debugf("Deleted unreferenced %s", file)
+		deleted++
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return deleted, fmt.Errorf("failed to clean up enclosures: %w", err)
+	}
+	return deleted, nil
+}
+
+// Path returns where an enclosure is saved, relative to the enclosures
+// directory and with forward slashes: a directory named for the feed, and a
+// file named for the item's date and title. A hash of the URL keeps items
+// with the same title, and items with several enclosures, apart.
+func Path(enclosure *database.ItemEnclosure) string {
+	feed := slug(enclosure.FeedTitle)
+	if feed == "" {
+		if u, err := url.Parse(enclosure.FeedURL); err == nil {
+			feed = slug(u.Host)
+		}
+	}
+	if feed == "" {
+		feed = "feed"
+	}
+
+	name := enclosure.PublishedDate.UTC().Format("2006-01-02")
+	if title := slug(enclosure.ItemTitle); title != "" {
+		name += "-" + title
+	}
+
+	sum := sha256.Sum256([]byte(enclosure.URL))
+	return feed + "/" + name + "-" + hex.EncodeToString(sum[:3]) + extension(enclosure)
+}
+
+// extension returns the file extension of an enclosure's URL, or one for its
+// type when the URL has none.
+func extension(enclosure *database.ItemEnclosure) string {
+	if u, err := url.Parse(enclosure.URL); err == nil {
+		if ext := strings.ToLower(path.Ext(u.Path)); extensionPattern.MatchString(ext) {
+			return ext
+		}
+	}
+	mediaType, _, _ := mime.ParseMediaType(enclosure.Type)
+	if ext, ok := extensions[mediaType]; ok {
+		return ext
+	}
+	if exts, err := mime.ExtensionsByType(enclosure.Type); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// slug turns a name into a file name: lowercase letters and digits, with
+// runs of anything else collapsed to a single dash.
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	length := 0
+	for _, r := range strings.ToLower(name) {
+		if length >= maxSlugLength {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+			length++
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+			length++
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
@@ -0,0 +1,242 @@
+package enclosure
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/database/databasetest"
+)
+
+const testFeedURL = "https://example.com/podcast.xml"
+
+// episodeData is the content served for every episode.
+var episodeData = bytes.Repeat([]byte("0123456789"), 100)
+
+// episodeServer serves episodes with Range support, recording the Range
+// header of each request.
+type episodeServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	ranges []string
+}
+
+func newEpisodeServer(t *testing.T) *episodeServer {
+	t.Helper()
+
+	s := &episodeServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.ranges = append(s.ranges, r.Header.Get("Range"))
+		s.mu.Unlock()
+
+		if strings.HasPrefix(r.URL.Path, "/missing") {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, "episode.mp3", time.Time{}, bytes.NewReader(episodeData))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *episodeServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ranges...)
+}
+
+func setupTestDatabase(t *testing.T) *database.DB {
+	t.Helper()
+
+	db := databasetest.New(t)
+	if err := db.UpsertFeed(&database.Feed{URL: testFeedURL, Title: "The Podcast"}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// addEpisode stores an item published days after 2024-03-01 with the given
+// enclosures.
+func addEpisode(t *testing.T, db *database.DB, guid string, days int, enclosures ...*database.Enclosure) {
+	t.Helper()
+
+	item := &database.Item{
+		FeedURL:       testFeedURL,
+		GUID:          guid,
+		Title:         "Episode " + guid,
+		PublishedDate: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC).AddDate(0, 0, days),
+	}
+	if err := db.UpsertItem(item); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetItemEnclosures(testFeedURL, guid, enclosures); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPath(t *testing.T) {
+	enclosure := &database.ItemEnclosure{
+		Enclosure:     database.Enclosure{URL: "https://cdn.example.com/audio/ep1.MP3?token=abc", Type: "audio/mpeg"},
+		FeedURL:       testFeedURL,
+		FeedTitle:     "The Podcast!",
+		ItemTitle:     "Episode 1: Hello, World",
+		PublishedDate: time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC),
+	}
+
+	got := Path(enclosure)
+	if !strings.HasPrefix(got, "the-podcast/2024-03-01-episode-1-hello-world-") || !strings.HasSuffix(got, ".mp3") {
+		t.Errorf("Path() = %q, want the feed directory and a dated, titled .mp3", got)
+	}
+
+	enclosure.FeedTitle = ""
+	enclosure.URL = "https://cdn.example.com/stream"
+	enclosure.Type = "video/mp4"
+	if got := Path(enclosure); !strings.HasPrefix(got, "example-com/") || !strings.HasSuffix(got, ".mp4") {
+		t.Errorf("Path() = %q, want the feed host and an extension for the type", got)
+	}
+}
+
+func TestRunKeepsNewest(t *testing.T) {
+	db := setupTestDatabase(t)
+	server := newEpisodeServer(t)
+	dir := t.TempDir()
+
+	for i, guid := range []string{"1", "2", "3"} {
+		addEpisode(t, db, guid, i, &database.Enclosure{URL: server.URL + "/" + guid + ".mp3", Type: "audio/mpeg"})
+	}
+	addEpisode(t, db, "cover", 5, &database.Enclosure{URL: server.URL + "/cover.jpg", Type: "image/jpeg"})
+
+	cfg := config.EnclosuresConfig{Dir: dir, Keep: 2, Types: config.DefaultEnclosureTypes}
+	result, err := New(cfg, db).Run(nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Downloaded != 2 || result.Failed != 0 || result.Bytes != int64(2*len(episodeData)) {
+		t.Errorf("Run() = %+v, want the two newest episodes downloaded", result)
+	}
+
+	enclosures, err := db.GetItemEnclosures(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloaded := map[string]string{}
+	for _, enclosure := range enclosures {
+		if enclosure.LocalPath.Valid {
+			downloaded[enclosure.ItemTitle] = enclosure.LocalPath.String
+		}
+	}
+	if len(downloaded) != 2 || downloaded["Episode 3"] == "" || downloaded["Episode 2"] == "" {
+		t.Fatalf("downloaded = %v, want episodes 2 and 3", downloaded)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(downloaded["Episode 3"])))
+	if err != nil || !bytes.Equal(data, episodeData) {
+		t.Errorf("downloaded file = %d bytes, %v; want the episode", len(data), err)
+	}
+
+	// Keeping fewer deletes the older download and files nothing refers to
+	stray := filepath.Join(dir, "the-podcast", "stray.mp3")
+	if err := os.WriteFile(stray, []byte("stray"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	requests := len(server.requests())
+	cfg.Keep = 1
+	result, err = New(cfg, db).Run(nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Downloaded != 0 || result.Deleted != 2 || len(server.requests()) != requests {
+		t.Errorf("Run() = %+v, want two files deleted and nothing downloaded again", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(downloaded["Episode 2"]))); !os.IsNotExist(err) {
+		t.Errorf("older download still exists: %v", err)
+	}
+	if _, err := os.Stat(stray); !os.IsNotExist(err) {
+		t.Errorf("unreferenced file still exists: %v", err)
+	}
+}
+
+func TestRunResumes(t *testing.T) {
+	db := setupTestDatabase(t)
+	server := newEpisodeServer(t)
+	dir := t.TempDir()
+
+	addEpisode(t, db, "1", 0, &database.Enclosure{URL: server.URL + "/1.mp3", Type: "audio/mpeg"})
+	enclosures, err := db.GetItemEnclosures(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, filepath.FromSlash(Path(enclosures[0])))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target+partSuffix, episodeData[:400], 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := New(config.EnclosuresConfig{Dir: dir}, db).Run(nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Downloaded != 1 || result.Bytes != int64(len(episodeData)-400) {
+		t.Errorf("Run() = %+v, want the rest of the episode downloaded", result)
+	}
+	if got := server.requests(); len(got) != 1 || got[0] != "bytes=400-" {
+		t.Errorf("requests = %q, want one resuming at byte 400", got)
+	}
+	data, err := os.ReadFile(target)
+	if err != nil || !bytes.Equal(data, episodeData) {
+		t.Errorf("resumed file = %d bytes, %v; want the whole episode", len(data), err)
+	}
+}
+
+func TestRunFailures(t *testing.T) {
+	db := setupTestDatabase(t)
+	server := newEpisodeServer(t)
+	dir := t.TempDir()
+
+	addEpisode(t, db, "large", 0, &database.Enclosure{URL: server.URL + "/large.mp3", Type: "audio/mpeg"})
+	addEpisode(t, db, "declared", 1,
+		&database.Enclosure{URL: server.URL + "/declared.mp3", Type: "audio/mpeg", Length: 1 << 30})
+	addEpisode(t, db, "missing", 2, &database.Enclosure{URL: server.URL + "/missing.mp3", Type: "audio/mpeg"})
+
+	result, err := New(config.EnclosuresConfig{Dir: dir, MaxSize: 500}, db).Run(nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Downloaded != 0 || result.Failed != 3 {
+		t.Errorf("Run() = %+v, want every download to fail", result)
+	}
+	// The declared size is checked before any request
+	if got := server.requests(); len(got) != 2 {
+		t.Errorf("requests = %q, want 2", got)
+	}
+
+	enclosures, err := db.GetItemEnclosures(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, enclosure := range enclosures {
+		if enclosure.LocalPath.Valid || !enclosure.DownloadError.Valid {
+			t.Errorf("enclosure %s = %+v, want a download error", enclosure.ItemTitle, enclosure.Enclosure)
+		}
+	}
+
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			t.Errorf("left behind %s", path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
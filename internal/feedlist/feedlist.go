@@ -1,13 +1,17 @@
 package feedlist
 
 import (
+	"encoding/xml"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/lmorchard/feedspool-go/internal/database"
 	"github.com/lmorchard/feedspool-go/internal/opml"
+	"github.com/lmorchard/feedspool-go/internal/structlist"
 	"github.com/lmorchard/feedspool-go/internal/textlist"
 )
 
@@ -17,6 +21,8 @@ type Format string
 const (
 	FormatOPML Format = "opml"
 	FormatText Format = "text"
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
 )
 
 // String returns the string representation of the format.
@@ -24,11 +30,26 @@ func (f Format) String() string {
 	return string(f)
 }
 
+// Entry is a feed in a list along with its tags and per-feed settings.
+type Entry struct {
+	URL      string
+	Tags     []string
+	Settings database.FeedSettings
+}
+
 // FeedList interface provides unified access to different feed list formats.
+// Tags are folder names; formats without folders ignore them and return nil
+// from GetTags. Likewise, formats without per-feed settings ignore them and
+// return nil from GetSettings.
 type FeedList interface {
 	GetURLs() []string
-	AddURL(url string) error
+	GetTags() map[string][]string
+	GetSettings() map[string]database.FeedSettings
+	GetEntries() []Entry
+	AddURL(url string, tags ...string) error
+	AddEntry(entry Entry) error
 	RemoveURL(url string) error
+	ReplaceURL(oldURL, newURL string) error
 	Save(filename string) error
 }
 
@@ -56,6 +77,8 @@ func LoadFeedList(format Format, filename string) (FeedList, error) {
 		return loadOPMLFeedList(file)
 	case FormatText:
 		return loadTextFeedList(file)
+	case FormatYAML, FormatJSON:
+		return loadStructuredFeedList(format, file)
 	default:
 		return nil, fmt.Errorf("unsupported feed list format: %s", format)
 	}
@@ -76,6 +99,11 @@ func NewFeedList(format Format) FeedList {
 		return &TextFeedList{
 			urls: []string{},
 		}
+	case FormatYAML, FormatJSON:
+		return &StructuredFeedList{
+			format: format,
+			list:   &structlist.List{Feeds: []structlist.Feed{}},
+		}
 	default:
 		// Default to text format if invalid format provided
 		return &TextFeedList{
@@ -92,6 +120,10 @@ func DetectFormat(filename string) Format {
 		return FormatOPML
 	case ".txt", ".text":
 		return FormatText
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".json":
+		return FormatJSON
 	default:
 		// Default to text format
 		return FormatText
@@ -104,6 +136,9 @@ func loadOPMLFeedList(reader io.Reader) (FeedList, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse OPML: %w", err)
 	}
+	if err := validateOutlineSettings(opmlData.Body.Outlines); err != nil {
+		return nil, err
+	}
 
 	urls := opml.ExtractFeedURLs(opmlData)
 	return &OPMLFeedList{
@@ -131,54 +166,229 @@ func (ofl *OPMLFeedList) GetURLs() []string {
 	return ofl.urls
 }
 
-// AddURL adds a URL to the OPML feed list.
-func (ofl *OPMLFeedList) AddURL(url string) error {
-	// Check if URL already exists
-	for _, existingURL := range ofl.urls {
-		if existingURL == url {
-			return nil // URL already exists, no error
+// GetTags returns the folder path and categories of each feed in the OPML
+// feed list.
+func (ofl *OPMLFeedList) GetTags() map[string][]string {
+	return opml.ExtractFeedTags(ofl.opml)
+}
+
+// GetSettings returns the settings of each feed in the OPML feed list, kept
+// in feedspool attributes.
+func (ofl *OPMLFeedList) GetSettings() map[string]database.FeedSettings {
+	settings := make(map[string]database.FeedSettings)
+	for _, entry := range ofl.GetEntries() {
+		settings[entry.URL] = entry.Settings
+	}
+	return settings
+}
+
+// GetEntries returns each feed in the OPML feed list once, in order. An
+// outline's text is only a label in OPML, so only a feedspoolTitle attribute
+// sets a title override.
+func (ofl *OPMLFeedList) GetEntries() []Entry {
+	tags := ofl.GetTags()
+	entries := []Entry{}
+	seen := make(map[string]bool)
+	var walk func(outlines []opml.Outline)
+	walk = func(outlines []opml.Outline) {
+		for _, outline := range outlines {
+			if outline.XMLURL != "" && !seen[outline.XMLURL] {
+				seen[outline.XMLURL] = true
+				settings, _ := outlineSettings(outline) // Checked on load
+				entries = append(entries, Entry{URL: outline.XMLURL, Tags: tags[outline.XMLURL], Settings: settings})
+			}
+			walk(outline.Outlines)
 		}
 	}
+	walk(ofl.opml.Body.Outlines)
+	return entries
+}
 
-	// Add to URLs slice
-	ofl.urls = append(ofl.urls, url)
+// AddURL adds a URL to the OPML feed list. With tags, the feed goes in the
+// folder of each tag, created if needed, with a "/" in a tag nesting folders.
+func (ofl *OPMLFeedList) AddURL(url string, tags ...string) error {
+	return ofl.AddEntry(Entry{URL: url, Tags: tags})
+}
+
+// AddEntry adds a feed to the OPML feed list like AddURL, using its title
+// override as the outline's text and keeping its settings in feedspool
+// attributes.
+func (ofl *OPMLFeedList) AddEntry(entry Entry) error {
+	// Check if URL already exists
+	if containsURL(ofl.urls, entry.URL) {
+		return nil // URL already exists, no error
+	}
 
-	// Add to OPML structure
+	text := entry.Settings.TitleOverride
+	if text == "" {
+		text = entry.URL
+	}
 	outline := opml.Outline{
-		Text:    url,
-		Title:   url,
+		Text:    text,
+		Title:   text,
 		Type:    "rss",
-		XMLURL:  url,
+		XMLURL:  entry.URL,
 		HTMLURL: "",
 	}
-	ofl.opml.Body.Outlines = append(ofl.opml.Body.Outlines, outline)
+	setOutlineSettings(&outline, entry.Settings)
 
+	if len(entry.Tags) == 0 {
+		ofl.opml.Body.Outlines = append(ofl.opml.Body.Outlines, outline)
+	}
+	for _, tag := range entry.Tags {
+		folder := ofl.findOrCreateFolder(tag)
+		folder.Outlines = append(folder.Outlines, outline)
+	}
+
+	ofl.urls = opml.ExtractFeedURLs(ofl.opml)
 	return nil
 }
 
-// RemoveURL removes a URL from the OPML feed list.
-func (ofl *OPMLFeedList) RemoveURL(url string) error {
-	// Remove from URLs slice
-	newURLs := make([]string, 0)
-	for _, existingURL := range ofl.urls {
-		if existingURL != url {
-			newURLs = append(newURLs, existingURL)
+// outlineSettings reads the feedspool settings attributes of an outline.
+func outlineSettings(outline opml.Outline) (database.FeedSettings, error) {
+	settings := database.FeedSettings{TitleOverride: strings.TrimSpace(outline.TitleOverride)}
+	var err error
+	if outline.Interval != "" {
+		if settings.FetchInterval, err = parseSettingDuration(outline.Interval); err != nil {
+			return settings, fmt.Errorf("invalid feedspoolInterval for %s: %w", outline.XMLURL, err)
 		}
 	}
-	ofl.urls = newURLs
+	if outline.MaxItems != "" {
+		if settings.MaxItems, err = strconv.Atoi(outline.MaxItems); err != nil || settings.MaxItems < 0 {
+			return settings, fmt.Errorf("invalid feedspoolMaxItems for %s: %q", outline.XMLURL, outline.MaxItems)
+		}
+	}
+	if outline.Hidden != "" {
+		if settings.Hidden, err = strconv.ParseBool(outline.Hidden); err != nil {
+			return settings, fmt.Errorf("invalid feedspoolHidden for %s: %q", outline.XMLURL, outline.Hidden)
+		}
+	}
+	if outline.Retention != "" {
+		if settings.Retention, err = parseSettingDuration(outline.Retention); err != nil {
+			return settings, fmt.Errorf("invalid feedspoolRetention for %s: %w", outline.XMLURL, err)
+		}
+	}
+	return settings, nil
+}
 
-	// Remove from OPML structure
-	newOutlines := make([]opml.Outline, 0)
-	for _, outline := range ofl.opml.Body.Outlines {
-		if outline.XMLURL != url {
-			newOutlines = append(newOutlines, outline)
+// setOutlineSettings writes settings into the feedspool attributes of an
+// outline, leaving unset ones empty.
+func setOutlineSettings(outline *opml.Outline, settings database.FeedSettings) {
+	outline.TitleOverride = settings.TitleOverride
+	outline.Interval = formatSettingDuration(settings.FetchInterval)
+	outline.Retention = formatSettingDuration(settings.Retention)
+	outline.MaxItems = ""
+	if settings.MaxItems > 0 {
+		outline.MaxItems = strconv.Itoa(settings.MaxItems)
+	}
+	outline.Hidden = ""
+	if settings.Hidden {
+		outline.Hidden = "true"
+	}
+}
+
+// validateOutlineSettings checks the feedspool attributes of every feed
+// outline, including in nested folders.
+func validateOutlineSettings(outlines []opml.Outline) error {
+	for _, outline := range outlines {
+		if outline.XMLURL != "" {
+			if _, err := outlineSettings(outline); err != nil {
+				return err
+			}
+		}
+		if err := validateOutlineSettings(outline.Outlines); err != nil {
+			return err
 		}
 	}
-	ofl.opml.Body.Outlines = newOutlines
+	return nil
+}
+
+// findOrCreateFolder returns the folder outline for a tag, with a nested
+// folder for each "/"-separated part of it, appending any that don't exist yet.
+func (ofl *OPMLFeedList) findOrCreateFolder(tag string) *opml.Outline {
+	outlines := &ofl.opml.Body.Outlines
+	var folder *opml.Outline
+	for _, name := range strings.Split(tag, "/") {
+		if name = strings.TrimSpace(name); name != "" {
+			folder = findOrAppendFolder(outlines, name)
+			outlines = &folder.Outlines
+		}
+	}
+	if folder == nil {
+		folder = findOrAppendFolder(outlines, tag)
+	}
+	return folder
+}
+
+// findOrAppendFolder returns the folder outline with the given name among
+// outlines, appending a new one if there isn't one yet.
+func findOrAppendFolder(outlines *[]opml.Outline, name string) *opml.Outline {
+	for i := range *outlines {
+		if (*outlines)[i].XMLURL == "" && opml.FolderName((*outlines)[i]) == name {
+			return &(*outlines)[i]
+		}
+	}
+
+	*outlines = append(*outlines, opml.Outline{Text: name, Title: name})
+	return &(*outlines)[len(*outlines)-1]
+}
+
+// RemoveURL removes a URL from the OPML feed list, including from folders.
+func (ofl *OPMLFeedList) RemoveURL(url string) error {
+	ofl.opml.Body.Outlines = removeOutlines(ofl.opml.Body.Outlines, url)
+	ofl.urls = opml.ExtractFeedURLs(ofl.opml)
+	return nil
+}
 
+// ReplaceURL points the OPML entry for oldURL at newURL, keeping its place in
+// any folder. If newURL is already listed, the entry for oldURL is removed.
+func (ofl *OPMLFeedList) ReplaceURL(oldURL, newURL string) error {
+	if containsURL(ofl.urls, newURL) {
+		ofl.opml.Body.Outlines = removeOutlines(ofl.opml.Body.Outlines, oldURL)
+	} else {
+		replaceOutlineURL(ofl.opml.Body.Outlines, oldURL, newURL)
+	}
+	ofl.urls = opml.ExtractFeedURLs(ofl.opml)
 	return nil
 }
 
+// replaceOutlineURL rewrites outlines for oldURL, including in nested folders.
+// Text and title that were just the old URL are updated too.
+func replaceOutlineURL(outlines []opml.Outline, oldURL, newURL string) {
+	for i := range outlines {
+		outline := &outlines[i]
+		if outline.XMLURL == oldURL {
+			outline.XMLURL = newURL
+			if outline.Text == oldURL {
+				outline.Text = newURL
+			}
+			if outline.Title == oldURL {
+				outline.Title = newURL
+			}
+		}
+		replaceOutlineURL(outline.Outlines, oldURL, newURL)
+	}
+}
+
+// removeOutlines returns outlines without entries for url, including in nested
+// folders. Folders left empty by the removal are dropped.
+func removeOutlines(outlines []opml.Outline, url string) []opml.Outline {
+	kept := make([]opml.Outline, 0, len(outlines))
+	for _, outline := range outlines {
+		if outline.XMLURL == url {
+			continue
+		}
+		if len(outline.Outlines) > 0 {
+			outline.Outlines = removeOutlines(outline.Outlines, url)
+			if len(outline.Outlines) == 0 && outline.XMLURL == "" {
+				continue
+			}
+		}
+		kept = append(kept, outline)
+	}
+	return kept
+}
+
 // Save saves the OPML feed list to a file.
 func (ofl *OPMLFeedList) Save(filename string) error {
 	file, err := os.Create(filename)
@@ -191,7 +401,7 @@ func (ofl *OPMLFeedList) Save(filename string) error {
 	header := `<?xml version="1.0" encoding="UTF-8"?>
 <opml version="2.0">
     <head>
-        <title>` + ofl.opml.Head.Title + `</title>
+        <title>` + escapeXML(ofl.opml.Head.Title) + `</title>
     </head>
     <body>
 `
@@ -200,12 +410,8 @@ func (ofl *OPMLFeedList) Save(filename string) error {
 	}
 
 	// Write outlines
-	for _, outline := range ofl.opml.Body.Outlines {
-		line := fmt.Sprintf(`        <outline text=%q type=%q xmlUrl=%q />%s`,
-			outline.Text, outline.Type, outline.XMLURL, "\n")
-		if _, err := file.WriteString(line); err != nil {
-			return fmt.Errorf("failed to write OPML outline: %w", err)
-		}
+	if err := writeOutlines(file, ofl.opml.Body.Outlines, 2); err != nil {
+		return err
 	}
 
 	// Write OPML footer
@@ -219,6 +425,71 @@ func (ofl *OPMLFeedList) Save(filename string) error {
 	return nil
 }
 
+// writeOutlines writes outlines at the given indent depth, nesting folder
+// outlines so their structure survives a save.
+func writeOutlines(w io.Writer, outlines []opml.Outline, depth int) error {
+	indent := strings.Repeat("    ", depth)
+	for _, outline := range outlines {
+		line := indent + "<outline" + xmlAttr("text", outline.Text)
+		if outline.Title != "" && outline.Title != outline.Text {
+			line += xmlAttr("title", outline.Title)
+		}
+		if len(outline.Outlines) > 0 {
+			line += ">\n"
+		} else {
+			line += xmlAttr("type", outline.Type) + xmlAttr("xmlUrl", outline.XMLURL)
+			for _, attr := range [][2]string{
+				{"category", outline.Category},
+				{"feedspoolTitle", outline.TitleOverride},
+				{"feedspoolInterval", outline.Interval},
+				{"feedspoolMaxItems", outline.MaxItems},
+				{"feedspoolHidden", outline.Hidden},
+				{"feedspoolRetention", outline.Retention},
+			} {
+				if attr[1] != "" {
+					line += xmlAttr(attr[0], attr[1])
+				}
+			}
+			line += " />\n"
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return fmt.Errorf("failed to write OPML outline: %w", err)
+		}
+
+		if len(outline.Outlines) > 0 {
+			if err := writeOutlines(w, outline.Outlines, depth+1); err != nil {
+				return err
+			}
+			if _, err := io.WriteString(w, indent+"</outline>\n"); err != nil {
+				return fmt.Errorf("failed to write OPML outline: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// xmlAttr formats an XML attribute with a leading space.
+func xmlAttr(name, value string) string {
+	return " " + name + `="` + escapeXML(value) + `"`
+}
+
+// escapeXML escapes text for use in XML content and attribute values.
+func escapeXML(text string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(text)) // Writing to a strings.Builder can't fail
+	return b.String()
+}
+
+// containsURL reports whether urls contains url.
+func containsURL(urls []string, url string) bool {
+	for _, existingURL := range urls {
+		if existingURL == url {
+			return true
+		}
+	}
+	return false
+}
+
 // TextFeedList methods.
 
 // GetURLs returns all URLs in the text feed list.
@@ -226,8 +497,27 @@ func (tfl *TextFeedList) GetURLs() []string {
 	return tfl.urls
 }
 
-// AddURL adds a URL to the text feed list.
-func (tfl *TextFeedList) AddURL(url string) error {
+// GetTags returns nil, as text feed lists have no folders.
+func (tfl *TextFeedList) GetTags() map[string][]string {
+	return nil
+}
+
+// GetSettings returns nil, as text feed lists have no per-feed settings.
+func (tfl *TextFeedList) GetSettings() map[string]database.FeedSettings {
+	return nil
+}
+
+// GetEntries returns an entry for each URL in the text feed list.
+func (tfl *TextFeedList) GetEntries() []Entry {
+	entries := make([]Entry, 0, len(tfl.urls))
+	for _, url := range tfl.urls {
+		entries = append(entries, Entry{URL: url})
+	}
+	return entries
+}
+
+// AddURL adds a URL to the text feed list. Tags are ignored.
+func (tfl *TextFeedList) AddURL(url string, _ ...string) error {
 	// Check if URL already exists
 	for _, existingURL := range tfl.urls {
 		if existingURL == url {
@@ -239,6 +529,12 @@ func (tfl *TextFeedList) AddURL(url string) error {
 	return nil
 }
 
+// AddEntry adds the entry's URL to the text feed list. Tags and settings are
+// ignored.
+func (tfl *TextFeedList) AddEntry(entry Entry) error {
+	return tfl.AddURL(entry.URL)
+}
+
 // RemoveURL removes a URL from the text feed list.
 func (tfl *TextFeedList) RemoveURL(url string) error {
 	newURLs := make([]string, 0)
@@ -251,6 +547,21 @@ func (tfl *TextFeedList) RemoveURL(url string) error {
 	return nil
 }
 
+// ReplaceURL swaps oldURL for newURL in place. If newURL is already listed,
+// oldURL is removed instead.
+func (tfl *TextFeedList) ReplaceURL(oldURL, newURL string) error {
+	if containsURL(tfl.urls, newURL) {
+		return tfl.RemoveURL(oldURL)
+	}
+
+	for i, existingURL := range tfl.urls {
+		if existingURL == oldURL {
+			tfl.urls[i] = newURL
+		}
+	}
+	return nil
+}
+
 // Save saves the text feed list to a file.
 func (tfl *TextFeedList) Save(filename string) error {
 	file, err := os.Create(filename)
@@ -5,6 +5,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
 )
 
 const (
@@ -22,6 +25,9 @@ func TestDetectFormat(t *testing.T) {
 		{"feeds.xml", FormatOPML},
 		{"feeds.txt", FormatText},
 		{"feeds.text", FormatText},
+		{"feeds.yaml", FormatYAML},
+		{"feeds.yml", FormatYAML},
+		{"feeds.json", FormatJSON},
 		{"feeds.unknown", FormatText}, // Default to text
 		{"feeds", FormatText},         // No extension defaults to text
 	}
@@ -278,3 +284,308 @@ func TestLoadInvalidFormat(t *testing.T) {
 		t.Error("LoadFeedList() should return error for invalid format")
 	}
 }
+
+func TestTextFeedListReplaceURL(t *testing.T) {
+	list := NewFeedList(FormatText)
+	list.AddURL(testURL1)
+	list.AddURL(testURL2)
+
+	if err := list.ReplaceURL(testURL1, testURL3); err != nil {
+		t.Fatalf("ReplaceURL() error = %v", err)
+	}
+
+	urls := list.GetURLs()
+	if len(urls) != 2 || urls[0] != testURL3 || urls[1] != testURL2 {
+		t.Errorf("URLs after ReplaceURL() = %v, want [%s %s]", urls, testURL3, testURL2)
+	}
+
+	// Replacing with a URL already in the list drops the old entry
+	if err := list.ReplaceURL(testURL3, testURL2); err != nil {
+		t.Fatalf("ReplaceURL() error = %v", err)
+	}
+
+	urls = list.GetURLs()
+	if len(urls) != 1 || urls[0] != testURL2 {
+		t.Errorf("URLs after duplicate ReplaceURL() = %v, want [%s]", urls, testURL2)
+	}
+}
+
+func TestOPMLFeedListReplaceURLInFolder(t *testing.T) {
+	tmpDir := t.TempDir()
+	filename := filepath.Join(tmpDir, "nested.opml")
+
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head><title>Nested</title></head>
+  <body>
+    <outline text="Tech" title="Technology">
+      <outline text="Example" type="rss" xmlUrl="` + testURL1 + `" />
+    </outline>
+    <outline text="Another" type="rss" xmlUrl="` + testURL2 + `" />
+  </body>
+</opml>`
+	if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := LoadFeedList(FormatOPML, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := list.ReplaceURL(testURL1, testURL3); err != nil {
+		t.Fatalf("ReplaceURL() error = %v", err)
+	}
+	if err := list.Save(filename); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadFeedList(FormatOPML, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	urls := loaded.GetURLs()
+	if len(urls) != 2 || urls[0] != testURL3 || urls[1] != testURL2 {
+		t.Errorf("URLs after ReplaceURL() = %v, want [%s %s]", urls, testURL3, testURL2)
+	}
+
+	folder := loaded.(*OPMLFeedList).opml.Body.Outlines[0]
+	if folder.Text != "Tech" || folder.Title != "Technology" || len(folder.Outlines) != 1 ||
+		folder.Outlines[0].XMLURL != testURL3 {
+		t.Errorf("folder after save = %+v, want Tech containing %s", folder, testURL3)
+	}
+}
+
+func TestOPMLFeedListTagsRoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+	filename := filepath.Join(tmpDir, "tagged.opml")
+
+	list := NewFeedList(FormatOPML)
+	if err := list.AddURL(testURL1, "golang"); err != nil {
+		t.Fatalf("AddURL() error = %v", err)
+	}
+	if err := list.AddURL(testURL2); err != nil {
+		t.Fatalf("AddURL() error = %v", err)
+	}
+	if err := list.AddURL(testURL3, "golang", "news/daily"); err != nil {
+		t.Fatalf("AddURL() error = %v", err)
+	}
+	if err := list.Save(filename); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// A feed goes in the folder of every tag, nesting folders for paths
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(content), `xmlUrl="`+testURL3+`"`) != 2 ||
+		!strings.Contains(string(content), `<outline text="daily">`) {
+		t.Errorf("saved OPML should list %s under golang and news/daily:\n%s", testURL3, content)
+	}
+
+	loaded, err := LoadFeedList(FormatOPML, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	urls := loaded.GetURLs()
+	if len(urls) != 3 || urls[0] != testURL1 || urls[1] != testURL3 || urls[2] != testURL2 {
+		t.Errorf("URLs after load = %v, want [%s %s %s]", urls, testURL1, testURL3, testURL2)
+	}
+
+	tags := loaded.GetTags()
+	if strings.Join(tags[testURL1], ",") != "golang" {
+		t.Errorf("tags[%s] = %v, want [golang]", testURL1, tags[testURL1])
+	}
+	if len(tags[testURL2]) != 0 {
+		t.Errorf("tags[%s] = %v, want none", testURL2, tags[testURL2])
+	}
+	if strings.Join(tags[testURL3], ",") != "golang,news/daily" {
+		t.Errorf("tags[%s] = %v, want [golang news/daily]", testURL3, tags[testURL3])
+	}
+
+	// Removing the last feed in a folder drops the folder too
+	if err := loaded.RemoveURL(testURL1); err != nil {
+		t.Fatalf("RemoveURL() error = %v", err)
+	}
+	if err := loaded.RemoveURL(testURL3); err != nil {
+		t.Fatalf("RemoveURL() error = %v", err)
+	}
+	outlines := loaded.(*OPMLFeedList).opml.Body.Outlines
+	if len(outlines) != 1 || outlines[0].XMLURL != testURL2 {
+		t.Errorf("outlines after RemoveURL() = %+v, want only %s", outlines, testURL2)
+	}
+}
+
+func TestStructuredFeedListSaveAndLoad(t *testing.T) {
+	settings := database.FeedSettings{
+		TitleOverride: "Example",
+		FetchInterval: 6 * time.Hour,
+		MaxItems:      20,
+		Hidden:        true,
+		Retention:     90 * 24 * time.Hour,
+	}
+
+	for _, format := range []Format{FormatYAML, FormatJSON} {
+		t.Run(format.String(), func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "feeds."+format.String())
+
+			list := NewFeedList(format)
+			if err := list.AddEntry(Entry{URL: testURL1, Tags: []string{"golang", "news"}, Settings: settings}); err != nil {
+				t.Fatalf("AddEntry() error = %v", err)
+			}
+			if err := list.AddURL(testURL2); err != nil {
+				t.Fatalf("AddURL() error = %v", err)
+			}
+			if err := list.Save(filename); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+
+			content, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(content), "90d") || !strings.Contains(string(content), "6h") {
+				t.Errorf("saved list should write durations as written by hand:\n%s", content)
+			}
+
+			loaded, err := LoadFeedList(format, filename)
+			if err != nil {
+				t.Fatalf("LoadFeedList() error = %v", err)
+			}
+			if urls := loaded.GetURLs(); len(urls) != 2 || urls[0] != testURL1 || urls[1] != testURL2 {
+				t.Errorf("URLs after load = %v, want [%s %s]", urls, testURL1, testURL2)
+			}
+			if tags := loaded.GetTags(); strings.Join(tags[testURL1], ",") != "golang,news" || len(tags[testURL2]) != 0 {
+				t.Errorf("tags after load = %v", tags)
+			}
+			got := loaded.GetSettings()
+			if got[testURL1] != settings || got[testURL2] != (database.FeedSettings{}) {
+				t.Errorf("settings after load = %+v, want %+v for %s", got, settings, testURL1)
+			}
+
+			if err := loaded.ReplaceURL(testURL1, testURL3); err != nil {
+				t.Fatalf("ReplaceURL() error = %v", err)
+			}
+			if got := loaded.GetSettings()[testURL3]; got != settings {
+				t.Errorf("settings after ReplaceURL() = %+v, want %+v", got, settings)
+			}
+		})
+	}
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+	entries := []Entry{
+		{URL: testURL1, Tags: []string{"golang", "news"}, Settings: database.FeedSettings{
+			TitleOverride: `Example "Go" & <friends>`,
+			FetchInterval: 90 * time.Minute,
+			MaxItems:      5,
+			Retention:     14 * 24 * time.Hour,
+		}},
+		{URL: testURL2, Settings: database.FeedSettings{Hidden: true}},
+		{URL: testURL3, Tags: []string{"golang"}},
+	}
+
+	list := NewFeedList(FormatYAML)
+	for _, entry := range entries {
+		if err := list.AddEntry(entry); err != nil {
+			t.Fatalf("AddEntry() error = %v", err)
+		}
+	}
+
+	// YAML -> OPML -> JSON -> YAML, saving and loading at each step
+	for _, format := range []Format{FormatOPML, FormatJSON, FormatYAML} {
+		converted, err := Convert(list, format)
+		if err != nil {
+			t.Fatalf("Convert(%s) error = %v", format, err)
+		}
+		filename := filepath.Join(tmpDir, "feeds."+format.String())
+		if err := converted.Save(filename); err != nil {
+			t.Fatalf("Save(%s) error = %v", format, err)
+		}
+		if list, err = LoadFeedList(format, filename); err != nil {
+			t.Fatalf("LoadFeedList(%s) error = %v", format, err)
+		}
+	}
+
+	got := list.GetEntries()
+	if len(got) != len(entries) {
+		t.Fatalf("GetEntries() = %+v, want %+v", got, entries)
+	}
+	byURL := make(map[string]Entry)
+	for _, entry := range got {
+		byURL[entry.URL] = entry
+	}
+	for _, want := range entries {
+		entry := byURL[want.URL]
+		if strings.Join(entry.Tags, ",") != strings.Join(want.Tags, ",") || entry.Settings != want.Settings {
+			t.Errorf("entry after round trip = %+v, want %+v", entry, want)
+		}
+	}
+}
+
+func TestOPMLFeedListSettings(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "feeds.opml")
+	content := `<?xml version="1.0"?>
+<opml version="2.0"><body>
+  <outline text="Example" xmlUrl="` + testURL1 + `" feedspoolInterval="2h" feedspoolHidden="true" />
+  <outline text="` + testURL2 + `" xmlUrl="` + testURL2 + `" feedspoolTitle="Renamed" />
+</body></opml>`
+	if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := LoadFeedList(FormatOPML, filename)
+	if err != nil {
+		t.Fatalf("LoadFeedList() error = %v", err)
+	}
+
+	// An outline's text is only a label, so only feedspoolTitle overrides
+	// the title
+	settings := list.GetSettings()
+	want := database.FeedSettings{FetchInterval: 2 * time.Hour, Hidden: true}
+	if settings[testURL1] != want {
+		t.Errorf("settings[%s] = %+v, want %+v", testURL1, settings[testURL1], want)
+	}
+	if entries := list.GetEntries(); entries[0].Settings.TitleOverride != "" ||
+		entries[1].Settings.TitleOverride != "Renamed" {
+		t.Errorf("GetEntries() = %+v, want a title for the second entry only", entries)
+	}
+
+	if NewFeedList(FormatText).GetSettings() != nil {
+		t.Error("text feed list GetSettings() should return nil")
+	}
+}
+
+func TestLoadInvalidSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		content string
+		wantErr string
+	}{
+		{"YAML interval", FormatYAML, "feeds:\n  - url: " + testURL1 + "\n    interval: often\n", "invalid interval"},
+		{"JSON retention", FormatJSON, `{"feeds": [{"url": "` + testURL1 + `", "retention": "-1d"}]}`, "invalid retention"},
+		{
+			"OPML max items", FormatOPML,
+			`<opml><body><outline xmlUrl="` + testURL1 + `" feedspoolMaxItems="lots" /></body></opml>`,
+			"invalid feedspoolMaxItems",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "feeds")
+			if err := os.WriteFile(filename, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadFeedList(tt.format, filename)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadFeedList() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
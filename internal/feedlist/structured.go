@@ -0,0 +1,214 @@
+package feedlist
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/structlist"
+)
+
+// day is the unit durations are written in when they are whole days.
+const day = 24 * time.Hour
+
+// StructuredFeedList is a YAML or JSON feed list, which keeps tags and
+// per-feed settings with each entry.
+type StructuredFeedList struct {
+	format Format
+	list   *structlist.List
+}
+
+// loadStructuredFeedList loads a YAML or JSON feed list from a reader,
+// checking that its durations parse.
+func loadStructuredFeedList(format Format, reader io.Reader) (FeedList, error) {
+	var list *structlist.List
+	var err error
+	if format == FormatJSON {
+		list, err = structlist.ParseJSON(reader)
+	} else {
+		list, err = structlist.ParseYAML(reader)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s feed list: %w", format, err)
+	}
+
+	for _, feed := range list.Feeds {
+		if _, err := feedSettings(feed); err != nil {
+			return nil, err
+		}
+	}
+
+	return &StructuredFeedList{format: format, list: list}, nil
+}
+
+// feedSettings converts the settings of a list entry, parsing its durations.
+func feedSettings(feed structlist.Feed) (database.FeedSettings, error) {
+	settings := database.FeedSettings{
+		TitleOverride: feed.Title,
+		MaxItems:      feed.MaxItems,
+		Hidden:        feed.Hidden,
+	}
+	var err error
+	if feed.Interval != "" {
+		if settings.FetchInterval, err = parseSettingDuration(feed.Interval); err != nil {
+			return settings, fmt.Errorf("invalid interval for %s: %w", feed.URL, err)
+		}
+	}
+	if feed.Retention != "" {
+		if settings.Retention, err = parseSettingDuration(feed.Retention); err != nil {
+			return settings, fmt.Errorf("invalid retention for %s: %w", feed.URL, err)
+		}
+	}
+	return settings, nil
+}
+
+// parseSettingDuration parses a duration such as "6h", "90d" or "2w",
+// rejecting negative ones.
+func parseSettingDuration(value string) (time.Duration, error) {
+	duration, err := database.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("negative duration %q", value)
+	}
+	return duration, nil
+}
+
+// formatSettingDuration formats a duration the way parseSettingDuration
+// reads it, in whole days or hours where possible, or "" when it is unset.
+func formatSettingDuration(duration time.Duration) string {
+	switch {
+	case duration <= 0:
+		return ""
+	case duration%day == 0:
+		return fmt.Sprintf("%dd", duration/day)
+	case duration%time.Hour == 0:
+		return fmt.Sprintf("%dh", duration/time.Hour)
+	default:
+		return duration.String()
+	}
+}
+
+// GetURLs returns all URLs in the structured feed list.
+func (sfl *StructuredFeedList) GetURLs() []string {
+	urls := make([]string, 0, len(sfl.list.Feeds))
+	for _, feed := range sfl.list.Feeds {
+		urls = append(urls, feed.URL)
+	}
+	return urls
+}
+
+// GetTags returns the tags listed for each feed. Feeds without tags are left
+// out.
+func (sfl *StructuredFeedList) GetTags() map[string][]string {
+	tags := make(map[string][]string)
+	for _, feed := range sfl.list.Feeds {
+		if len(feed.Tags) > 0 {
+			tags[feed.URL] = feed.Tags
+		}
+	}
+	return tags
+}
+
+// GetSettings returns the settings of each feed in the structured feed list.
+func (sfl *StructuredFeedList) GetSettings() map[string]database.FeedSettings {
+	settings := make(map[string]database.FeedSettings)
+	for _, feed := range sfl.list.Feeds {
+		settings[feed.URL], _ = feedSettings(feed) // Checked on load
+	}
+	return settings
+}
+
+// GetEntries returns the feeds in the structured feed list, in order.
+func (sfl *StructuredFeedList) GetEntries() []Entry {
+	entries := make([]Entry, 0, len(sfl.list.Feeds))
+	for _, feed := range sfl.list.Feeds {
+		settings, _ := feedSettings(feed) // Checked on load
+		entries = append(entries, Entry{URL: feed.URL, Tags: feed.Tags, Settings: settings})
+	}
+	return entries
+}
+
+// AddURL adds a URL with the given tags to the structured feed list.
+func (sfl *StructuredFeedList) AddURL(url string, tags ...string) error {
+	return sfl.AddEntry(Entry{URL: url, Tags: tags})
+}
+
+// AddEntry adds a feed with its tags and settings to the structured feed
+// list.
+func (sfl *StructuredFeedList) AddEntry(entry Entry) error {
+	if containsURL(sfl.GetURLs(), entry.URL) {
+		return nil // URL already exists, no error
+	}
+
+	feed := structlist.Feed{
+		URL:       entry.URL,
+		Title:     entry.Settings.TitleOverride,
+		Interval:  formatSettingDuration(entry.Settings.FetchInterval),
+		MaxItems:  entry.Settings.MaxItems,
+		Hidden:    entry.Settings.Hidden,
+		Retention: formatSettingDuration(entry.Settings.Retention),
+	}
+	if len(entry.Tags) > 0 {
+		feed.Tags = append([]string{}, entry.Tags...)
+	}
+	sfl.list.Feeds = append(sfl.list.Feeds, feed)
+	return nil
+}
+
+// RemoveURL removes a URL from the structured feed list.
+func (sfl *StructuredFeedList) RemoveURL(url string) error {
+	kept := make([]structlist.Feed, 0, len(sfl.list.Feeds))
+	for _, feed := range sfl.list.Feeds {
+		if feed.URL != url {
+			kept = append(kept, feed)
+		}
+	}
+	sfl.list.Feeds = kept
+	return nil
+}
+
+// ReplaceURL points the entry for oldURL at newURL, keeping its tags and
+// settings. If newURL is already listed, the entry for oldURL is removed.
+func (sfl *StructuredFeedList) ReplaceURL(oldURL, newURL string) error {
+	if containsURL(sfl.GetURLs(), newURL) {
+		return sfl.RemoveURL(oldURL)
+	}
+
+	for i := range sfl.list.Feeds {
+		if sfl.list.Feeds[i].URL == oldURL {
+			sfl.list.Feeds[i].URL = newURL
+		}
+	}
+	return nil
+}
+
+// Save saves the structured feed list to a file.
+func (sfl *StructuredFeedList) Save(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create %s file %s: %w", sfl.format, filename, err)
+	}
+	defer file.Close()
+
+	if sfl.format == FormatJSON {
+		return structlist.WriteJSON(file, sfl.list)
+	}
+	return structlist.WriteYAML(file, sfl.list)
+}
+
+// Convert copies the feeds of a list, with their tags and settings, into a
+// new list of the given format. Formats without folders or settings drop
+// them.
+func Convert(list FeedList, format Format) (FeedList, error) {
+	converted := NewFeedList(format)
+	for _, entry := range list.GetEntries() {
+		if err := converted.AddEntry(entry); err != nil {
+			return nil, fmt.Errorf("failed to add URL %s to feed list: %w", entry.URL, err)
+		}
+	}
+	return converted, nil
+}
@@ -0,0 +1,78 @@
+package fetcher
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrorBackoff returns how long to wait before retrying a feed that has failed
+// errorCount times in a row. The delay starts at base and doubles with each
+// consecutive failure, up to maxDelay.
+func ErrorBackoff(errorCount int, base, maxDelay time.Duration) time.Duration {
+	if base <= 0 {
+		base = time.Minute
+	}
+
+	delay := base
+	for i := 1; i < errorCount; i++ {
+		delay *= 2
+		if maxDelay > 0 && delay >= maxDelay {
+			return maxDelay
+		}
+	}
+
+	if maxDelay > 0 && delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+// applyErrorPolicy backs off a failing feed's next fetch and disables it once
+// it reaches the configured number of consecutive errors.
+func (f *Fetcher) applyErrorPolicy(feed *database.Feed) {
+	delay := ErrorBackoff(feed.ErrorCount, f.minInterval, f.maxInterval)
+	feed.NextFetchAt = sql.NullTime{Time: time.Now().Add(delay), Valid: true}
+
+	if f.disableAfter > 0 && feed.ErrorCount >= f.disableAfter && !feed.Disabled {
+		feed.Disabled = true
+		logrus.Warnf("Disabling %s after %d consecutive errors (last: %s)",
+			feed.URL, feed.ErrorCount, feed.LastError)
+	}
+}
+
+// SuggestAction returns a short hint on how to deal with a failing feed,
+// based on its last error.
+func SuggestAction(feed *database.Feed) string {
+	lastError := strings.ToLower(feed.LastError)
+
+	var action string
+	switch {
+	case strings.Contains(lastError, "http 404"), strings.Contains(lastError, "http 410"):
+		action = "Feed is gone; find its new URL or unsubscribe"
+	case strings.Contains(lastError, "http 401"), strings.Contains(lastError, "http 403"):
+		action = "Access denied; check whether the feed now needs credentials"
+	case strings.Contains(lastError, "http 429"), strings.Contains(lastError, "http 5"):
+		action = "Server-side trouble; usually transient, wait for backoff"
+	case strings.Contains(lastError, "connection refused"):
+		action = "Server is refusing connections; usually transient, wait for backoff"
+	case strings.Contains(lastError, "no such host"):
+		action = "Domain no longer resolves; unsubscribe if it doesn't return"
+	case strings.Contains(lastError, "deadline exceeded"), strings.Contains(lastError, "timeout"):
+		action = "Server is slow; try a longer --timeout"
+	case strings.Contains(lastError, "certificate"), strings.Contains(lastError, "tls"):
+		action = "TLS problem; check the site's certificate"
+	case strings.Contains(lastError, "failed to parse"):
+		action = "Not a valid feed; check the URL still serves RSS/Atom"
+	default:
+		action = "Check the URL in a browser"
+	}
+
+	if feed.Disabled {
+		action += "; then run 'feedspool feeds enable'"
+	}
+	return action
+}
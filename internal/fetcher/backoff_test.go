@@ -0,0 +1,109 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/database/databasetest"
+)
+
+func TestErrorBackoff(t *testing.T) {
+	tests := []struct {
+		errorCount int
+		want       time.Duration
+	}{
+		{1, 15 * time.Minute},
+		{2, 30 * time.Minute},
+		{3, time.Hour},
+		{7, 16 * time.Hour},
+		{8, 24 * time.Hour},
+		{100, 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		got := ErrorBackoff(tt.errorCount, 15*time.Minute, 24*time.Hour)
+		if got != tt.want {
+			t.Errorf("ErrorBackoff(%d) = %v, want %v", tt.errorCount, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestAction(t *testing.T) {
+	tests := []struct {
+		lastError string
+		disabled  bool
+		contains  string
+	}{
+		{"HTTP 404", false, "gone"},
+		{"HTTP 503", false, "transient"},
+		{"failed to fetch: dial tcp: lookup nowhere.invalid: no such host", false, "resolves"},
+		{"failed to parse: Failed to detect feed type", true, "feeds enable"},
+		{"something else", false, "browser"},
+	}
+
+	for _, tt := range tests {
+		feed := &database.Feed{LastError: tt.lastError, Disabled: tt.disabled}
+		if got := SuggestAction(feed); !strings.Contains(got, tt.contains) {
+			t.Errorf("SuggestAction(%q) = %q, want it to contain %q", tt.lastError, got, tt.contains)
+		}
+	}
+}
+
+func TestFetchFeedBacksOffAndDisables(t *testing.T) {
+	db := databasetest.New(t)
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	if err := db.UpsertFeed(&database.Feed{URL: server.URL, FeedJSON: database.JSON(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	fetcher := NewFetcher(db, 30*time.Second, 100, false)
+	fetcher.SetScheduleBounds(15*time.Minute, 24*time.Hour)
+	fetcher.SetDisableAfter(2)
+
+	if result := fetcher.FetchFeed(server.URL); result.Error == nil {
+		t.Fatal("FetchFeed() expected error for 404")
+	}
+
+	feed, err := db.GetFeed(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Disabled {
+		t.Error("feed should not be disabled after one error")
+	}
+	if !feed.NextFetchAt.Valid || time.Until(feed.NextFetchAt.Time) < 14*time.Minute {
+		t.Errorf("next fetch = %v, want backoff of about 15m", feed.NextFetchAt)
+	}
+
+	fetcher.FetchFeed(server.URL)
+
+	feed, err = db.GetFeed(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !feed.Disabled {
+		t.Error("feed should be disabled after reaching the threshold")
+	}
+
+	// Disabled feeds are skipped even when forced
+	forced := NewFetcher(db, 30*time.Second, 100, true)
+	results := fetchConcurrent(forced, []string{server.URL}, 1, 0, true)
+	if !results[0].Disabled {
+		t.Error("fetchConcurrent() should skip disabled feeds")
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("server received %d requests, want 2", n)
+	}
+}
@@ -1,52 +1,159 @@
 package fetcher
 
 import (
-	"context"
+	"bytes"
 	"database/sql"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"sync"
 	"time"
 
+	"github.com/lmorchard/feedspool-go/internal/config"
 	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/dedupe"
 	"github.com/lmorchard/feedspool-go/internal/httpclient"
+	"github.com/lmorchard/feedspool-go/internal/httpoverride"
+	"github.com/lmorchard/feedspool-go/internal/media"
+	"github.com/lmorchard/feedspool-go/internal/rules"
 	"github.com/lmorchard/feedspool-go/internal/unfurl"
+	"github.com/lmorchard/feedspool-go/internal/urlcanon"
 	"github.com/mmcdole/gofeed"
 	"github.com/sirupsen/logrus"
 )
 
 type FetchResult struct {
-	URL       string
-	Feed      *database.Feed
-	ItemCount int
-	Cached    bool
-	Error     error
+	URL           string
+	Feed          *database.Feed
+	ItemCount     int
+	NewItems      int
+	UpdatedItems  int   // Stored items whose content changed
+	ArchivedItems int   // Stored items no longer in the feed
+	StatusCode    int   // HTTP status of the response, 0 when none was received
+	Bytes         int64 // Size of the response body
+	Cached        bool
+	Disabled      bool   // Skipped because the feed is disabled
+	Parked        bool   // Rate limited by the server, not fetched until parked_until
+	MovedTo       string // New URL the feed was migrated to after a permanent redirect
+	Error         error
+}
+
+// itemCounts tallies what processing a feed did to its items.
+type itemCounts struct {
+	saved    int // Items stored, new or not
+	added    int
+	updated  int
+	archived int
 }
 
 type Fetcher struct {
-	client      *httpclient.Client
-	timeout     time.Duration
-	maxItems    int
-	forceFlag   bool
-	db          *database.DB
-	unfurlQueue *unfurl.UnfurlQueue
+	client       *httpclient.Client
+	timeout      time.Duration
+	maxItems     int
+	forceFlag    bool
+	db           *database.DB
+	unfurlQueue  *unfurl.UnfurlQueue
+	minInterval  time.Duration
+	maxInterval  time.Duration
+	disableAfter int
+	rules        *rules.Engine
+	detector     *dedupe.Detector
+	links        *urlcanon.Canonicalizer
+	mediaCache   *media.Cache
+	overrides    *httpoverride.Set
+	listSettings map[string]database.FeedSettings
+
+	parkedMu    sync.Mutex
+	parkedHosts map[string]time.Time
 }
 
 func NewFetcher(db *database.DB, timeout time.Duration, maxItems int, force bool) *Fetcher {
-	httpClient := httpclient.NewClient(&httpclient.Config{
-		Timeout:   timeout,
-		UserAgent: httpclient.DefaultUserAgent,
+	fetcher := &Fetcher{
+		timeout:      timeout,
+		maxItems:     maxItems,
+		forceFlag:    force,
+		db:           db,
+		minInterval:  config.DefaultMinFetchInterval,
+		maxInterval:  config.DefaultMaxFetchInterval,
+		disableAfter: config.DefaultDisableAfter,
+		parkedHosts:  make(map[string]time.Time),
+	}
+	fetcher.SetHostLimits(config.DefaultPerHostConcurrency, config.DefaultPerHostRate)
+	return fetcher
+}
+
+// SetHostLimits sets how many requests may run at once against a single host
+// and how many new requests per second it may receive. Zero or less removes
+// the limit.
+func (f *Fetcher) SetHostLimits(concurrency int, rate float64) {
+	f.client = httpclient.NewClient(&httpclient.Config{
+		Timeout:            f.timeout,
+		UserAgent:          httpclient.DefaultUserAgent,
+		PerHostConcurrency: concurrency,
+		PerHostRate:        rate,
 	})
+}
+
+// SetScheduleBounds sets the shortest and longest intervals used when
+// scheduling a feed's next fetch.
+func (f *Fetcher) SetScheduleBounds(minInterval, maxInterval time.Duration) {
+	f.minInterval = minInterval
+	f.maxInterval = maxInterval
+}
+
+// SetDisableAfter sets how many consecutive errors disable a feed. Zero or
+// less never disables feeds.
+func (f *Fetcher) SetDisableAfter(errorCount int) {
+	f.disableAfter = errorCount
+}
+
+// SetRules sets the rules applied to items before they are stored.
+func (f *Fetcher) SetRules(engine *rules.Engine) {
+	f.rules = engine
+}
+
+// SetDetector sets the detector grouping new items with their duplicates
+// from other feeds.
+func (f *Fetcher) SetDetector(detector *dedupe.Detector) {
+	f.detector = detector
+}
+
+// SetCanonicalizer sets how item links are canonicalized before they are
+// stored and unfurled.
+func (f *Fetcher) SetCanonicalizer(links *urlcanon.Canonicalizer) {
+	f.links = links
+}
+
+// SetMediaCache sets the cache that images in new items are downloaded into.
+func (f *Fetcher) SetMediaCache(cache *media.Cache) {
+	f.mediaCache = cache
+}
 
-	return &Fetcher{
-		client:    httpClient,
-		timeout:   timeout,
-		maxItems:  maxItems,
-		forceFlag: force,
-		db:        db,
+// SetOverrides sets the per-feed and per-host changes made to feed requests,
+// such as credentials, extra headers and proxies.
+func (f *Fetcher) SetOverrides(overrides *httpoverride.Set) {
+	f.overrides = overrides
+}
+
+// SetListSettings sets the per-feed settings from the feed list being
+// fetched, used for feeds that are not in the database yet.
+func (f *Fetcher) SetListSettings(settings map[string]database.FeedSettings) {
+	f.listSettings = settings
+}
+
+// feedSettings returns the per-feed settings of a feed, from the feed list
+// when it has them and from the database otherwise.
+func (f *Fetcher) feedSettings(feedURL string) database.FeedSettings {
+	if settings, ok := f.listSettings[feedURL]; ok {
+		return settings
 	}
+	settings, err := f.db.GetFeedSettings(feedURL)
+	if err != nil {
+		logrus.Warnf("Failed to get settings for %s: %v", feedURL, err)
+	}
+	return settings
 }
 
 // SetUnfurlQueue sets the unfurl queue for parallel unfurl operations.
@@ -54,7 +161,60 @@ func (f *Fetcher) SetUnfurlQueue(queue *unfurl.UnfurlQueue) {
 	f.unfurlQueue = queue
 }
 
+// clientFor returns the client to fetch a feed with, adding the headers of
+// any matching HTTP overrides.
+func (f *Fetcher) clientFor(feedURL string, headers map[string]string) (*httpclient.Client, error) {
+	match, err := f.overrides.For(feedURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply http overrides: %w", err)
+	}
+	if match == nil {
+		return f.client, nil
+	}
+
+	for name, value := range match.Headers {
+		headers[name] = value
+	}
+	if match.Transport != nil {
+		return f.client.WithTransport(match.Transport), nil
+	}
+	return f.client, nil
+}
+
 func (f *Fetcher) FetchFeed(feedURL string) *FetchResult {
+	started := time.Now()
+	result := f.fetchFeed(feedURL)
+	f.recordFetch(result, started)
+	return result
+}
+
+// recordFetch adds a fetch attempt to the fetch log, under the feed's new URL
+// if it moved.
+func (f *Fetcher) recordFetch(result *FetchResult, started time.Time) {
+	entry := &database.FetchLogEntry{
+		FeedURL:       result.URL,
+		StartedAt:     started,
+		Duration:      time.Since(started),
+		StatusCode:    result.StatusCode,
+		Bytes:         result.Bytes,
+		Cached:        result.Cached,
+		NewItems:      result.NewItems,
+		UpdatedItems:  result.UpdatedItems,
+		ArchivedItems: result.ArchivedItems,
+	}
+	if result.MovedTo != "" {
+		entry.FeedURL = result.MovedTo
+	}
+	if result.Error != nil {
+		entry.Error = result.Error.Error()
+	}
+	if err := f.db.RecordFetch(entry); err != nil {
+		logrus.Warnf("Failed to record fetch of %s: %v", entry.FeedURL, err)
+	}
+}
+
+// fetchFeed fetches a feed and saves its items, without logging the attempt.
+func (f *Fetcher) fetchFeed(feedURL string) *FetchResult {
 	result := &FetchResult{
 		URL: feedURL,
 	}
@@ -65,7 +225,18 @@ func (f *Fetcher) FetchFeed(feedURL string) *FetchResult {
 		return result
 	}
 
+	if until, parked := f.hostParkedUntil(feedURL); parked {
+		return f.parkFeed(result, feedURL, existingFeed, until, "host is rate limiting requests")
+	}
+
 	headers := make(map[string]string)
+	client, err := f.clientFor(feedURL, headers)
+	if err != nil {
+		result.Error = err
+		f.updateFeedError(feedURL, existingFeed, result.Error.Error())
+		return result
+	}
+
 	if !f.forceFlag && existingFeed != nil {
 		if existingFeed.ETag != "" {
 			headers["If-None-Match"] = existingFeed.ETag
@@ -75,45 +246,89 @@ func (f *Fetcher) FetchFeed(feedURL string) *FetchResult {
 		}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), f.timeout)
-	defer cancel()
-
-	resp, err := f.client.Do(&httpclient.Request{
+	// The client's own timeout applies once any per-host rate limit wait is over
+	resp, err := client.Do(&httpclient.Request{
 		URL:     feedURL,
 		Method:  "GET",
 		Headers: headers,
-		Context: ctx,
 	})
 	if err != nil {
 		result.Error = fmt.Errorf("failed to fetch: %w", err)
-		f.updateFeedError(existingFeed, result.Error.Error())
+		f.updateFeedError(feedURL, existingFeed, result.Error.Error())
 		return result
 	}
 	defer resp.Body.Close()
+	result.StatusCode = resp.StatusCode
 
 	if resp.StatusCode == http.StatusNotModified {
-		return f.handleCachedFeed(result, existingFeed)
+		f.followPermanentRedirect(result, existingFeed, resp.PermanentURL)
+		return f.handleCachedFeed(result, existingFeed, resp.Header)
+	}
+
+	if isRateLimited(resp.StatusCode) {
+		until := time.Now().Add(f.retryDelay(resp.Header))
+		f.parkHost(feedURL, until)
+		return f.parkFeed(result, feedURL, existingFeed, until, fmt.Sprintf("HTTP %d", resp.StatusCode))
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		result.Error = fmt.Errorf("HTTP %d", resp.StatusCode)
-		f.updateFeedError(existingFeed, result.Error.Error())
+		f.updateFeedError(feedURL, existingFeed, result.Error.Error())
+		return result
+	}
+
+	// Keep the body around to look for a WebSub hub after parsing
+	body, err := io.ReadAll(resp.BodyReader)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to read: %w", err)
+		f.updateFeedError(feedURL, existingFeed, result.Error.Error())
 		return result
 	}
+	result.Bytes = int64(len(body))
 
 	parser := gofeed.NewParser()
-	gofeedData, err := parser.Parse(resp.BodyReader)
+	gofeedData, err := parser.Parse(bytes.NewReader(body))
 	if err != nil {
 		result.Error = fmt.Errorf("failed to parse: %w", err)
-		f.updateFeedError(existingFeed, result.Error.Error())
+		f.updateFeedError(feedURL, existingFeed, result.Error.Error())
 		return result
 	}
 
-	return f.processParsedFeed(result, gofeedData, feedURL, resp)
+	if f.followPermanentRedirect(result, existingFeed, resp.PermanentURL) {
+		feedURL = result.MovedTo
+	}
+
+	result = f.processParsedFeed(result, gofeedData, feedURL, resp, existingFeed)
+	if result.Error == nil {
+		f.recordHub(feedURL, resp.Header, body)
+	}
+	return result
+}
+
+// followPermanentRedirect moves a feed that was fetched successfully through
+// only 301/308 redirects to its new URL, so it's fetched from there from now
+// on. Returns true if the feed moved.
+func (f *Fetcher) followPermanentRedirect(result *FetchResult, existingFeed *database.Feed, newURL string) bool {
+	if newURL == "" || newURL == result.URL {
+		return false
+	}
+
+	if existingFeed != nil {
+		if err := f.db.MigrateFeedURL(result.URL, newURL); err != nil {
+			logrus.Warnf("Failed to migrate %s to %s: %v", result.URL, newURL, err)
+			return false
+		}
+		existingFeed.URL = newURL
+	}
+
+	logrus.Infof("Feed moved permanently: %s -> %s", result.URL, newURL)
+	result.MovedTo = newURL
+	return true
 }
 
 func (f *Fetcher) processParsedFeed(
 	result *FetchResult, gofeedData *gofeed.Feed, feedURL string, resp *httpclient.Response,
+	existingFeed *database.Feed,
 ) *FetchResult {
 	feed, err := database.FeedFromGofeed(gofeedData, feedURL)
 	if err != nil {
@@ -127,6 +342,8 @@ func (f *Fetcher) processParsedFeed(
 	feed.LastSuccessfulFetch = time.Now()
 	feed.ErrorCount = 0
 	feed.LastError = ""
+	// Only saved for new feeds, but used below for the item limit and schedule
+	feed.FeedSettings = f.feedSettings(feedURL)
 
 	// Save feed first to satisfy foreign key constraints
 	if err := f.db.UpsertFeed(feed); err != nil {
@@ -135,21 +352,59 @@ func (f *Fetcher) processParsedFeed(
 	}
 
 	// Process items and get the latest item date based on clamped published dates
-	itemCount, latestItemDate := f.processFeedItems(gofeedData, feedURL)
+	counts, latestItemDate, err := f.processFeedItems(gofeedData, feedURL, true)
+	if err != nil {
+		// Forget the validators saved above, so the next fetch gets the items
+		// again, and count the error on top of the feed's earlier ones
+		feed.ETag, feed.LastModified = "", ""
+		feed.ErrorCount, feed.LastSuccessfulFetch = 0, time.Time{}
+		if existingFeed != nil {
+			feed.ErrorCount = existingFeed.ErrorCount
+			feed.LastSuccessfulFetch = existingFeed.LastSuccessfulFetch
+		}
+		result.Error = err
+		f.updateFeedError(feedURL, feed, err.Error())
+		return result
+	}
 	if !latestItemDate.IsZero() {
 		// Update feed with latest item date from processed items
 		feed.LatestItemDate = sql.NullTime{Time: latestItemDate, Valid: true}
-		if err := f.db.UpsertFeed(feed); err != nil {
-			logrus.Warnf("Failed to update feed with latest item date: %v", err)
-		}
 	}
 	// Note: If no items with valid dates, we preserve the existing latest_item_date in the database
 
-	result.ItemCount = itemCount
+	// Schedule the next fetch now that the item history includes this fetch
+	f.scheduleNextFetch(feed, resp.Header)
+	if err := f.db.UpsertFeed(feed); err != nil {
+		logrus.Warnf("Failed to update feed with latest item date and schedule: %v", err)
+	}
+
+	result.ItemCount = counts.saved
+	result.NewItems = counts.added
+	result.UpdatedItems = counts.updated
+	result.ArchivedItems = counts.archived
 	result.Feed = feed
 	return result
 }
 
+// annotateItem adds the tags from rules to a stored item and, for new items,
+// groups it with its duplicates from other feeds and caches its images.
+func (f *Fetcher) annotateItem(item *database.Item, tags []string, isNewItem bool) {
+	if len(tags) > 0 {
+		if err := f.db.AddItemTags(item.FeedURL, item.GUID, tags); err != nil {
+			logrus.Warnf("Failed to tag item: %v", err)
+		}
+	}
+
+	if isNewItem {
+		if _, err := f.detector.Group(item); err != nil {
+			logrus.Warnf("Failed to group duplicate item: %v", err)
+		}
+		if err := f.mediaCache.StoreItem(item); err != nil {
+			logrus.Warnf("Failed to cache item images: %v", err)
+		}
+	}
+}
+
 // clampItemDate clamps a date to a reasonable range.
 // For future dates, clamps to firstSeen (when we first saw the item), or now() as fallback.
 // For very old dates, clamps to MinReasonableItemDate.
@@ -185,41 +440,77 @@ func clampItemDate(itemDate time.Time, firstSeen sql.NullTime) time.Time {
 	return itemDate
 }
 
+// processFeedItems saves a feed's items. With archiveMissing, stored items no
+// longer in the feed are archived; pushed content may only carry new items, so
+// it is processed without. Only the stored items the feed still lists are
+// loaded, and its items are saved in one transaction, so nothing is saved if
+// saving fails.
+//
 //nolint:cyclop // Complex feed processing logic requires multiple conditions
-func (f *Fetcher) processFeedItems(gofeedData *gofeed.Feed, feedURL string) (int, time.Time) {
-	activeGUIDs := []string{}
-	itemCount := 0
+func (f *Fetcher) processFeedItems(
+	gofeedData *gofeed.Feed, feedURL string, archiveMissing bool,
+) (itemCounts, time.Time, error) {
+	var counts itemCounts
 	var latestItemDate time.Time
-	var newItemURLs []string
 
 	maxItems := f.maxItems
-	if maxItems <= 0 {
+	if settings := f.feedSettings(feedURL); settings.MaxItems > 0 {
+		maxItems = settings.MaxItems
+	}
+	if maxItems <= 0 || maxItems > len(gofeedData.Items) {
 		maxItems = len(gofeedData.Items)
 	}
 
-	for i, gofeedItem := range gofeedData.Items {
-		if i >= maxItems {
-			break
-		}
-
-		item, err := database.ItemFromGofeed(gofeedItem, feedURL)
+	items := make([]*database.Item, maxItems)
+	guids := make([]string, 0, maxItems)
+	for i, gofeedItem := range gofeedData.Items[:maxItems] {
+		item, err := database.ItemFromGofeed(gofeedItem, feedURL, f.links.Canonicalize)
 		if err != nil {
 			logrus.Warnf("Failed to convert item: %v", err)
 			continue
 		}
+		items[i] = item
+		guids = append(guids, item.GUID)
+	}
 
-		// Check if this is a new item (before upserting)
-		isNewItem := f.isNewItem(feedURL, item.GUID)
+	stored, err := f.db.GetStoredItems(feedURL, guids)
+	if err != nil {
+		return counts, latestItemDate, fmt.Errorf("failed to load items: %w", err)
+	}
 
-		// Set first_seen timestamp for new items, or load it for existing items
+	var batch []*database.IngestItem
+	var pending []pendingItem
+	for i, item := range items {
+		if item == nil {
+			continue
+		}
+		gofeedItem := gofeedData.Items[i]
+
+		existing := stored[item.GUID]
+		isNewItem := existing == nil
+		item.Link = f.resolveLink(item, existing)
+
+		outcome := f.rules.Evaluate(item)
+		if outcome.Skip {
+			logrus.Debugf("Skipping item %s from %s by rules %v", item.GUID, feedURL, outcome.Matched)
+			continue
+		}
+		item.Highlighted = outcome.Highlight
+		item.Read = outcome.MarkRead && isNewItem
+
+		// Set first_seen timestamp for new items, or keep it for existing items
+		linkChanged, contentChanged := false, false
 		if isNewItem {
 			item.FirstSeen = sql.NullTime{Time: time.Now(), Valid: true}
 		} else {
-			// Load first_seen from database for existing items (needed as fallback)
-			existingFirstSeen, err := f.getItemFirstSeen(feedURL, item.GUID)
-			if err == nil && existingFirstSeen.Valid {
-				item.FirstSeen = existingFirstSeen
+			// first_seen of existing items is needed as fallback
+			if existing.FirstSeen.Valid {
+				item.FirstSeen = existing.FirstSeen
 			}
+			// Links stored before canonicalization change, and need unfurling again
+			linkChanged = existing.Link != item.Link
+			contentChanged = linkChanged || existing.Title != item.Title ||
+				existing.Content != item.Content || existing.Summary != item.Summary
 		}
 
 		// Track the latest item date based on published_date (clamped to reasonable range)
@@ -240,16 +531,42 @@ func (f *Fetcher) processFeedItems(gofeedData *gofeed.Feed, feedURL string) (int
 		}
 
 		item.Archived = false
-		if err := f.db.UpsertItem(item); err != nil {
-			logrus.Warnf("Failed to save item: %v", err)
-			continue
+		// A GUID repeated later in the feed updates this item, as if it were stored
+		stored[item.GUID] = &database.StoredItem{
+			FirstSeen: item.FirstSeen, Link: item.Link,
+			Title: item.Title, Content: item.Content, Summary: item.Summary,
 		}
 
-		activeGUIDs = append(activeGUIDs, item.GUID)
-		itemCount++
+		batch = append(batch, &database.IngestItem{
+			Item:       item,
+			Enclosures: database.EnclosuresFromGofeed(gofeedItem, item.Link),
+		})
+		pending = append(pending, pendingItem{
+			tags: outcome.Tags, isNew: isNewItem, linkChanged: linkChanged, contentChanged: contentChanged,
+		})
+	}
+
+	archived, err := f.db.SaveFeedItems(feedURL, batch, archiveMissing)
+	if err != nil {
+		return itemCounts{}, time.Time{}, fmt.Errorf("failed to save items: %w", err)
+	}
+	counts.archived = int(archived)
 
-		// If this is a new item and we have an unfurl queue, validate and enqueue the item URL
-		if isNewItem && f.unfurlQueue != nil && item.Link != "" {
+	// Annotating uses the database too, so waits until the items are saved
+	var newItemURLs []string
+	for i, ingest := range batch {
+		item, state := ingest.Item, pending[i]
+		f.annotateItem(item, state.tags, state.isNew)
+
+		counts.saved++
+		if state.isNew {
+			counts.added++
+		} else if state.contentChanged {
+			counts.updated++
+		}
+
+		// If this is a new item or link and we have an unfurl queue, validate and enqueue the item URL
+		if (state.isNew || state.linkChanged) && f.unfurlQueue != nil && item.Link != "" {
 			if f.isValidURL(item.Link) {
 				newItemURLs = append(newItemURLs, item.Link)
 			} else {
@@ -258,59 +575,86 @@ func (f *Fetcher) processFeedItems(gofeedData *gofeed.Feed, feedURL string) (int
 		}
 	}
 
-	// Enqueue new item URLs for unfurl processing
-	if len(newItemURLs) > 0 && f.unfurlQueue != nil {
-		// Filter out URLs that already have metadata
-		urlsNeedingUnfurl, err := f.filterURLsNeedingUnfurl(newItemURLs)
+	f.enqueueUnfurls(feedURL, newItemURLs)
+
+	return counts, latestItemDate, nil
+}
+
+// pendingItem is what to do with an item once it is saved.
+type pendingItem struct {
+	tags           []string
+	isNew          bool
+	linkChanged    bool
+	contentChanged bool
+}
+
+// enqueueUnfurls enqueues the links of a feed's new items for unfurl
+// processing.
+func (f *Fetcher) enqueueUnfurls(feedURL string, newItemURLs []string) {
+	if len(newItemURLs) == 0 || f.unfurlQueue == nil {
+		return
+	}
+
+	// Filter out URLs that already have metadata, unless the feed wants full
+	// text, which the unfurl queue extracts from pages cached without it
+	fullText := f.isFullTextFeed(feedURL)
+	urlsNeedingUnfurl := newItemURLs
+	if !fullText {
+		var err error
+		urlsNeedingUnfurl, err = f.filterURLsNeedingUnfurl(newItemURLs)
 		if err != nil {
 			logrus.Warnf("Error filtering URLs for unfurl: %v", err)
 			// Continue with all URLs if filtering fails
 			urlsNeedingUnfurl = newItemURLs
 		}
-
-		filteredCount := len(newItemURLs) - len(urlsNeedingUnfurl)
-		if filteredCount > 0 {
-			logrus.Debugf("Filtered %d items that already have metadata", filteredCount)
-		}
-
-		if len(urlsNeedingUnfurl) > 0 {
-			logrus.Debugf("Enqueuing %d new items for unfurl from feed %s", len(urlsNeedingUnfurl), feedURL)
-			for _, url := range urlsNeedingUnfurl {
-				f.unfurlQueue.Enqueue(unfurl.UnfurlJob{URL: url})
-			}
-			logrus.Infof("Enqueued %d items for unfurl", len(urlsNeedingUnfurl))
-		}
 	}
 
-	if err := f.db.MarkItemsArchived(feedURL, activeGUIDs); err != nil {
-		logrus.Warnf("Failed to mark archived items: %v", err)
+	filteredCount := len(newItemURLs) - len(urlsNeedingUnfurl)
+	if filteredCount > 0 {
+		logrus.Debugf("Filtered %d items that already have metadata", filteredCount)
 	}
 
-	return itemCount, latestItemDate
+	if len(urlsNeedingUnfurl) > 0 {
+		logrus.Debugf("Enqueuing %d new items for unfurl from feed %s", len(urlsNeedingUnfurl), feedURL)
+		for _, url := range urlsNeedingUnfurl {
+			f.unfurlQueue.Enqueue(unfurl.UnfurlJob{URL: url, FullText: fullText})
+		}
+		logrus.Infof("Enqueued %d items for unfurl", len(urlsNeedingUnfurl))
+	}
 }
 
-// isNewItem checks if an item with the given GUID already exists for the feed.
-func (f *Fetcher) isNewItem(feedURL, guid string) bool {
-	query := `SELECT COUNT(*) FROM items WHERE feed_url = ? AND guid = ?`
-	var count int
-	err := f.db.GetConnection().QueryRow(query, feedURL, guid).Scan(&count)
-	if err != nil {
-		logrus.Debugf("Error checking if item is new: %v", err)
-		return false // Assume not new if we can't check
+// resolveLink returns the link to store for an item. Links on redirector
+// hosts are followed to their target for new items, while existing items keep
+// the link stored when they were new, so each redirect is only followed once.
+// Redirects are followed with the feed's client, sharing its per-host limits,
+// proxy and TLS settings but not its credentials. stored is nil for new items.
+func (f *Fetcher) resolveLink(item *database.Item, stored *database.StoredItem) string {
+	if !f.links.IsRedirector(item.Link) {
+		return item.Link
+	}
+	if stored == nil {
+		client, err := f.clientFor(item.FeedURL, map[string]string{})
+		if err != nil {
+			logrus.Debugf("Failed to resolve redirect for %s: %v", item.Link, err)
+			return item.Link
+		}
+		return f.links.Resolve(item.Link, client)
+	}
+	if stored.Link == "" {
+		return item.Link
 	}
-	return count == 0
+	return stored.Link
 }
 
-// getItemFirstSeen retrieves the first_seen timestamp for an existing item.
-// This is used as a fallback when published_date is missing or invalid.
-func (f *Fetcher) getItemFirstSeen(feedURL, guid string) (sql.NullTime, error) {
-	query := `SELECT first_seen FROM items WHERE feed_url = ? AND guid = ?`
-	var firstSeen sql.NullTime
-	err := f.db.GetConnection().QueryRow(query, feedURL, guid).Scan(&firstSeen)
+// isFullTextFeed reports whether the article text of the feed's items should
+// be extracted when they are unfurled.
+func (f *Fetcher) isFullTextFeed(feedURL string) bool {
+	var fullText bool
+	err := f.db.GetConnection().QueryRow(`SELECT full_text FROM feeds WHERE url = ?`, feedURL).Scan(&fullText)
 	if err != nil {
-		return sql.NullTime{}, err
+		return false
 	}
-	return firstSeen, nil
+	return fullText
 }
 
 // filterURLsNeedingUnfurl filters URLs to only include those that don't already have metadata.
@@ -360,10 +704,14 @@ func (f *Fetcher) isValidURL(urlStr string) bool {
 	return true
 }
 
-func (f *Fetcher) handleCachedFeed(result *FetchResult, existingFeed *database.Feed) *FetchResult {
+func (f *Fetcher) handleCachedFeed(
+	result *FetchResult, existingFeed *database.Feed, header http.Header,
+) *FetchResult {
 	if existingFeed != nil {
 		existingFeed.LastFetchTime = time.Now()
 		existingFeed.LastSuccessfulFetch = time.Now()
+		existingFeed.ParkedUntil = sql.NullTime{}
+		f.scheduleNextFetch(existingFeed, header)
 		if upsertErr := f.db.UpsertFeed(existingFeed); upsertErr != nil {
 			logrus.WithError(upsertErr).Warn("Failed to update feed in database")
 		}
@@ -373,13 +721,23 @@ func (f *Fetcher) handleCachedFeed(result *FetchResult, existingFeed *database.F
 	return result
 }
 
-func (f *Fetcher) updateFeedError(feed *database.Feed, errorMsg string) {
-	if feed != nil {
-		feed.ErrorCount++
-		feed.LastError = errorMsg
-		feed.LastFetchTime = time.Now()
-		if err := f.db.UpsertFeed(feed); err != nil {
-			logrus.WithError(err).Warn("Failed to update feed error in database")
+// updateFeedError records a failed fetch. Feeds that have never been fetched
+// successfully get a row too, so their failures are backed off and reported.
+func (f *Fetcher) updateFeedError(feedURL string, feed *database.Feed, errorMsg string) {
+	if feed == nil {
+		feed = &database.Feed{URL: feedURL}
+	}
+
+	feed.ErrorCount++
+	feed.LastError = errorMsg
+	feed.LastFetchTime = time.Now()
+	f.applyErrorPolicy(feed)
+	if err := f.db.UpsertFeed(feed); err != nil {
+		logrus.WithError(err).Warn("Failed to update feed error in database")
+	}
+	if feed.Disabled {
+		if err := f.db.DisableFeed(feed.URL); err != nil {
+			logrus.WithError(err).Warn("Failed to disable feed in database")
 		}
 	}
 }
@@ -405,6 +763,19 @@ func FetchConcurrentWithUnfurl(
 	if unfurlQueue != nil {
 		fetcher.SetUnfurlQueue(unfurlQueue)
 	}
+	return fetchConcurrent(fetcher, urls, concurrency, maxAge, false)
+}
+
+// fetchConcurrent fetches urls with the given fetcher, at most concurrency at
+// a time. Disabled feeds and feeds parked by a rate-limiting server are always
+// skipped. Unless the fetcher is forced,
+// feeds fetched within maxAge are skipped, as are feeds whose scheduled next
+// fetch hasn't arrived yet when ignoreSchedule is false.
+func fetchConcurrent(
+	fetcher *Fetcher, urls []string, concurrency int, maxAge time.Duration, ignoreSchedule bool,
+) []*FetchResult {
+	db := fetcher.db
+	force := fetcher.forceFlag
 	results := make([]*FetchResult, len(urls))
 
 	sem := make(chan struct{}, concurrency)
@@ -434,9 +805,18 @@ func FetchConcurrentWithUnfurl(
 					completedCount++
 					percentage := int(float64(completedCount) / float64(len(urls)) * 100)
 
-					if event.result.Error != nil {
+					if event.result.Disabled {
+						logrus.Infof("Disabled %3d%% (%*d/%d) %s",
+							percentage, totalWidth, completedCount, len(urls), event.result.URL)
+					} else if event.result.Parked {
+						logrus.Infof("Parked   %3d%% (%*d/%d) %s",
+							percentage, totalWidth, completedCount, len(urls), event.result.URL)
+					} else if event.result.Error != nil {
 						logrus.Infof("Failed   %3d%% (%*d/%d) %s: %v",
 							percentage, totalWidth, completedCount, len(urls), event.result.URL, event.result.Error)
+					} else if event.result.MovedTo != "" {
+						logrus.Infof("Moved    %3d%% (%*d/%d) %s -> %s",
+							percentage, totalWidth, completedCount, len(urls), event.result.URL, event.result.MovedTo)
 					} else if event.result.Cached {
 						logrus.Infof("Cached   %3d%% (%*d/%d) %s",
 							percentage, totalWidth, completedCount, len(urls), event.result.URL)
@@ -465,9 +845,23 @@ func FetchConcurrentWithUnfurl(
 
 			var result *FetchResult
 
-			if maxAge > 0 && !force {
-				existingFeed, _ := db.GetFeed(feedURL)
-				if existingFeed != nil && time.Since(existingFeed.LastFetchTime) < maxAge {
+			existingFeed, _ := db.GetFeed(feedURL)
+			if existingFeed != nil && existingFeed.Disabled {
+				result = &FetchResult{
+					URL:      feedURL,
+					Feed:     existingFeed,
+					Disabled: true,
+				}
+			} else if isFeedParked(existingFeed, time.Now()) {
+				result = &FetchResult{
+					URL:    feedURL,
+					Feed:   existingFeed,
+					Parked: true,
+				}
+			} else if !force {
+				recent := maxAge > 0 && existingFeed != nil && time.Since(existingFeed.LastFetchTime) < maxAge
+				notDue := !ignoreSchedule && !isFeedDue(existingFeed, time.Now())
+				if recent || notDue {
 					result = &FetchResult{
 						URL:    feedURL,
 						Feed:   existingFeed,
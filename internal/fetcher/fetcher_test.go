@@ -1,6 +1,8 @@
 package fetcher
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -9,7 +11,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/lmorchard/feedspool-go/internal/config"
 	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/database/databasetest"
+	"github.com/lmorchard/feedspool-go/internal/feedlist"
+	"github.com/lmorchard/feedspool-go/internal/httpoverride"
+	"github.com/lmorchard/feedspool-go/internal/rules"
+	"github.com/sirupsen/logrus"
 )
 
 const testFeedXML = `<?xml version="1.0" encoding="UTF-8"?>
@@ -35,34 +43,8 @@ const testFeedXML = `<?xml version="1.0" encoding="UTF-8"?>
     </channel>
 </rss>`
 
-func setupTestDatabase(t *testing.T) *database.DB {
-	t.Helper()
-
-	// Create temporary database file
-	tmpDir := t.TempDir()
-	dbPath := filepath.Join(tmpDir, "fetcher_test.db")
-
-	// Initialize database
-	db, err := database.New(dbPath)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	if err := db.InitSchema(); err != nil {
-		t.Fatal(err)
-	}
-
-	// Cleanup function
-	t.Cleanup(func() {
-		db.Close()
-		os.Remove(dbPath)
-	})
-
-	return db
-}
-
 func TestNewFetcher(t *testing.T) {
-	db := setupTestDatabase(t)
+	db := databasetest.New(t)
 	timeout := 30 * time.Second
 	maxItems := 50
 	force := true
@@ -89,7 +71,7 @@ func TestNewFetcher(t *testing.T) {
 func TestFetchFeedSuccess(t *testing.T) {
 	const testETag = "test-etag"
 
-	db := setupTestDatabase(t)
+	db := databasetest.New(t)
 
 	// Create test server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -133,10 +115,134 @@ func TestFetchFeedSuccess(t *testing.T) {
 	}
 }
 
+func TestFetchFeedRules(t *testing.T) {
+	db := databasetest.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(testFeedXML))
+	}))
+	defer server.Close()
+
+	engine, err := rules.New([]config.RuleConfig{
+		{Title: "Item 1$", Action: rules.ActionSkip},
+		{Content: "/second/i", Action: rules.ActionTag, Tag: "second"},
+		{Link: "item2", Action: rules.ActionMarkRead},
+		{Feed: server.URL, Action: rules.ActionHighlight},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetcher := NewFetcher(db, 30*time.Second, 100, false)
+	fetcher.SetRules(engine)
+	result := fetcher.FetchFeed(server.URL)
+	if result.Error != nil {
+		t.Fatalf("FetchFeed() error = %v", result.Error)
+	}
+	if result.ItemCount != 1 {
+		t.Errorf("FetchFeed() ItemCount = %d, want 1 with Test Item 1 skipped", result.ItemCount)
+	}
+
+	items, err := db.ListItems(&database.ItemFilter{FeedURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].GUID != "item-2" {
+		t.Fatalf("stored items = %v, want only item-2", items)
+	}
+	if !items[0].Read || !items[0].Highlighted {
+		t.Errorf("item-2 read = %v, highlighted = %v, want both true", items[0].Read, items[0].Highlighted)
+	}
+
+	tagged, err := db.ListItems(&database.ItemFilter{Tag: "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tagged) != 1 {
+		t.Errorf("items tagged second = %d, want 1", len(tagged))
+	}
+}
+
+func TestFetchFeedEnclosures(t *testing.T) {
+	db := databasetest.New(t)
+
+	podcastXML := `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+    <channel>
+        <title>Test Podcast</title>
+        <link>https://example.com</link>
+        <item>
+            <title>Episode 1</title>
+            <link>https://example.com/episodes/1</link>
+            <guid>episode-1</guid>
+            <enclosure url="https://cdn.example.com/1.mp3" length="1234" type="audio/mpeg"/>
+            <itunes:duration>12:34</itunes:duration>
+        </item>
+    </channel>
+</rss>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(podcastXML))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(db, 30*time.Second, 100, false)
+	if result := fetcher.FetchFeed(server.URL); result.Error != nil {
+		t.Fatalf("FetchFeed() error = %v", result.Error)
+	}
+
+	enclosures, err := db.GetItemEnclosures([]string{server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enclosures) != 1 {
+		t.Fatalf("stored enclosures = %d, want 1", len(enclosures))
+	}
+	got := enclosures[0]
+	if got.URL != "https://cdn.example.com/1.mp3" || got.Type != "audio/mpeg" || got.Length != 1234 ||
+		got.Duration != "12:34" || got.ItemTitle != "Episode 1" {
+		t.Errorf("stored enclosure = %+v", got)
+	}
+}
+
+func TestFetchFeedOverrides(t *testing.T) {
+	db := databasetest.New(t)
+	t.Setenv("TEST_FEED_PASSWORD", "hunter2")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "reader" || password != "hunter2" || r.UserAgent() != "PrivateReader/1.0" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(testFeedXML))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(db, 30*time.Second, 100, false)
+	if result := fetcher.FetchFeed(server.URL); result.Error == nil {
+		t.Fatal("FetchFeed() without overrides error = nil, want unauthorized")
+	}
+
+	overrides, err := httpoverride.New([]config.HTTPOverrideConfig{
+		{Host: "127.0.0.1", UserAgent: "PrivateReader/1.0"},
+		{Feed: server.URL, Username: "reader", Password: "env:TEST_FEED_PASSWORD"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetcher.SetOverrides(overrides)
+	if result := fetcher.FetchFeed(server.URL); result.Error != nil || result.ItemCount != 2 {
+		t.Errorf("FetchFeed() with overrides = %d items, %v; want 2 items", result.ItemCount, result.Error)
+	}
+}
+
 func TestFetchFeedNotModified(t *testing.T) {
 	const testETag = "test-etag"
 
-	db := setupTestDatabase(t)
+	db := databasetest.New(t)
 
 	// Create test server that returns 304 for conditional requests
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -179,10 +285,132 @@ func TestFetchFeedNotModified(t *testing.T) {
 	if result.ItemCount != 0 {
 		t.Errorf("FetchFeed() ItemCount = %v, want 0 for cached response", result.ItemCount)
 	}
+
+	entries, err := db.GetFetchLog(server.URL, time.Time{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].StatusCode != http.StatusNotModified || !entries[0].Cached {
+		t.Errorf("GetFetchLog() = %+v, want one cached 304", entries)
+	}
+}
+
+func TestFetchFeedSaveFailure(t *testing.T) {
+	const testETag = "test-etag"
+
+	db := databasetest.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == testETag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Header().Set("ETag", testETag)
+		w.Write([]byte(testFeedXML))
+	}))
+	defer server.Close()
+
+	// Fail the second item, after the first is written
+	_, err := db.GetConnection().Exec(`CREATE TRIGGER fail_item BEFORE INSERT ON items
+		WHEN NEW.title = 'Test Item 2' BEGIN SELECT RAISE(ABORT, 'disk I/O error'); END`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetcher := NewFetcher(db, 30*time.Second, 100, false)
+	result := fetcher.FetchFeed(server.URL)
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "failed to save items") {
+		t.Fatalf("FetchFeed() error = %v, want the failed save", result.Error)
+	}
+
+	feed, err := db.GetFeed(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.ETag != "" || feed.ErrorCount != 1 {
+		t.Errorf("feed ETag = %q, ErrorCount = %d, want no ETag and one error", feed.ETag, feed.ErrorCount)
+	}
+	if items, _ := db.GetItemsForFeed(server.URL, 0, time.Time{}, time.Time{}); len(items) != 0 {
+		t.Errorf("%d items saved, want none from a failed save", len(items))
+	}
+
+	// Failures add up, rather than each one being counted as the first
+	fetcher.FetchFeed(server.URL)
+	feed, err = db.GetFeed(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.ErrorCount != 2 || !feed.LastSuccessfulFetch.IsZero() {
+		t.Errorf("feed ErrorCount = %d, LastSuccessfulFetch = %v, want two errors and no success",
+			feed.ErrorCount, feed.LastSuccessfulFetch)
+	}
+
+	// Without the ETag, the next fetch gets the items again
+	if _, err := db.GetConnection().Exec("DROP TRIGGER fail_item"); err != nil {
+		t.Fatal(err)
+	}
+	result = fetcher.FetchFeed(server.URL)
+	if result.Error != nil || result.Cached || result.NewItems != 2 {
+		t.Errorf("FetchFeed() after the failure = %+v, want both items new", result)
+	}
+}
+
+func TestFetchFeedRecordsFetchLog(t *testing.T) {
+	db := databasetest.New(t)
+
+	feedXML := testFeedXML
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(feedXML))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(db, 30*time.Second, 100, true)
+	if result := fetcher.FetchFeed(server.URL); result.Error != nil {
+		t.Fatalf("FetchFeed() error = %v", result.Error)
+	}
+
+	// Drop the first item and retitle the second
+	feedXML = strings.Replace(testFeedXML, "Test Item 2", "Retitled Item 2", 1)
+	feedXML = feedXML[:strings.Index(feedXML, "<item>")] + feedXML[strings.Index(feedXML, "</item>")+len("</item>"):]
+	result := fetcher.FetchFeed(server.URL)
+	if result.Error != nil {
+		t.Fatalf("FetchFeed() error = %v", result.Error)
+	}
+	if result.NewItems != 0 || result.UpdatedItems != 1 || result.ArchivedItems != 1 {
+		t.Errorf("FetchFeed() counts = %d new, %d updated, %d archived; want 0, 1, 1",
+			result.NewItems, result.UpdatedItems, result.ArchivedItems)
+	}
+
+	entries, err := db.GetFetchLog(server.URL, time.Time{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("GetFetchLog() = %+v, want two attempts", entries)
+	}
+	first := entries[1]
+	if first.StatusCode != http.StatusOK || first.Bytes != int64(len(testFeedXML)) || first.NewItems != 2 ||
+		first.Cached || first.Error != "" {
+		t.Errorf("first attempt = %+v, want 200 with %d bytes and 2 new items", first, len(testFeedXML))
+	}
+	if entries[0].UpdatedItems != 1 || entries[0].ArchivedItems != 1 {
+		t.Errorf("second attempt = %+v, want 1 updated and 1 archived", entries[0])
+	}
+
+	server.Close()
+	if result := fetcher.FetchFeed(server.URL); result.Error == nil {
+		t.Fatal("FetchFeed() of a closed server succeeded")
+	}
+	entries, _ = db.GetFetchLog(server.URL, time.Time{}, 1)
+	if len(entries) != 1 || entries[0].Error == "" || entries[0].StatusCode != 0 {
+		t.Errorf("GetFetchLog() = %+v, want the failed attempt with its error", entries)
+	}
 }
 
 func TestFetchFeedHTTPError(t *testing.T) {
-	db := setupTestDatabase(t)
+	db := databasetest.New(t)
 
 	// Create test server that returns 404
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -203,7 +431,7 @@ func TestFetchFeedHTTPError(t *testing.T) {
 }
 
 func TestFetchFeedInvalidXML(t *testing.T) {
-	db := setupTestDatabase(t)
+	db := databasetest.New(t)
 
 	// Create test server that returns invalid XML
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -226,7 +454,7 @@ func TestFetchFeedInvalidXML(t *testing.T) {
 }
 
 func TestFetchFeedMaxItems(t *testing.T) {
-	db := setupTestDatabase(t)
+	db := databasetest.New(t)
 
 	// Create test server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -249,8 +477,70 @@ func TestFetchFeedMaxItems(t *testing.T) {
 	}
 }
 
+func TestFetchFromFileAppliesListSettings(t *testing.T) {
+	db := databasetest.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(testFeedXML))
+	}))
+	defer server.Close()
+
+	filename := filepath.Join(t.TempDir(), "feeds.yaml")
+	writeList := func(settings string) {
+		t.Helper()
+		content := "feeds:\n  - url: " + server.URL + "\n" + settings
+		if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fetch := func() *FetchResult {
+		t.Helper()
+		orchestrator := NewOrchestrator(db, config.GetDefault())
+		results, err := orchestrator.FetchFromFile(context.Background(), feedlist.FormatYAML, filename, FetchOptions{
+			Timeout:        30 * time.Second,
+			MaxItems:       100,
+			Force:          true,
+			IgnoreSchedule: true,
+			Concurrency:    1,
+		})
+		if err != nil || len(results) != 1 || results[0].Error != nil {
+			t.Fatalf("FetchFromFile() = %+v, %v", results, err)
+		}
+		return results[0]
+	}
+
+	// The list's settings apply to the first fetch of a new feed
+	writeList("    title: Mine\n    max_items: 1\n    interval: 3h\n")
+	before := time.Now()
+	if result := fetch(); result.ItemCount != 1 {
+		t.Errorf("ItemCount = %d, want 1 (limited by the list's max_items)", result.ItemCount)
+	}
+	feed, err := db.GetFeed(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := database.FeedSettings{TitleOverride: "Mine", MaxItems: 1, FetchInterval: 3 * time.Hour}
+	if feed.FeedSettings != want {
+		t.Errorf("saved settings = %+v, want %+v", feed.FeedSettings, want)
+	}
+	if got := feed.NextFetchAt.Time.Sub(before); got < 3*time.Hour-time.Minute || got > 3*time.Hour+time.Minute {
+		t.Errorf("next fetch in %v, want the list's 3h interval", got)
+	}
+
+	// Settings dropped from the list are cleared on the next fetch
+	writeList("")
+	if result := fetch(); result.ItemCount != 2 {
+		t.Errorf("ItemCount = %d, want 2 once max_items is dropped", result.ItemCount)
+	}
+	if settings, _ := db.GetFeedSettings(server.URL); settings != (database.FeedSettings{}) {
+		t.Errorf("settings after dropping them = %+v, want none", settings)
+	}
+}
+
 func TestFetchFeedForce(t *testing.T) {
-	db := setupTestDatabase(t)
+	db := databasetest.New(t)
 
 	requestCount := 0
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -299,7 +589,7 @@ func TestFetchFeedForce(t *testing.T) {
 }
 
 func TestFetchConcurrent(t *testing.T) {
-	db := setupTestDatabase(t)
+	db := databasetest.New(t)
 
 	// Create test servers
 	server1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
@@ -359,7 +649,7 @@ func TestFetchConcurrent(t *testing.T) {
 }
 
 func TestFetchConcurrentWithMaxAge(t *testing.T) {
-	db := setupTestDatabase(t)
+	db := databasetest.New(t)
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.Header().Set("Content-Type", "application/rss+xml")
@@ -398,3 +688,70 @@ func TestFetchConcurrentWithMaxAge(t *testing.T) {
 		t.Errorf("FetchConcurrent() should skip recently fetched feed (cached=true)")
 	}
 }
+
+// benchmarkFeedXML builds a feed with the given number of items.
+func benchmarkFeedXML(feed, items int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"><channel><title>Feed %d</title><link>https://example.com/%d</link>`, feed, feed)
+	for i := 0; i < items; i++ {
+		fmt.Fprintf(&b, `<item><title>Item %d</title><link>https://example.com/%d/%d</link>
+<guid>https://example.com/%d/%d</guid><description>%s</description>
+<pubDate>Mon, 01 Jan 2024 12:00:00 GMT</pubDate></item>`,
+			i, feed, i, feed, i, strings.Repeat("Some item content. ", 20))
+	}
+	b.WriteString(`</channel></rss>`)
+	return b.String()
+}
+
+// BenchmarkFetchConcurrent fetches 500 feeds of 25 items each, 32 at a time,
+// into an empty database and again into one that already has their items.
+func BenchmarkFetchConcurrent(b *testing.B) {
+	const feeds, items = 500, 25
+
+	level := logrus.GetLevel()
+	logrus.SetLevel(logrus.WarnLevel)
+	b.Cleanup(func() { logrus.SetLevel(level) })
+
+	bodies := make(map[string]string, feeds)
+	urls := make([]string, feeds)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(bodies[r.URL.Path]))
+	}))
+	defer server.Close()
+	for i := range urls {
+		path := fmt.Sprintf("/feed/%d", i)
+		bodies[path] = benchmarkFeedXML(i, items)
+		urls[i] = server.URL + path
+	}
+
+	fetchAll := func(b *testing.B, db *database.DB) {
+		b.Helper()
+		fetcher := NewFetcher(db, 30*time.Second, 0, true)
+		fetcher.SetHostLimits(0, 0)
+		for _, result := range fetchConcurrent(fetcher, urls, 32, 0, true) {
+			if result.Error != nil {
+				b.Fatalf("fetch of %s failed: %v", result.URL, result.Error)
+			}
+		}
+	}
+
+	b.Run("new", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			db := databasetest.New(b)
+			b.StartTimer()
+			fetchAll(b, db)
+		}
+	})
+
+	b.Run("refetch", func(b *testing.B) {
+		db := databasetest.New(b)
+		fetchAll(b, db)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			fetchAll(b, db)
+		}
+	})
+}
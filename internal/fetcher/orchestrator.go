@@ -7,26 +7,36 @@ import (
 
 	"github.com/lmorchard/feedspool-go/internal/config"
 	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/dedupe"
 	"github.com/lmorchard/feedspool-go/internal/feedlist"
+	"github.com/lmorchard/feedspool-go/internal/httpoverride"
+	"github.com/lmorchard/feedspool-go/internal/media"
+	"github.com/lmorchard/feedspool-go/internal/rules"
 	"github.com/lmorchard/feedspool-go/internal/unfurl"
+	"github.com/lmorchard/feedspool-go/internal/urlcanon"
 	"github.com/sirupsen/logrus"
 )
 
 // FetchOptions contains all configuration options for fetch operations.
 type FetchOptions struct {
-	Timeout       time.Duration
-	MaxItems      int
-	MaxAge        time.Duration
-	Force         bool
-	Concurrency   int
-	WithUnfurl    bool
-	RemoveMissing bool
+	Timeout        time.Duration
+	MaxItems       int
+	MaxAge         time.Duration
+	Force          bool
+	IgnoreSchedule bool
+	Concurrency    int
+	WithUnfurl     bool
+	RemoveMissing  bool
+
+	listSettings map[string]database.FeedSettings // Per-feed settings of the feed list being fetched
 }
 
 // Orchestrator handles high-level fetch operations with unfurl integration.
 type Orchestrator struct {
-	db     *database.DB
-	config *config.Config
+	db        *database.DB
+	config    *config.Config
+	rules     *rules.Engine
+	overrides *httpoverride.Set
 }
 
 // NewOrchestrator creates a new fetch orchestrator.
@@ -37,16 +47,22 @@ func NewOrchestrator(db *database.DB, cfg *config.Config) *Orchestrator {
 	}
 }
 
+// SetRules sets the rules applied to fetched items.
+func (o *Orchestrator) SetRules(engine *rules.Engine) {
+	o.rules = engine
+}
+
+// SetOverrides sets the per-feed and per-host changes made to feed requests.
+func (o *Orchestrator) SetOverrides(overrides *httpoverride.Set) {
+	o.overrides = overrides
+}
+
 // FetchSingle executes a single URL fetch with optional unfurl.
 func (o *Orchestrator) FetchSingle(ctx context.Context, feedURL string, opts FetchOptions) (*FetchResult, error) {
 	unfurlQueue := o.createUnfurlQueue(ctx, opts.WithUnfurl)
 	defer o.cleanupUnfurlQueue(ctx, unfurlQueue)
 
-	fetcher := NewFetcher(o.db, opts.Timeout, opts.MaxItems, opts.Force)
-	if unfurlQueue != nil {
-		fetcher.SetUnfurlQueue(unfurlQueue)
-	}
-
+	fetcher := o.newFetcher(opts, unfurlQueue)
 	result := fetcher.FetchFeed(feedURL)
 
 	o.awaitUnfurlCompletion(ctx, unfurlQueue)
@@ -76,8 +92,22 @@ func (o *Orchestrator) FetchFromFile(
 		logrus.Infof("Found %d feeds in %s", len(feedURLs), filename)
 	}
 
+	// Apply the list's settings before fetching, so changes to them take
+	// effect on this run
+	o.syncFeedSettings(list)
+	opts.listSettings = list.GetSettings()
+
 	results := o.fetchConcurrentWithUnfurl(ctx, feedURLs, opts)
 
+	// Point the list at feeds that moved permanently, before any removal
+	// would otherwise delete them as missing
+	if o.updateMovedFeeds(list, filename, results) {
+		feedURLs = list.GetURLs()
+	}
+
+	o.syncFeedTags(list)
+	o.syncFeedSettings(list)
+
 	// Handle feed removal if requested
 	if opts.RemoveMissing {
 		removedCount := o.removeMissingFeeds(feedURLs)
@@ -124,23 +154,33 @@ func (o *Orchestrator) fetchConcurrentWithUnfurl(
 	unfurlQueue := o.createUnfurlQueue(ctx, opts.WithUnfurl)
 	defer o.cleanupUnfurlQueue(ctx, unfurlQueue)
 
-	// Fetch feeds concurrently
-	results := FetchConcurrentWithUnfurl(
-		o.db,
-		feedURLs,
-		opts.Concurrency,
-		opts.Timeout,
-		opts.MaxItems,
-		opts.MaxAge,
-		opts.Force,
-		unfurlQueue,
-	)
+	// Fetch feeds concurrently, skipping feeds that aren't due yet
+	fetcher := o.newFetcher(opts, unfurlQueue)
+	results := fetchConcurrent(fetcher, feedURLs, opts.Concurrency, opts.MaxAge, opts.IgnoreSchedule)
 
 	o.awaitUnfurlCompletion(ctx, unfurlQueue)
 
 	return results
 }
 
+// newFetcher creates a fetcher configured from the fetch options and config.
+func (o *Orchestrator) newFetcher(opts FetchOptions, unfurlQueue *unfurl.UnfurlQueue) *Fetcher {
+	fetcher := NewFetcher(o.db, opts.Timeout, opts.MaxItems, opts.Force)
+	fetcher.SetScheduleBounds(o.config.Fetch.MinInterval, o.config.Fetch.MaxInterval)
+	fetcher.SetDisableAfter(o.config.Fetch.DisableAfter)
+	fetcher.SetHostLimits(o.config.Fetch.PerHostConcurrency, o.config.Fetch.PerHostRate)
+	fetcher.SetRules(o.rules)
+	fetcher.SetOverrides(o.overrides)
+	fetcher.SetListSettings(opts.listSettings)
+	fetcher.SetDetector(o.detector())
+	fetcher.SetCanonicalizer(urlcanon.New(o.config.Links))
+	fetcher.SetMediaCache(media.New(o.config.Media, o.db, opts.Timeout))
+	if unfurlQueue != nil {
+		fetcher.SetUnfurlQueue(unfurlQueue)
+	}
+	return fetcher
+}
+
 // createUnfurlQueue creates and starts an unfurl queue if needed.
 func (o *Orchestrator) createUnfurlQueue(ctx context.Context, withUnfurl bool) *unfurl.UnfurlQueue {
 	if !withUnfurl {
@@ -172,6 +212,7 @@ func (o *Orchestrator) createUnfurlQueue(ctx context.Context, withUnfurl bool) *
 		o.config.Unfurl.SkipRobots,
 		o.config.Unfurl.RetryAfter,
 	)
+	queue.SetMediaCache(media.New(o.config.Media, o.db, o.config.Timeout))
 	queue.Start()
 
 	return queue
@@ -201,10 +242,33 @@ func (o *Orchestrator) awaitUnfurlCompletion(ctx context.Context, unfurlQueue *u
 		finalEnqueued, finalProcessed := unfurlQueue.Stats()
 		if finalEnqueued > 0 {
 			logrus.Infof("All operations completed: %d unfurl operations processed", finalProcessed)
+			o.groupUnfurled(unfurlQueue.Unfurled())
 		}
 	}
 }
 
+// detector returns the duplicate detector from the dedupe config, or nil when
+// deduplication is disabled.
+func (o *Orchestrator) detector() *dedupe.Detector {
+	if !o.config.Dedupe.Enabled {
+		return nil
+	}
+	return dedupe.New(o.db, o.config.Dedupe.Titles)
+}
+
+// groupUnfurled regroups the items linking to the unfurled URLs, now that
+// their canonical URLs are known.
+func (o *Orchestrator) groupUnfurled(links []string) {
+	grouped, err := o.detector().GroupLinks(links)
+	if err != nil {
+		logrus.Warnf("Failed to group unfurled items: %v", err)
+		return
+	}
+	if grouped > 0 {
+		logrus.Infof("Grouped %d unfurled items with their duplicates", grouped)
+	}
+}
+
 // cleanupUnfurlQueue ensures proper cleanup of unfurl queue resources.
 func (o *Orchestrator) cleanupUnfurlQueue(ctx context.Context, unfurlQueue *unfurl.UnfurlQueue) {
 	if unfurlQueue != nil && ctx.Err() != nil {
@@ -231,6 +295,78 @@ func (o *Orchestrator) validateUnfurlConfig() error {
 	return nil
 }
 
+// updateMovedFeeds rewrites feed list entries for feeds that moved to a new
+// URL and saves the list. Returns true if any entries changed.
+func (o *Orchestrator) updateMovedFeeds(list feedlist.FeedList, filename string, results []*FetchResult) bool {
+	moved := false
+	for _, result := range results {
+		if result.MovedTo == "" {
+			continue
+		}
+		if err := list.ReplaceURL(result.URL, result.MovedTo); err != nil {
+			logrus.Warnf("Failed to update %s in feed list: %v", result.URL, err)
+			continue
+		}
+		moved = true
+	}
+
+	if !moved {
+		return false
+	}
+
+	if err := list.Save(filename); err != nil {
+		logrus.Warnf("Failed to save feed list %s: %v", filename, err)
+	} else {
+		logrus.Infof("Updated moved feeds in %s", filename)
+	}
+	return true
+}
+
+// syncFeedTags saves the folder tags from the feed list to the database, so
+// feeds moved between folders are retagged. Lists without folders leave tags
+// alone, and feeds not yet in the database are skipped.
+func (o *Orchestrator) syncFeedTags(list feedlist.FeedList) {
+	tags := list.GetTags()
+	if tags == nil {
+		return
+	}
+
+	existingURLs, err := o.db.GetFeedURLs()
+	if err != nil {
+		logrus.Warnf("Failed to get existing feeds: %v", err)
+		return
+	}
+	existing := make(map[string]bool)
+	for _, url := range existingURLs {
+		existing[url] = true
+	}
+
+	for _, url := range list.GetURLs() {
+		if !existing[url] {
+			continue
+		}
+		if err := o.db.SetFeedTags(url, tags[url]); err != nil {
+			logrus.Warnf("Failed to save tags for %s: %v", url, err)
+		}
+	}
+}
+
+// syncFeedSettings saves the per-feed settings from the feed list to the
+// database, clearing any the list no longer sets. Lists without settings
+// leave them alone, and feeds not yet in the database are skipped.
+func (o *Orchestrator) syncFeedSettings(list feedlist.FeedList) {
+	settings := list.GetSettings()
+	if settings == nil {
+		return
+	}
+
+	for url, feedSettings := range settings {
+		if _, err := o.db.SetFeedSettings(url, feedSettings); err != nil {
+			logrus.Warnf("Failed to save settings for %s: %v", url, err)
+		}
+	}
+}
+
 // removeMissingFeeds removes feeds from database that are not in the provided URL list.
 func (o *Orchestrator) removeMissingFeeds(feedURLs []string) int {
 	existingURLs, err := o.db.GetFeedURLs()
@@ -0,0 +1,125 @@
+package fetcher
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// ParseRetryAfter parses a Retry-After header value, given either as a number
+// of seconds or as an HTTP date, into a delay from now.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if retryAt, err := http.ParseTime(value); err == nil {
+		if !retryAt.After(now) {
+			return 0, true
+		}
+		return retryAt.Sub(now), true
+	}
+
+	return 0, false
+}
+
+// isRateLimited reports whether an HTTP status asks the client to back off.
+func isRateLimited(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// isFeedParked reports whether a feed is parked until a time still in the future.
+func isFeedParked(feed *database.Feed, now time.Time) bool {
+	return feed != nil && feed.ParkedUntil.Valid && now.Before(feed.ParkedUntil.Time)
+}
+
+// retryDelay returns how long to park a feed after a rate-limited response:
+// the server's Retry-After when given, otherwise the minimum fetch interval,
+// capped at the maximum fetch interval.
+func (f *Fetcher) retryDelay(header http.Header) time.Duration {
+	delay, ok := ParseRetryAfter(header.Get("Retry-After"), time.Now())
+	if !ok || delay <= 0 {
+		delay = f.minInterval
+	}
+	if f.maxInterval > 0 && delay > f.maxInterval {
+		delay = f.maxInterval
+	}
+	return delay
+}
+
+// parkHost records that feedURL's host asked us to back off until the given
+// time, so other feeds on that host are parked without a request for the
+// rest of this fetcher's run.
+func (f *Fetcher) parkHost(feedURL string, until time.Time) {
+	host := feedHost(feedURL)
+	if host == "" {
+		return
+	}
+
+	f.parkedMu.Lock()
+	defer f.parkedMu.Unlock()
+	if until.After(f.parkedHosts[host]) {
+		f.parkedHosts[host] = until
+	}
+}
+
+// hostParkedUntil returns when feedURL's host may be requested again, if it
+// is currently parked.
+func (f *Fetcher) hostParkedUntil(feedURL string) (time.Time, bool) {
+	f.parkedMu.Lock()
+	defer f.parkedMu.Unlock()
+
+	until, ok := f.parkedHosts[feedHost(feedURL)]
+	if !ok || !time.Now().Before(until) {
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// parkFeed stores that a feed must not be fetched again until the given
+// time. Parking is not counted as a fetch error, so it never disables a feed.
+func (f *Fetcher) parkFeed(
+	result *FetchResult, feedURL string, existingFeed *database.Feed, until time.Time, reason string,
+) *FetchResult {
+	feed := existingFeed
+	if feed == nil {
+		feed = &database.Feed{URL: feedURL}
+	}
+
+	feed.ParkedUntil = sql.NullTime{Time: until, Valid: true}
+	feed.NextFetchAt = feed.ParkedUntil
+	feed.LastError = reason
+	if err := f.db.UpsertFeed(feed); err != nil {
+		logrus.WithError(err).Warn("Failed to park feed in database")
+	}
+
+	logrus.Debugf("Parked %s until %s: %s", feedURL, until.Format(time.RFC3339), reason)
+	result.Feed = feed
+	result.Parked = true
+	result.Error = fmt.Errorf("%s; parked until %s", reason, until.Format(time.RFC3339))
+	return result
+}
+
+// feedHost returns the host part of a feed URL, or "" if it can't be parsed.
+func feedHost(feedURL string) string {
+	parsed, err := url.Parse(feedURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
@@ -0,0 +1,93 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database/databasetest"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"empty", "", 0, false},
+		{"seconds", "120", 2 * time.Minute, true},
+		{"negative seconds", "-5", 0, false},
+		{"http date", now.Add(time.Hour).Format(http.TimeFormat), time.Hour, true},
+		{"past date", now.Add(-time.Hour).Format(http.TimeFormat), 0, true},
+		{"garbage", "soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseRetryAfter(tt.value, now)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ParseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFetchConcurrentParksRateLimitedHost(t *testing.T) {
+	db := databasetest.New(t)
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	urls := []string{server.URL + "/a", server.URL + "/b"}
+
+	fetcher := NewFetcher(db, 30*time.Second, 100, false)
+	fetcher.SetScheduleBounds(15*time.Minute, 24*time.Hour)
+
+	results := fetchConcurrent(fetcher, urls, 1, 0, false)
+	for _, result := range results {
+		if !result.Parked {
+			t.Errorf("%s: Parked = false, want true", result.URL)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("server received %d requests, want 1 once the host was parked", n)
+	}
+
+	summary := ProcessResults(results)
+	if summary.Parked != 2 || summary.Errors != 0 {
+		t.Errorf("summary parked=%d errors=%d, want 2 and 0", summary.Parked, summary.Errors)
+	}
+
+	for _, url := range urls {
+		feed, err := db.GetFeed(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !feed.ParkedUntil.Valid || time.Until(feed.ParkedUntil.Time) < 59*time.Minute {
+			t.Errorf("%s: parked until %v, want about an hour from now", url, feed.ParkedUntil)
+		}
+		if feed.ErrorCount != 0 {
+			t.Errorf("%s: error count = %d, parking should not count as an error", url, feed.ErrorCount)
+		}
+	}
+
+	// A later run honors the stored parking, even when forced
+	forced := NewFetcher(db, 30*time.Second, 100, true)
+	results = fetchConcurrent(forced, urls, 1, 0, true)
+	if !results[0].Parked || !results[1].Parked {
+		t.Error("fetchConcurrent() should skip parked feeds")
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("server received %d requests, want no more for parked feeds", n)
+	}
+}
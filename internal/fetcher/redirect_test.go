@@ -0,0 +1,139 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/database/databasetest"
+	"github.com/lmorchard/feedspool-go/internal/feedlist"
+)
+
+func newRedirectServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusPermanentRedirect)
+	})
+	mux.HandleFunc("/temporary", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusFound)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(testFeedXML))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetchFeedFollowsPermanentRedirect(t *testing.T) {
+	db := databasetest.New(t)
+	server := newRedirectServer(t)
+
+	oldURL := server.URL + "/old"
+	newURL := server.URL + "/new"
+
+	fetcher := NewFetcher(db, 30*time.Second, 100, false)
+
+	// Seed the old URL as if the feed had been fetched before it moved
+	if err := db.UpsertFeed(&database.Feed{URL: oldURL, FeedJSON: database.JSON(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpsertItem(&database.Item{FeedURL: oldURL, GUID: "old-item", ItemJSON: database.JSON(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	result := fetcher.FetchFeed(oldURL)
+	if result.Error != nil {
+		t.Fatalf("FetchFeed() error = %v", result.Error)
+	}
+	if result.MovedTo != newURL {
+		t.Fatalf("FetchFeed() MovedTo = %q, want %q", result.MovedTo, newURL)
+	}
+
+	if feed, _ := db.GetFeed(oldURL); feed != nil {
+		t.Error("old feed should have been migrated")
+	}
+	items, err := db.GetItemsForFeed(newURL, 0, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The old item is archived but kept, alongside the two fetched items
+	if len(items) != 3 {
+		t.Errorf("GetItemsForFeed(new) returned %d items, want 3", len(items))
+	}
+}
+
+func TestFetchFeedIgnoresTemporaryRedirect(t *testing.T) {
+	db := databasetest.New(t)
+	server := newRedirectServer(t)
+
+	feedURL := server.URL + "/temporary"
+	fetcher := NewFetcher(db, 30*time.Second, 100, false)
+
+	result := fetcher.FetchFeed(feedURL)
+	if result.Error != nil {
+		t.Fatalf("FetchFeed() error = %v", result.Error)
+	}
+	if result.MovedTo != "" {
+		t.Errorf("FetchFeed() MovedTo = %q, want empty for a 302", result.MovedTo)
+	}
+	if feed, _ := db.GetFeed(feedURL); feed == nil {
+		t.Error("feed should stay under its original URL")
+	}
+}
+
+func TestFetchFromFileRewritesMovedFeeds(t *testing.T) {
+	db := databasetest.New(t)
+	server := newRedirectServer(t)
+
+	oldURL := server.URL + "/old"
+	newURL := server.URL + "/new"
+
+	filename := filepath.Join(t.TempDir(), "feeds.txt")
+	if err := os.WriteFile(filename, []byte(oldURL+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	orchestrator := NewOrchestrator(db, config.GetDefault())
+	results, err := orchestrator.FetchFromFile(context.Background(), feedlist.FormatText, filename, FetchOptions{
+		Timeout:       30 * time.Second,
+		MaxItems:      100,
+		Concurrency:   1,
+		RemoveMissing: true,
+	})
+	if err != nil {
+		t.Fatalf("FetchFromFile() error = %v", err)
+	}
+
+	summary := ProcessResults(results)
+	if len(summary.MovedFeeds) != 1 || summary.MovedFeeds[0].To != newURL {
+		t.Errorf("summary MovedFeeds = %+v, want move to %s", summary.MovedFeeds, newURL)
+	}
+
+	list, err := feedlist.LoadFeedList(feedlist.FormatText, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if urls := list.GetURLs(); len(urls) != 1 || urls[0] != newURL {
+		t.Errorf("feed list URLs = %v, want [%s]", urls, newURL)
+	}
+
+	// The moved feed must survive --remove-missing
+	if feed, _ := db.GetFeed(newURL); feed == nil {
+		t.Error("moved feed was removed as missing")
+	}
+}
@@ -15,8 +15,19 @@ type FetchSummary struct {
 	Successful   int    `json:"successful"`
 	Errors       int    `json:"errors"`
 	Cached       int    `json:"cached"`
+	Disabled     int    `json:"disabled,omitempty"`
+	Parked       int    `json:"parked,omitempty"`
 	TotalItems   int    `json:"totalItems"`
+	NewItems     int    `json:"newItems"`
 	RemovedFeeds int    `json:"removedFeeds,omitempty"`
+
+	MovedFeeds []FeedMove `json:"movedFeeds,omitempty"`
+}
+
+// FeedMove records a feed migrated to a new URL after a permanent redirect.
+type FeedMove struct {
+	From string `json:"from"`
+	To   string `json:"to"`
 }
 
 // ProcessResults analyzes fetch results and returns summary statistics.
@@ -26,13 +37,22 @@ func ProcessResults(results []*FetchResult) FetchSummary {
 	}
 
 	for _, result := range results {
-		if result.Error != nil {
+		if result.Parked {
+			summary.Parked++
+		} else if result.Error != nil {
 			summary.Errors++
+		} else if result.Disabled {
+			summary.Disabled++
 		} else if result.Cached {
 			summary.Cached++
 		} else {
 			summary.Successful++
 			summary.TotalItems += result.ItemCount
+			summary.NewItems += result.NewItems
+		}
+
+		if result.MovedTo != "" {
+			summary.MovedFeeds = append(summary.MovedFeeds, FeedMove{From: result.URL, To: result.MovedTo})
 		}
 	}
 
@@ -67,12 +87,30 @@ func (s FetchSummary) printText() {
 	fmt.Printf("  Cached/Skipped: %d\n", s.Cached)
 	//nolint:forbidigo // Required for command output
 	fmt.Printf("  Errors: %d\n", s.Errors)
+	if s.Disabled > 0 {
+		//nolint:forbidigo // Required for command output
+		fmt.Printf("  Disabled (skipped): %d\n", s.Disabled)
+	}
+	if s.Parked > 0 {
+		//nolint:forbidigo // Required for command output
+		fmt.Printf("  Parked (rate limited): %d\n", s.Parked)
+	}
 	//nolint:forbidigo // Required for command output
 	fmt.Printf("  Total items: %d\n", s.TotalItems)
+	//nolint:forbidigo // Required for command output
+	fmt.Printf("  New items: %d\n", s.NewItems)
 	if s.RemovedFeeds > 0 {
 		//nolint:forbidigo // Required for command output
 		fmt.Printf("  Removed feeds: %d\n", s.RemovedFeeds)
 	}
+	if len(s.MovedFeeds) > 0 {
+		//nolint:forbidigo // Required for command output
+		fmt.Printf("  Moved feeds: %d\n", len(s.MovedFeeds))
+		for _, move := range s.MovedFeeds {
+			//nolint:forbidigo // Required for command output
+			fmt.Printf("    %s -> %s\n", move.From, move.To)
+		}
+	}
 }
 
 // SingleURLOutput contains output data for single URL operations.
@@ -83,6 +121,7 @@ type SingleURLOutput struct {
 	Items       int    `json:"items"`
 	Title       string `json:"title,omitempty"`
 	Description string `json:"description,omitempty"`
+	MovedTo     string `json:"movedTo,omitempty"`
 	Error       string `json:"error,omitempty"`
 }
 
@@ -92,10 +131,11 @@ type SingleURLOutput struct {
 func PrintSingleResult(result *FetchResult, cfg *config.Config) {
 	if cfg.JSON {
 		output := SingleURLOutput{
-			Mode:   "single",
-			URL:    result.URL,
-			Cached: result.Cached,
-			Items:  result.ItemCount,
+			Mode:    "single",
+			URL:     result.URL,
+			Cached:  result.Cached,
+			Items:   result.ItemCount,
+			MovedTo: result.MovedTo,
 		}
 
 		if result.Error != nil {
@@ -123,6 +163,10 @@ func PrintSingleResult(result *FetchResult, cfg *config.Config) {
 			//nolint:forbidigo // Required for command output
 			fmt.Printf("  Items: %d\n", result.ItemCount)
 		}
+		if result.Error == nil && result.MovedTo != "" {
+			//nolint:forbidigo // Required for command output
+			fmt.Printf("  Moved permanently to: %s\n", result.MovedTo)
+		}
 	}
 }
 
@@ -136,8 +180,12 @@ func (f FormatValidation) ValidateFormat(format string) (feedlist.Format, error)
 		return feedlist.FormatOPML, nil
 	case string(feedlist.FormatText):
 		return feedlist.FormatText, nil
+	case string(feedlist.FormatYAML):
+		return feedlist.FormatYAML, nil
+	case string(feedlist.FormatJSON):
+		return feedlist.FormatJSON, nil
 	default:
-		return "", fmt.Errorf("unsupported format: %s (must be 'opml' or 'text')", format)
+		return "", fmt.Errorf("unsupported format: %s (must be 'opml', 'text', 'yaml' or 'json')", format)
 	}
 }
 
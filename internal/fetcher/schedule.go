@@ -0,0 +1,136 @@
+package fetcher
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// scheduleHistorySize is how many recent items are used to estimate a feed's publish rate.
+	scheduleHistorySize = 20
+	// defaultScheduleInterval is used when a feed has too little history to estimate a rate.
+	defaultScheduleInterval = time.Hour
+)
+
+// NextFetchInterval computes how long to wait before polling a feed again.
+//
+// The interval is half the median gap between recent publish times, so a feed
+// is typically polled about twice per post. It stretches to half the time
+// since the latest post, so feeds that have gone quiet back off on their own,
+// and never undercuts a cache lifetime advertised by the server. The result is
+// clamped to [minInterval, maxInterval].
+func NextFetchInterval(
+	publishTimes []time.Time, cacheLifetime time.Duration, now time.Time, minInterval, maxInterval time.Duration,
+) time.Duration {
+	interval := defaultScheduleInterval
+
+	if len(publishTimes) >= 2 {
+		sorted := make([]time.Time, len(publishTimes))
+		copy(sorted, publishTimes)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].After(sorted[j]) })
+
+		gaps := make([]time.Duration, 0, len(sorted)-1)
+		for i := 1; i < len(sorted); i++ {
+			gaps = append(gaps, sorted[i-1].Sub(sorted[i]))
+		}
+		sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+
+		interval = gaps[len(gaps)/2] / 2
+
+		if quiet := now.Sub(sorted[0]) / 2; quiet > interval {
+			interval = quiet
+		}
+	}
+
+	if cacheLifetime > interval {
+		interval = cacheLifetime
+	}
+
+	if interval < minInterval {
+		interval = minInterval
+	}
+	if maxInterval > 0 && interval > maxInterval {
+		interval = maxInterval
+	}
+
+	return interval
+}
+
+// CacheLifetime extracts how long a response may be considered fresh from its
+// Cache-Control max-age or Expires header. It returns 0 when neither is usable.
+func CacheLifetime(header http.Header, now time.Time) time.Duration {
+	if header == nil {
+		return 0
+	}
+
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.TrimSpace(strings.ToLower(directive))
+		if directive == "no-cache" || directive == "no-store" {
+			return 0
+		}
+		if value, ok := strings.CutPrefix(directive, "max-age="); ok {
+			if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+				return time.Duration(seconds) * time.Second
+			}
+		}
+	}
+
+	if expires := header.Get("Expires"); expires != "" {
+		if expiresAt, err := http.ParseTime(expires); err == nil && expiresAt.After(now) {
+			return expiresAt.Sub(now)
+		}
+	}
+
+	return 0
+}
+
+// scheduleNextFetch sets feed.NextFetchAt from the feed's item history and the
+// caching headers of the latest response, or from the feed's own fetch
+// interval when its list sets one.
+func (f *Fetcher) scheduleNextFetch(feed *database.Feed, header http.Header) {
+	now := time.Now()
+
+	if feed.FetchInterval > 0 {
+		feed.NextFetchAt = sql.NullTime{Time: now.Add(feed.FetchInterval), Valid: true}
+		logrus.Debugf("Scheduled next fetch of %s in %v (fixed interval)", feed.URL, feed.FetchInterval)
+		return
+	}
+
+	var publishTimes []time.Time
+	items, err := f.db.GetRecentItemDates(feed.URL, scheduleHistorySize)
+	if err != nil {
+		logrus.WithError(err).Warnf("Failed to load item history for %s", feed.URL)
+	}
+	for i := range items {
+		publishTime := items[i].PublishedDate
+		if publishTime.IsZero() && items[i].FirstSeen.Valid {
+			publishTime = items[i].FirstSeen.Time
+		}
+		publishTime = clampItemDate(publishTime, items[i].FirstSeen)
+		if !publishTime.IsZero() {
+			publishTimes = append(publishTimes, publishTime)
+		}
+	}
+
+	interval := NextFetchInterval(publishTimes, CacheLifetime(header, now), now, f.minInterval, f.maxInterval)
+
+	// Feeds a hub pushes to only need the occasional poll as a safety net
+	if active, err := f.db.HasActiveWebSubSubscription(feed.URL); err == nil && active && f.maxInterval > interval {
+		interval = f.maxInterval
+	}
+	feed.NextFetchAt = sql.NullTime{Time: now.Add(interval), Valid: true}
+	logrus.Debugf("Scheduled next fetch of %s in %v", feed.URL, interval)
+}
+
+// isFeedDue reports whether a feed's scheduled next fetch time has arrived.
+// Feeds that have never been scheduled are always due.
+func isFeedDue(feed *database.Feed, now time.Time) bool {
+	return feed == nil || !feed.NextFetchAt.Valid || !now.Before(feed.NextFetchAt.Time)
+}
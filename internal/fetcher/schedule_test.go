@@ -0,0 +1,183 @@
+package fetcher
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/database/databasetest"
+)
+
+func TestNextFetchInterval(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	hoursAgo := func(hours ...int) []time.Time {
+		times := make([]time.Time, 0, len(hours))
+		for _, h := range hours {
+			times = append(times, now.Add(-time.Duration(h)*time.Hour))
+		}
+		return times
+	}
+
+	tests := []struct {
+		name          string
+		publishTimes  []time.Time
+		cacheLifetime time.Duration
+		want          time.Duration
+	}{
+		{
+			name:         "no history uses default",
+			publishTimes: nil,
+			want:         defaultScheduleInterval,
+		},
+		{
+			name:         "hourly feed polled every half hour",
+			publishTimes: hoursAgo(0, 1, 2, 3, 4),
+			want:         30 * time.Minute,
+		},
+		{
+			name:         "daily feed polled twice a day",
+			publishTimes: hoursAgo(0, 24, 48, 72),
+			want:         12 * time.Hour,
+		},
+		{
+			name:         "quiet feed backs off to max",
+			publishTimes: hoursAgo(24*365, 24*366, 24*367),
+			want:         24 * time.Hour,
+		},
+		{
+			name:         "bursty feed clamped to min",
+			publishTimes: hoursAgo(0, 0, 0, 0),
+			want:         15 * time.Minute,
+		},
+		{
+			name:          "cache lifetime extends interval",
+			publishTimes:  hoursAgo(0, 1, 2, 3),
+			cacheLifetime: 3 * time.Hour,
+			want:          3 * time.Hour,
+		},
+		{
+			name:          "cache lifetime shorter than interval is ignored",
+			publishTimes:  hoursAgo(0, 24, 48),
+			cacheLifetime: time.Hour,
+			want:          12 * time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NextFetchInterval(tt.publishTimes, tt.cacheLifetime, now, 15*time.Minute, 24*time.Hour)
+			if got != tt.want {
+				t.Errorf("NextFetchInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheLifetime(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    time.Duration
+	}{
+		{"no headers", nil, 0},
+		{"max-age", map[string]string{"Cache-Control": "public, max-age=3600"}, time.Hour},
+		{"no-cache wins", map[string]string{"Cache-Control": "no-cache, max-age=3600"}, 0},
+		{"expires", map[string]string{"Expires": now.Add(2 * time.Hour).Format(http.TimeFormat)}, 2 * time.Hour},
+		{"expired", map[string]string{"Expires": now.Add(-time.Hour).Format(http.TimeFormat)}, 0},
+		{"invalid expires", map[string]string{"Expires": "0"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tt.headers {
+				header.Set(k, v)
+			}
+			if got := CacheLifetime(header, now); got != tt.want {
+				t.Errorf("CacheLifetime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchFeedSchedulesNextFetch(t *testing.T) {
+	db := databasetest.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Header().Set("Cache-Control", "max-age=7200")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(testFeedXML))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(db, 30*time.Second, 100, false)
+	fetcher.SetScheduleBounds(15*time.Minute, 24*time.Hour)
+
+	before := time.Now()
+	result := fetcher.FetchFeed(server.URL)
+	if result.Error != nil {
+		t.Fatalf("FetchFeed() error = %v", result.Error)
+	}
+
+	feed, err := db.GetFeed(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !feed.NextFetchAt.Valid {
+		t.Fatal("FetchFeed() did not schedule the next fetch")
+	}
+
+	// Items are from 2024, so the quiet-feed backoff should hit the max interval
+	if got := feed.NextFetchAt.Time.Sub(before); got < 23*time.Hour || got > 25*time.Hour {
+		t.Errorf("next fetch in %v, want about 24h", got)
+	}
+}
+
+func TestFetchConcurrentSkipsFeedsNotDue(t *testing.T) {
+	db := databasetest.New(t)
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(testFeedXML))
+	}))
+	defer server.Close()
+
+	feed := &database.Feed{
+		URL:         server.URL,
+		Title:       "Scheduled Feed",
+		FeedJSON:    database.JSON(`{}`),
+		NextFetchAt: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+	if err := db.UpsertFeed(feed); err != nil {
+		t.Fatal(err)
+	}
+
+	fetcher := NewFetcher(db, 30*time.Second, 100, false)
+
+	results := fetchConcurrent(fetcher, []string{server.URL}, 1, 0, false)
+	if !results[0].Cached {
+		t.Error("fetchConcurrent() should skip a feed that isn't due")
+	}
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("fetchConcurrent() made %d requests, want 0", n)
+	}
+
+	results = fetchConcurrent(fetcher, []string{server.URL}, 1, 0, true)
+	if results[0].Cached || results[0].Error != nil {
+		t.Errorf("fetchConcurrent() with ignoreSchedule should fetch, got cached=%v err=%v",
+			results[0].Cached, results[0].Error)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("fetchConcurrent() made %d requests, want 1", n)
+	}
+}
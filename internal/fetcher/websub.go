@@ -0,0 +1,59 @@
+package fetcher
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/lmorchard/feedspool-go/internal/websub"
+	"github.com/mmcdole/gofeed"
+	"github.com/sirupsen/logrus"
+)
+
+// recordHub stores the WebSub hub a fetched feed advertises, if any, so the
+// subscription manager can subscribe to it.
+func (f *Fetcher) recordHub(feedURL string, header http.Header, body []byte) {
+	hub, self := websub.DiscoverHub(header, body)
+	if hub == "" {
+		return
+	}
+	if self == "" {
+		self = feedURL
+	}
+
+	if err := f.db.UpsertWebSubHub(feedURL, hub, self); err != nil {
+		logrus.Warnf("Failed to record WebSub hub for %s: %v", feedURL, err)
+	}
+}
+
+// IngestPush stores feed content a WebSub hub pushed for a known feed. Items
+// are processed as in a fetch, except that missing items are not archived.
+// Returns the number of items processed.
+func (f *Fetcher) IngestPush(feedURL string, body io.Reader) (int, error) {
+	feed, err := f.db.GetFeed(feedURL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load feed: %w", err)
+	}
+	if feed == nil {
+		return 0, fmt.Errorf("unknown feed: %s", feedURL)
+	}
+
+	gofeedData, err := gofeed.NewParser().Parse(body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse: %w", err)
+	}
+
+	counts, latestItemDate, err := f.processFeedItems(gofeedData, feedURL, false)
+	if err != nil {
+		return 0, err
+	}
+	if !latestItemDate.IsZero() && (!feed.LatestItemDate.Valid || latestItemDate.After(feed.LatestItemDate.Time)) {
+		feed.LatestItemDate = sql.NullTime{Time: latestItemDate, Valid: true}
+		if err := f.db.UpsertFeed(feed); err != nil {
+			return counts.saved, fmt.Errorf("failed to update feed: %w", err)
+		}
+	}
+
+	return counts.saved, nil
+}
@@ -0,0 +1,100 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database/databasetest"
+)
+
+const testHubFeedXML = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+    <channel>
+        <title>Hub Feed</title>
+        <link>https://example.com</link>
+        <atom:link rel="hub" href="https://hub.example.com/"/>
+        <item>
+            <title>Test Item 1</title>
+            <pubDate>Mon, 01 Jan 2024 12:00:00 GMT</pubDate>
+            <guid>item-1</guid>
+        </item>
+        <item>
+            <title>Test Item 2</title>
+            <pubDate>Mon, 01 Jan 2024 13:00:00 GMT</pubDate>
+            <guid>item-2</guid>
+        </item>
+    </channel>
+</rss>`
+
+const testPushedXML = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+    <channel>
+        <title>Hub Feed</title>
+        <item>
+            <title>Test Item 3</title>
+            <pubDate>Tue, 02 Jan 2024 12:00:00 GMT</pubDate>
+            <guid>item-3</guid>
+        </item>
+    </channel>
+</rss>`
+
+func TestFetchRecordsHubAndIngestsPush(t *testing.T) {
+	db := databasetest.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(testHubFeedXML))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(db, 30*time.Second, 100, false)
+	result := fetcher.FetchFeed(server.URL)
+	if result.Error != nil {
+		t.Fatalf("FetchFeed() error = %v", result.Error)
+	}
+
+	subs, err := db.GetWebSubSubscriptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 1 || subs[0].HubURL != "https://hub.example.com/" || subs[0].TopicURL != server.URL {
+		t.Fatalf("GetWebSubSubscriptions() = %+v, want the advertised hub with the feed URL as topic", subs)
+	}
+
+	itemCount, err := fetcher.IngestPush(server.URL, strings.NewReader(testPushedXML))
+	if err != nil {
+		t.Fatalf("IngestPush() error = %v", err)
+	}
+	if itemCount != 1 {
+		t.Errorf("IngestPush() = %d items, want 1", itemCount)
+	}
+
+	items, err := db.GetItemsForFeed(server.URL, 0, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("feed has %d items, want 3", len(items))
+	}
+	for _, item := range items {
+		if item.Archived {
+			t.Errorf("item %s archived, pushed content must not archive missing items", item.GUID)
+		}
+	}
+
+	feed, err := db.GetFeed(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	if !feed.LatestItemDate.Valid || !feed.LatestItemDate.Time.Equal(want) {
+		t.Errorf("LatestItemDate = %v, want %v", feed.LatestItemDate, want)
+	}
+
+	if _, err := fetcher.IngestPush("https://unknown.example.com/feed", strings.NewReader(testPushedXML)); err == nil {
+		t.Error("IngestPush() for an unknown feed should fail")
+	}
+}
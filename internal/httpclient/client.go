@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/lmorchard/feedspool-go/internal/metrics"
 	"github.com/sirupsen/logrus"
 )
 
@@ -14,7 +16,8 @@ const (
 	// DefaultUserAgent mimics a common browser to avoid blocking.
 	DefaultUserAgent = "Mozilla/5.0 (compatible; feedspool/1.0; +https://github.com/lmorchard/feedspool-go)"
 	DefaultTimeout   = 30 * time.Second
-	MaxResponseSize  = 100 * 1024 // 100KB for metadata fetching
+	MaxResponseSize  = 100 * 1024      // 100KB for metadata fetching
+	MaxFullTextSize  = 2 * 1024 * 1024 // 2MB for extracting whole articles
 )
 
 // Client is a shared HTTP client for feedspool.
@@ -23,6 +26,7 @@ type Client struct {
 	userAgent       string
 	timeout         time.Duration
 	maxResponseSize int64
+	limiter         *HostLimiter
 }
 
 // Config holds configuration for the HTTP client.
@@ -30,6 +34,10 @@ type Config struct {
 	Timeout         time.Duration
 	UserAgent       string
 	MaxResponseSize int64
+	// PerHostConcurrency caps requests in flight per host; zero is unlimited.
+	PerHostConcurrency int
+	// PerHostRate caps new requests per second per host; zero is unlimited.
+	PerHostRate float64
 }
 
 // NewClient creates a new HTTP client with the given configuration.
@@ -48,6 +56,11 @@ func NewClient(config *Config) *Client {
 		config.MaxResponseSize = MaxResponseSize
 	}
 
+	var limiter *HostLimiter
+	if config.PerHostConcurrency > 0 || config.PerHostRate > 0 {
+		limiter = NewHostLimiter(config.PerHostConcurrency, config.PerHostRate)
+	}
+
 	return &Client{
 		httpClient: &http.Client{
 			Timeout: config.Timeout,
@@ -66,9 +79,20 @@ func NewClient(config *Config) *Client {
 		userAgent:       config.UserAgent,
 		timeout:         config.Timeout,
 		maxResponseSize: config.MaxResponseSize,
+		limiter:         limiter,
 	}
 }
 
+// WithTransport returns a copy of the client that sends its requests through
+// the given transport, sharing the client's per-host limits.
+func (c *Client) WithTransport(transport http.RoundTripper) *Client {
+	httpClient := *c.httpClient
+	httpClient.Transport = transport
+	client := *c
+	client.httpClient = &httpClient
+	return &client
+}
+
 // Request represents an HTTP request with additional options.
 type Request struct {
 	URL               string
@@ -83,6 +107,9 @@ type Request struct {
 type Response struct {
 	*http.Response
 	BodyReader io.Reader
+	// PermanentURL is the final URL when every redirect followed was a 301 or
+	// 308, and empty when there were no redirects or any was temporary.
+	PermanentURL string
 }
 
 // Do performs an HTTP request with the configured client.
@@ -114,12 +141,30 @@ func (c *Client) Do(req *Request) (*Response, error) {
 		httpReq.Header.Set(key, value)
 	}
 
+	// Wait for the host's rate limit before the client timeout starts, so
+	// queued requests don't time out just for waiting their turn
+	release := func() {}
+	if c.limiter != nil {
+		release, err = c.limiter.Acquire(req.Context, httpReq.URL.Host)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit wait canceled: %w", err)
+		}
+	}
+
+	started := time.Now()
 	resp, err := c.httpClient.Do(httpReq) //nolint:bodyclose // Response body is closed by caller
 	if err != nil {
+		release()
+		metrics.Default.Add("feedspool_http_request_errors_total",
+			"HTTP requests that failed without a response.", nil, 1)
 		logrus.Debugf("HTTP request failed for %s: %v", req.URL, err)
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
+	metrics.Default.Observe("feedspool_http_request_duration_seconds",
+		"Time until response headers arrived, by HTTP status code.", metrics.DurationBuckets,
+		metrics.Labels{"code": strconv.Itoa(resp.StatusCode)}, time.Since(started).Seconds())
 	// Note: resp.Body is intentionally not closed here as it's returned to caller
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
 
 	logrus.Debugf("HTTP %d %s %s (content-length: %d)",
 		resp.StatusCode, req.Method, req.URL, resp.ContentLength)
@@ -136,11 +181,33 @@ func (c *Client) Do(req *Request) (*Response, error) {
 
 	// Note: Response.Body.Close() should be called by the caller
 	return &Response{
-		Response:   resp,
-		BodyReader: bodyReader,
+		Response:     resp,
+		BodyReader:   bodyReader,
+		PermanentURL: permanentRedirectURL(resp),
 	}, nil
 }
 
+// permanentRedirectURL walks back through the redirect chain that led to resp
+// and returns the final URL if every hop was a permanent redirect.
+func permanentRedirectURL(resp *http.Response) string {
+	if resp.Request == nil || resp.Request.Response == nil {
+		return ""
+	}
+
+	for req := resp.Request; req.Response != nil; req = req.Response.Request {
+		switch req.Response.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+		default:
+			return ""
+		}
+		if req.Response.Request == nil {
+			break
+		}
+	}
+
+	return resp.Request.URL.String()
+}
+
 // Get performs a simple GET request.
 func (c *Client) Get(url string) (*Response, error) {
 	return c.Do(&Request{
@@ -0,0 +1,126 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// HostLimiter caps how many requests run at once against each host and how
+// often new requests to that host may start, using a token bucket per host.
+type HostLimiter struct {
+	maxConcurrent int
+	rate          float64 // requests per second, <= 0 for no rate limit
+	burst         float64
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	slots  chan struct{} // nil when concurrency is unlimited
+	tokens float64
+	last   time.Time
+}
+
+// NewHostLimiter creates a limiter allowing maxConcurrent requests in flight
+// and rate new requests per second for each host. Zero or less disables the
+// corresponding limit. The bucket holds up to maxConcurrent tokens, so a host
+// can absorb a short burst before requests are spaced out.
+func NewHostLimiter(maxConcurrent int, rate float64) *HostLimiter {
+	burst := float64(maxConcurrent)
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &HostLimiter{
+		maxConcurrent: maxConcurrent,
+		rate:          rate,
+		burst:         burst,
+		hosts:         make(map[string]*hostState),
+	}
+}
+
+// Acquire blocks until a request to host may start. The returned release
+// function must be called once the request is finished.
+func (l *HostLimiter) Acquire(ctx context.Context, host string) (func(), error) {
+	state := l.host(host)
+
+	release := func() {}
+	if state.slots != nil {
+		select {
+		case state.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		release = func() { <-state.slots }
+	}
+
+	if wait := l.reserve(state); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+
+	return release, nil
+}
+
+// host returns the state for host, creating it on first use.
+func (l *HostLimiter) host(host string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.hosts[host]
+	if !ok {
+		state = &hostState{tokens: l.burst, last: time.Now()}
+		if l.maxConcurrent > 0 {
+			state.slots = make(chan struct{}, l.maxConcurrent)
+		}
+		l.hosts[host] = state
+	}
+	return state
+}
+
+// reserve takes a token from the host's bucket and returns how long to wait
+// before it may be used.
+func (l *HostLimiter) reserve(state *hostState) time.Duration {
+	if l.rate <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	state.tokens += now.Sub(state.last).Seconds() * l.rate
+	if state.tokens > l.burst {
+		state.tokens = l.burst
+	}
+	state.last = now
+
+	state.tokens--
+	if state.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-state.tokens / l.rate * float64(time.Second))
+}
+
+// releasingBody releases a host slot when the response body is closed, so
+// the slot stays held while the caller reads the body.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
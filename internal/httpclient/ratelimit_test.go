@@ -0,0 +1,87 @@
+package httpclient
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostLimiterCapsConcurrencyPerHost(t *testing.T) {
+	limiter := NewHostLimiter(2, 0)
+
+	var inFlight, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := limiter.Acquire(context.Background(), "example.com")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer release()
+
+			current := atomic.AddInt32(&inFlight, 1)
+			for {
+				old := atomic.LoadInt32(&peak)
+				if current <= old || atomic.CompareAndSwapInt32(&peak, old, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+		}()
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("peak concurrency = %d, want at most 2", peak)
+	}
+}
+
+func TestHostLimiterSpacesRequestsAfterBurst(t *testing.T) {
+	limiter := NewHostLimiter(1, 20) // burst of 1, then one request every 50ms
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := limiter.Acquire(context.Background(), "example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("3 requests took %v, want at least ~100ms", elapsed)
+	}
+
+	// Other hosts have their own bucket
+	start = time.Now()
+	release, err := limiter.Acquire(context.Background(), "other.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("first request to another host waited %v", elapsed)
+	}
+}
+
+func TestHostLimiterHonorsCancellation(t *testing.T) {
+	limiter := NewHostLimiter(1, 0)
+
+	release, err := limiter.Acquire(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, "example.com"); err == nil {
+		t.Error("Acquire() should fail when the context is canceled while waiting")
+	}
+}
@@ -0,0 +1,294 @@
+package httpoverride
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/lmorchard/feedspool-go/internal/config"
+)
+
+// Prefixes of config values read from somewhere else.
+const (
+	envPrefix  = "env:"
+	filePrefix = "file:"
+)
+
+// override is a validated entry from the http_overrides config, with its
+// secrets resolved.
+type override struct {
+	feed               string
+	host               string
+	headers            map[string]string
+	proxy              string
+	caFile             string
+	insecureSkipVerify bool
+}
+
+// Match is what the overrides matching a feed change about its requests.
+type Match struct {
+	Headers   map[string]string
+	Transport http.RoundTripper // Nil when the default transport will do
+}
+
+// Set holds the http_overrides config, in order. A nil Set has no overrides.
+type Set struct {
+	overrides []*override
+
+	mu         sync.Mutex
+	transports map[string]http.RoundTripper // Keyed by proxy and TLS settings
+}
+
+// New resolves and checks the http_overrides config, failing on a secret
+// that cannot be read, a bad proxy URL or an unreadable CA file.
+func New(configs []config.HTTPOverrideConfig) (*Set, error) {
+	set := &Set{transports: make(map[string]http.RoundTripper)}
+	for i, cfg := range configs {
+		o, err := compile(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid http override %d: %w", i+1, err)
+		}
+		set.overrides = append(set.overrides, o)
+	}
+	return set, nil
+}
+
+// compile checks an override and resolves its secrets into headers.
+func compile(cfg config.HTTPOverrideConfig) (*override, error) {
+	if cfg.Feed == "" && cfg.Host == "" {
+		return nil, fmt.Errorf("no feed or host to match")
+	}
+	if cfg.Host != "" {
+		if _, err := path.Match(cfg.Host, ""); err != nil {
+			return nil, fmt.Errorf("bad host pattern %q: %w", cfg.Host, err)
+		}
+	}
+
+	headers, err := resolveHeaders(cfg)
+	if err != nil {
+		return nil, err
+	}
+	o := &override{
+		feed:               cfg.Feed,
+		host:               strings.ToLower(cfg.Host),
+		headers:            headers,
+		caFile:             cfg.CAFile,
+		insecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.Proxy != "" {
+		if o.proxy, err = resolve(cfg.Proxy); err != nil {
+			return nil, fmt.Errorf("proxy: %w", err)
+		}
+		// The URL may hold credentials, so it's left out of the error
+		proxy, err := url.Parse(o.proxy)
+		if err != nil {
+			return nil, fmt.Errorf("bad proxy URL")
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q (must be http, https or socks5)", proxy.Scheme)
+		}
+	}
+
+	if o.caFile != "" {
+		if _, err := loadCertPool(o.caFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return o, nil
+}
+
+// resolveHeaders returns the headers an override sends, including its
+// credentials and cookie.
+func resolveHeaders(cfg config.HTTPOverrideConfig) (map[string]string, error) {
+	headers := make(map[string]string)
+	for name, value := range cfg.Headers {
+		resolved, err := resolve(value)
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %w", name, err)
+		}
+		headers[http.CanonicalHeaderKey(name)] = resolved
+	}
+	if cfg.UserAgent != "" {
+		headers["User-Agent"] = cfg.UserAgent
+	}
+
+	username, err := resolve(cfg.Username)
+	if err != nil {
+		return nil, fmt.Errorf("username: %w", err)
+	}
+	password, err := resolve(cfg.Password)
+	if err != nil {
+		return nil, fmt.Errorf("password: %w", err)
+	}
+	token, err := resolve(cfg.Token)
+	if err != nil {
+		return nil, fmt.Errorf("token: %w", err)
+	}
+	cookie, err := resolve(cfg.Cookie)
+	if err != nil {
+		return nil, fmt.Errorf("cookie: %w", err)
+	}
+
+	switch {
+	case token != "" && (username != "" || password != ""):
+		return nil, fmt.Errorf("both a token and a username or password")
+	case token != "":
+		headers["Authorization"] = "Bearer " + token
+	case username != "" || password != "":
+		credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+		headers["Authorization"] = "Basic " + credentials
+	}
+	if cookie != "" {
+		headers["Cookie"] = cookie
+	}
+
+	return headers, nil
+}
+
+// resolve reads a value given as env:NAME or file:PATH, trimming whitespace
+// around a file's contents. Other values are returned as they are.
+func resolve(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, envPrefix):
+		name := strings.TrimPrefix(value, envPrefix)
+		resolved, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return resolved, nil
+	case strings.HasPrefix(value, filePrefix):
+		data, err := os.ReadFile(strings.TrimPrefix(value, filePrefix))
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	default:
+		return value, nil
+	}
+}
+
+// loadCertPool returns the system's trusted certificates along with those in
+// a PEM file.
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return pool, nil
+}
+
+// matches reports whether the override applies to a feed URL. Host patterns
+// use shell globbing, so *.example.com matches www.example.com but not
+// example.com itself.
+func (o *override) matches(feedURL string, host string) bool {
+	if o.feed != "" && o.feed != feedURL {
+		return false
+	}
+	if o.host != "" {
+		matched, err := path.Match(o.host, host)
+		if err != nil || !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// For returns what the overrides change about requests for a feed, or nil
+// when none match. When several match, they apply in order, later ones
+// replacing the headers and settings of earlier ones.
+func (s *Set) For(feedURL string) (*Match, error) {
+	if s == nil || len(s.overrides) == 0 {
+		return nil, nil
+	}
+
+	var host string
+	if parsed, err := url.Parse(feedURL); err == nil {
+		host = strings.ToLower(parsed.Hostname())
+	}
+
+	var match *Match
+	var proxy, caFile string
+	var insecureSkipVerify bool
+	for _, o := range s.overrides {
+		if !o.matches(feedURL, host) {
+			continue
+		}
+		if match == nil {
+			match = &Match{Headers: make(map[string]string)}
+		}
+		for name, value := range o.headers {
+			match.Headers[name] = value
+		}
+		if o.proxy != "" {
+			proxy = o.proxy
+		}
+		if o.caFile != "" {
+			caFile = o.caFile
+		}
+		insecureSkipVerify = insecureSkipVerify || o.insecureSkipVerify
+	}
+	if match == nil || (proxy == "" && caFile == "" && !insecureSkipVerify) {
+		return match, nil
+	}
+
+	transport, err := s.transport(proxy, caFile, insecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	match.Transport = transport
+	return match, nil
+}
+
+// transport returns a transport using the given proxy and TLS settings,
+// creating it the first time they are asked for so connections are reused.
+func (s *Set) transport(proxy, caFile string, insecureSkipVerify bool) (http.RoundTripper, error) {
+	key := fmt.Sprintf("%s|%s|%t", proxy, caFile, insecureSkipVerify)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if transport, ok := s.transports[key]; ok {
+		return transport, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("bad proxy URL")
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if caFile != "" || insecureSkipVerify {
+		//nolint:gosec // Skipping verification is an explicit opt-in for feeds with broken certificates
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+		if caFile != "" {
+			pool, err := loadCertPool(caFile)
+			if err != nil {
+				return nil, err
+			}
+			transport.TLSClientConfig.RootCAs = pool
+		}
+	}
+
+	s.transports[key] = transport
+	return transport, nil
+}
@@ -0,0 +1,187 @@
+package httpoverride
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lmorchard/feedspool-go/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	badCA := filepath.Join(dir, "bad.pem")
+	if err := os.WriteFile(badCA, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     config.HTTPOverrideConfig
+		wantErr string
+	}{
+		{"nothing to match", config.HTTPOverrideConfig{Token: "abc"}, "no feed or host"},
+		{"bad host pattern", config.HTTPOverrideConfig{Host: "[example.com"}, "bad host pattern"},
+		{
+			"token and password",
+			config.HTTPOverrideConfig{Host: "example.com", Token: "abc", Password: "def"},
+			"both a token",
+		},
+		{"unset variable", config.HTTPOverrideConfig{Host: "example.com", Token: "env:TEST_UNSET_TOKEN"}, "not set"},
+		{
+			"missing secret file",
+			config.HTTPOverrideConfig{Host: "example.com", Cookie: "file:" + filepath.Join(dir, "missing")},
+			"failed to read secret",
+		},
+		{"unsupported proxy", config.HTTPOverrideConfig{Host: "example.com", Proxy: "ftp://proxy:21"}, "proxy scheme"},
+		{"no certificates", config.HTTPOverrideConfig{Host: "example.com", CAFile: badCA}, "no certificates"},
+		{"valid", config.HTTPOverrideConfig{Host: "*.example.com", Proxy: "socks5://127.0.0.1:1080"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New([]config.HTTPOverrideConfig{tt.cfg})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("New() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFor(t *testing.T) {
+	t.Setenv("TEST_FEED_TOKEN", "s3cret")
+	cookieFile := filepath.Join(t.TempDir(), "cookie")
+	if err := os.WriteFile(cookieFile, []byte("session=abc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	set, err := New([]config.HTTPOverrideConfig{
+		{Host: "*.example.com", UserAgent: "Reader/1.0", Headers: map[string]string{"x-team": "news"}},
+		{Feed: "https://private.example.com/feed.xml", Token: "env:TEST_FEED_TOKEN", Cookie: "file:" + cookieFile},
+		{Host: "*.example.com", Headers: map[string]string{"X-Team": "sports"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	match, err := set.For("https://private.example.com/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"User-Agent":    "Reader/1.0",
+		"X-Team":        "sports",
+		"Authorization": "Bearer s3cret",
+		"Cookie":        "session=abc",
+	}
+	if match == nil || len(match.Headers) != len(want) || match.Transport != nil {
+		t.Fatalf("For() = %+v, want headers %v and no transport", match, want)
+	}
+	for name, value := range want {
+		if match.Headers[name] != value {
+			t.Errorf("For() header %s = %q, want %q", name, match.Headers[name], value)
+		}
+	}
+
+	if match, _ := set.For("https://WWW.Example.com/other.xml"); match == nil || match.Headers["Authorization"] != "" {
+		t.Errorf("For(other feed) = %+v, want the host overrides only", match)
+	}
+	if match, _ := set.For("https://example.com/feed.xml"); match != nil {
+		t.Errorf("For(bare domain) = %+v, want nil", match)
+	}
+
+	var nilSet *Set
+	if match, err := nilSet.For("https://example.com/feed.xml"); match != nil || err != nil {
+		t.Errorf("nil Set For() = %+v, %v; want nil", match, err)
+	}
+}
+
+func TestForBasicAuth(t *testing.T) {
+	set, err := New([]config.HTTPOverrideConfig{{Host: "example.com", Username: "reader", Password: "pw"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	match, err := set.For("https://example.com/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/feed.xml", nil)
+	req.Header.Set("Authorization", match.Headers["Authorization"])
+	if username, password, ok := req.BasicAuth(); !ok || username != "reader" || password != "pw" {
+		t.Errorf("Authorization = %q, want basic auth for reader", match.Headers["Authorization"])
+	}
+}
+
+func TestForTransport(t *testing.T) {
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("secure"))
+	}))
+	defer tlsServer.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	proxied := make(chan string, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied <- r.URL.String()
+		w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+
+	set, err := New([]config.HTTPOverrideConfig{
+		{Host: "127.0.0.1", CAFile: caFile},
+		{Host: "feeds.example.com", Proxy: proxy.URL},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(feedURL string) string {
+		t.Helper()
+		match, err := set.For(feedURL)
+		if err != nil || match == nil || match.Transport == nil {
+			t.Fatalf("For(%s) = %+v, %v; want a transport", feedURL, match, err)
+		}
+		resp, err := (&http.Client{Transport: match.Transport}).Get(feedURL)
+		if err != nil {
+			t.Fatalf("GET %s error = %v", feedURL, err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	if got := get(tlsServer.URL); got != "secure" {
+		t.Errorf("GET with the CA file = %q, want secure", got)
+	}
+	if got := get("http://feeds.example.com/feed.xml"); got != "proxied" {
+		t.Errorf("GET through the proxy = %q, want proxied", got)
+	}
+	if got := <-proxied; got != "http://feeds.example.com/feed.xml" {
+		t.Errorf("proxy saw %q, want the feed URL", got)
+	}
+
+	// Feeds with the same settings share a transport and its connections
+	first, _ := set.For("http://feeds.example.com/a.xml")
+	second, _ := set.For("http://feeds.example.com/b.xml")
+	if first.Transport != second.Transport {
+		t.Error("For() created a second transport for the same settings")
+	}
+}
@@ -0,0 +1,355 @@
+package media
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/httpclient"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// retryAfter is how long a failed download is left before it is tried again.
+const retryAfter = 24 * time.Hour
+
+// extensions maps the image types that are cached to their file extensions.
+// Other types aren't cached, SVG included, since it can carry scripts.
+var extensions = map[string]string{
+	"image/jpeg":               ".jpg",
+	"image/png":                ".png",
+	"image/gif":                ".gif",
+	"image/webp":               ".webp",
+	"image/avif":               ".avif",
+	"image/bmp":                ".bmp",
+	"image/x-icon":             ".ico",
+	"image/vnd.microsoft.icon": ".ico",
+}
+
+// Cache downloads images into a directory, each named by the hash of its
+// content, and indexes them by URL in the database. A nil Cache caches
+// nothing.
+type Cache struct {
+	db      *database.DB
+	client  *httpclient.Client
+	dir     string
+	maxSize int64
+}
+
+// New creates a cache from the media config, or returns nil when caching is
+// disabled.
+func New(cfg config.MediaConfig, db *database.DB, timeout time.Duration) *Cache {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	maxSize := cfg.MaxSize
+	if maxSize <= 0 {
+		maxSize = config.DefaultMediaMaxSize
+	}
+	dir := cfg.Dir
+	if dir == "" {
+		dir = config.DefaultMediaDir
+	}
+
+	return &Cache{
+		db: db,
+		client: httpclient.NewClient(&httpclient.Config{
+			Timeout: timeout,
+			// One byte over the limit, to tell images at the limit from larger ones
+			MaxResponseSize: maxSize + 1,
+		}),
+		dir:     dir,
+		maxSize: maxSize,
+	}
+}
+
+// Path returns where a cached file is stored, relative to the cache
+// directory and with forward slashes: the hash of its content, under a
+// directory named by the first two characters of the hash.
+func Path(media *database.Media) string {
+	hash := media.Hash.String
+	return hash[:2] + "/" + hash + extensions[media.ContentType]
+}
+
+// Store downloads an image into the cache, unless it is already there or
+// failed to download recently. Returns its cache entry, which has no hash if
+// the download failed.
+func (c *Cache) Store(imageURL string) (*database.Media, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	existing, err := c.db.GetMedia(imageURL)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && (existing.Hash.Valid || time.Since(existing.FetchedAt) < retryAfter) {
+		return existing, nil
+	}
+
+	media := &database.Media{URL: imageURL, FetchedAt: time.Now()}
+	if err := c.download(media); err != nil {
+		logrus.Debugf("Failed to cache %s: %v", imageURL, err)
+		media.FetchError = sql.NullString{String: err.Error(), Valid: true}
+	} else {
+		logrus.Debugf("Cached %s as %s", imageURL, Path(media))
+	}
+
+	if err := c.db.UpsertMedia(media); err != nil {
+		return nil, err
+	}
+	return media, nil
+}
+
+// StoreItem caches the images in an item's content and summary, and records
+// that the item references them.
+func (c *Cache) StoreItem(item *database.Item) error {
+	if c == nil {
+		return nil
+	}
+
+	urls := ImageURLs(item.Content + item.Summary)
+	for _, imageURL := range urls {
+		if _, err := c.Store(imageURL); err != nil {
+			logrus.Warnf("Failed to cache image %s: %v", imageURL, err)
+		}
+	}
+	return c.db.AddItemMedia(item.FeedURL, item.GUID, urls)
+}
+
+// StoreMetadata caches the image and favicon of a page's metadata.
+func (c *Cache) StoreMetadata(metadata *database.URLMetadata) {
+	if c == nil || metadata == nil {
+		return
+	}
+
+	for _, imageURL := range []sql.NullString{metadata.ImageURL, metadata.FaviconURL} {
+		if !imageURL.Valid || !isHTTP(imageURL.String) {
+			continue
+		}
+		if _, err := c.Store(imageURL.String); err != nil {
+			logrus.Warnf("Failed to cache image %s: %v", imageURL.String, err)
+		}
+	}
+}
+
+// download fetches an image and writes it to the cache, filling in the
+// entry's hash, type and size.
+func (c *Cache) download(media *database.Media) error {
+	resp, err := c.client.GetLimited(media.URL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if resp.ContentLength > c.maxSize {
+		return fmt.Errorf("image of %d bytes is over the %d byte limit", resp.ContentLength, c.maxSize)
+	}
+
+	data, err := io.ReadAll(resp.BodyReader)
+	if err != nil {
+		return fmt.Errorf("failed to read image: %w", err)
+	}
+	if int64(len(data)) > c.maxSize {
+		return fmt.Errorf("image is over the %d byte limit", c.maxSize)
+	}
+
+	contentType := detectContentType(data, resp.Header.Get("Content-Type"))
+	if extensions[contentType] == "" {
+		return fmt.Errorf("unsupported content type %q", contentType)
+	}
+
+	sum := sha256.Sum256(data)
+	media.Hash = sql.NullString{String: hex.EncodeToString(sum[:]), Valid: true}
+	media.ContentType = contentType
+	media.Size = int64(len(data))
+
+	return c.write(Path(media), data)
+}
+
+// detectContentType sniffs the type of an image, trusting the server's
+// Content-Type only for images the sniffer doesn't know.
+func detectContentType(data []byte, header string) string {
+	sniffed := http.DetectContentType(data)
+	if sniffed != "application/octet-stream" {
+		return sniffed
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return sniffed
+	}
+	return mediaType
+}
+
+// write stores data at a path in the cache, unless a file with the same
+// content is already there. The file is written under a temporary name and
+// renamed, so it is never seen half written.
+func (c *Cache) write(path string, data []byte) error {
+	target := filepath.Join(c.dir, filepath.FromSlash(path))
+	if _, err := os.Stat(target); err == nil {
+		return nil
+	}
+
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, config.DefaultDirPerm); err != nil {
+		return fmt.Errorf("failed to create media directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".download-*")
+	if err != nil {
+		return fmt.Errorf("failed to create media file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write media file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write media file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("failed to store media file: %w", err)
+	}
+	return nil
+}
+
+// CollectGarbage deletes cache entries that nothing references any more, then
+// the files no entry refers to. Returns the number of files deleted.
+func (c *Cache) CollectGarbage() (int, error) {
+	if c == nil {
+		return 0, nil
+	}
+
+	if _, err := c.db.DeleteUnreferencedMedia(); err != nil {
+		return 0, err
+	}
+	hashes, err := c.db.GetMediaHashes()
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	err = filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		name := d.Name()
+		if hashes[strings.TrimSuffix(name, filepath.Ext(name))] || isDownloading(d) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to delete media file: %w", err)
+		}
+		deleted++
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return deleted, fmt.Errorf("failed to collect media garbage: %w", err)
+	}
+
+	logrus.Debugf("Deleted %d unreferenced media files", deleted)
+	return deleted, nil
+}
+
+// ImageURLs returns the distinct http(s) URLs of the images in HTML content.
+func ImageURLs(content string) []string {
+	urls := []string{}
+	seen := make(map[string]bool)
+	Rewrite(content, func(src string) string {
+		if isHTTP(src) && !seen[src] {
+			seen[src] = true
+			urls = append(urls, src)
+		}
+		return src
+	})
+	return urls
+}
+
+// Rewrite returns HTML content with the src of each image replaced by what
+// link returns for it. Everything else is left as it was.
+func Rewrite(content string, link func(src string) string) string {
+	if !strings.Contains(strings.ToLower(content), "<img") {
+		return content
+	}
+
+	var buf strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			if tokenizer.Err() != io.EOF {
+				return content
+			}
+			return buf.String()
+		}
+
+		raw := tokenizer.Raw()
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			buf.Write(raw)
+			continue
+		}
+
+		// Token() may overwrite the raw text, so copy it first
+		raw = append([]byte(nil), raw...)
+		token := tokenizer.Token()
+		if token.DataAtom != atom.Img {
+			buf.Write(raw)
+			continue
+		}
+
+		changed := false
+		for i := range token.Attr {
+			if token.Attr[i].Key != "src" || token.Attr[i].Namespace != "" {
+				continue
+			}
+			if src := link(token.Attr[i].Val); src != token.Attr[i].Val {
+				token.Attr[i].Val = src
+				changed = true
+			}
+		}
+		if changed {
+			buf.WriteString(token.String())
+		} else {
+			buf.Write(raw)
+		}
+	}
+}
+
+// isDownloading reports whether a file is the temporary file of a download
+// that may still be running.
+func isDownloading(d fs.DirEntry) bool {
+	if !strings.HasPrefix(d.Name(), ".download-") {
+		return false
+	}
+	info, err := d.Info()
+	return err == nil && time.Since(info.ModTime()) < time.Hour
+}
+
+func isHTTP(rawURL string) bool {
+	return strings.HasPrefix(rawURL, "https://") || strings.HasPrefix(rawURL, "http://")
+}
@@ -0,0 +1,182 @@
+package media
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/database/databasetest"
+)
+
+// pngData is the start of a PNG file, enough to be sniffed as one.
+var pngData = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x10\x00\x00\x00\x10")
+
+func newImageServer(t *testing.T, requests *int64) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(requests, 1)
+		switch r.URL.Path {
+		case "/a.png", "/copy-of-a.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(pngData)
+		case "/large.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(append(append([]byte{}, pngData...), bytes.Repeat([]byte{0}, 1024)...))
+		case "/drawing.svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+			_, _ = w.Write([]byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewDisabled(t *testing.T) {
+	var cache *Cache
+	if New(config.MediaConfig{}, nil, time.Second) != nil {
+		t.Error("New() should return nil when media caching is disabled")
+	}
+	if media, err := cache.Store("https://example.com/a.png"); media != nil || err != nil {
+		t.Errorf("nil Store() = %v, %v; want nil", media, err)
+	}
+	if err := cache.StoreItem(&database.Item{Content: `<img src="https://example.com/a.png">`}); err != nil {
+		t.Errorf("nil StoreItem() error = %v", err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	db := databasetest.New(t)
+	var requests int64
+	server := newImageServer(t, &requests)
+	dir := t.TempDir()
+	cache := New(config.MediaConfig{Enabled: true, Dir: dir, MaxSize: 512}, db, 5*time.Second)
+
+	media, err := cache.Store(server.URL + "/a.png")
+	if err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if !media.Hash.Valid || media.ContentType != "image/png" || media.Size != int64(len(pngData)) {
+		t.Fatalf("Store() = %+v, want a cached PNG", media)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(Path(media))))
+	if err != nil || !bytes.Equal(data, pngData) {
+		t.Errorf("cached file = %q, %v; want the image", data, err)
+	}
+
+	// Cached images aren't downloaded again, and copies share a file
+	if _, err := cache.Store(server.URL + "/a.png"); err != nil || atomic.LoadInt64(&requests) != 1 {
+		t.Errorf("Store() again made %d requests, want 1", atomic.LoadInt64(&requests))
+	}
+	copied, err := cache.Store(server.URL + "/copy-of-a.png")
+	if err != nil || Path(copied) != Path(media) {
+		t.Errorf("Store(copy) path = %q, want %q", Path(copied), Path(media))
+	}
+
+	for _, path := range []string{"/large.png", "/drawing.svg", "/missing.png"} {
+		failed, err := cache.Store(server.URL + path)
+		if err != nil {
+			t.Fatalf("Store(%s) error = %v", path, err)
+		}
+		if failed.Hash.Valid || !failed.FetchError.Valid {
+			t.Errorf("Store(%s) = %+v, want a failed download", path, failed)
+		}
+	}
+
+	// Failed downloads aren't retried right away
+	before := atomic.LoadInt64(&requests)
+	if _, err := cache.Store(server.URL + "/missing.png"); err != nil || atomic.LoadInt64(&requests) != before {
+		t.Errorf("Store() retried a failed download right away")
+	}
+}
+
+func TestStoreItemAndCollectGarbage(t *testing.T) {
+	db := databasetest.New(t)
+	var requests int64
+	server := newImageServer(t, &requests)
+	dir := t.TempDir()
+	cache := New(config.MediaConfig{Enabled: true, Dir: dir}, db, 5*time.Second)
+
+	feedURL := "https://example.com/feed"
+	if err := db.UpsertFeed(&database.Feed{URL: feedURL}); err != nil {
+		t.Fatal(err)
+	}
+	item := &database.Item{
+		FeedURL: feedURL,
+		GUID:    "item-1",
+		Content: `<p>Look: <img src="` + server.URL + `/a.png" alt="a"></p>`,
+		Summary: `<img src="` + server.URL + `/a.png">`,
+	}
+	if err := db.UpsertItem(item); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.StoreItem(item); err != nil {
+		t.Fatalf("StoreItem() error = %v", err)
+	}
+
+	cached, err := db.GetCachedMedia()
+	if err != nil || len(cached) != 1 {
+		t.Fatalf("GetCachedMedia() = %v, %v; want the item's image", cached, err)
+	}
+	file := filepath.Join(dir, filepath.FromSlash(Path(cached[server.URL+"/a.png"])))
+
+	// A stray file, and the image while the item is kept, survive only as long as they are referenced
+	stray := filepath.Join(dir, "ff", "ffff.png")
+	if err := os.MkdirAll(filepath.Dir(stray), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stray, pngData, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if deleted, err := cache.CollectGarbage(); err != nil || deleted != 1 {
+		t.Errorf("CollectGarbage() = %d, %v; want the stray file deleted", deleted, err)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("CollectGarbage() deleted a referenced file: %v", err)
+	}
+
+	if err := db.DeleteFeed(feedURL); err != nil {
+		t.Fatal(err)
+	}
+	if deleted, err := cache.CollectGarbage(); err != nil || deleted != 1 {
+		t.Errorf("CollectGarbage() = %d, %v; want the item's image deleted", deleted, err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("CollectGarbage() kept an unreferenced file: %v", err)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	content := `<p>One <IMG SRC="https://example.com/a.png" alt="a &amp; b"> two ` +
+		`<img src="/relative.png"> <img src="https://example.com/a.png"/> <a href="https://example.com/a.png">a</a></p>`
+
+	urls := ImageURLs(content)
+	if len(urls) != 1 || urls[0] != "https://example.com/a.png" {
+		t.Errorf("ImageURLs() = %v, want the one absolute image", urls)
+	}
+
+	got := Rewrite(content, func(src string) string {
+		if src == "https://example.com/a.png" {
+			return "/media/aa/aaaa.png"
+		}
+		return src
+	})
+	want := `<p>One <img src="/media/aa/aaaa.png" alt="a &amp; b"> two ` +
+		`<img src="/relative.png"> <img src="/media/aa/aaaa.png"/> <a href="https://example.com/a.png">a</a></p>`
+	if got != want {
+		t.Errorf("Rewrite() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := Rewrite("<p>No images</p>", nil); got != "<p>No images</p>" {
+		t.Errorf("Rewrite() without images = %q", got)
+	}
+}
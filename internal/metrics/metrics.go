@@ -0,0 +1,348 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of metric, as named in the text exposition format.
+const (
+	kindCounter   = "counter"
+	kindGauge     = "gauge"
+	kindHistogram = "histogram"
+)
+
+// ContentType is the media type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DurationBuckets are histogram bucket bounds in seconds, suited to HTTP
+// requests.
+var DurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Default is the registry the rest of feedspool records into.
+var Default = NewRegistry()
+
+// Labels are the label names and values of a metric series.
+type Labels map[string]string
+
+// Registry holds counters, gauges and histograms and writes them in the
+// Prometheus text exposition format.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// family is a metric name and its series, one per distinct set of labels.
+type family struct {
+	help    string
+	kind    string
+	buckets []float64
+	series  map[string]*series // Keyed by rendered labels
+	fn      func() float64     // Gauges read when written, without series
+}
+
+type series struct {
+	labels Labels
+	value  float64  // Counter or gauge value; the sum of observations for histograms
+	counts []uint64 // Observations per bucket, not cumulative
+	count  uint64
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// lookup returns the series of a metric with the given labels, creating it
+// if needed. Returns nil if the name is already used by another kind of
+// metric. The registry must be locked.
+func (r *Registry) lookup(name, help, kind string, buckets []float64, labels Labels) *series {
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{help: help, kind: kind, buckets: buckets, series: make(map[string]*series)}
+		r.families[name] = f
+	}
+	if f.kind != kind || f.fn != nil {
+		return nil
+	}
+
+	key := formatLabels(labels)
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: labels}
+		if kind == kindHistogram {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Add adds delta to a counter.
+func (r *Registry) Add(name, help string, labels Labels, delta float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s := r.lookup(name, help, kindCounter, nil, labels); s != nil {
+		s.value += delta
+	}
+}
+
+// Set sets a gauge.
+func (r *Registry) Set(name, help string, labels Labels, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s := r.lookup(name, help, kindGauge, nil, labels); s != nil {
+		s.value = value
+	}
+}
+
+// Observe records a value in a histogram with the given bucket bounds. The
+// bounds used the first time a histogram is observed are kept.
+func (r *Registry) Observe(name, help string, buckets []float64, labels Labels, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.lookup(name, help, kindHistogram, buckets, labels)
+	if s == nil {
+		return
+	}
+	for i, bound := range r.families[name].buckets {
+		if value <= bound {
+			s.counts[i]++
+			break
+		}
+	}
+	s.value += value
+	s.count++
+}
+
+// GaugeFunc registers a gauge read from fn each time metrics are written,
+// replacing any registered under the same name.
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.families[name] = &family{help: help, kind: kindGauge, fn: fn}
+}
+
+// Write writes every metric in the text exposition format, sorted by name
+// and then by labels.
+func (r *Registry) Write(w io.Writer) error {
+	return r.write(w, nil)
+}
+
+// write writes every metric, adding the extra labels to series that don't
+// have labels of the same names.
+func (r *Registry) write(w io.Writer, extra Labels) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Gauge functions are read outside the lock, in case they are slow
+	rendered := make([]bytes.Buffer, len(names))
+	fns := make(map[int]*family)
+	for i, name := range names {
+		f := r.families[name]
+		if f.fn != nil {
+			fns[i] = f
+			continue
+		}
+		writeFamily(&rendered[i], name, f, extra)
+	}
+	r.mu.Unlock()
+
+	for i, f := range fns {
+		writeHeader(&rendered[i], names[i], f.help, kindGauge)
+		fmt.Fprintf(&rendered[i], "%s%s %s\n", names[i], formatLabels(extra), formatValue(f.fn()))
+	}
+
+	var buf bytes.Buffer
+	for i := range rendered {
+		buf.Write(rendered[i].Bytes())
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeHeader(buf *bytes.Buffer, name, help, kind string) {
+	if help != "" {
+		fmt.Fprintf(buf, "# HELP %s %s\n", name, escapeHelp(help))
+	}
+	fmt.Fprintf(buf, "# TYPE %s %s\n", name, kind)
+}
+
+func writeFamily(buf *bytes.Buffer, name string, f *family, extra Labels) {
+	if len(f.series) == 0 {
+		return
+	}
+	writeHeader(buf, name, f.help, f.kind)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+		if len(extra) > 0 {
+			key = formatLabels(withDefaults(s.labels, extra))
+		}
+		if f.kind != kindHistogram {
+			fmt.Fprintf(buf, "%s%s %s\n", name, key, formatValue(s.value))
+			continue
+		}
+
+		var cumulative uint64
+		for i, bound := range f.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(buf, "%s_bucket%s %d\n", name, withLabel(key, "le", formatValue(bound)), cumulative)
+		}
+		fmt.Fprintf(buf, "%s_bucket%s %d\n", name, withLabel(key, "le", "+Inf"), s.count)
+		fmt.Fprintf(buf, "%s_sum%s %s\n", name, key, formatValue(s.value))
+		fmt.Fprintf(buf, "%s_count%s %d\n", name, key, s.count)
+	}
+}
+
+// formatLabels renders labels as they appear after a metric name, sorted by
+// name, or as nothing when there are none.
+func formatLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + escapeLabelValue(labels[name]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// withDefaults returns labels with the defaults added for names it lacks.
+func withDefaults(labels, defaults Labels) Labels {
+	merged := make(Labels, len(labels)+len(defaults))
+	for name, value := range defaults {
+		merged[name] = value
+	}
+	for name, value := range labels {
+		merged[name] = value
+	}
+	return merged
+}
+
+// withLabel adds a label to rendered labels.
+func withLabel(rendered, name, value string) string {
+	pair := name + `="` + escapeLabelValue(value) + `"`
+	if rendered == "" {
+		return "{" + pair + "}"
+	}
+	return strings.TrimSuffix(rendered, "}") + "," + pair + "}"
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+}
+
+// Handler serves the registry's metrics for Prometheus to scrape.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_ = r.Write(w)
+	})
+}
+
+// WriteFile writes the metrics to a file for node_exporter's textfile
+// collector, adding labels to series that lack them so files written by
+// several processes don't repeat each other's series. The file is written
+// under a temporary name and renamed, so the collector never reads it half
+// written.
+func (r *Registry) WriteFile(path string, labels Labels) error {
+	var buf bytes.Buffer
+	if err := r.write(&buf, labels); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".metrics-*")
+	if err != nil {
+		return fmt.Errorf("failed to create metrics file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	// The collector usually runs as another user
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil { //nolint:gosec // Metrics are meant to be read
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store metrics file: %w", err)
+	}
+	return nil
+}
+
+// Push sends the metrics to a Prometheus Pushgateway URL such as
+// http://pushgateway:9091/metrics/job/feedspool, replacing those pushed
+// before under the same grouping.
+func (r *Registry) Push(url string, timeout time.Duration) error {
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, url, &buf)
+	if err != nil {
+		return fmt.Errorf("failed to create push request: %w", err)
+	}
+	req.Header.Set("Content-Type", ContentType)
+
+	resp, err := (&http.Client{Timeout: timeout}).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to push metrics: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	r := NewRegistry()
+	r.Add("test_fetches_total", "Feeds fetched.", Labels{"result": "error"}, 1)
+	r.Add("test_fetches_total", "Feeds fetched.", Labels{"result": "cached"}, 2)
+	r.Add("test_fetches_total", "Feeds fetched.", Labels{"result": "cached"}, 1)
+	r.Set("test_depth", "Jobs waiting.\nSecond line.", nil, 7)
+	r.Set("test_depth", "Jobs waiting.\nSecond line.", nil, 4)
+	r.Observe("test_seconds", "Request time.", []float64{0.1, 1}, Labels{"code": "200"}, 0.05)
+	r.Observe("test_seconds", "Request time.", []float64{0.1, 1}, Labels{"code": "200"}, 0.5)
+	r.Observe("test_seconds", "Request time.", []float64{0.1, 1}, Labels{"code": "200"}, 3)
+	r.GaugeFunc("test_bytes", "", func() float64 { return 1024 })
+	r.Add("test_label_escaping_total", "", Labels{"url": `a "quoted" \ path`}, 1)
+
+	// Recording a name as another kind of metric is ignored
+	r.Set("test_fetches_total", "", Labels{"result": "cached"}, 100)
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# TYPE test_bytes gauge
+test_bytes 1024
+# HELP test_depth Jobs waiting.\nSecond line.
+# TYPE test_depth gauge
+test_depth 4
+# HELP test_fetches_total Feeds fetched.
+# TYPE test_fetches_total counter
+test_fetches_total{result="cached"} 3
+test_fetches_total{result="error"} 1
+# TYPE test_label_escaping_total counter
+test_label_escaping_total{url="a \"quoted\" \\ path"} 1
+# HELP test_seconds Request time.
+# TYPE test_seconds histogram
+test_seconds_bucket{code="200",le="0.1"} 1
+test_seconds_bucket{code="200",le="1"} 2
+test_seconds_bucket{code="200",le="+Inf"} 3
+test_seconds_sum{code="200"} 3.55
+test_seconds_count{code="200"} 3
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.Set("test_depth", "", nil, 1)
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Header().Get("Content-Type") != ContentType {
+		t.Errorf("Content-Type = %q, want %q", rec.Header().Get("Content-Type"), ContentType)
+	}
+	if got := rec.Body.String(); got != "# TYPE test_depth gauge\ntest_depth 1\n" {
+		t.Errorf("body = %q", got)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	r := NewRegistry()
+	r.Set("test_depth", "", nil, 1)
+	r.Add("test_runs_total", "", Labels{"command": "render"}, 1)
+	r.GaugeFunc("test_bytes", "", func() float64 { return 2 })
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "feedspool.prom")
+	if err := r.WriteFile(path, Labels{"command": "fetch"}); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# TYPE test_bytes gauge
+test_bytes{command="fetch"} 2
+# TYPE test_depth gauge
+test_depth{command="fetch"} 1
+# TYPE test_runs_total counter
+test_runs_total{command="render"} 1
+`
+	if string(data) != want {
+		t.Errorf("file =\n%s\nwant\n%s", data, want)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o644 {
+		t.Errorf("file mode = %v, want 0644", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the metrics file", len(entries))
+	}
+}
+
+func TestPush(t *testing.T) {
+	var method, contentType, body string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		method = req.Method
+		contentType = req.Header.Get("Content-Type")
+		data, _ := io.ReadAll(req.Body)
+		body = string(data)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	r := NewRegistry()
+	r.Set("test_depth", "", nil, 1)
+
+	if err := r.Push(server.URL+"/metrics/job/feedspool", time.Second); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	if method != http.MethodPut || contentType != ContentType || body != "# TYPE test_depth gauge\ntest_depth 1\n" {
+		t.Errorf("Push() sent %s %q %q", method, contentType, body)
+	}
+
+	status = http.StatusBadRequest
+	if err := r.Push(server.URL+"/metrics/job/feedspool", time.Second); err == nil {
+		t.Error("Push() error = nil, want the rejection reported")
+	}
+}
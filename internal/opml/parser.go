@@ -4,6 +4,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"strings"
 )
 
 type OPML struct {
@@ -26,7 +27,15 @@ type Outline struct {
 	Type     string    `xml:"type,attr"`
 	XMLURL   string    `xml:"xmlUrl,attr"`
 	HTMLURL  string    `xml:"htmlUrl,attr"`
+	Category string    `xml:"category,attr"`
 	Outlines []Outline `xml:"outline"`
+
+	// feedspool's per-feed settings, so lists converted to OPML keep them
+	TitleOverride string `xml:"feedspoolTitle,attr"`
+	Interval      string `xml:"feedspoolInterval,attr"`
+	MaxItems      string `xml:"feedspoolMaxItems,attr"`
+	Hidden        string `xml:"feedspoolHidden,attr"`
+	Retention     string `xml:"feedspoolRetention,attr"`
 }
 
 func ParseOPML(reader io.Reader) (*OPML, error) {
@@ -44,19 +53,91 @@ func ParseOPML(reader io.Reader) (*OPML, error) {
 	return opml, nil
 }
 
+// ExtractFeedURLs returns the feed URLs in the order they first appear. A
+// feed filed in several folders is listed once.
 func ExtractFeedURLs(opml *OPML) []string {
 	urls := []string{}
-	extractFromOutlines(opml.Body.Outlines, &urls)
+	extractFromOutlines(opml.Body.Outlines, &urls, make(map[string]bool))
 	return urls
 }
 
-func extractFromOutlines(outlines []Outline, urls *[]string) {
+func extractFromOutlines(outlines []Outline, urls *[]string, seen map[string]bool) {
 	for _, outline := range outlines {
-		if outline.XMLURL != "" {
+		if outline.XMLURL != "" && !seen[outline.XMLURL] {
+			seen[outline.XMLURL] = true
 			*urls = append(*urls, outline.XMLURL)
 		}
 		if len(outline.Outlines) > 0 {
-			extractFromOutlines(outline.Outlines, urls)
+			extractFromOutlines(outline.Outlines, urls, seen)
+		}
+	}
+}
+
+// ExtractFeedTags returns the tags for each feed URL: the path of the folder
+// outline it sits in, with nested folder names joined by "/", plus any listed
+// in its category attribute. Feeds without tags are left out.
+func ExtractFeedTags(opml *OPML) map[string][]string {
+	tags := make(map[string][]string)
+	extractTagsFromOutlines(opml.Body.Outlines, nil, tags)
+	return tags
+}
+
+func extractTagsFromOutlines(outlines []Outline, folders []string, tags map[string][]string) {
+	for _, outline := range outlines {
+		if outline.XMLURL != "" {
+			var feedTags []string
+			if len(folders) > 0 {
+				feedTags = append(feedTags, strings.Join(folders, "/"))
+			}
+			for _, tag := range append(feedTags, ParseCategories(outline.Category)...) {
+				if !containsTag(tags[outline.XMLURL], tag) {
+					tags[outline.XMLURL] = append(tags[outline.XMLURL], tag)
+				}
+			}
+		}
+		if len(outline.Outlines) > 0 {
+			childFolders := folders
+			if outline.XMLURL == "" && FolderName(outline) != "" {
+				childFolders = append(append([]string{}, folders...), FolderName(outline))
+			}
+			extractTagsFromOutlines(outline.Outlines, childFolders, tags)
+		}
+	}
+}
+
+// FolderName returns the name of a folder outline, preferring its text.
+func FolderName(outline Outline) string {
+	name := strings.TrimSpace(outline.Text)
+	if name == "" {
+		name = strings.TrimSpace(outline.Title)
+	}
+	return name
+}
+
+// ParseCategories splits an OPML category attribute, a comma-separated list of
+// slash-delimited paths such as "/Tech/Go,News", into tags, one per path
+// ("Tech/Go" and "News").
+func ParseCategories(category string) []string {
+	var tags []string
+	for _, part := range strings.Split(category, ",") {
+		var names []string
+		for _, name := range strings.Split(part, "/") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		if tag := strings.Join(names, "/"); tag != "" && !containsTag(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, existing := range tags {
+		if existing == tag {
+			return true
 		}
 	}
+	return false
 }
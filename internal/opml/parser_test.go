@@ -56,3 +56,40 @@ func TestExtractFeedURLsEmpty(t *testing.T) {
 		t.Errorf("len(urls) = %v, want %v", len(urls), 0)
 	}
 }
+
+func TestExtractFeedTags(t *testing.T) {
+	opmlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+    <head><title>Folders</title></head>
+    <body>
+        <outline text="Tech">
+            <outline text="Go">
+                <outline text="Go Blog" type="rss" xmlUrl="https://go.dev/blog/feed.atom" />
+            </outline>
+            <outline text="Lobsters" type="rss" xmlUrl="https://lobste.rs/rss" category="/News/Daily,Tech" />
+        </outline>
+        <outline text="Loose" type="rss" xmlUrl="https://example.com/feed.xml" />
+    </body>
+</opml>`
+
+	opml, err := ParseOPML(strings.NewReader(opmlContent))
+	if err != nil {
+		t.Fatalf("ParseOPML() error = %v", err)
+	}
+
+	tags := ExtractFeedTags(opml)
+	expected := map[string][]string{
+		"https://go.dev/blog/feed.atom": {"Tech/Go"},
+		"https://lobste.rs/rss":         {"Tech", "News/Daily"},
+	}
+
+	if len(tags) != len(expected) {
+		t.Fatalf("ExtractFeedTags() = %v, want %v", tags, expected)
+	}
+	for url, want := range expected {
+		got := tags[url]
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("tags[%s] = %v, want %v", url, got, want)
+		}
+	}
+}
@@ -8,6 +8,7 @@
  * - Layout controller for view mode switching and preference persistence
  * - Lightbox overlay for card view modal display
  * - Shared utilities for debouncing and other common functions
+ * - Read and starred state via the JSON API, when served with `serve --api`
  */
 
 // Import all custom elements and utilities
@@ -18,6 +19,7 @@ import './js/lightbox-overlay.js';
 import './js/link-loader.js';
 import './js/feed-navigator.js';
 import './js/time-formatter.js';
+import './js/reader-api.js';
 
 // All custom elements are automatically registered when their modules are imported
 // No additional initialization needed - the modules handle their own setup
\ No newline at end of file
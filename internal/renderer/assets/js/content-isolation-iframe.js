@@ -83,8 +83,16 @@ export class ContentIsolationIframe extends HTMLElement {
         const dataSrc = this.iframe.getAttribute('data-src');
         if (!dataSrc) return;
 
-        // Set the actual src from data-src
-        this.iframe.src = dataSrc;
+        // With media caching, load rendered content through srcdoc rather than
+        // its data: URL, so site-relative links to cached images resolve
+        // against this page. The sandbox keeps it isolated all the same.
+        const content = document.body.hasAttribute('data-local-media') ? decodeDataURL(dataSrc) : null;
+        if (content !== null) {
+            this.iframe.setAttribute('sandbox', 'allow-scripts');
+            this.iframe.srcdoc = content;
+        } else {
+            this.iframe.src = dataSrc;
+        }
         this.iframe.removeAttribute('data-src');
         this.isLoaded = true;
 
@@ -128,6 +136,23 @@ export class ContentIsolationIframe extends HTMLElement {
     }
 }
 
+/**
+ * Decode the HTML of a base64 data: URL, or return null for any other URL
+ */
+function decodeDataURL(url) {
+    const prefix = 'data:text/html;charset=utf-8;base64,';
+    if (!url.startsWith(prefix)) return null;
+
+    try {
+        const binary = atob(url.slice(prefix.length));
+        const bytes = Uint8Array.from(binary, (c) => c.charCodeAt(0));
+        return new TextDecoder().decode(bytes);
+    } catch (error) {
+        console.warn('content-isolation-iframe: Failed to decode content', error);
+        return null;
+    }
+}
+
 function getContentIsolationIframeSharedIntersectionObserver() {
     if (!sharedContentIsolationIframeIntersectionObserver) {
         sharedContentIsolationIframeIntersectionObserver = createLazyLoadObserver(
@@ -145,7 +170,7 @@ function getContentIsolationIframeSharedIntersectionObserver() {
 function setupSharedContentIsolationIframeMessageHandler() {
     if (!sharedContentIsolationIframeMessageHandler) {
         sharedContentIsolationIframeMessageHandler = (event) => {
-            // Security: Only accept messages from our own iframes (data URLs)
+            // Security: Only accept messages from our own iframes (data URLs or sandboxed srcdoc)
             if (!event.origin.startsWith('data:') && event.origin !== 'null') {
                 return;
             }
@@ -0,0 +1,167 @@
+/**
+ * Reader API
+ * Progressive enhancement for sites served by `feedspool serve --api`:
+ * opening an item marks it read, the star button toggles starred, and unread
+ * badges follow the database instead of the last render. On a plain static
+ * host the API is absent and the page keeps its rendered state.
+ */
+
+const API_ROOT = '/api/';
+const TOKEN_KEY = 'feedspool.apiToken';
+
+let availability = null;
+
+/**
+ * Builds request headers, adding the API token when serve.api_token is set.
+ * Store it once per browser with localStorage.setItem('feedspool.apiToken', '...')
+ * @param {Object} [headers] - Other headers to send
+ * @returns {Object}
+ */
+function apiHeaders(headers = {}) {
+    let token = null;
+    try {
+        token = localStorage.getItem(TOKEN_KEY);
+    } catch (error) {
+        // Storage can be disabled; requests then go without a token
+    }
+    return token ? { ...headers, Authorization: `Bearer ${token}` } : headers;
+}
+
+/**
+ * Checks once whether the JSON API is being served alongside the site
+ * @returns {Promise<boolean>}
+ */
+function apiAvailable() {
+    if (!availability) {
+        availability = fetch(API_ROOT, { headers: apiHeaders({ Accept: 'application/json' }) })
+            .then((response) => response.ok)
+            .catch(() => false);
+    }
+    return availability;
+}
+
+/**
+ * Updates an item's read and starred state
+ * @param {string} id - Item ID
+ * @param {Object} state - { read?: boolean, starred?: boolean }
+ * @returns {Promise<Object>} The updated item
+ */
+async function updateItem(id, state) {
+    const response = await fetch(`${API_ROOT}items/${encodeURIComponent(id)}`, {
+        method: 'PATCH',
+        headers: apiHeaders({ 'Content-Type': 'application/json' }),
+        body: JSON.stringify(state)
+    });
+    if (!response.ok) {
+        throw new Error(`Failed to update item ${id}: ${response.status}`);
+    }
+    return response.json();
+}
+
+/**
+ * Sets the unread badges for a feed (or the total, when feedURL is null)
+ * @param {string|null} feedURL
+ * @param {number} count
+ */
+function setUnreadBadges(feedURL, count) {
+    const selector = feedURL === null
+        ? '.unread-count[data-unread-total]'
+        : `.unread-count[data-feed-url="${CSS.escape(feedURL)}"]`;
+    document.querySelectorAll(selector).forEach((badge) => {
+        badge.textContent = count;
+        badge.title = `${count} unread`;
+        badge.hidden = count === 0;
+    });
+}
+
+/**
+ * Adjusts the badges for a feed and the total by delta
+ * @param {string} feedURL
+ * @param {number} delta
+ */
+function adjustUnreadBadges(feedURL, delta) {
+    for (const badge of document.querySelectorAll('.unread-count[data-feed-url], .unread-count[data-unread-total]')) {
+        if (badge.hasAttribute('data-unread-total') || badge.dataset.feedUrl === feedURL) {
+            const count = Math.max(0, (parseInt(badge.textContent, 10) || 0) + delta);
+            badge.textContent = count;
+            badge.title = `${count} unread`;
+            badge.hidden = count === 0;
+        }
+    }
+}
+
+async function refreshUnreadCounts() {
+    const response = await fetch(`${API_ROOT}feeds`, { headers: apiHeaders() });
+    if (!response.ok) {
+        return;
+    }
+    const { feeds } = await response.json();
+    // A tag section's total only counts feeds with its tag
+    const totalBadge = document.querySelector('.unread-count[data-unread-total]');
+    const tag = totalBadge ? totalBadge.dataset.unreadTag : undefined;
+    let total = 0;
+    for (const feed of feeds) {
+        setUnreadBadges(feed.url, feed.unread_count);
+        if (!tag || (feed.tags || []).includes(tag)) {
+            total += feed.unread_count;
+        }
+    }
+    setUnreadBadges(null, total);
+}
+
+async function markRead(item) {
+    if (!item.classList.contains('item-unread')) {
+        return;
+    }
+    // Update the page first so a quick close and reopen doesn't double count
+    item.classList.replace('item-unread', 'item-read');
+    adjustUnreadBadges(item.dataset.feedUrl, -1);
+    try {
+        await updateItem(item.dataset.itemId, { read: true });
+    } catch (error) {
+        console.warn('ReaderAPI:', error);
+        item.classList.replace('item-read', 'item-unread');
+        adjustUnreadBadges(item.dataset.feedUrl, 1);
+    }
+}
+
+async function toggleStar(item, button) {
+    const starred = !item.classList.contains('item-starred');
+    try {
+        await updateItem(item.dataset.itemId, { starred });
+        item.classList.toggle('item-starred', starred);
+        button.setAttribute('aria-pressed', String(starred));
+    } catch (error) {
+        console.warn('ReaderAPI:', error);
+    }
+}
+
+async function init() {
+    if (!await apiAvailable()) {
+        return;
+    }
+    document.documentElement.classList.add('api-enabled');
+
+    // Items arrive later in fragments loaded by link-loader, so listen on the
+    // document. The toggle event doesn't bubble, hence the capture phase.
+    document.addEventListener('toggle', (event) => {
+        const item = event.target;
+        if (item.matches && item.matches('details.item[data-item-id]') && item.open) {
+            markRead(item);
+        }
+    }, true);
+
+    document.addEventListener('click', (event) => {
+        const button = event.target.closest && event.target.closest('.item-star-toggle');
+        const item = button && button.closest('details.item[data-item-id]');
+        if (item) {
+            // The button sits in the summary; don't let it open the item
+            event.preventDefault();
+            toggleStar(item, button);
+        }
+    });
+
+    refreshUnreadCounts().catch((error) => console.warn('ReaderAPI:', error));
+}
+
+init();
@@ -3,6 +3,9 @@
  * Finds all <time> elements with datetime attributes and formats them
  * as relative time ("2 hours ago") or localized absolute time in user's timezone
  *
+ * Elements with data-format="day" hold a calendar date (YYYY-MM-DD, in UTC) and
+ * are shown as "Today", "Yesterday" or a localized date, as river day headings.
+ *
  * Listens for 'content-loaded' events from other components to format newly loaded content.
  */
 
@@ -55,7 +58,9 @@ export class TimeFormatter extends HTMLElement {
         const date = new Date(datetime);
         if (isNaN(date.getTime())) return;
 
-        const formatted = this.formatDateTime(date);
+        const formatted = timeElement.dataset.format === 'day'
+            ? this.formatDay(date)
+            : this.formatDateTime(date);
 
         // Store original text as title for hover (only on first format)
         if (!timeElement.hasAttribute('title')) {
@@ -128,6 +133,26 @@ export class TimeFormatter extends HTMLElement {
         });
     }
 
+    formatDay(date) {
+        // Day headings are UTC dates, so compare against today's UTC date
+        const now = new Date();
+        const today = Date.UTC(now.getUTCFullYear(), now.getUTCMonth(), now.getUTCDate());
+        const diffDays = Math.round((today - date.getTime()) / 86400000);
+
+        if (diffDays === 0) {
+            return 'Today';
+        }
+        if (diffDays === 1) {
+            return 'Yesterday';
+        }
+
+        const options = { weekday: 'long', month: 'short', day: 'numeric', timeZone: 'UTC' };
+        if (diffDays >= 7) {
+            options.year = 'numeric';
+        }
+        return date.toLocaleDateString(undefined, options);
+    }
+
     formatTime(date) {
         return date.toLocaleTimeString(undefined, {
             hour: 'numeric',
@@ -0,0 +1,92 @@
+package renderer
+
+import (
+	"github.com/lmorchard/feedspool-go/internal/database"
+)
+
+// DuplicateLink is another feed's copy of a rendered item, collapsed into it.
+type DuplicateLink struct {
+	FeedTitle string
+	FeedURL   string
+	Link      string // The copy's own link, e.g. an aggregator's discussion page
+}
+
+// collapseDuplicates keeps one item of each duplicate group: the group's
+// first item if it is rendered, otherwise the earliest seen. The other copies
+// are dropped and returned as links keyed by the kept item's ID, except that
+// each feed keeps its newest copies when needed to show at least minItems
+// items. Feeds left without items are dropped.
+func collapseDuplicates(feeds []database.Feed, items map[string][]database.Item, minItems int,
+) ([]database.Feed, map[string][]database.Item, map[int64][]DuplicateLink) {
+	kept := make(map[int64]*database.Item)
+	for i := range feeds {
+		feedItems := items[feeds[i].URL]
+		for j := range feedItems {
+			item := &feedItems[j]
+			group := duplicateGroup(item)
+			if current, ok := kept[group]; !ok || keepBefore(item, current, group) {
+				kept[group] = item
+			}
+		}
+	}
+
+	duplicates := make(map[int64][]DuplicateLink)
+	collapsed := make(map[string][]database.Item)
+	remaining := []database.Feed{}
+	for i := range feeds {
+		// Copies the feed keeps to stay at minItems
+		spare := minItems
+		for _, item := range items[feeds[i].URL] {
+			if kept[duplicateGroup(&item)].ID == item.ID {
+				spare--
+			}
+		}
+
+		feedItems := []database.Item{}
+		for _, item := range items[feeds[i].URL] {
+			keeper := kept[duplicateGroup(&item)]
+			if keeper.ID == item.ID {
+				feedItems = append(feedItems, item)
+				continue
+			}
+			if spare > 0 {
+				spare--
+				feedItems = append(feedItems, item)
+				continue
+			}
+			duplicates[keeper.ID] = append(duplicates[keeper.ID], DuplicateLink{
+				FeedTitle: feeds[i].Title,
+				FeedURL:   feeds[i].URL,
+				Link:      item.Link,
+			})
+		}
+
+		if len(feedItems) > 0 {
+			collapsed[feeds[i].URL] = feedItems
+			remaining = append(remaining, feeds[i])
+		}
+	}
+
+	return remaining, collapsed, duplicates
+}
+
+// duplicateGroup returns the ID of the first item of the item's group, which
+// is the item itself when it has no duplicate.
+func duplicateGroup(item *database.Item) int64 {
+	if item.DuplicateOf.Valid {
+		return item.DuplicateOf.Int64
+	}
+	return item.ID
+}
+
+// keepBefore reports whether item should be shown for its group instead of
+// current.
+func keepBefore(item, current *database.Item, group int64) bool {
+	if current.ID == group || item.ID == group {
+		return item.ID == group
+	}
+	if item.FirstSeen.Valid && current.FirstSeen.Valid && !item.FirstSeen.Time.Equal(current.FirstSeen.Time) {
+		return item.FirstSeen.Time.Before(current.FirstSeen.Time)
+	}
+	return item.ID < current.ID
+}
@@ -0,0 +1,121 @@
+package renderer
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+)
+
+func TestCollapseDuplicates(t *testing.T) {
+	seen := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	group := sql.NullInt64{Int64: 1, Valid: true}
+	feeds := []database.Feed{
+		{URL: "https://a.example/feed", Title: "Alpha"},
+		{URL: "https://b.example/feed", Title: "Beta"},
+		{URL: "https://c.example/feed", Title: "Gamma"},
+	}
+	items := map[string][]database.Item{
+		"https://a.example/feed": {
+			{ID: 2, FeedURL: "https://a.example/feed", Title: "Story", Link: "https://a.example/story",
+				DuplicateOf: group, FirstSeen: sql.NullTime{Time: seen.Add(time.Hour), Valid: true}},
+			{ID: 5, FeedURL: "https://a.example/feed", Title: "Other"},
+		},
+		"https://b.example/feed": {
+			{ID: 3, FeedURL: "https://b.example/feed", Title: "Story", Link: "https://b.example/comments",
+				DuplicateOf: group, FirstSeen: sql.NullTime{Time: seen, Valid: true}},
+		},
+		"https://c.example/feed": {
+			{ID: 4, FeedURL: "https://c.example/feed", Title: "Story", DuplicateOf: group,
+				FirstSeen: sql.NullTime{Time: seen.Add(2 * time.Hour), Valid: true}},
+		},
+	}
+
+	// The group's first item (ID 1) isn't rendered, so the earliest seen copy is kept
+	remaining, collapsed, duplicates := collapseDuplicates(feeds, items, 0)
+
+	if len(remaining) != 2 || remaining[0].Title != "Alpha" || remaining[1].Title != "Beta" {
+		t.Errorf("collapseDuplicates() feeds = %v, want Alpha and Beta", remaining)
+	}
+	if len(collapsed["https://a.example/feed"]) != 1 || collapsed["https://a.example/feed"][0].ID != 5 {
+		t.Errorf("Alpha items = %v, want only item 5", collapsed["https://a.example/feed"])
+	}
+	if len(collapsed["https://b.example/feed"]) != 1 || collapsed["https://b.example/feed"][0].ID != 3 {
+		t.Errorf("Beta items = %v, want item 3", collapsed["https://b.example/feed"])
+	}
+
+	links := duplicates[3]
+	if len(links) != 2 || links[0].FeedTitle != "Alpha" || links[0].Link != "https://a.example/story" ||
+		links[1].FeedTitle != "Gamma" {
+		t.Errorf("duplicates[3] = %+v, want Alpha then Gamma", links)
+	}
+	if len(duplicates[5]) != 0 {
+		t.Errorf("duplicates[5] = %+v, want none", duplicates[5])
+	}
+}
+
+func TestCollapseDuplicatesMinItems(t *testing.T) {
+	group := sql.NullInt64{Int64: 1, Valid: true}
+	feeds := []database.Feed{
+		{URL: "https://a.example/feed", Title: "Alpha"},
+		{URL: "https://b.example/feed", Title: "Beta"},
+	}
+	items := map[string][]database.Item{
+		"https://a.example/feed": {
+			{ID: 1, FeedURL: "https://a.example/feed", Title: "Story"},
+			{ID: 2, FeedURL: "https://a.example/feed", Title: "Other"},
+		},
+		"https://b.example/feed": {
+			{ID: 3, FeedURL: "https://b.example/feed", Title: "Story", DuplicateOf: group},
+			{ID: 4, FeedURL: "https://b.example/feed", Title: "Second", DuplicateOf: sql.NullInt64{Int64: 2, Valid: true}},
+			{ID: 5, FeedURL: "https://b.example/feed", Title: "Own"},
+		},
+	}
+
+	// Beta keeps its newest copy to show two items, and collapses the other
+	remaining, collapsed, duplicates := collapseDuplicates(feeds, items, 2)
+
+	if len(remaining) != 2 {
+		t.Errorf("collapseDuplicates() feeds = %v, want both", remaining)
+	}
+	beta := collapsed["https://b.example/feed"]
+	if len(beta) != 2 || beta[0].ID != 3 || beta[1].ID != 5 {
+		t.Errorf("Beta items = %v, want items 3 and 5", beta)
+	}
+	if len(duplicates[1]) != 0 || len(duplicates[2]) != 1 || duplicates[2][0].FeedTitle != "Beta" {
+		t.Errorf("duplicates = %+v, want only item 4 collapsed into item 2", duplicates)
+	}
+}
+
+func TestRenderDuplicates(t *testing.T) {
+	outputDir := t.TempDir()
+	feed := database.Feed{URL: "https://b.example/feed", Title: "Beta"}
+	context := &TemplateContext{
+		Items: map[string][]database.Item{
+			feed.URL: {{ID: 3, FeedURL: feed.URL, Title: "Story", Link: "https://b.example/story"}},
+		},
+		Duplicates: map[int64][]DuplicateLink{
+			3: {
+				{FeedTitle: "Alpha", FeedURL: "https://a.example/feed", Link: "https://a.example/story"},
+				{FeedTitle: "Gamma", FeedURL: "https://c.example/feed"},
+			},
+		},
+	}
+
+	if err := renderSingleFeed(NewRenderer("", ""), outputDir, &feed, context); err != nil {
+		t.Fatalf("renderSingleFeed() error = %v", err)
+	}
+
+	page, err := os.ReadFile(filepath.Join(outputDir, generateFeedID(feed.URL)+".html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `also in: <a href="https://a.example/story" target="_blank">Alpha</a>, Gamma`
+	if !strings.Contains(string(page), want) {
+		t.Errorf("feed page missing %q", want)
+	}
+}
@@ -0,0 +1,125 @@
+package renderer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	configpkg "github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/media"
+	"github.com/sirupsen/logrus"
+)
+
+// mediaDirName is the directory of the site holding copies of cached images.
+const mediaDirName = "media"
+
+// mediaLinker links images to their copies in the site, copying them from
+// the media cache as they are first linked.
+type mediaLinker struct {
+	cached    map[string]*database.Media // Image URL -> cache entry
+	cacheDir  string
+	outputDir string
+	prefix    string          // URL of the site's media directory, ending in a slash
+	published map[string]bool // Paths already copied into the site
+}
+
+// newMediaLinker creates a linker for the images cached in cacheDir. Copies
+// are linked from baseURL when it is set, otherwise from the site root.
+func newMediaLinker(db *database.DB, cacheDir, outputDir, baseURL string) (*mediaLinker, error) {
+	cached, err := db.GetCachedMedia()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached media: %w", err)
+	}
+
+	return &mediaLinker{
+		cached:    cached,
+		cacheDir:  cacheDir,
+		outputDir: outputDir,
+		prefix:    strings.TrimSuffix(baseURL, "/") + "/" + mediaDirName + "/",
+		published: make(map[string]bool),
+	}, nil
+}
+
+// link returns the URL of the site's copy of an image, or the image URL
+// itself if it isn't cached.
+func (l *mediaLinker) link(imageURL string) string {
+	entry := l.cached[imageURL]
+	if entry == nil {
+		return imageURL
+	}
+
+	path := media.Path(entry)
+	if !l.published[path] {
+		if err := l.publish(path); err != nil {
+			logrus.Warnf("Failed to publish cached copy of %s: %v", imageURL, err)
+			return imageURL
+		}
+		l.published[path] = true
+	}
+	return l.prefix + path
+}
+
+// publish copies a cached file into the site, unless it is already there.
+// Files are named by their content, so an existing copy is always current.
+func (l *mediaLinker) publish(path string) error {
+	target := filepath.Join(l.outputDir, mediaDirName, filepath.FromSlash(path))
+	if _, err := os.Stat(target); err == nil {
+		return nil
+	}
+
+	source, err := os.Open(filepath.Join(l.cacheDir, filepath.FromSlash(path)))
+	if err != nil {
+		return fmt.Errorf("failed to open cached file: %w", err)
+	}
+	defer source.Close()
+
+	if err := os.MkdirAll(filepath.Dir(target), configpkg.DefaultDirPerm); err != nil {
+		return fmt.Errorf("failed to create media directory: %w", err)
+	}
+	file, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("failed to create media file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, source); err != nil {
+		return fmt.Errorf("failed to copy media file: %w", err)
+	}
+	return nil
+}
+
+// localizeMedia links the images in items, metadata and favicons to their
+// cached copies. Items are copied rather than changed, so syndication feeds
+// keep linking the originals.
+func localizeMedia(linker *mediaLinker, items map[string][]database.Item,
+	metadata map[string]*database.URLMetadata, feedFavicon map[string]string,
+) map[string][]database.Item {
+	localized := make(map[string][]database.Item, len(items))
+	for feedURL, feedItems := range items {
+		copied := make([]database.Item, len(feedItems))
+		for i := range feedItems {
+			copied[i] = feedItems[i]
+			copied[i].Content = media.Rewrite(feedItems[i].Content, linker.link)
+			copied[i].Summary = media.Rewrite(feedItems[i].Summary, linker.link)
+		}
+		localized[feedURL] = copied
+	}
+
+	for _, meta := range metadata {
+		if meta.ImageURL.Valid {
+			meta.ImageURL.String = linker.link(meta.ImageURL.String)
+		}
+		if meta.Content.Valid {
+			meta.Content.String = media.Rewrite(meta.Content.String, linker.link)
+		}
+	}
+
+	for feedURL, favicon := range feedFavicon {
+		feedFavicon[feedURL] = linker.link(favicon)
+	}
+
+	return localized
+}
@@ -0,0 +1,107 @@
+package renderer
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+)
+
+func TestLocalizeMedia(t *testing.T) {
+	cacheDir := t.TempDir()
+	outputDir := t.TempDir()
+
+	thumb := &database.Media{
+		URL:         "https://cdn.example/thumb.png",
+		Hash:        sql.NullString{String: "abcd1234", Valid: true},
+		ContentType: "image/png",
+	}
+	icon := &database.Media{
+		URL:         "https://a.example/favicon.ico",
+		Hash:        sql.NullString{String: "ef567890", Valid: true},
+		ContentType: "image/x-icon",
+	}
+	for _, path := range []string{"ab/abcd1234.png", "ef/ef567890.ico"} {
+		file := filepath.Join(cacheDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(path), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	linker := &mediaLinker{
+		cached:    map[string]*database.Media{thumb.URL: thumb, icon.URL: icon},
+		cacheDir:  cacheDir,
+		outputDir: outputDir,
+		prefix:    "/media/",
+		published: make(map[string]bool),
+	}
+
+	items := map[string][]database.Item{
+		"https://a.example/feed": {{
+			ID:      1,
+			Content: `<p><img src="https://cdn.example/thumb.png"> <img src="https://cdn.example/other.png"></p>`,
+		}},
+	}
+	metadata := map[string]*database.URLMetadata{
+		"https://a.example/post": {ImageURL: sql.NullString{String: "https://cdn.example/thumb.png", Valid: true}},
+	}
+	favicons := map[string]string{"https://a.example/feed": "https://a.example/favicon.ico"}
+
+	localized := localizeMedia(linker, items, metadata, favicons)
+
+	want := `<p><img src="/media/ab/abcd1234.png"> <img src="https://cdn.example/other.png"></p>`
+	if got := localized["https://a.example/feed"][0].Content; got != want {
+		t.Errorf("localized content = %s, want %s", got, want)
+	}
+	if items["https://a.example/feed"][0].Content == want {
+		t.Error("localizeMedia() should leave the original items unchanged")
+	}
+	if got := metadata["https://a.example/post"].ImageURL.String; got != "/media/ab/abcd1234.png" {
+		t.Errorf("localized thumbnail = %s", got)
+	}
+	if got := favicons["https://a.example/feed"]; got != "/media/ef/ef567890.ico" {
+		t.Errorf("localized favicon = %s", got)
+	}
+
+	for _, path := range []string{"ab/abcd1234.png", "ef/ef567890.ico"} {
+		data, err := os.ReadFile(filepath.Join(outputDir, mediaDirName, filepath.FromSlash(path)))
+		if err != nil || string(data) != path {
+			t.Errorf("published %s = %q, %v", path, data, err)
+		}
+	}
+
+	// Images missing from the cache directory keep their original URLs
+	linker.cached["https://cdn.example/gone.png"] = &database.Media{
+		Hash:        sql.NullString{String: "99999999", Valid: true},
+		ContentType: "image/png",
+	}
+	if got := linker.link("https://cdn.example/gone.png"); got != "https://cdn.example/gone.png" {
+		t.Errorf("link(missing file) = %s, want the original URL", got)
+	}
+}
+
+func TestRenderLocalMediaFlag(t *testing.T) {
+	feed := database.Feed{URL: "https://a.example/feed", Title: "Alpha"}
+
+	for _, localMedia := range []bool{false, true} {
+		outputDir := t.TempDir()
+		context := &TemplateContext{LocalMedia: localMedia}
+		if err := renderSingleFeed(NewRenderer("", ""), outputDir, &feed, context); err != nil {
+			t.Fatalf("renderSingleFeed() error = %v", err)
+		}
+
+		page, err := os.ReadFile(filepath.Join(outputDir, generateFeedID(feed.URL)+".html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(string(page), "<body data-local-media>"); got != localMedia {
+			t.Errorf("LocalMedia = %v: page marked for local media = %v", localMedia, got)
+		}
+	}
+}
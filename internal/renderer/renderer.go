@@ -25,8 +25,19 @@ type TemplateContext struct {
 	Items       map[string][]database.Item
 	Metadata    map[string]*database.URLMetadata // URL -> metadata
 	FeedFavicon map[string]string                // feed URL -> favicon URL
+	FeedTags    map[string][]string              // feed URL -> tags
+	ItemTags    map[int64][]string               // item ID -> tags added by rules
+	Duplicates  map[int64][]DuplicateLink        // item ID -> copies from other feeds collapsed into it
+	Enclosures  map[int64][]database.Enclosure   // item ID -> attached media files, such as podcast episodes
 	GeneratedAt time.Time
 	TimeWindow  string
+	// UnreadCounts maps feed URL to its number of unread items; feeds with none are absent.
+	UnreadCounts map[string]int
+	TotalUnread  int
+	Tags         []TagLink // Tag sections, for navigation; empty when no feed is tagged
+	CurrentTag   string    // Tag this page is the section for, empty on the main index
+	RootPath     string    // Relative path from this page to the site root, e.g. "../../"
+	LocalMedia   bool      // Images link to cached copies in the site, which item content must reach
 }
 
 // FeedTemplateContext contains data for a single feed template.
@@ -35,9 +46,15 @@ type FeedTemplateContext struct {
 	Items       []database.Item
 	Metadata    map[string]*database.URLMetadata // URL -> metadata
 	FeedFavicon string
+	Tags        []string
+	ItemTags    map[int64][]string             // item ID -> tags added by rules
+	Duplicates  map[int64][]DuplicateLink      // item ID -> copies from other feeds collapsed into it
+	Enclosures  map[int64][]database.Enclosure // item ID -> attached media files
 	GeneratedAt time.Time
 	TimeWindow  string
 	FeedID      string // Hash-based ID for the feed
+	UnreadCount int    // Unread items in the feed
+	LocalMedia  bool   // Images link to cached copies in the site
 }
 
 // PageTemplateContext contains data for a paginated feed list fragment.
@@ -46,10 +63,36 @@ type PageTemplateContext struct {
 	Items       map[string][]database.Item
 	Metadata    map[string]*database.URLMetadata
 	FeedFavicon map[string]string
+	FeedTags    map[string][]string
 	GeneratedAt time.Time
 	TimeWindow  string
 	PageNumber  int // 1-indexed page number
 	TotalPages  int // Total number of pages
+	// UnreadCounts maps feed URL to its number of unread items; feeds with none are absent.
+	UnreadCounts map[string]int
+	TotalUnread  int
+	RootPath     string // Relative path from the page loading this fragment to the site root
+}
+
+// RiverTemplateContext contains data for the river index, which lists items
+// across feeds in time order instead of grouped by feed.
+type RiverTemplateContext struct {
+	*TemplateContext
+	Pages      []RiverPageLink // River pages, loaded in order
+	TotalItems int
+}
+
+// RiverPageTemplateContext contains data for a river page fragment.
+type RiverPageTemplateContext struct {
+	Days        []RiverDay // Items on the page, grouped by day, newest first
+	Metadata    map[string]*database.URLMetadata
+	ItemTags    map[int64][]string
+	Duplicates  map[int64][]DuplicateLink
+	Enclosures  map[int64][]database.Enclosure
+	GeneratedAt time.Time
+	PageNumber  int    // 1-indexed page number
+	TotalPages  int    // Total number of pages
+	RootPath    string // Relative path from the page loading this fragment to the site root
 }
 
 // Renderer handles template loading and rendering.
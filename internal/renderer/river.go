@@ -0,0 +1,179 @@
+package renderer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	configpkg "github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/database"
+)
+
+// River paging modes.
+const (
+	RiverPagingItems = "items" // Fixed number of items per page
+	RiverPagingDay   = "day"   // One page per day
+)
+
+// riverPagesDir is the subdirectory of a section holding its river pages.
+const riverPagesDir = "river"
+
+// RiverItem is an item in the river, attributed to the feed it came from.
+type RiverItem struct {
+	database.Item
+	FeedTitle   string
+	FeedID      string
+	FeedFavicon string
+}
+
+// RiverDay is a run of river items published on the same day (UTC).
+type RiverDay struct {
+	Date  time.Time
+	Items []RiverItem
+}
+
+// RiverPageLink describes a river page for the index's page loaders.
+type RiverPageLink struct {
+	Number int
+	Label  string
+}
+
+// buildRiver merges the items of every feed into one list, newest first.
+func buildRiver(feeds []FeedWithID, items map[string][]database.Item, feedFavicon map[string]string) []RiverItem {
+	river := []RiverItem{}
+	for i := range feeds {
+		for _, item := range items[feeds[i].URL] {
+			river = append(river, RiverItem{
+				Item:        item,
+				FeedTitle:   feeds[i].Title,
+				FeedID:      feeds[i].ID,
+				FeedFavicon: feedFavicon[feeds[i].URL],
+			})
+		}
+	}
+
+	sort.SliceStable(river, func(i, j int) bool {
+		return river[i].PublishedDate.After(river[j].PublishedDate)
+	})
+	return river
+}
+
+// groupRiverByDay splits river items, already sorted newest first, into runs
+// published on the same UTC day.
+func groupRiverByDay(river []RiverItem) []RiverDay {
+	days := []RiverDay{}
+	for i := range river {
+		date := river[i].PublishedDate.UTC().Truncate(24 * time.Hour)
+		if len(days) == 0 || !days[len(days)-1].Date.Equal(date) {
+			days = append(days, RiverDay{Date: date})
+		}
+		last := &days[len(days)-1]
+		last.Items = append(last.Items, river[i])
+	}
+	return days
+}
+
+// paginateRiver splits the river into pages, each grouped by day. With
+// RiverPagingDay every day is a page; otherwise pages hold itemsPerPage items
+// (0 = a single page).
+func paginateRiver(river []RiverItem, paging string, itemsPerPage int) [][]RiverDay {
+	if paging == RiverPagingDay {
+		pages := [][]RiverDay{}
+		for _, day := range groupRiverByDay(river) {
+			pages = append(pages, []RiverDay{day})
+		}
+		return pages
+	}
+
+	if itemsPerPage <= 0 {
+		itemsPerPage = len(river)
+	}
+	pages := [][]RiverDay{}
+	for i := 0; i < len(river); i += itemsPerPage {
+		end := i + itemsPerPage
+		if end > len(river) {
+			end = len(river)
+		}
+		pages = append(pages, groupRiverByDay(river[i:end]))
+	}
+	return pages
+}
+
+// riverPageLinks labels each river page for its placeholder on the index.
+func riverPageLinks(pages [][]RiverDay, paging string) []RiverPageLink {
+	links := make([]RiverPageLink, len(pages))
+	first := 1
+	for i, page := range pages {
+		count := 0
+		for _, day := range page {
+			count += len(day.Items)
+		}
+
+		label := fmt.Sprintf("items %d - %d", first, first+count-1)
+		if paging == RiverPagingDay {
+			label = fmt.Sprintf("%s (%d items)", page[0].Date.Format("Mon, Jan 2, 2006"), count)
+		}
+		links[i] = RiverPageLink{Number: i + 1, Label: label}
+		first += count
+	}
+	return links
+}
+
+// renderRiver writes dir/index.html as a river of every item in the context,
+// newest first, loading its pages from dir/river/page-N.html.
+func renderRiver(r *Renderer, dir string, context *TemplateContext, paging string, itemsPerPage int) error {
+	river := buildRiver(context.Feeds, context.Items, context.FeedFavicon)
+	pages := paginateRiver(river, paging, itemsPerPage)
+
+	riverContext := &RiverTemplateContext{
+		TemplateContext: context,
+		Pages:           riverPageLinks(pages, paging),
+		TotalItems:      len(river),
+	}
+	if err := renderTemplateFile(r, filepath.Join(dir, "index.html"), "river.html", riverContext); err != nil {
+		return err
+	}
+
+	pagesDir := filepath.Join(dir, riverPagesDir)
+	if err := os.MkdirAll(pagesDir, configpkg.DefaultDirPerm); err != nil {
+		return fmt.Errorf("failed to create river directory: %w", err)
+	}
+
+	for i, days := range pages {
+		pageContext := &RiverPageTemplateContext{
+			Days:        days,
+			Metadata:    context.Metadata,
+			ItemTags:    context.ItemTags,
+			Duplicates:  context.Duplicates,
+			Enclosures:  context.Enclosures,
+			GeneratedAt: context.GeneratedAt,
+			PageNumber:  i + 1,
+			TotalPages:  len(pages),
+			RootPath:    context.RootPath,
+		}
+		pageFile := filepath.Join(pagesDir, fmt.Sprintf("page-%d.html", i+1))
+		if err := renderTemplateFile(r, pageFile, "river-page.html", pageContext); err != nil {
+			return fmt.Errorf("failed to render river page %d: %w", i+1, err)
+		}
+	}
+
+	//nolint:forbidigo // User-facing output
+	fmt.Printf("Generated river of %d items in %d pages\n", len(river), len(pages))
+	return nil
+}
+
+// renderTemplateFile renders a template into a new file.
+func renderTemplateFile(r *Renderer, outputFile, templateName string, context interface{}) error {
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer file.Close()
+
+	if err := r.Render(file, templateName, context); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+	return nil
+}
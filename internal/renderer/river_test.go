@@ -0,0 +1,149 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+)
+
+func riverTestFeeds() ([]FeedWithID, map[string][]database.Item) {
+	feeds := []FeedWithID{
+		{Feed: database.Feed{URL: "https://a.example/feed", Title: "Alpha"}, ID: "aaaa"},
+		{Feed: database.Feed{URL: "https://b.example/feed", Title: "Beta"}, ID: "bbbb"},
+	}
+	items := map[string][]database.Item{
+		"https://a.example/feed": {
+			{ID: 1, FeedURL: "https://a.example/feed", Title: "A new", PublishedDate: time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC)},
+			{ID: 2, FeedURL: "https://a.example/feed", Title: "A old", PublishedDate: time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)},
+		},
+		"https://b.example/feed": {
+			{ID: 3, FeedURL: "https://b.example/feed", Title: "B new", PublishedDate: time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)},
+			{ID: 4, FeedURL: "https://b.example/feed", Title: "B old", PublishedDate: time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)},
+		},
+	}
+	return feeds, items
+}
+
+func riverTitles(river []RiverItem) []string {
+	titles := make([]string, len(river))
+	for i := range river {
+		titles[i] = river[i].Title
+	}
+	return titles
+}
+
+func TestBuildRiver(t *testing.T) {
+	feeds, items := riverTestFeeds()
+	favicons := map[string]string{"https://b.example/feed": "https://b.example/favicon.ico"}
+
+	river := buildRiver(feeds, items, favicons)
+
+	if got := strings.Join(riverTitles(river), ","); got != "B new,A new,B old,A old" {
+		t.Errorf("buildRiver() order = %s, want B new,A new,B old,A old", got)
+	}
+	if river[0].FeedTitle != "Beta" || river[0].FeedID != "bbbb" || river[0].FeedFavicon != "https://b.example/favicon.ico" {
+		t.Errorf("river[0] attribution = %q %q %q", river[0].FeedTitle, river[0].FeedID, river[0].FeedFavicon)
+	}
+	if river[1].FeedFavicon != "" {
+		t.Errorf("river[1].FeedFavicon = %q, want empty", river[1].FeedFavicon)
+	}
+}
+
+func TestPaginateRiver(t *testing.T) {
+	feeds, items := riverTestFeeds()
+	river := buildRiver(feeds, items, nil)
+
+	tests := []struct {
+		name         string
+		paging       string
+		itemsPerPage int
+		expected     [][]int // Items per day, per page
+	}{
+		{"by day", RiverPagingDay, 1, [][]int{{2}, {2}}},
+		{"by items", RiverPagingItems, 3, [][]int{{2, 1}, {1}}},
+		{"unpaginated", RiverPagingItems, 0, [][]int{{2, 2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages := paginateRiver(river, tt.paging, tt.itemsPerPage)
+			if len(pages) != len(tt.expected) {
+				t.Fatalf("paginateRiver() = %d pages, want %d", len(pages), len(tt.expected))
+			}
+			for i, page := range pages {
+				if len(page) != len(tt.expected[i]) {
+					t.Fatalf("page %d has %d days, want %d", i+1, len(page), len(tt.expected[i]))
+				}
+				for j, day := range page {
+					if len(day.Items) != tt.expected[i][j] {
+						t.Errorf("page %d day %d has %d items, want %d", i+1, j+1, len(day.Items), tt.expected[i][j])
+					}
+				}
+			}
+		})
+	}
+
+	pages := paginateRiver(river, RiverPagingDay, 0)
+	if !pages[1][0].Date.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("second day = %v, want 2024-03-01", pages[1][0].Date)
+	}
+	links := riverPageLinks(pages, RiverPagingDay)
+	if links[0].Label != "Sat, Mar 2, 2024 (2 items)" {
+		t.Errorf("day page label = %q", links[0].Label)
+	}
+	links = riverPageLinks(paginateRiver(river, RiverPagingItems, 3), RiverPagingItems)
+	if links[1].Label != "items 4 - 4" {
+		t.Errorf("items page label = %q", links[1].Label)
+	}
+}
+
+func TestRenderRiver(t *testing.T) {
+	outputDir := t.TempDir()
+	feeds, items := riverTestFeeds()
+	context := &TemplateContext{
+		Feeds:       feeds,
+		Items:       items,
+		FeedFavicon: map[string]string{"https://a.example/feed": "https://a.example/favicon.ico"},
+		GeneratedAt: time.Date(2024, 3, 2, 13, 0, 0, 0, time.UTC),
+	}
+
+	if err := renderRiver(NewRenderer("", ""), outputDir, context, RiverPagingDay, 0); err != nil {
+		t.Fatalf("renderRiver() error = %v", err)
+	}
+
+	index, err := os.ReadFile(filepath.Join(outputDir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`river/page-1.html#page-1`, `river/page-2.html#page-2`, `4 items`} {
+		if !strings.Contains(string(index), want) {
+			t.Errorf("index.html missing %q", want)
+		}
+	}
+
+	page, err := os.ReadFile(filepath.Join(outputDir, riverPagesDir, "page-1.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(page)
+	for _, want := range []string{
+		`id="page-1"`,
+		`<time datetime="2024-03-02" data-format="day">`,
+		`href="feeds/aaaa.html">Alpha</a>`,
+		`src="https://a.example/favicon.ico"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("page-1.html missing %q", want)
+		}
+	}
+	if strings.Index(html, "B new") > strings.Index(html, "A new") {
+		t.Errorf("page-1.html should list B new before A new")
+	}
+	if strings.Contains(html, "A old") {
+		t.Errorf("page-1.html should only hold items from 2024-03-02")
+	}
+}
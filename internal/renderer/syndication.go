@@ -0,0 +1,420 @@
+package renderer
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	configpkg "github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/sanitize"
+)
+
+// Syndication output file names. The river covers every rendered feed and
+// sits next to index.html; per-feed files sit next to feeds/<id>.html.
+const (
+	riverBaseName = "feed"
+	atomExt       = ".atom"
+	rssExt        = ".rss"
+	jsonFeedExt   = ".json"
+
+	riverTitle      = "feedspool"
+	jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+)
+
+// syndicationFeed is a feed to write in every syndication format.
+type syndicationFeed struct {
+	ID          string // Atom ID, a URI
+	Title       string
+	Description string
+	HomePageURL string // Site the feed describes, if known
+	SelfURL     string // Absolute URL of the file being written, without extension; empty without a base URL
+	Updated     time.Time
+	Entries     []syndicationEntry
+}
+
+// syndicationEntry is an item together with the feed it came from.
+type syndicationEntry struct {
+	Item   *database.Item
+	Source *database.Feed
+}
+
+// writeSyndicationFeeds writes the river feed of all rendered items and a
+// feed for each source feed, in Atom, RSS 2.0 and JSON Feed 1.1.
+func writeSyndicationFeeds(outputDir, baseURL string, feeds []database.Feed,
+	items map[string][]database.Item, generatedAt time.Time,
+) error {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	river := newRiverFeed(feeds, items)
+	river.ID = "urn:feedspool:river"
+	river.Title = riverTitle
+	river.Description = "Items from all feeds"
+	if baseURL != "" {
+		river.ID = baseURL + "/"
+		river.HomePageURL = baseURL + "/"
+		river.SelfURL = baseURL + "/" + riverBaseName
+	}
+	if err := writeFeedFiles(filepath.Join(outputDir, riverBaseName), river, generatedAt); err != nil {
+		return err
+	}
+
+	feedsDir := filepath.Join(outputDir, "feeds")
+	if err := os.MkdirAll(feedsDir, configpkg.DefaultDirPerm); err != nil {
+		return fmt.Errorf("failed to create feeds directory: %w", err)
+	}
+
+	for i := range feeds {
+		feed := &feeds[i]
+		feedItems := items[feed.URL]
+		if len(feedItems) == 0 {
+			continue
+		}
+
+		feedID := generateFeedID(feed.URL)
+		source := &syndicationFeed{
+			ID:          feed.URL,
+			Title:       feedTitle(feed),
+			Description: feed.Description,
+			HomePageURL: feedHomePage(feed),
+		}
+		if baseURL != "" {
+			source.SelfURL = baseURL + "/feeds/" + feedID
+		}
+		for j := range feedItems {
+			source.Entries = append(source.Entries, syndicationEntry{Item: &feedItems[j], Source: feed})
+		}
+
+		if err := writeFeedFiles(filepath.Join(feedsDir, feedID), source, generatedAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newRiverFeed returns a feed with the items of all the given feeds as entries.
+func newRiverFeed(feeds []database.Feed, items map[string][]database.Item) *syndicationFeed {
+	river := &syndicationFeed{}
+	for i := range feeds {
+		feedItems := items[feeds[i].URL]
+		for j := range feedItems {
+			river.Entries = append(river.Entries, syndicationEntry{Item: &feedItems[j], Source: &feeds[i]})
+		}
+	}
+	return river
+}
+
+// writeFeedFiles writes feed to basePath plus each format's extension.
+func writeFeedFiles(basePath string, feed *syndicationFeed, generatedAt time.Time) error {
+	sort.SliceStable(feed.Entries, func(i, j int) bool {
+		return feed.Entries[i].Item.PublishedDate.After(feed.Entries[j].Item.PublishedDate)
+	})
+
+	feed.Updated = generatedAt
+	if len(feed.Entries) > 0 {
+		feed.Updated = feed.Entries[0].Item.PublishedDate
+	}
+
+	writers := map[string]func(io.Writer, *syndicationFeed) error{
+		atomExt:     writeAtom,
+		rssExt:      writeRSS,
+		jsonFeedExt: writeJSONFeed,
+	}
+	for ext, write := range writers {
+		if err := writeFile(basePath+ext, func(w io.Writer) error { return write(w, feed) }); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	if err := write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return file.Close()
+}
+
+// feedHomePage returns the site link a feed advertises, if any.
+func feedHomePage(feed *database.Feed) string {
+	var parsed struct {
+		Link string `json:"link"`
+	}
+	if err := json.Unmarshal(feed.FeedJSON, &parsed); err != nil {
+		return ""
+	}
+	return parsed.Link
+}
+
+// entryID returns a stable URI for an item: its GUID when that is already an
+// absolute URI, otherwise a URN derived from the feed URL and GUID.
+func entryID(item *database.Item) string {
+	if u, err := url.Parse(item.GUID); err == nil && u.IsAbs() {
+		return item.GUID
+	}
+	hash := sha256.Sum256([]byte(item.FeedURL + "\x00" + item.GUID))
+	return fmt.Sprintf("urn:feedspool:item:%x", hash[:16])
+}
+
+// entryHTML returns the item's content, falling back to its summary, cleaned
+// as it is for the site's pages, with relative URLs resolved against its link.
+func entryHTML(item *database.Item) string {
+	content := item.Content
+	if content == "" {
+		content = item.Summary
+	}
+	return sanitize.HTML(content, item.Link)
+}
+
+// feedTitle returns a display title for a source feed.
+func feedTitle(feed *database.Feed) string {
+	if feed.Title != "" {
+		return feed.Title
+	}
+	return feed.URL
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Updated   string     `xml:"updated"`
+	Published string     `xml:"published"`
+	Links     []atomLink `xml:"link"`
+	Summary   *atomText  `xml:"summary,omitempty"`
+	Content   *atomText  `xml:"content,omitempty"`
+	Author    atomAuthor `xml:"author"`
+	Source    atomSource `xml:"source"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomSource struct {
+	ID    string     `xml:"id"`
+	Title string     `xml:"title"`
+	Links []atomLink `xml:"link"`
+}
+
+func writeAtom(w io.Writer, feed *syndicationFeed) error {
+	doc := atomFeed{
+		ID:      feed.ID,
+		Title:   feed.Title,
+		Updated: feed.Updated.UTC().Format(time.RFC3339),
+	}
+	if feed.HomePageURL != "" {
+		doc.Links = append(doc.Links, atomLink{Rel: "alternate", Type: "text/html", Href: feed.HomePageURL})
+	}
+	if feed.SelfURL != "" {
+		doc.Links = append(doc.Links, atomLink{Rel: "self", Type: "application/atom+xml", Href: feed.SelfURL + atomExt})
+	}
+
+	for _, entry := range feed.Entries {
+		item := entry.Item
+		date := item.PublishedDate.UTC().Format(time.RFC3339)
+		atom := atomEntry{
+			ID:        entryID(item),
+			Title:     item.Title,
+			Updated:   date,
+			Published: date,
+			Author:    atomAuthor{Name: feedTitle(entry.Source)},
+			Source: atomSource{
+				ID:    entry.Source.URL,
+				Title: feedTitle(entry.Source),
+				Links: []atomLink{{Rel: "self", Href: entry.Source.URL}},
+			},
+		}
+		if item.Link != "" {
+			atom.Links = []atomLink{{Rel: "alternate", Type: "text/html", Href: item.Link}}
+		}
+		if item.Summary != "" && item.Content != "" {
+			atom.Summary = &atomText{Type: "html", Body: item.Summary}
+		}
+		if body := entryHTML(item); body != "" {
+			atom.Content = &atomText{Type: "html", Body: body}
+		}
+		doc.Entries = append(doc.Entries, atom)
+	}
+
+	return encodeXML(w, doc)
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	LastBuildDate string     `xml:"lastBuildDate"`
+	Generator     string     `xml:"generator"`
+	AtomLink      *atomLink  `xml:"atom:link,omitempty"`
+	Items         []rssEntry `xml:"item"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssSource struct {
+	URL   string `xml:"url,attr"`
+	Title string `xml:",chardata"`
+}
+
+type rssEntry struct {
+	Title       string    `xml:"title,omitempty"`
+	Link        string    `xml:"link,omitempty"`
+	Description string    `xml:"description,omitempty"`
+	GUID        rssGUID   `xml:"guid"`
+	PubDate     string    `xml:"pubDate"`
+	Source      rssSource `xml:"source"`
+}
+
+func writeRSS(w io.Writer, feed *syndicationFeed) error {
+	channel := rssChannel{
+		Title:         feed.Title,
+		Link:          feed.HomePageURL,
+		Description:   feed.Description,
+		LastBuildDate: feed.Updated.UTC().Format(time.RFC1123Z),
+		Generator:     "feedspool",
+	}
+	if channel.Link == "" {
+		channel.Link = feed.ID
+	}
+	if channel.Description == "" {
+		channel.Description = feed.Title
+	}
+	if feed.SelfURL != "" {
+		channel.AtomLink = &atomLink{Rel: "self", Type: "application/rss+xml", Href: feed.SelfURL + rssExt}
+	}
+
+	for _, entry := range feed.Entries {
+		item := entry.Item
+		channel.Items = append(channel.Items, rssEntry{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: entryHTML(item),
+			GUID:        rssGUID{Value: entryID(item), IsPermaLink: item.Link != "" && entryID(item) == item.Link},
+			PubDate:     item.PublishedDate.UTC().Format(time.RFC1123Z),
+			Source:      rssSource{URL: entry.Source.URL, Title: feedTitle(entry.Source)},
+		})
+	}
+
+	return encodeXML(w, rssDocument{Version: "2.0", AtomNS: "http://www.w3.org/2005/Atom", Channel: channel})
+}
+
+func encodeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url,omitempty"`
+	FeedURL     string         `json:"feed_url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url,omitempty"`
+	Title         string           `json:"title,omitempty"`
+	ContentHTML   string           `json:"content_html,omitempty"`
+	Summary       string           `json:"summary,omitempty"`
+	DatePublished string           `json:"date_published"`
+	Authors       []jsonFeedAuthor `json:"authors"`
+	// Source is a feedspool extension naming the feed the item came from.
+	Source jsonFeedAuthor `json:"_feedspool_source"`
+}
+
+func writeJSONFeed(w io.Writer, feed *syndicationFeed) error {
+	doc := jsonFeed{
+		Version:     jsonFeedVersion,
+		Title:       feed.Title,
+		HomePageURL: feed.HomePageURL,
+		Description: feed.Description,
+		Items:       []jsonFeedItem{},
+	}
+	if feed.SelfURL != "" {
+		doc.FeedURL = feed.SelfURL + jsonFeedExt
+	}
+
+	for _, entry := range feed.Entries {
+		item := entry.Item
+		source := jsonFeedAuthor{Name: feedTitle(entry.Source), URL: entry.Source.URL}
+		jsonItem := jsonFeedItem{
+			ID:            entryID(item),
+			URL:           item.Link,
+			Title:         item.Title,
+			ContentHTML:   entryHTML(item),
+			DatePublished: item.PublishedDate.UTC().Format(time.RFC3339),
+			Authors:       []jsonFeedAuthor{{Name: source.Name}},
+			Source:        source,
+		}
+		if item.Content != "" {
+			jsonItem.Summary = stripHTML(item.Summary)
+		}
+		doc.Items = append(doc.Items, jsonItem)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
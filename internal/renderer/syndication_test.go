@@ -0,0 +1,138 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/mmcdole/gofeed"
+)
+
+func TestWriteSyndicationFeeds(t *testing.T) {
+	outputDir := t.TempDir()
+
+	feeds := []database.Feed{
+		{URL: "https://go.example.com/feed", Title: "Go", FeedJSON: database.JSON(`{"link": "https://go.example.com/"}`)},
+		{URL: "https://rust.example.com/feed", Title: "Rust & Friends", FeedJSON: database.JSON(`{}`)},
+	}
+	items := map[string][]database.Item{
+		"https://go.example.com/feed": {
+			{
+				FeedURL: "https://go.example.com/feed", GUID: "https://go.example.com/generics",
+				Title: "Generics", Link: "https://go.example.com/generics", Content: "<p>Type parameters</p>",
+				PublishedDate: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			},
+			{
+				FeedURL: "https://go.example.com/feed", GUID: "errors", Title: "Errors",
+				Summary: "Wrapping <b>errors</b>", PublishedDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		"https://rust.example.com/feed": {
+			{
+				FeedURL: "https://rust.example.com/feed", GUID: "ownership", Title: "Ownership",
+				Link: "https://rust.example.com/ownership", PublishedDate: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	generatedAt := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	err := writeSyndicationFeeds(outputDir, "https://feeds.example.com/", feeds, items, generatedAt)
+	if err != nil {
+		t.Fatalf("writeSyndicationFeeds() error = %v", err)
+	}
+
+	parser := gofeed.NewParser()
+	for _, ext := range []string{atomExt, rssExt, jsonFeedExt} {
+		t.Run(ext, func(t *testing.T) {
+			river := parseFeedFile(t, parser, filepath.Join(outputDir, riverBaseName+ext))
+			if river.Title != riverTitle || len(river.Items) != 3 {
+				t.Fatalf("river: title %q with %d items, want %q with 3", river.Title, len(river.Items), riverTitle)
+			}
+
+			// Newest first across feeds
+			want := []string{"Generics", "Ownership", "Errors"}
+			for i, title := range want {
+				if river.Items[i].Title != title {
+					t.Errorf("river item %d = %q, want %q", i, river.Items[i].Title, title)
+				}
+			}
+			if river.Items[0].GUID != "https://go.example.com/generics" {
+				t.Errorf("URI GUID became %q, want it kept", river.Items[0].GUID)
+			}
+			if river.Items[2].GUID == "errors" || river.Items[2].GUID == "" {
+				t.Errorf("non-URI GUID = %q, want a URN", river.Items[2].GUID)
+			}
+			if river.FeedLink != "https://feeds.example.com/feed"+ext && ext != rssExt {
+				t.Errorf("self link = %q, want it under the base URL", river.FeedLink)
+			}
+
+			rust := parseFeedFile(t, parser, filepath.Join(outputDir, "feeds", generateFeedID(feeds[1].URL)+ext))
+			if rust.Title != "Rust & Friends" || len(rust.Items) != 1 {
+				t.Errorf("per-feed: title %q with %d items, want the rust feed's one item", rust.Title, len(rust.Items))
+			}
+		})
+	}
+
+	// Feeds without items get no per-feed files
+	items["https://rust.example.com/feed"] = nil
+	quietDir := t.TempDir()
+	if err := writeSyndicationFeeds(quietDir, "", feeds, items, generatedAt); err != nil {
+		t.Fatalf("writeSyndicationFeeds() without base URL error = %v", err)
+	}
+	quietPath := filepath.Join(quietDir, "feeds", generateFeedID(feeds[1].URL)+atomExt)
+	if _, err := os.Stat(quietPath); !os.IsNotExist(err) {
+		t.Errorf("feed without items was written to %s", quietPath)
+	}
+}
+
+func TestWriteSyndicationFeedsSanitizesContent(t *testing.T) {
+	outputDir := t.TempDir()
+
+	feeds := []database.Feed{{URL: "https://a.example/feed", Title: "Alpha", FeedJSON: database.JSON(`{}`)}}
+	items := map[string][]database.Item{
+		"https://a.example/feed": {{
+			FeedURL: "https://a.example/feed", GUID: "post", Title: "Post", Link: "https://a.example/posts/1",
+			Content:       `<p>Hello</p><script>alert(1)</script><img src="/photo.jpg" onerror="alert(2)">`,
+			PublishedDate: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		}},
+	}
+
+	err := writeSyndicationFeeds(outputDir, "", feeds, items, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("writeSyndicationFeeds() error = %v", err)
+	}
+
+	parser := gofeed.NewParser()
+	for _, ext := range []string{atomExt, jsonFeedExt} {
+		river := parseFeedFile(t, parser, filepath.Join(outputDir, riverBaseName+ext))
+		if len(river.Items) != 1 {
+			t.Fatalf("river%s has %d items, want 1", ext, len(river.Items))
+		}
+		content := river.Items[0].Content
+		if strings.Contains(content, "script") || strings.Contains(content, "onerror") {
+			t.Errorf("river%s content = %s, want scripts and event handlers removed", ext, content)
+		}
+		if !strings.Contains(content, `src="https://a.example/photo.jpg"`) {
+			t.Errorf("river%s content = %s, want the image URL resolved", ext, content)
+		}
+	}
+}
+
+func parseFeedFile(t *testing.T, parser *gofeed.Parser, path string) *gofeed.Feed {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	feed, err := parser.Parse(file)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", path, err)
+	}
+	return feed
+}
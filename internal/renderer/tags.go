@@ -0,0 +1,157 @@
+package renderer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	configpkg "github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/database"
+)
+
+// tagsDirName is the output subdirectory holding one section per tag.
+const tagsDirName = "tags"
+
+// TagLink describes a tag section for navigation between sections.
+type TagLink struct {
+	Name        string
+	Slug        string // Directory name under tags/
+	FeedCount   int    // Rendered feeds with the tag
+	UnreadCount int    // Unread items across those feeds
+}
+
+// collectTags returns a link for each tag on the rendered feeds, ordered by
+// name, with counts aggregated over the feeds carrying it.
+func collectTags(feeds []FeedWithID, feedTags map[string][]string, unreadCounts map[string]int) []TagLink {
+	byName := make(map[string]*TagLink)
+	for i := range feeds {
+		for _, tag := range feedTags[feeds[i].URL] {
+			link, ok := byName[tag]
+			if !ok {
+				link = &TagLink{Name: tag}
+				byName[tag] = link
+			}
+			link.FeedCount++
+			link.UnreadCount += unreadCounts[feeds[i].URL]
+		}
+	}
+
+	tags := make([]TagLink, 0, len(byName))
+	for _, link := range byName {
+		tags = append(tags, *link)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+
+	// Tags differing only in case or punctuation would share a slug
+	used := make(map[string]bool)
+	for i := range tags {
+		slug := tagSlug(tags[i].Name)
+		for n := 2; used[slug]; n++ {
+			slug = fmt.Sprintf("%s-%d", tagSlug(tags[i].Name), n)
+		}
+		used[slug] = true
+		tags[i].Slug = slug
+	}
+
+	return tags
+}
+
+// tagSlug turns a tag name into a directory name: lowercase letters and
+// digits, with runs of anything else collapsed to a single dash.
+func tagSlug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		slug = "tag"
+	}
+	return slug
+}
+
+// feedsWithTag returns the feeds carrying tag, keeping their order.
+func feedsWithTag(feeds []FeedWithID, feedTags map[string][]string, tag string) []FeedWithID {
+	tagged := []FeedWithID{}
+	for i := range feeds {
+		for _, feedTag := range feedTags[feeds[i].URL] {
+			if feedTag == tag {
+				tagged = append(tagged, feeds[i])
+				break
+			}
+		}
+	}
+	return tagged
+}
+
+// renderTagSections writes tags/<slug>/index.html for each tag, with its own
+// pagination, unread counts and river feeds. Feed pages stay shared in the
+// top-level feeds directory.
+func renderTagSections(r *Renderer, config *WorkflowConfig, context *TemplateContext,
+	items map[string][]database.Item, feedsPerPage int,
+) error {
+	for i := range context.Tags {
+		tag := &context.Tags[i]
+		tagFeeds := feedsWithTag(context.Feeds, context.FeedTags, tag.Name)
+
+		tagDir := filepath.Join(config.OutputDir, tagsDirName, tag.Slug)
+		if err := os.MkdirAll(tagDir, configpkg.DefaultDirPerm); err != nil {
+			return fmt.Errorf("failed to create tag directory: %w", err)
+		}
+
+		sourceFeeds := make([]database.Feed, len(tagFeeds))
+		for i := range tagFeeds {
+			sourceFeeds[i] = tagFeeds[i].Feed
+		}
+
+		tagContext := *context
+		tagContext.Feeds = tagFeeds
+		tagContext.CurrentTag = tag.Name
+		tagContext.RootPath = "../../"
+		tagContext.UnreadCounts, tagContext.TotalUnread = unreadCountsForFeeds(sourceFeeds, context.UnreadCounts)
+
+		if err := renderSection(r, config, tagDir, &tagContext, feedsPerPage); err != nil {
+			return err
+		}
+
+		if err := writeTagRiver(tagDir, config.BaseURL, tag, sourceFeeds, items, context.GeneratedAt); err != nil {
+			return fmt.Errorf("failed to write syndication feeds for tag %s: %w", tag.Name, err)
+		}
+	}
+
+	//nolint:forbidigo // User-facing output
+	fmt.Printf("Generated %d tag sections in %s\n", len(context.Tags), filepath.Join(config.OutputDir, tagsDirName))
+	return nil
+}
+
+// writeTagRiver writes the river feed of a tag's items into its section.
+func writeTagRiver(tagDir, baseURL string, tag *TagLink, feeds []database.Feed,
+	items map[string][]database.Item, generatedAt time.Time,
+) error {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	river := newRiverFeed(feeds, items)
+	river.ID = "urn:feedspool:river:" + tag.Slug
+	river.Title = riverTitle + ": " + tag.Name
+	river.Description = "Items from feeds tagged " + tag.Name
+	if baseURL != "" {
+		sectionURL := baseURL + "/" + tagsDirName + "/" + tag.Slug + "/"
+		river.ID = sectionURL
+		river.HomePageURL = sectionURL
+		river.SelfURL = sectionURL + riverBaseName
+	}
+
+	return writeFeedFiles(filepath.Join(tagDir, riverBaseName), river, generatedAt)
+}
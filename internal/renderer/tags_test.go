@@ -0,0 +1,122 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+)
+
+func TestTagSlug(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"golang", "golang"},
+		{"Web Dev", "web-dev"},
+		{"C/C++ & Rust!", "c-c-rust"},
+		{"  Café  ", "café"},
+		{"???", "tag"},
+	}
+
+	for _, tt := range tests {
+		if got := tagSlug(tt.name); got != tt.expected {
+			t.Errorf("tagSlug(%q) = %q, want %q", tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestCollectTags(t *testing.T) {
+	feeds := []FeedWithID{
+		{Feed: database.Feed{URL: "https://a.example/feed"}},
+		{Feed: database.Feed{URL: "https://b.example/feed"}},
+		{Feed: database.Feed{URL: "https://c.example/feed"}},
+	}
+	feedTags := map[string][]string{
+		"https://a.example/feed":          {"security", "Go"},
+		"https://b.example/feed":          {"go"},
+		"https://c.example/feed":          {"security"},
+		"https://unrendered.example/feed": {"frontend"},
+	}
+	unread := map[string]int{"https://a.example/feed": 2, "https://c.example/feed": 3}
+
+	tags := collectTags(feeds, feedTags, unread)
+
+	expected := []TagLink{
+		{Name: "Go", Slug: "go", FeedCount: 1, UnreadCount: 2},
+		{Name: "go", Slug: "go-2", FeedCount: 1, UnreadCount: 0},
+		{Name: "security", Slug: "security", FeedCount: 2, UnreadCount: 5},
+	}
+	if len(tags) != len(expected) {
+		t.Fatalf("collectTags() = %+v, want %+v", tags, expected)
+	}
+	for i := range expected {
+		if tags[i] != expected[i] {
+			t.Errorf("tags[%d] = %+v, want %+v", i, tags[i], expected[i])
+		}
+	}
+}
+
+func TestRenderTagSections(t *testing.T) {
+	outputDir := t.TempDir()
+
+	feeds := []FeedWithID{
+		{Feed: database.Feed{URL: "https://a.example/feed", Title: "Alpha", FeedJSON: database.JSON(`{}`)}, ID: "aaaa"},
+		{Feed: database.Feed{URL: "https://b.example/feed", Title: "Beta", FeedJSON: database.JSON(`{}`)}, ID: "bbbb"},
+		{Feed: database.Feed{URL: "https://c.example/feed", Title: "Gamma", FeedJSON: database.JSON(`{}`)}, ID: "cccc"},
+	}
+	items := map[string][]database.Item{}
+	for _, feed := range feeds {
+		items[feed.URL] = []database.Item{{FeedURL: feed.URL, GUID: feed.ID, Title: feed.Title + " item"}}
+	}
+	feedTags := map[string][]string{
+		"https://a.example/feed": {"security"},
+		"https://b.example/feed": {"security", "frontend"},
+	}
+
+	context := &TemplateContext{
+		Feeds:       feeds,
+		Items:       items,
+		FeedTags:    feedTags,
+		GeneratedAt: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+	}
+	context.Tags = collectTags(feeds, feedTags, nil)
+
+	config := &WorkflowConfig{OutputDir: outputDir, FeedsPerPage: 1}
+	if err := renderTagSections(NewRenderer("", ""), config, context, items, 1); err != nil {
+		t.Fatalf("renderTagSections() error = %v", err)
+	}
+
+	index, err := os.ReadFile(filepath.Join(outputDir, "tags", "security", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(index)
+	for _, want := range []string{`href="../../index.css"`, `href="../../tags/frontend/index.html"`, `feeds/page-2.html`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("security index.html missing %q", want)
+		}
+	}
+
+	page, err := os.ReadFile(filepath.Join(outputDir, "tags", "security", "feeds", "page-2.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), `href="../../feeds/bbbb.html#feed-bbbb"`) {
+		t.Errorf("security page-2.html should link to the shared feed page, got %s", page)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "tags", "security", "feeds", "page-3.html")); !os.IsNotExist(err) {
+		t.Errorf("security section should have 2 pages, found page-3.html")
+	}
+
+	river, err := os.ReadFile(filepath.Join(outputDir, "tags", "frontend", riverBaseName+atomExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(river), "Beta item") || strings.Contains(string(river), "Alpha item") {
+		t.Errorf("frontend river should only hold Beta's items, got %s", river)
+	}
+}
@@ -6,8 +6,9 @@ import (
 	"encoding/base64"
 	"html/template"
 	"io/fs"
-	"regexp"
 	"strings"
+
+	"github.com/lmorchard/feedspool-go/internal/sanitize"
 )
 
 //go:embed templates
@@ -38,6 +39,22 @@ func GetEmbeddedAssets() fs.FS {
 	return assetsFS
 }
 
+// stripHTML returns the text of HTML content with whitespace normalized, for
+// excerpts.
+func stripHTML(s string) string {
+	return sanitize.Text(s)
+}
+
+// enclosureKind returns "audio" or "video" for an enclosure's type, or "" for
+// anything else.
+func enclosureKind(mediaType string) string {
+	kind, _, _ := strings.Cut(strings.ToLower(mediaType), "/")
+	if kind == "audio" || kind == "video" {
+		return kind
+	}
+	return ""
+}
+
 // LoadTemplateFromFS loads and parses a template from the given filesystem.
 func LoadTemplateFromFS(fsys fs.FS, name string) (*template.Template, error) {
 	// Load the iframe template first (for use in the function)
@@ -57,19 +74,28 @@ func LoadTemplateFromFS(fsys fs.FS, name string) (*template.Template, error) {
 
 	tmpl := template.New(name).Funcs(template.FuncMap{
 		"html": func(s string) template.HTML {
-			// #nosec G203 - Intentional HTML output for template rendering
-			return template.HTML(s)
+			// #nosec G203 - Sanitized HTML output for template rendering
+			return template.HTML(sanitize.HTML(s, ""))
 		},
-		"stripHTML": func(s string) string {
-			// Remove HTML tags and clean up text for excerpts
-			re := regexp.MustCompile(`<[^>]*>`)
-			text := re.ReplaceAllString(s, "")
-			// Clean up extra whitespace and normalize
-			text = strings.TrimSpace(text)
-			text = regexp.MustCompile(`\s+`).ReplaceAllString(text, " ")
-			return text
+		// sanitize is html with relative URLs resolved against a base URL, such
+		// as the item link
+		"sanitize": func(s, base string) template.HTML {
+			// #nosec G203 - Sanitized HTML output for template rendering
+			return template.HTML(sanitize.HTML(s, base))
 		},
-		"iframeContent": func(content string) template.URL {
+		"stripHTML": stripHTML,
+		// enclosureKind is "audio" or "video" for enclosures a player can play
+		"enclosureKind": enclosureKind,
+		// iframeContent takes the item link as an optional base, since relative
+		// URLs can't resolve inside a data: URL
+		"iframeContent": func(content string, base ...string) template.URL {
+			// Sanitize here too, for items stored before content was sanitized
+			link := ""
+			if len(base) > 0 {
+				link = base[0]
+			}
+			content = sanitize.HTML(content, link)
+
 			// Render the content through the iframe template
 			var buf bytes.Buffer
 			// #nosec G203 - Intentional HTML output for iframe content rendering
@@ -0,0 +1,51 @@
+package renderer
+
+import (
+	"encoding/base64"
+	"html"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIframeContentResolvesRelativeURLs(t *testing.T) {
+	iframe, err := fs.ReadFile(GetEmbeddedTemplates(), "iframe_content.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{
+		"iframe_content.html": {Data: iframe},
+		"test.html":           {Data: []byte(`{{iframeContent .Content .Link}}|{{.Content | iframeContent}}`)},
+	}
+	tmpl, err := LoadTemplateFromFS(fsys, "test.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	err = tmpl.Execute(&out, map[string]string{
+		"Content": `<img src="/images/photo.jpg">`,
+		"Link":    "https://blog.example/posts/1",
+	})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	decode := func(dataURL string) string {
+		encoded := strings.TrimPrefix(html.UnescapeString(dataURL), "data:text/html;charset=utf-8;base64,")
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			t.Fatalf("failed to decode %q: %v", dataURL, err)
+		}
+		return string(decoded)
+	}
+
+	withBase, withoutBase, _ := strings.Cut(out.String(), "|")
+	if got := decode(withBase); !strings.Contains(got, `src="https://blog.example/images/photo.jpg"`) {
+		t.Errorf("iframeContent with base = %s, want the image URL resolved", got)
+	}
+	if got := decode(withoutBase); !strings.Contains(got, `src="/images/photo.jpg"`) {
+		t.Errorf("iframeContent without base = %s, want the image URL kept", got)
+	}
+}
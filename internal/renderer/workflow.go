@@ -27,6 +27,13 @@ type WorkflowConfig struct {
 	Format          string
 	Database        string
 	Clean           bool
+	BaseURL         string   // Public URL of the site, for absolute links in syndication feeds
+	Tags            []string // Only render feeds with any of these tags (empty = all feeds)
+	River           bool     // Render indexes as a river of items in time order instead of grouped by feed
+	RiverPaging     string   // River pagination: RiverPagingItems or RiverPagingDay
+	ItemsPerPage    int      // River items per page with RiverPagingItems (0 = no pagination)
+	Collapse        bool     // Show items syndicated through several feeds once, with "also in" links
+	MediaDir        string   // Media cache to link local copies of images from (empty = link the originals)
 }
 
 // ExecuteWorkflow performs the complete render operation with the given configuration.
@@ -61,6 +68,18 @@ func ExecuteWorkflow(config *WorkflowConfig) error {
 		return err
 	}
 
+	// Narrow to tagged feeds if requested
+	if len(config.Tags) > 0 {
+		feedURLs, err = filterFeedURLsByTags(db, feedURLs, config.Tags)
+		if err != nil {
+			return err
+		}
+		if len(feedURLs) == 0 {
+			fmt.Println("No feeds found matching criteria") //nolint:forbidigo // User-facing output
+			return nil
+		}
+	}
+
 	// Create output directory
 	if err := os.MkdirAll(config.OutputDir, configpkg.DefaultDirPerm); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
@@ -77,6 +96,12 @@ func ExecuteWorkflow(config *WorkflowConfig) error {
 		return nil
 	}
 
+	// Show each duplicate group once, before limiting so copies don't use up the limit
+	var duplicates map[int64][]DuplicateLink
+	if config.Collapse {
+		feeds, items, duplicates = collapseDuplicates(feeds, items, config.MinItemsPerFeed)
+	}
+
 	// Apply max items per feed limit if configured
 	if config.MaxItemsPerFeed > 0 {
 		items = limitItemsPerFeed(items, config.MaxItemsPerFeed)
@@ -85,7 +110,7 @@ func ExecuteWorkflow(config *WorkflowConfig) error {
 	}
 
 	// Generate site
-	return generateSite(config, feeds, items, startTime, endTime)
+	return generateSite(config, feeds, items, duplicates, startTime, endTime)
 }
 
 func loadFeedURLs(feedsFile, format string) ([]string, error) {
@@ -99,8 +124,12 @@ func loadFeedURLs(feedsFile, format string) ([]string, error) {
 		feedFormat = feedlist.FormatOPML
 	case "text":
 		feedFormat = feedlist.FormatText
+	case "yaml":
+		feedFormat = feedlist.FormatYAML
+	case "json":
+		feedFormat = feedlist.FormatJSON
 	default:
-		return nil, fmt.Errorf("unsupported feed format: %s (must be 'opml' or 'text')", format)
+		return nil, fmt.Errorf("unsupported feed format: %s (must be 'opml', 'text', 'yaml' or 'json')", format)
 	}
 
 	feedList, err := feedlist.LoadFeedList(feedFormat, feedsFile)
@@ -111,6 +140,32 @@ func loadFeedURLs(feedsFile, format string) ([]string, error) {
 	return feedList.GetURLs(), nil
 }
 
+// filterFeedURLsByTags returns the feeds with any of the given tags. When
+// feedURLs is not empty, only feeds also in feedURLs are kept.
+func filterFeedURLsByTags(db *database.DB, feedURLs, tags []string) ([]string, error) {
+	taggedURLs, err := db.GetFeedURLsByTags(tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feeds by tag: %w", err)
+	}
+	fmt.Printf("Using %d feeds tagged %v\n", len(taggedURLs), tags) //nolint:forbidigo // User-facing output
+
+	if len(feedURLs) == 0 {
+		return taggedURLs, nil
+	}
+
+	listed := make(map[string]bool)
+	for _, url := range feedURLs {
+		listed[url] = true
+	}
+	filtered := []string{}
+	for _, url := range taggedURLs {
+		if listed[url] {
+			filtered = append(filtered, url)
+		}
+	}
+	return filtered, nil
+}
+
 func queryData(
 	db *database.DB, startTime, endTime time.Time, feedURLs []string, minItemsPerFeed int,
 ) ([]database.Feed, map[string][]database.Item, error) {
@@ -128,11 +183,31 @@ func queryData(
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to query feeds and items: %w", err)
 	}
+	feeds, items = applyFeedSettings(feeds, items)
 
 	fmt.Printf("Found %d feeds with items\n", len(feeds)) //nolint:forbidigo // User-facing output
 	return feeds, items, nil
 }
 
+// applyFeedSettings leaves out feeds their list hides, along with their
+// items, and shows title overrides in place of the feeds' own titles.
+func applyFeedSettings(
+	feeds []database.Feed, items map[string][]database.Item,
+) ([]database.Feed, map[string][]database.Item) {
+	visible := make([]database.Feed, 0, len(feeds))
+	for _, feed := range feeds {
+		if feed.Hidden {
+			delete(items, feed.URL)
+			continue
+		}
+		if feed.TitleOverride != "" {
+			feed.Title = feed.TitleOverride
+		}
+		visible = append(visible, feed)
+	}
+	return visible, items
+}
+
 // limitItemsPerFeed limits the number of items for each feed to the specified maximum.
 func limitItemsPerFeed(items map[string][]database.Item, maxItems int) map[string][]database.Item {
 	if maxItems <= 0 {
@@ -151,7 +226,7 @@ func limitItemsPerFeed(items map[string][]database.Item, maxItems int) map[strin
 }
 
 func generateSite(config *WorkflowConfig, feeds []database.Feed, items map[string][]database.Item,
-	startTime, endTime time.Time,
+	duplicates map[int64][]DuplicateLink, startTime, endTime time.Time,
 ) error {
 	db, err := database.New(config.Database)
 	if err != nil {
@@ -164,20 +239,55 @@ func generateSite(config *WorkflowConfig, feeds []database.Feed, items map[strin
 	// Fetch metadata and favicons
 	metadata, feedFavicon := fetchMetadataAndFavicons(db, feeds, items)
 
+	unreadCounts, err := db.GetUnreadCounts()
+	if err != nil {
+		return fmt.Errorf("failed to count unread items: %w", err)
+	}
+
+	feedTags, err := db.GetAllFeedTags()
+	if err != nil {
+		return fmt.Errorf("failed to get feed tags: %w", err)
+	}
+
+	itemTags, err := db.GetAllItemTags()
+	if err != nil {
+		return fmt.Errorf("failed to get item tags: %w", err)
+	}
+
+	enclosures, err := db.GetAllEnclosures()
+	if err != nil {
+		return fmt.Errorf("failed to get enclosures: %w", err)
+	}
+
+	// Link images to cached copies, published into the site
+	pageItems := items
+	if config.MediaDir != "" {
+		linker, err := newMediaLinker(db, config.MediaDir, config.OutputDir, config.BaseURL)
+		if err != nil {
+			return err
+		}
+		pageItems = localizeMedia(linker, items, metadata, feedFavicon)
+	}
+
 	// Generate template context
-	context := createTemplateContext(feeds, items, metadata, feedFavicon, startTime, endTime, config.MaxAge)
+	context := createTemplateContext(feeds, pageItems, metadata, feedFavicon, startTime, endTime, config.MaxAge)
+	context.UnreadCounts, context.TotalUnread = unreadCountsForFeeds(feeds, unreadCounts)
+	context.FeedTags = feedTags
+	context.ItemTags = itemTags
+	context.Duplicates = duplicates
+	context.Enclosures = enclosures
+	context.Tags = collectTags(context.Feeds, feedTags, context.UnreadCounts)
+	context.LocalMedia = config.MediaDir != ""
 
 	// Calculate pagination info
 	feedsPerPage := config.FeedsPerPage
 	if feedsPerPage <= 0 {
 		feedsPerPage = len(feeds) // Disable pagination
 	}
-	pages := splitFeedsIntoPages(context.Feeds, feedsPerPage)
-	totalPages := len(pages)
 
-	// Render main index file
+	// Render main index file, with its page fragments
 	outputFile := filepath.Join(config.OutputDir, "index.html")
-	if err := renderIndexFile(r, outputFile, context, totalPages, feedsPerPage); err != nil {
+	if err := renderSection(r, config, config.OutputDir, context, feedsPerPage); err != nil {
 		return err
 	}
 
@@ -186,13 +296,9 @@ func generateSite(config *WorkflowConfig, feeds []database.Feed, items map[strin
 		return fmt.Errorf("failed to copy assets: %w", err)
 	}
 
-	feedsDir := filepath.Join(config.OutputDir, "feeds")
-
-	// Render feed list page fragments (if pagination enabled)
-	if totalPages > 1 {
-		if err := renderFeedPages(r, feedsDir, context.Feeds, items, metadata,
-			feedFavicon, endTime, getTimeWindow(startTime, endTime, config.MaxAge),
-			feedsPerPage); err != nil {
+	// Render a section per tag, with its own pages and river feeds
+	if len(context.Tags) > 0 {
+		if err := renderTagSections(r, config, context, items, feedsPerPage); err != nil {
 			return err
 		}
 	}
@@ -200,13 +306,17 @@ func generateSite(config *WorkflowConfig, feeds []database.Feed, items map[strin
 	// Render individual feed pages (only if feed.html template exists)
 	feedsGenerated := 0
 	if hasFeedTemplate(config.TemplatesDir) {
-		if err := renderIndividualFeeds(r, feedsDir, feeds, items, metadata, feedFavicon, endTime,
-			getTimeWindow(startTime, endTime, config.MaxAge)); err != nil {
+		if err := renderIndividualFeeds(r, filepath.Join(config.OutputDir, "feeds"), feeds, context); err != nil {
 			return err
 		}
 		feedsGenerated = len(feeds)
 	}
 
+	// Write Atom, RSS and JSON Feed versions of the river and of each feed
+	if err := writeSyndicationFeeds(config.OutputDir, config.BaseURL, feeds, items, endTime); err != nil {
+		return fmt.Errorf("failed to write syndication feeds: %w", err)
+	}
+
 	printSuccessMessage(feedsGenerated, config.OutputDir, outputFile)
 	return nil
 }
@@ -257,6 +367,25 @@ func createTemplateContext(feeds []database.Feed, items map[string][]database.It
 	}
 }
 
+// renderSection writes dir/index.html and the page fragments it loads: a
+// river of items when config.River is set, otherwise feeds with their items.
+func renderSection(r *Renderer, config *WorkflowConfig, dir string, context *TemplateContext,
+	feedsPerPage int,
+) error {
+	if config.River {
+		return renderRiver(r, dir, context, config.RiverPaging, config.ItemsPerPage)
+	}
+
+	totalPages := len(splitFeedsIntoPages(context.Feeds, feedsPerPage))
+	if err := renderIndexFile(r, filepath.Join(dir, "index.html"), context, totalPages, feedsPerPage); err != nil {
+		return err
+	}
+	if totalPages > 1 {
+		return renderFeedPages(r, filepath.Join(dir, "feeds"), context, feedsPerPage)
+	}
+	return nil
+}
+
 func renderIndexFile(r *Renderer, outputFile string, context *TemplateContext, totalPages, feedsPerPage int) error {
 	// Wrap context with pagination info for template
 	type IndexContext struct {
@@ -302,29 +431,42 @@ func splitFeedsIntoPages(feeds []FeedWithID, pageSize int) [][]FeedWithID {
 	return pages
 }
 
+// unreadCountsForFeeds narrows the unread counts to the rendered feeds and totals them.
+func unreadCountsForFeeds(feeds []database.Feed, counts map[string]int) (map[string]int, int) {
+	unread := make(map[string]int)
+	total := 0
+	for i := range feeds {
+		if count := counts[feeds[i].URL]; count > 0 {
+			unread[feeds[i].URL] = count
+			total += count
+		}
+	}
+	return unread, total
+}
+
 // renderFeedPages renders paginated feed list pages in feeds/page-N.html.
-func renderFeedPages(r *Renderer, feedsDir string, feeds []FeedWithID,
-	items map[string][]database.Item, metadata map[string]*database.URLMetadata,
-	feedFavicon map[string]string, generatedAt time.Time, timeWindow string,
-	feedsPerPage int,
-) error {
+func renderFeedPages(r *Renderer, feedsDir string, context *TemplateContext, feedsPerPage int) error {
 	if err := os.MkdirAll(feedsDir, configpkg.DefaultDirPerm); err != nil {
 		return fmt.Errorf("failed to create feeds directory: %w", err)
 	}
 
-	pages := splitFeedsIntoPages(feeds, feedsPerPage)
+	pages := splitFeedsIntoPages(context.Feeds, feedsPerPage)
 	totalPages := len(pages)
 
 	for pageNum, pageFeeds := range pages {
 		pageContext := &PageTemplateContext{
-			Feeds:       pageFeeds,
-			Items:       items, // Full items map (feeds reference what they need)
-			Metadata:    metadata,
-			FeedFavicon: feedFavicon,
-			GeneratedAt: generatedAt,
-			TimeWindow:  timeWindow,
-			PageNumber:  pageNum + 1, // 1-indexed
-			TotalPages:  totalPages,
+			Feeds:        pageFeeds,
+			Items:        context.Items, // Full items map (feeds reference what they need)
+			Metadata:     context.Metadata,
+			FeedFavicon:  context.FeedFavicon,
+			FeedTags:     context.FeedTags,
+			GeneratedAt:  context.GeneratedAt,
+			TimeWindow:   context.TimeWindow,
+			PageNumber:   pageNum + 1, // 1-indexed
+			TotalPages:   totalPages,
+			UnreadCounts: context.UnreadCounts,
+			TotalUnread:  context.TotalUnread,
+			RootPath:     context.RootPath,
 		}
 
 		pageFile := filepath.Join(feedsDir, fmt.Sprintf("page-%d.html", pageNum+1))
@@ -346,23 +488,18 @@ func renderFeedPages(r *Renderer, feedsDir string, feeds []FeedWithID,
 	return nil
 }
 
-func renderIndividualFeeds(r *Renderer, feedsDir string, feeds []database.Feed,
-	items map[string][]database.Item, metadata map[string]*database.URLMetadata,
-	feedFavicon map[string]string, generatedAt time.Time, timeWindow string,
-) error {
+func renderIndividualFeeds(r *Renderer, feedsDir string, feeds []database.Feed, context *TemplateContext) error {
 	if err := os.MkdirAll(feedsDir, configpkg.DefaultDirPerm); err != nil {
 		return fmt.Errorf("failed to create feeds directory: %w", err)
 	}
 
 	for i := range feeds {
 		feed := &feeds[i]
-		feedItems := items[feed.URL]
-		if len(feedItems) == 0 {
+		if len(context.Items[feed.URL]) == 0 {
 			continue
 		}
 
-		if err := renderSingleFeed(r, feedsDir, feed, feedItems, metadata,
-			feedFavicon[feed.URL], generatedAt, timeWindow); err != nil {
+		if err := renderSingleFeed(r, feedsDir, feed, context); err != nil {
 			return err
 		}
 	}
@@ -370,19 +507,22 @@ func renderIndividualFeeds(r *Renderer, feedsDir string, feeds []database.Feed,
 	return nil
 }
 
-func renderSingleFeed(r *Renderer, feedsDir string, feed *database.Feed,
-	feedItems []database.Item, metadata map[string]*database.URLMetadata,
-	favicon string, generatedAt time.Time, timeWindow string,
-) error {
+func renderSingleFeed(r *Renderer, feedsDir string, feed *database.Feed, context *TemplateContext) error {
 	feedID := generateFeedID(feed.URL)
 	feedContext := &FeedTemplateContext{
 		Feed:        *feed,
-		Items:       feedItems,
-		Metadata:    metadata,
-		FeedFavicon: favicon,
-		GeneratedAt: generatedAt,
-		TimeWindow:  timeWindow,
+		Items:       context.Items[feed.URL],
+		Metadata:    context.Metadata,
+		FeedFavicon: context.FeedFavicon[feed.URL],
+		Tags:        context.FeedTags[feed.URL],
+		ItemTags:    context.ItemTags,
+		Duplicates:  context.Duplicates,
+		Enclosures:  context.Enclosures,
+		GeneratedAt: context.GeneratedAt,
+		TimeWindow:  context.TimeWindow,
 		FeedID:      feedID,
+		UnreadCount: context.UnreadCounts[feed.URL],
+		LocalMedia:  context.LocalMedia,
 	}
 
 	feedFile := filepath.Join(feedsDir, fmt.Sprintf("%s.html", feedID))
@@ -432,6 +572,9 @@ func printSuccessMessage(feedCount int, outputDir, outputFile string) {
 		fmt.Printf("(feed.html template not found - skipped individual feed pages)\n")
 	}
 	//nolint:forbidigo // User-facing output
+	fmt.Printf("Aggregated feeds: %[1]s%[2]s, %[1]s%[3]s and %[1]s%[4]s\n",
+		riverBaseName, atomExt, rssExt, jsonFeedExt)
+	//nolint:forbidigo // User-facing output
 	fmt.Printf("Open %s in your browser to view the site\n", outputFile)
 }
 
@@ -0,0 +1,226 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/database"
+)
+
+// Actions a rule can take on the items it matches.
+const (
+	ActionSkip      = "skip"      // Do not store the item
+	ActionTag       = "tag"       // Add the rule's tag to the item
+	ActionMarkRead  = "mark-read" // Store new items as already read
+	ActionHighlight = "highlight" // Flag the item as highlighted
+)
+
+// Rule is a compiled rule from the rules config.
+type Rule struct {
+	config.RuleConfig
+	title   *regexp.Regexp
+	content *regexp.Regexp
+	author  *regexp.Regexp
+	link    *regexp.Regexp
+}
+
+// Outcome is what the matching rules decided for an item.
+type Outcome struct {
+	Skip      bool
+	MarkRead  bool
+	Highlight bool
+	Tags      []string
+	Matched   []string // Names of the rules that matched
+}
+
+// Engine evaluates items against an ordered list of rules. A nil Engine has
+// no rules.
+type Engine struct {
+	rules []*Rule
+}
+
+// New compiles the rules config, rejecting unknown actions and bad patterns.
+// Rules without a name are named after their position, e.g. "rule-2".
+func New(configs []config.RuleConfig) (*Engine, error) {
+	engine := &Engine{}
+	for i, cfg := range configs {
+		if cfg.Name == "" {
+			cfg.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		rule, err := compile(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %s: %w", cfg.Name, err)
+		}
+		engine.rules = append(engine.rules, rule)
+	}
+	return engine, nil
+}
+
+// compile checks a rule's action and compiles its patterns.
+func compile(cfg config.RuleConfig) (*Rule, error) {
+	switch cfg.Action {
+	case ActionSkip, ActionMarkRead, ActionHighlight:
+	case ActionTag:
+		if strings.TrimSpace(cfg.Tag) == "" {
+			return nil, fmt.Errorf("the %s action needs a tag", ActionTag)
+		}
+	case "":
+		return nil, fmt.Errorf("no action (must be %s, %s, %s or %s)",
+			ActionSkip, ActionTag, ActionMarkRead, ActionHighlight)
+	default:
+		return nil, fmt.Errorf("unknown action %q (must be %s, %s, %s or %s)",
+			cfg.Action, ActionSkip, ActionTag, ActionMarkRead, ActionHighlight)
+	}
+
+	if cfg.Feed == "" && cfg.Title == "" && cfg.Content == "" && cfg.Author == "" && cfg.Link == "" {
+		return nil, fmt.Errorf("no feed or pattern to match")
+	}
+
+	rule := &Rule{RuleConfig: cfg}
+	patterns := []struct {
+		field   string
+		pattern string
+		target  **regexp.Regexp
+	}{
+		{"title", cfg.Title, &rule.title},
+		{"content", cfg.Content, &rule.content},
+		{"author", cfg.Author, &rule.author},
+		{"link", cfg.Link, &rule.link},
+	}
+	for _, p := range patterns {
+		if p.pattern == "" {
+			continue
+		}
+		re, err := compilePattern(p.pattern)
+		if err != nil {
+			return nil, fmt.Errorf("bad %s pattern: %w", p.field, err)
+		}
+		*p.target = re
+	}
+
+	return rule, nil
+}
+
+// compilePattern compiles a regular expression, also accepting the
+// /pattern/flags form with the i, m and s flags.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") {
+		if end := strings.LastIndex(pattern, "/"); end > 0 {
+			flags := pattern[end+1:]
+			if strings.Trim(flags, "ims") == "" {
+				pattern = pattern[1:end]
+				if flags != "" {
+					pattern = "(?" + flags + ")" + pattern
+				}
+			}
+		}
+	}
+	return regexp.Compile(pattern)
+}
+
+// Rules returns the engine's rules in order.
+func (e *Engine) Rules() []*Rule {
+	if e == nil {
+		return nil
+	}
+	return e.rules
+}
+
+// Evaluate runs every rule against the item and combines the actions of the
+// ones that match.
+func (e *Engine) Evaluate(item *database.Item) Outcome {
+	var outcome Outcome
+	for _, rule := range e.Rules() {
+		if !rule.Matches(item) {
+			continue
+		}
+
+		outcome.Matched = append(outcome.Matched, rule.Name)
+		switch rule.Action {
+		case ActionSkip:
+			outcome.Skip = true
+		case ActionTag:
+			outcome.Tags = appendUnique(outcome.Tags, strings.TrimSpace(rule.Tag))
+		case ActionMarkRead:
+			outcome.MarkRead = true
+		case ActionHighlight:
+			outcome.Highlight = true
+		}
+	}
+	return outcome
+}
+
+// Matches reports whether the rule applies to the item: it must come from the
+// rule's feed, if set, and every set pattern must match, unless the rule is
+// inverted. Inverting never extends a rule to other feeds.
+func (r *Rule) Matches(item *database.Item) bool {
+	if r.Feed != "" && r.Feed != item.FeedURL {
+		return false
+	}
+
+	matched := matchAny(r.title, item.Title) &&
+		matchAny(r.content, item.Content, item.Summary) &&
+		matchAny(r.author, itemAuthors(item)...) &&
+		matchAny(r.link, item.Link)
+
+	return matched != r.Invert
+}
+
+// matchAny reports whether re matches any of the values. A nil pattern
+// matches everything.
+func matchAny(re *regexp.Regexp, values ...string) bool {
+	if re == nil {
+		return true
+	}
+	for _, value := range values {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// itemAuthors returns the author names and emails recorded in the item JSON.
+func itemAuthors(item *database.Item) []string {
+	type person struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	var parsed struct {
+		Author  *person   `json:"author"`
+		Authors []*person `json:"authors"`
+	}
+	if len(item.ItemJSON) == 0 || json.Unmarshal(item.ItemJSON, &parsed) != nil {
+		return nil
+	}
+
+	people := parsed.Authors
+	if parsed.Author != nil {
+		people = append(people, parsed.Author)
+	}
+
+	authors := []string{}
+	for _, p := range people {
+		if p == nil {
+			continue
+		}
+		for _, value := range []string{p.Name, p.Email} {
+			if value != "" {
+				authors = append(authors, value)
+			}
+		}
+	}
+	return authors
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,132 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/database"
+)
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule config.RuleConfig
+		want string
+	}{
+		{"no action", config.RuleConfig{Title: "x"}, "no action"},
+		{"unknown action", config.RuleConfig{Title: "x", Action: "delete"}, "unknown action"},
+		{"tag without tag", config.RuleConfig{Title: "x", Action: ActionTag}, "needs a tag"},
+		{"nothing to match", config.RuleConfig{Action: ActionSkip}, "no feed or pattern"},
+		{"bad pattern", config.RuleConfig{Title: "(", Action: ActionSkip}, "bad title pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New([]config.RuleConfig{tt.rule})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New() error = %v, want %q", err, tt.want)
+			}
+			if err != nil && !strings.Contains(err.Error(), "rule-1") {
+				t.Errorf("New() error = %v, should name the unnamed rule rule-1", err)
+			}
+		})
+	}
+}
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{"sponsored", "Sponsored post", false},
+		{"/sponsored/i", "Sponsored post", true},
+		{"(?i)sponsored", "SPONSORED", true},
+		{"/a/b/", "x/a/b", true},
+		{"/feed/xml", "/feed/xml", true}, // "xml" aren't flags, so the slashes are literal
+		{"/feed/xml", "feed", false},
+	}
+
+	for _, tt := range tests {
+		re, err := compilePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("compilePattern(%q) error = %v", tt.pattern, err)
+		}
+		if got := re.MatchString(tt.value); got != tt.want {
+			t.Errorf("compilePattern(%q).MatchString(%q) = %v, want %v", tt.pattern, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	engine, err := New([]config.RuleConfig{
+		{Name: "no-sponsored", Feed: "https://x.example/feed", Title: "/sponsored/i", Action: ActionSkip},
+		{Name: "k8s-only", Feed: "https://k8s.example/feed", Content: "/kubernetes/i", Invert: true, Action: ActionSkip},
+		{Name: "mute-y", Author: "^Yvonne", Action: ActionMarkRead},
+		{Name: "go", Title: "/\\bgo\\b/i", Action: ActionTag, Tag: "golang"},
+		{Name: "releases", Link: "/releases/", Action: ActionHighlight},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		item database.Item
+		want Outcome
+	}{
+		{
+			name: "sponsored title in feed X",
+			item: database.Item{FeedURL: "https://x.example/feed", Title: "SPONSORED: buy things"},
+			want: Outcome{Skip: true, Matched: []string{"no-sponsored"}},
+		},
+		{
+			name: "sponsored title elsewhere",
+			item: database.Item{FeedURL: "https://other.example/feed", Title: "Sponsored"},
+			want: Outcome{},
+		},
+		{
+			name: "kubernetes feed without kubernetes",
+			item: database.Item{FeedURL: "https://k8s.example/feed", Title: "Cooking", Content: "Pasta"},
+			want: Outcome{Skip: true, Matched: []string{"k8s-only"}},
+		},
+		{
+			name: "kubernetes feed mentioning kubernetes in summary",
+			item: database.Item{FeedURL: "https://k8s.example/feed", Summary: "Running Kubernetes at home"},
+			want: Outcome{},
+		},
+		{
+			name: "muted author",
+			item: database.Item{ItemJSON: database.JSON(`{"authors":[{"name":"Yvonne Y","email":"y@example.com"}]}`)},
+			want: Outcome{MarkRead: true, Matched: []string{"mute-y"}},
+		},
+		{
+			name: "tag and highlight",
+			item: database.Item{Title: "Go 1.23 is out", Link: "https://go.dev/releases/1.23"},
+			want: Outcome{Highlight: true, Tags: []string{"golang"}, Matched: []string{"go", "releases"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := engine.Evaluate(&tt.item)
+			if got.Skip != tt.want.Skip || got.MarkRead != tt.want.MarkRead || got.Highlight != tt.want.Highlight {
+				t.Errorf("Evaluate() = %+v, want %+v", got, tt.want)
+			}
+			if strings.Join(got.Tags, ",") != strings.Join(tt.want.Tags, ",") {
+				t.Errorf("Evaluate() tags = %v, want %v", got.Tags, tt.want.Tags)
+			}
+			if strings.Join(got.Matched, ",") != strings.Join(tt.want.Matched, ",") {
+				t.Errorf("Evaluate() matched = %v, want %v", got.Matched, tt.want.Matched)
+			}
+		})
+	}
+}
+
+func TestNilEngine(t *testing.T) {
+	var engine *Engine
+	if got := engine.Evaluate(&database.Item{Title: "anything"}); got.Skip || len(got.Matched) > 0 {
+		t.Errorf("nil Engine Evaluate() = %+v, want no matches", got)
+	}
+}
@@ -0,0 +1,251 @@
+package sanitize
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// droppedElements are removed along with everything inside them.
+var droppedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Iframe: true, atom.Frame: true, atom.Frameset: true, atom.Object: true, atom.Embed: true,
+	atom.Applet: true, atom.Form: true, atom.Input: true, atom.Button: true, atom.Select: true,
+	atom.Textarea: true, atom.Svg: true, atom.Math: true, atom.Link: true, atom.Meta: true,
+	atom.Base: true, atom.Head: true, atom.Title: true,
+}
+
+// allowedAttributes lists the elements kept, each with the attributes kept on
+// it. Elements not listed here or in droppedElements are replaced by their
+// children.
+var allowedAttributes = map[atom.Atom][]string{
+	atom.P: nil, atom.Br: nil, atom.Hr: nil, atom.Div: nil, atom.Span: nil,
+	atom.H1: nil, atom.H2: nil, atom.H3: nil, atom.H4: nil, atom.H5: nil, atom.H6: nil,
+	atom.Ul: nil, atom.Ol: {"start"}, atom.Li: nil, atom.Dl: nil, atom.Dt: nil, atom.Dd: nil,
+	atom.Blockquote: {"cite"}, atom.Q: {"cite"}, atom.Cite: nil, atom.Pre: nil, atom.Code: nil,
+	atom.Kbd: nil, atom.Samp: nil, atom.Var: nil, atom.Em: nil, atom.Strong: nil, atom.B: nil,
+	atom.I: nil, atom.U: nil, atom.S: nil, atom.Del: nil, atom.Ins: nil, atom.Mark: nil,
+	atom.Small: nil, atom.Sub: nil, atom.Sup: nil, atom.Abbr: {"title"}, atom.Time: {"datetime"},
+	atom.A:      {"href", "title"},
+	atom.Img:    {"src", "alt", "title", "width", "height"},
+	atom.Figure: nil, atom.Figcaption: nil,
+	atom.Table: nil, atom.Caption: nil, atom.Thead: nil, atom.Tbody: nil, atom.Tfoot: nil,
+	atom.Tr: nil, atom.Th: {"colspan", "rowspan", "scope"}, atom.Td: {"colspan", "rowspan"},
+	atom.Details: nil, atom.Summary: nil,
+	atom.Audio:  {"src", "controls"},
+	atom.Video:  {"src", "poster", "controls", "width", "height"},
+	atom.Source: {"src", "type"},
+}
+
+// urlAttributes hold URLs, resolved against the base URL and dropped unless
+// they are http(s), or mailto for links.
+var urlAttributes = map[string]bool{"href": true, "src": true, "cite": true, "poster": true}
+
+// trackingHosts serve invisible images that only count readers.
+var trackingHosts = map[string]bool{
+	"feeds.feedburner.com":     true,
+	"pixel.wp.com":             true,
+	"stats.wordpress.com":      true,
+	"www.google-analytics.com": true,
+	"pixel.quantserve.com":     true,
+}
+
+// blockElements separate words in extracted text.
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Br: true, atom.Hr: true, atom.Div: true, atom.Li: true, atom.Dt: true,
+	atom.Dd: true, atom.Blockquote: true, atom.Pre: true, atom.Tr: true, atom.Td: true, atom.Th: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Figcaption: true, atom.Section: true, atom.Article: true,
+}
+
+// HTML returns content with only allow-listed elements and attributes: scripts,
+// styles, iframes, forms, event handlers, inline styles and tracking pixels
+// are removed. Relative URLs are resolved against base, when given, and links
+// get rel="noopener noreferrer".
+func HTML(content, base string) string {
+	if strings.TrimSpace(content) == "" {
+		return ""
+	}
+
+	var baseURL *url.URL
+	if base != "" {
+		if parsed, err := url.Parse(base); err == nil && parsed.IsAbs() {
+			baseURL = parsed
+		}
+	}
+
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(content), context)
+	if err != nil {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	for _, node := range nodes {
+		for _, cleaned := range Nodes(node, baseURL) {
+			if err := html.Render(&buf, cleaned); err != nil {
+				return ""
+			}
+		}
+	}
+	// Apostrophes need no escaping in text or in the double-quoted attributes
+	// html.Render writes, and are common in prose
+	return strings.ReplaceAll(strings.TrimSpace(buf.String()), "&#39;", "'")
+}
+
+// Nodes copies n into new nodes holding only allow-listed elements and
+// attributes, as HTML does. An element that isn't kept is replaced by its
+// children, so the result may hold several nodes or none.
+func Nodes(n *html.Node, baseURL *url.URL) []*html.Node {
+	switch n.Type {
+	case html.TextNode:
+		return []*html.Node{{Type: html.TextNode, Data: n.Data}}
+	case html.ElementNode:
+	default:
+		return nil
+	}
+
+	if droppedElements[n.DataAtom] || (n.DataAtom == atom.Img && isTrackingPixel(n, baseURL)) {
+		return nil
+	}
+
+	children := []*html.Node{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		children = append(children, Nodes(c, baseURL)...)
+	}
+
+	attributes, kept := allowedAttributes[n.DataAtom]
+	if !kept || n.DataAtom == 0 {
+		return children
+	}
+
+	cleaned := &html.Node{Type: html.ElementNode, Data: n.Data, DataAtom: n.DataAtom}
+	for _, key := range attributes {
+		value, ok := attr(n, key)
+		if !ok {
+			continue
+		}
+		if urlAttributes[key] {
+			if value = safeURL(value, baseURL, key == "href"); value == "" {
+				continue
+			}
+		}
+		cleaned.Attr = append(cleaned.Attr, html.Attribute{Key: key, Val: value})
+	}
+
+	switch n.DataAtom {
+	case atom.Img:
+		if _, ok := attr(cleaned, "src"); !ok {
+			return nil
+		}
+	case atom.A:
+		cleaned.Attr = append(cleaned.Attr, html.Attribute{Key: "rel", Val: "noopener noreferrer"})
+	}
+	sort.SliceStable(cleaned.Attr, func(i, j int) bool { return cleaned.Attr[i].Key < cleaned.Attr[j].Key })
+
+	for _, child := range children {
+		cleaned.AppendChild(child)
+	}
+	return []*html.Node{cleaned}
+}
+
+// Text returns the text of HTML content, with entities decoded and
+// whitespace collapsed, leaving out scripts and styles.
+func Text(content string) string {
+	var buf strings.Builder
+	skipping := 0
+
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			if tokenizer.Err() != io.EOF {
+				return ""
+			}
+			return strings.Join(strings.Fields(buf.String()), " ")
+		case html.TextToken:
+			if skipping == 0 {
+				buf.Write(tokenizer.Text())
+			}
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tag := atom.Lookup(name)
+			if tag == atom.Script || tag == atom.Style {
+				if tokenType == html.StartTagToken {
+					skipping++
+				} else if tokenType == html.EndTagToken && skipping > 0 {
+					skipping--
+				}
+			}
+			if blockElements[tag] {
+				buf.WriteByte(' ')
+			}
+		}
+	}
+}
+
+// isTrackingPixel reports whether an image is invisible or served by a
+// tracking host.
+func isTrackingPixel(n *html.Node, baseURL *url.URL) bool {
+	for _, key := range []string{"width", "height"} {
+		if value, ok := attr(n, key); ok {
+			value = strings.TrimSpace(strings.TrimSuffix(value, "px"))
+			if value == "0" || value == "1" {
+				return true
+			}
+		}
+	}
+
+	src, _ := attr(n, "src")
+	parsed, err := url.Parse(safeURL(src, baseURL, false))
+	if err != nil {
+		return false
+	}
+	return trackingHosts[strings.ToLower(parsed.Hostname())]
+}
+
+// safeURL resolves value against baseURL and returns it if it is an http(s)
+// URL, a mailto URL when allowed, or a relative URL that can't be resolved.
+// Returns "" for anything else, such as javascript: and data: URLs.
+func safeURL(value string, baseURL *url.URL, allowMailto bool) string {
+	parsed, err := url.Parse(strings.TrimSpace(value))
+	if err != nil {
+		return ""
+	}
+	if baseURL != nil {
+		parsed = baseURL.ResolveReference(parsed)
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https":
+		return parsed.String()
+	case "mailto":
+		if allowMailto {
+			return parsed.String()
+		}
+		return ""
+	case "":
+		// Relative, with nothing to resolve it against
+		if parsed.Opaque == "" {
+			return parsed.String()
+		}
+		return ""
+	default:
+		return ""
+	}
+}
+
+func attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key && a.Namespace == "" {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,101 @@
+package sanitize
+
+import "testing"
+
+func TestHTML(t *testing.T) {
+	const base = "https://example.com/posts/story.html"
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "keeps basic formatting",
+			content:  `<p>It's <strong>bold</strong> &amp; <em>true</em></p>`,
+			expected: `<p>It's <strong>bold</strong> &amp; <em>true</em></p>`,
+		},
+		{
+			name:     "drops scripts, styles and iframes with their content",
+			content:  `<p>Text</p><script>alert(1)</script><style>p{}</style><iframe src="https://ads.example/"></iframe>`,
+			expected: `<p>Text</p>`,
+		},
+		{
+			name:     "drops event handlers, styles and classes",
+			content:  `<p onclick="steal()" style="color:red" class="lead" id="x">Text</p>`,
+			expected: `<p>Text</p>`,
+		},
+		{
+			name:     "unwraps unknown elements",
+			content:  `<section><font color="red">Text</font></section>`,
+			expected: `Text`,
+		},
+		{
+			name:     "resolves relative URLs and marks links",
+			content:  `<a href="../about">About</a> <img src="/img/a.png" alt="A">`,
+			expected: `<a href="https://example.com/about" rel="noopener noreferrer">About</a> <img alt="A" src="https://example.com/img/a.png"/>`,
+		},
+		{
+			name:     "drops script URLs",
+			content:  `<a href="javascript:steal()">Click</a><img src="data:image/png;base64,AAAA">`,
+			expected: `<a rel="noopener noreferrer">Click</a>`,
+		},
+		{
+			name:     "keeps mailto links",
+			content:  `<a href="mailto:editor@example.com">Write</a>`,
+			expected: `<a href="mailto:editor@example.com" rel="noopener noreferrer">Write</a>`,
+		},
+		{
+			name:     "drops tracking pixels",
+			content:  `<p>Text</p><img src="https://track.example/p.gif" width="1" height="1"><img src="https://feeds.feedburner.com/~r/example/~4/abc">`,
+			expected: `<p>Text</p>`,
+		},
+		{
+			name:     "escapes plain text",
+			content:  `Fish & chips`,
+			expected: `Fish &amp; chips`,
+		},
+		{
+			name:     "empty content",
+			content:  `  `,
+			expected: ``,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTML(tt.content, base); got != tt.expected {
+				t.Errorf("HTML() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHTMLWithoutBase(t *testing.T) {
+	got := HTML(`<a href="/about">About</a>`, "")
+	if got != `<a href="/about" rel="noopener noreferrer">About</a>` {
+		t.Errorf("HTML() without base = %q, want the relative link kept", got)
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{`<p>First</p><p>Second</p>`, "First Second"},
+		{`Line<br>break`, "Line break"},
+		{`<b>Bold</b>face`, "Boldface"},
+		{`Fish &amp; chips &lt;3`, "Fish & chips <3"},
+		{`<script>var x = "<p>";</script>Visible<style>p { color: red }</style>`, "Visible"},
+		{"  spaced \n\t out  ", "spaced out"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.content, func(t *testing.T) {
+			if got := Text(tt.content); got != tt.expected {
+				t.Errorf("Text(%q) = %q, want %q", tt.content, got, tt.expected)
+			}
+		})
+	}
+}
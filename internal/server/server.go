@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -20,9 +21,12 @@ const (
 
 // Config holds all configuration for server operations.
 type Config struct {
+	Host    string // Address to listen on; all interfaces when empty
 	Port    int
 	Dir     string
 	Verbose bool
+	// Handlers are extra handlers mounted by path pattern ahead of the static files.
+	Handlers map[string]http.Handler
 }
 
 // Server represents the HTTP server.
@@ -51,8 +55,17 @@ func (s *Server) Start() error {
 	// Create HTTP handler with middleware
 	handler := s.createHandler(fileServer)
 
+	if len(s.config.Handlers) > 0 {
+		mux := http.NewServeMux()
+		for pattern, h := range s.config.Handlers {
+			mux.Handle(pattern, h)
+		}
+		mux.Handle("/", handler)
+		handler = mux
+	}
+
 	// Create server
-	addr := ":" + strconv.Itoa(s.config.Port)
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
 	s.server = &http.Server{
 		Addr:         addr,
 		Handler:      handler,
@@ -61,9 +74,14 @@ func (s *Server) Start() error {
 		IdleTimeout:  idleTimeout * time.Second,
 	}
 
-	fmt.Printf("Starting HTTP server on http://localhost:%d\n", s.config.Port) //nolint:forbidigo // User-facing output
-	fmt.Printf("Serving files from: %s\n", s.config.Dir)                       //nolint:forbidigo // User-facing output
-	fmt.Println("Press Ctrl+C to stop the server")                             //nolint:forbidigo // User-facing output
+	displayHost := s.config.Host
+	if displayHost == "" {
+		displayHost = "localhost"
+	}
+	fmt.Printf("Starting HTTP server on http://%s\n", //nolint:forbidigo // User-facing output
+		net.JoinHostPort(displayHost, strconv.Itoa(s.config.Port)))
+	fmt.Printf("Serving files from: %s\n", s.config.Dir) //nolint:forbidigo // User-facing output
+	fmt.Println("Press Ctrl+C to stop the server")       //nolint:forbidigo // User-facing output
 
 	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("server failed to start: %w", err)
@@ -0,0 +1,95 @@
+package structlist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	"gopkg.in/yaml.v3"
+)
+
+// List is a feed list written as YAML or JSON.
+type List struct {
+	Feeds []Feed `yaml:"feeds" json:"feeds"`
+}
+
+// Feed is an entry in a list: a feed URL and its optional settings.
+// Durations are kept as written, e.g. "6h" or "90d".
+type Feed struct {
+	URL       string   `yaml:"url" json:"url"`
+	Title     string   `yaml:"title,omitempty" json:"title,omitempty"`
+	Tags      []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Interval  string   `yaml:"interval,omitempty" json:"interval,omitempty"`
+	MaxItems  int      `yaml:"max_items,omitempty" json:"max_items,omitempty"`
+	Hidden    bool     `yaml:"hidden,omitempty" json:"hidden,omitempty"`
+	Retention string   `yaml:"retention,omitempty" json:"retention,omitempty"`
+}
+
+// ParseYAML reads a YAML feed list. Unknown keys are rejected, so misspelled
+// settings don't go unnoticed, and an empty document is an empty list.
+func ParseYAML(reader io.Reader) (*List, error) {
+	list := &List{}
+	decoder := yaml.NewDecoder(reader)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(list); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	return list, validate(list)
+}
+
+// ParseJSON reads a JSON feed list, rejecting unknown keys.
+func ParseJSON(reader io.Reader) (*List, error) {
+	list := &List{}
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(list); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return list, validate(list)
+}
+
+// validate checks that every entry has a URL with a scheme and sensible
+// settings.
+func validate(list *List) error {
+	for i, feed := range list.Feeds {
+		if feed.URL == "" {
+			return fmt.Errorf("feed %d has no url", i+1)
+		}
+		parsedURL, err := url.Parse(feed.URL)
+		if err != nil {
+			return fmt.Errorf("invalid URL for feed %d: %s - %w", i+1, feed.URL, err)
+		}
+		if parsedURL.Scheme == "" {
+			return fmt.Errorf("URL missing scheme for feed %d: %s", i+1, feed.URL)
+		}
+		if feed.MaxItems < 0 {
+			return fmt.Errorf("negative max_items for feed %d: %s", i+1, feed.URL)
+		}
+	}
+	return nil
+}
+
+// WriteYAML writes a feed list as YAML.
+func WriteYAML(writer io.Writer, list *List) error {
+	encoder := yaml.NewEncoder(writer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(list); err != nil {
+		return fmt.Errorf("failed to write YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to write YAML: %w", err)
+	}
+	return nil
+}
+
+// WriteJSON writes a feed list as indented JSON.
+func WriteJSON(writer io.Writer, list *List) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(list); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+	return nil
+}
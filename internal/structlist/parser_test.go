@@ -0,0 +1,117 @@
+package structlist
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	content := `feeds:
+  - url: https://example.com/feed.xml
+    title: Example
+    tags: [golang, news]
+    interval: 6h
+    max_items: 20
+    hidden: true
+    retention: 90d
+  - url: https://another.com/rss
+`
+	list, err := ParseYAML(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+
+	if len(list.Feeds) != 2 {
+		t.Fatalf("len(Feeds) = %d, want 2", len(list.Feeds))
+	}
+	want := Feed{
+		URL: "https://example.com/feed.xml", Title: "Example", Tags: []string{"golang", "news"},
+		Interval: "6h", MaxItems: 20, Hidden: true, Retention: "90d",
+	}
+	got := list.Feeds[0]
+	if got.URL != want.URL || got.Title != want.Title || strings.Join(got.Tags, ",") != "golang,news" ||
+		got.Interval != want.Interval || got.MaxItems != want.MaxItems || !got.Hidden || got.Retention != want.Retention {
+		t.Errorf("Feeds[0] = %+v, want %+v", got, want)
+	}
+	if list.Feeds[1].URL != "https://another.com/rss" {
+		t.Errorf("Feeds[1].URL = %q, want https://another.com/rss", list.Feeds[1].URL)
+	}
+}
+
+func TestParseYAMLEmpty(t *testing.T) {
+	list, err := ParseYAML(strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("ParseYAML() error = %v", err)
+	}
+	if len(list.Feeds) != 0 {
+		t.Errorf("len(Feeds) = %d, want 0", len(list.Feeds))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		parse   func(string) (*List, error)
+		content string
+		wantErr string
+	}{
+		{"unknown YAML key", parseYAMLString, "feeds:\n  - url: https://example.com/\n    maxitems: 5\n", "maxitems"},
+		{"unknown JSON key", parseJSONString, `{"feeds": [{"url": "https://example.com/", "hide": true}]}`, "hide"},
+		{"missing url", parseYAMLString, "feeds:\n  - title: Example\n", "no url"},
+		{"missing scheme", parseJSONString, `{"feeds": [{"url": "example.com/feed"}]}`, "missing scheme"},
+		{"negative max items", parseYAMLString, "feeds:\n  - url: https://example.com/\n    max_items: -1\n", "max_items"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.parse(tt.content)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	list := &List{Feeds: []Feed{
+		{URL: "https://example.com/feed.xml", Tags: []string{"golang"}, Interval: "1d", MaxItems: 5},
+		{URL: "https://another.com/rss", Title: "Another", Hidden: true},
+	}}
+
+	for _, format := range []struct {
+		name  string
+		write func(*bytes.Buffer, *List) error
+		parse func(string) (*List, error)
+	}{
+		{"YAML", func(b *bytes.Buffer, l *List) error { return WriteYAML(b, l) }, parseYAMLString},
+		{"JSON", func(b *bytes.Buffer, l *List) error { return WriteJSON(b, l) }, parseJSONString},
+	} {
+		t.Run(format.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := format.write(&buf, list); err != nil {
+				t.Fatalf("write error = %v", err)
+			}
+			if strings.Contains(buf.String(), "retention") {
+				t.Errorf("output includes unset settings:\n%s", buf.String())
+			}
+
+			loaded, err := format.parse(buf.String())
+			if err != nil {
+				t.Fatalf("parse error = %v", err)
+			}
+			if len(loaded.Feeds) != 2 || loaded.Feeds[0].Interval != "1d" || loaded.Feeds[0].MaxItems != 5 ||
+				loaded.Feeds[0].Tags[0] != "golang" || loaded.Feeds[1].Title != "Another" || !loaded.Feeds[1].Hidden {
+				t.Errorf("round trip = %+v, want %+v", loaded.Feeds, list.Feeds)
+			}
+		})
+	}
+}
+
+func parseYAMLString(content string) (*List, error) {
+	return ParseYAML(strings.NewReader(content))
+}
+
+func parseJSONString(content string) (*List, error) {
+	return ParseJSON(strings.NewReader(content))
+}
@@ -50,8 +50,12 @@ func (m *Manager) ValidateFormat(format string) (feedlist.Format, error) {
 		return feedlist.FormatOPML, nil
 	case string(feedlist.FormatText):
 		return feedlist.FormatText, nil
+	case string(feedlist.FormatYAML):
+		return feedlist.FormatYAML, nil
+	case string(feedlist.FormatJSON):
+		return feedlist.FormatJSON, nil
 	default:
-		return "", fmt.Errorf("unsupported format: %s (must be 'opml' or 'text')", format)
+		return "", fmt.Errorf("unsupported format: %s (must be 'opml', 'text', 'yaml' or 'json')", format)
 	}
 }
 
@@ -75,15 +79,16 @@ type SubscribeResult struct {
 	Warnings   []string
 }
 
-// Subscribe adds one or more URLs to a feed list.
-func (m *Manager) Subscribe(format, filename string, urls []string) (*SubscribeResult, error) {
+// Subscribe adds one or more URLs to a feed list, placing them in the folders
+// named by tags when the format supports folders.
+func (m *Manager) Subscribe(format, filename string, urls []string, tags ...string) (*SubscribeResult, error) {
 	feedFormat, err := m.ValidateFormat(format)
 	if err != nil {
 		return nil, err
 	}
 
 	list, createdNew := m.LoadOrCreateFeedList(feedFormat, filename)
-	addedCount, warnings := m.addURLsToList(list, urls)
+	addedCount, warnings := m.addURLsToList(list, urls, tags)
 
 	result := &SubscribeResult{
 		CreatedNew: createdNew,
@@ -199,7 +204,9 @@ func (m *Manager) DiscoverFeeds(htmlURL string) ([]string, error) {
 	return resolvedFeeds, nil
 }
 
-func (m *Manager) addURLsToList(list feedlist.FeedList, urlsToAdd []string) (addedCount int, warnings []string) {
+func (m *Manager) addURLsToList(
+	list feedlist.FeedList, urlsToAdd, tags []string,
+) (addedCount int, warnings []string) {
 	existingURLs := list.GetURLs()
 	existingSet := make(map[string]bool)
 	for _, url := range existingURLs {
@@ -210,7 +217,7 @@ func (m *Manager) addURLsToList(list feedlist.FeedList, urlsToAdd []string) (add
 		if existingSet[feedURL] {
 			warnings = append(warnings, fmt.Sprintf("Feed URL already exists in list: %s", feedURL))
 		} else {
-			if err := list.AddURL(feedURL); err != nil {
+			if err := list.AddURL(feedURL, tags...); err != nil {
 				warnings = append(warnings, fmt.Sprintf("Failed to add URL %s: %v", feedURL, err))
 			} else {
 				logrus.Debugf("Added feed: %s", feedURL)
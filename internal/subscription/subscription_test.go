@@ -546,7 +546,7 @@ func TestAddURLsToList(t *testing.T) {
 		list := feedlist.NewFeedList(feedlist.FormatText)
 		urls := []string{testURL1, testURL2}
 
-		addedCount, warnings := manager.addURLsToList(list, urls)
+		addedCount, warnings := manager.addURLsToList(list, urls, nil)
 
 		if addedCount != 2 {
 			t.Errorf("Expected 2 URLs added, got %d", addedCount)
@@ -568,7 +568,7 @@ func TestAddURLsToList(t *testing.T) {
 
 		urls := []string{testURL1, testURL2, testURL3}
 
-		addedCount, warnings := manager.addURLsToList(list, urls)
+		addedCount, warnings := manager.addURLsToList(list, urls, nil)
 
 		if addedCount != 2 {
 			t.Errorf("Expected 2 URLs added (excluding duplicate), got %d", addedCount)
@@ -595,7 +595,7 @@ func TestAddURLsToList(t *testing.T) {
 		list := feedlist.NewFeedList(feedlist.FormatText)
 		urls := []string{}
 
-		addedCount, warnings := manager.addURLsToList(list, urls)
+		addedCount, warnings := manager.addURLsToList(list, urls, nil)
 
 		if addedCount != 0 {
 			t.Errorf("Expected 0 URLs added, got %d", addedCount)
@@ -8,6 +8,8 @@ import (
 
 	"github.com/lmorchard/feedspool-go/internal/database"
 	"github.com/lmorchard/feedspool-go/internal/httpclient"
+	"github.com/lmorchard/feedspool-go/internal/media"
+	"github.com/lmorchard/feedspool-go/internal/metrics"
 	"github.com/sirupsen/logrus"
 )
 
@@ -15,7 +17,8 @@ import (
 //
 //nolint:revive // UnfurlJob is clearer than Job in this context
 type UnfurlJob struct {
-	URL string
+	URL      string
+	FullText bool // Extract the page's article text too
 }
 
 // UnfurlQueue manages parallel unfurl operations.
@@ -35,6 +38,9 @@ type UnfurlQueue struct {
 	retryAfter     time.Duration
 	progressTicker *time.Ticker
 	progressDone   chan struct{}
+	startedAt      time.Time
+	unfurledMu     sync.Mutex
+	unfurled       []string
 }
 
 // NewUnfurlQueue creates a new unfurl queue with the specified concurrency.
@@ -81,9 +87,16 @@ func NewUnfurlQueue(
 	}
 }
 
+// SetMediaCache sets the cache that the images and favicons of unfurled
+// pages are downloaded into.
+func (q *UnfurlQueue) SetMediaCache(cache *media.Cache) {
+	q.service.SetMediaCache(cache)
+}
+
 // Start begins processing unfurl jobs with the configured number of workers.
 func (q *UnfurlQueue) Start() {
 	logrus.Infof("Starting unfurl queue with %d workers", q.concurrency)
+	q.startedAt = time.Now()
 
 	// Start progress ticker for periodic reports
 	q.progressTicker = time.NewTicker(30 * time.Second)
@@ -99,7 +112,7 @@ func (q *UnfurlQueue) Start() {
 func (q *UnfurlQueue) Enqueue(job UnfurlJob) {
 	select {
 	case q.jobs <- job:
-		atomic.AddInt64(&q.queueDepth, 1)
+		recordQueueDepth(atomic.AddInt64(&q.queueDepth, 1))
 		atomic.AddInt64(&q.totalEnqueued, 1)
 		logrus.Debugf("Enqueuing unfurl for: %s", job.URL)
 	case <-q.ctx.Done():
@@ -127,6 +140,7 @@ func (q *UnfurlQueue) Wait() {
 	totalProcessed := atomic.LoadInt64(&q.totalProcessed)
 	if totalProcessed > 0 {
 		logrus.Infof("All unfurl operations completed: %d total processed", totalProcessed)
+		recordThroughput(totalProcessed, time.Since(q.startedAt))
 	}
 }
 
@@ -154,6 +168,13 @@ func (q *UnfurlQueue) Stats() (enqueued, processed int64) {
 	return atomic.LoadInt64(&q.totalEnqueued), atomic.LoadInt64(&q.totalProcessed)
 }
 
+// Unfurled returns the URLs unfurled successfully so far.
+func (q *UnfurlQueue) Unfurled() []string {
+	q.unfurledMu.Lock()
+	defer q.unfurledMu.Unlock()
+	return append([]string(nil), q.unfurled...)
+}
+
 // worker processes unfurl jobs from the queue.
 func (q *UnfurlQueue) worker(workerID int) {
 	defer q.wg.Done()
@@ -169,7 +190,8 @@ func (q *UnfurlQueue) worker(workerID int) {
 			}
 
 			q.processJob(job, workerID)
-			atomic.AddInt64(&q.queueDepth, -1)
+			recordUnfurled(1)
+			recordQueueDepth(atomic.AddInt64(&q.queueDepth, -1))
 			atomic.AddInt64(&q.totalProcessed, 1)
 
 		case <-q.ctx.Done():
@@ -213,11 +235,36 @@ func (q *UnfurlQueue) processJob(job UnfurlJob, workerID int) {
 		q.retryAfter,
 		false, // Don't retry immediately by default
 		q.skipRobots,
+		job.FullText,
 	)
 
 	if err != nil {
 		logrus.Debugf("Worker %d unfurl failed for %s: %v", workerID, job.URL, err)
 	} else {
 		logrus.Debugf("Worker %d completed unfurl for: %s", workerID, job.URL)
+		q.unfurledMu.Lock()
+		q.unfurled = append(q.unfurled, job.URL)
+		q.unfurledMu.Unlock()
+	}
+}
+
+// recordQueueDepth records the number of unfurl jobs waiting or in progress.
+func recordQueueDepth(depth int64) {
+	metrics.Default.Set("feedspool_unfurl_queue_depth", "Unfurl jobs waiting or in progress.", nil, float64(depth))
+}
+
+// recordUnfurled counts unfurled URLs, whether or not their pages could be
+// fetched.
+func recordUnfurled(count int) {
+	metrics.Default.Add("feedspool_unfurl_processed_total", "URLs unfurled.", nil, float64(count))
+}
+
+// recordThroughput records how many URLs per second the last unfurl run
+// processed.
+func recordThroughput(processed int64, elapsed time.Duration) {
+	if elapsed <= 0 {
+		return
 	}
+	metrics.Default.Set("feedspool_unfurl_throughput_per_second", "URLs unfurled per second in the last run.",
+		nil, float64(processed)/elapsed.Seconds())
 }
@@ -0,0 +1,284 @@
+package unfurl
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/lmorchard/feedspool-go/internal/sanitize"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Full-text extraction thresholds.
+const (
+	minParagraphLength = 25  // Shorter text blocks don't count towards a container's score
+	minContentLength   = 250 // Extracted text shorter than this is discarded as a failed extraction
+	classWeight        = 25  // Score added or removed for a hinting class or id
+)
+
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|` +
+		`extra|footer|header|menu|modal|nav|newsletter|pager|popup|promo|related|remark|rss|share|shoutbox|` +
+		`sidebar|social|sponsor|subscribe|tags|tool|widget`)
+	maybeCandidate     = regexp.MustCompile(`(?i)and|article|body|column|main|shadow`)
+	positiveCandidates = regexp.MustCompile(`(?i)article|blog|body|content|entry|hentry|main|page|post|story|text`)
+	negativeCandidates = regexp.MustCompile(`(?i)byline|comment|footer|footnote|masthead|media|meta|outbrain|` +
+		`promo|related|scroll|shoutbox|sidebar|sponsor|shopping|skyscraper|tags|widget`)
+)
+
+// droppedElements are removed from pages, content and all, before scoring.
+var droppedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true, atom.Frame: true,
+	atom.Object: true, atom.Embed: true, atom.Form: true, atom.Button: true, atom.Input: true,
+	atom.Select: true, atom.Textarea: true, atom.Nav: true, atom.Aside: true, atom.Footer: true,
+	atom.Svg: true, atom.Canvas: true, atom.Link: true, atom.Meta: true, atom.Head: true,
+}
+
+// ExtractContent finds the main article text of an HTML page, in the manner
+// of Arc90's readability: the element holding the most paragraph text, minus
+// navigation and boilerplate, wins. The result is sanitized, with links and
+// images resolved against baseURL. Returns "" if no block of text long enough
+// to be an article is found.
+func ExtractContent(page []byte, baseURL *url.URL) string {
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return ""
+	}
+
+	removeBoilerplate(doc)
+
+	top := topCandidate(doc)
+	if top == nil {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	for _, block := range articleBlocks(top) {
+		for _, cleaned := range sanitize.Nodes(block, baseURL) {
+			if err := html.Render(&buf, cleaned); err != nil {
+				return ""
+			}
+		}
+	}
+
+	content := strings.TrimSpace(buf.String())
+	if len(textContent(top)) < minContentLength {
+		return ""
+	}
+	return content
+}
+
+// removeBoilerplate drops elements that never hold article text, and those
+// whose class or id marks them as navigation, comments or promotions.
+func removeBoilerplate(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode || (c.Type == html.ElementNode && isBoilerplate(c)) {
+			n.RemoveChild(c)
+		} else {
+			removeBoilerplate(c)
+		}
+		c = next
+	}
+}
+
+func isBoilerplate(n *html.Node) bool {
+	if droppedElements[n.DataAtom] {
+		return true
+	}
+	if n.DataAtom == atom.Body || n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+		return false
+	}
+	hint := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyCandidates.MatchString(hint) && !maybeCandidate.MatchString(hint)
+}
+
+// topCandidate scores the parents of every paragraph by the text it holds
+// and returns the best, or nil if the page has no paragraphs.
+func topCandidate(doc *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+		if n.Type != html.ElementNode || !isParagraph(n) {
+			return
+		}
+
+		text := textContent(n)
+		if len(text) < minParagraphLength {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+
+		if parent := n.Parent; parent != nil && parent.Type == html.ElementNode {
+			if _, ok := scores[parent]; !ok {
+				scores[parent] = initialScore(parent)
+			}
+			scores[parent] += score
+			if grandparent := parent.Parent; grandparent != nil && grandparent.Type == html.ElementNode {
+				if _, ok := scores[grandparent]; !ok {
+					scores[grandparent] = initialScore(grandparent)
+				}
+				scores[grandparent] += score / 2
+			}
+		}
+	}
+	visit(doc)
+
+	var top *html.Node
+	topScore := 0.0
+	for n, score := range scores {
+		score *= 1 - linkDensity(n)
+		scores[n] = score
+		if top == nil || score > topScore {
+			top, topScore = n, score
+		}
+	}
+	if top == nil {
+		return nil
+	}
+
+	// Prefer a parent that scores nearly as well, so split articles stay whole
+	for parent := top.Parent; parent != nil && parent.DataAtom != atom.Body; parent = parent.Parent {
+		if scores[parent] < topScore*0.75 {
+			break
+		}
+		top = parent
+	}
+	return top
+}
+
+func isParagraph(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.P, atom.Pre, atom.Td, atom.Blockquote, atom.Li:
+		return true
+	case atom.Div:
+		// Divs used as paragraphs, holding text but no other blocks
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && isBlock(c) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+func isBlock(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.P, atom.Div, atom.Pre, atom.Table, atom.Ul, atom.Ol, atom.Blockquote, atom.Section,
+		atom.Article, atom.Figure, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		return true
+	default:
+		return false
+	}
+}
+
+// initialScore favors containers by element and by class or id hints.
+func initialScore(n *html.Node) float64 {
+	score := 0.0
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		score += 10
+	case atom.Div, atom.Section:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Ol, atom.Ul, atom.Dl, atom.Form:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+
+	for _, hint := range []string{attr(n, "class"), attr(n, "id")} {
+		if hint == "" {
+			continue
+		}
+		if negativeCandidates.MatchString(hint) {
+			score -= classWeight
+		}
+		if positiveCandidates.MatchString(hint) {
+			score += classWeight
+		}
+	}
+	return score
+}
+
+// articleBlocks returns the top candidate, plus those of its siblings that
+// read as more of the article, in document order.
+func articleBlocks(top *html.Node) []*html.Node {
+	if top.Parent == nil {
+		return []*html.Node{top}
+	}
+
+	blocks := []*html.Node{}
+	for sibling := top.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling == top {
+			blocks = append(blocks, sibling)
+			continue
+		}
+		if sibling.Type != html.ElementNode || sibling.DataAtom != atom.P {
+			continue
+		}
+		text := textContent(sibling)
+		if len(text) > 80 && linkDensity(sibling) < 0.25 {
+			blocks = append(blocks, sibling)
+		}
+	}
+	return blocks
+}
+
+// linkDensity is the fraction of a node's text that sits inside links.
+func linkDensity(n *html.Node) float64 {
+	textLength := len(textContent(n))
+	if textLength == 0 {
+		return 0
+	}
+
+	linkLength := 0
+	var visit func(*html.Node)
+	visit = func(c *html.Node) {
+		if c.Type == html.ElementNode && c.DataAtom == atom.A {
+			linkLength += len(textContent(c))
+			return
+		}
+		for child := c.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+	}
+	visit(n)
+
+	return float64(linkLength) / float64(textLength)
+}
+
+// textContent returns the whitespace-normalized text inside a node.
+func textContent(n *html.Node) string {
+	var buf strings.Builder
+	var visit func(*html.Node)
+	visit = func(c *html.Node) {
+		if c.Type == html.TextNode {
+			buf.WriteString(c.Data)
+			buf.WriteByte(' ')
+		}
+		for child := c.FirstChild; child != nil; child = child.NextSibling {
+			visit(child)
+		}
+	}
+	visit(n)
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
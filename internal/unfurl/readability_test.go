@@ -0,0 +1,103 @@
+package unfurl
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testArticlePage = `<!DOCTYPE html>
+<html>
+<head>
+	<title>A long story</title>
+	<script>trackReader();</script>
+</head>
+<body>
+	<nav class="site-nav"><a href="/">Home</a> <a href="/about">About</a></nav>
+	<div class="sidebar">
+		<p>Subscribe to our newsletter for more stories like this one, every single week.</p>
+	</div>
+	<div id="main" class="post-content">
+		<h2>The story</h2>
+		<p onclick="track()">The first paragraph of the article, long enough to count, with a few commas, clauses, and asides.</p>
+		<p>A second paragraph links to <a href="/related/other-story">another story</a> and shows an
+			<img src="images/figure.png" alt="A figure"> inline, continuing the article at some length.</p>
+		<p>The third paragraph wraps up the article, again with enough text to be scored as article content.</p>
+		<script>showAds();</script>
+	</div>
+	<div class="comments">
+		<p>First! This comment is long enough to be a paragraph, but it sits in the comments section.</p>
+	</div>
+	<footer><p>Copyright, all rights reserved, by the publisher of this fine website.</p></footer>
+</body>
+</html>`
+
+func TestExtractContent(t *testing.T) {
+	base, _ := url.Parse("https://example.com/2024/story.html")
+	content := ExtractContent([]byte(testArticlePage), base)
+
+	for _, want := range []string{
+		"<h2>The story</h2>",
+		"The first paragraph of the article",
+		"The third paragraph wraps up",
+		`<a href="https://example.com/related/other-story" rel="noopener noreferrer">another story</a>`,
+		`<img alt="A figure" src="https://example.com/2024/images/figure.png"/>`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("ExtractContent() missing %q in:\n%s", want, content)
+		}
+	}
+
+	for _, unwanted := range []string{"Home", "newsletter", "First!", "Copyright", "script", "onclick", "class="} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("ExtractContent() should not contain %q:\n%s", unwanted, content)
+		}
+	}
+}
+
+func TestExtractContentTooShort(t *testing.T) {
+	page := `<html><body><div class="content"><p>Just one short paragraph of text here.</p></div></body></html>`
+	if content := ExtractContent([]byte(page), nil); content != "" {
+		t.Errorf("ExtractContent() of a short page = %q, want empty", content)
+	}
+	if content := ExtractContent([]byte("<html><body><nav>Menu</nav></body></html>"), nil); content != "" {
+		t.Errorf("ExtractContent() without paragraphs = %q, want empty", content)
+	}
+}
+
+func TestUnfurlPageFullText(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, testArticlePage)
+	}))
+	defer server.Close()
+
+	unfurler := NewUnfurler(nil)
+
+	result, err := unfurler.UnfurlPage(server.URL+"/story", true, false)
+	if err != nil {
+		t.Fatalf("UnfurlPage() error = %v", err)
+	}
+	if result.FullText || result.Content != "" {
+		t.Errorf("UnfurlPage() without full text extracted %q", result.Content)
+	}
+
+	result, err = unfurler.UnfurlPage(server.URL+"/story", true, true)
+	if err != nil {
+		t.Fatalf("UnfurlPage() error = %v", err)
+	}
+	if !result.FullText || !strings.Contains(result.Content, "The first paragraph of the article") {
+		t.Errorf("UnfurlPage() with full text = %q, want the article", result.Content)
+	}
+
+	metadata, err := unfurler.ToURLMetadata(server.URL+"/story", result, 200, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !metadata.Content.Valid || metadata.Content.String != result.Content {
+		t.Errorf("ToURLMetadata() content = %+v, want the extracted article", metadata.Content)
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/lmorchard/feedspool-go/internal/database"
 	"github.com/lmorchard/feedspool-go/internal/httpclient"
+	"github.com/lmorchard/feedspool-go/internal/media"
 	"github.com/sirupsen/logrus"
 )
 
@@ -19,6 +20,7 @@ const jsonFormat = "json"
 type Service struct {
 	db       *database.DB
 	unfurler *Unfurler
+	media    *media.Cache
 }
 
 // NewService creates a new unfurl service.
@@ -29,11 +31,19 @@ func NewService(db *database.DB, httpClient *httpclient.Client) *Service {
 	}
 }
 
-// ProcessSingleURL processes a single URL for metadata extraction.
+// SetMediaCache sets the cache that the images and favicons of unfurled
+// pages are downloaded into.
+func (s *Service) SetMediaCache(cache *media.Cache) {
+	s.media = cache
+}
+
+// ProcessSingleURL processes a single URL for metadata extraction. With
+// fullText set, the page's article text is extracted too, refetching pages
+// whose metadata is cached without it.
 //
 //nolint:cyclop // Complex URL processing logic
 func (s *Service) ProcessSingleURL(
-	targetURL, format string, retryAfter time.Duration, retryImmediate, skipRobots bool,
+	targetURL, format string, retryAfter time.Duration, retryImmediate, skipRobots, fullText bool,
 ) error {
 	// Validate URL
 	if _, err := url.Parse(targetURL); err != nil {
@@ -59,7 +69,8 @@ func (s *Service) ProcessSingleURL(
 
 	//nolint:nestif // Complex condition check is necessary
 	if existing != nil && existing.FetchStatusCode.Valid &&
-		existing.FetchStatusCode.Int64 >= 200 && existing.FetchStatusCode.Int64 < 300 {
+		existing.FetchStatusCode.Int64 >= 200 && existing.FetchStatusCode.Int64 < 300 &&
+		(!fullText || existing.Content.Valid) {
 		// Use existing successful metadata
 		logrus.Debugf("Using cached successful metadata for %s (status: %d)",
 			targetURL, existing.FetchStatusCode.Int64)
@@ -86,7 +97,7 @@ func (s *Service) ProcessSingleURL(
 			logrus.Debugf("Fetching metadata for %s...", targetURL)
 		}
 
-		result, err := s.unfurler.UnfurlWithOptions(targetURL, skipRobots)
+		result, err := s.unfurler.UnfurlPage(targetURL, skipRobots, fullText)
 		statusCode := 0
 		if err != nil {
 			statusCode = extractStatusCodeFromError(err)
@@ -115,6 +126,7 @@ func (s *Service) ProcessSingleURL(
 			return fmt.Errorf("failed to store metadata: %w", err)
 		}
 		logrus.Debugf("Successfully stored metadata for %s", targetURL)
+		s.media.StoreMetadata(metadata)
 
 		if format != jsonFormat && result != nil {
 			logrus.Debug("Successfully fetched metadata:")
@@ -140,7 +152,7 @@ func (s *Service) ProcessBatchURLs(
 	limit int, retryAfter time.Duration, concurrency int, retryImmediate, skipRobots bool,
 ) error {
 	// Get URLs that need fetching
-	var urls []string
+	var urls []database.UnfurlTarget
 	var err error
 	if retryImmediate {
 		// When retry immediate is enabled, use 0 duration to get all failed URLs
@@ -158,6 +170,7 @@ func (s *Service) ProcessBatchURLs(
 	}
 
 	logrus.Infof("Found %d URLs needing metadata fetching", len(urls))
+	started := time.Now()
 
 	// Set up worker pool
 	semaphore := make(chan struct{}, concurrency)
@@ -168,9 +181,9 @@ func (s *Service) ProcessBatchURLs(
 	failed := 0
 
 	// Process URLs concurrently
-	for i, targetURL := range urls {
+	for i, target := range urls {
 		wg.Add(1)
-		go func(url string, _ int) {
+		go func(url string, fullText bool, _ int) {
 			defer wg.Done()
 
 			// Acquire semaphore
@@ -178,7 +191,7 @@ func (s *Service) ProcessBatchURLs(
 			defer func() { <-semaphore }()
 
 			// Fetch metadata
-			result, fetchErr := s.unfurler.UnfurlWithOptions(url, skipRobots)
+			result, fetchErr := s.unfurler.UnfurlPage(url, skipRobots, fullText)
 			statusCode := 0
 			if fetchErr != nil {
 				statusCode = extractStatusCodeFromError(fetchErr)
@@ -205,6 +218,8 @@ func (s *Service) ProcessBatchURLs(
 				return
 			}
 
+			s.media.StoreMetadata(metadata)
+
 			mu.Lock()
 			processed++
 			if fetchErr == nil {
@@ -219,7 +234,7 @@ func (s *Service) ProcessBatchURLs(
 					processed, len(urls), successful, failed)
 			}
 			mu.Unlock()
-		}(targetURL, i)
+		}(target.URL, target.FullText, i)
 	}
 
 	// Wait for all workers to complete
@@ -227,6 +242,8 @@ func (s *Service) ProcessBatchURLs(
 
 	logrus.Infof("Batch unfurl complete: %d URLs processed (%d successful, %d failed)",
 		processed, successful, failed)
+	recordUnfurled(processed)
+	recordThroughput(int64(processed), time.Since(started))
 
 	return nil
 }
@@ -23,8 +23,9 @@ const (
 
 // Unfurler handles metadata extraction from URLs.
 type Unfurler struct {
-	client        *httpclient.Client
-	robotsChecker *RobotsChecker
+	client         *httpclient.Client
+	fullTextClient *httpclient.Client // Allows whole articles, beyond the metadata size limit
+	robotsChecker  *RobotsChecker
 }
 
 // NewUnfurler creates a new unfurler with the given HTTP client.
@@ -37,7 +38,12 @@ func NewUnfurler(client *httpclient.Client) *Unfurler {
 		})
 	}
 	return &Unfurler{
-		client:        client,
+		client: client,
+		fullTextClient: httpclient.NewClient(&httpclient.Config{
+			UserAgent:       httpclient.DefaultUserAgent,
+			Timeout:         httpclient.DefaultTimeout,
+			MaxResponseSize: httpclient.MaxFullTextSize,
+		}),
 		robotsChecker: NewRobotsChecker(client, "feedspool"),
 	}
 }
@@ -49,6 +55,8 @@ type Result struct {
 	ImageURL    string                 `json:"image_url,omitempty"`
 	FaviconURL  string                 `json:"favicon_url,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Content     string                 `json:"content,omitempty"` // Extracted article HTML, with full text
+	FullText    bool                   `json:"-"`                 // Whether extracting the article was attempted
 }
 
 // Unfurl fetches and extracts metadata from a URL.
@@ -57,9 +65,15 @@ func (u *Unfurler) Unfurl(targetURL string) (*Result, error) {
 }
 
 // UnfurlWithOptions fetches and extracts metadata from a URL with configurable options.
+func (u *Unfurler) UnfurlWithOptions(targetURL string, skipRobots bool) (*Result, error) {
+	return u.UnfurlPage(targetURL, skipRobots, false)
+}
+
+// UnfurlPage fetches and extracts metadata from a URL and, with fullText set,
+// the article text of the page as well; see ExtractContent.
 //
 //nolint:cyclop,funlen // Complex metadata extraction logic with detailed debug logging
-func (u *Unfurler) UnfurlWithOptions(targetURL string, skipRobots bool) (*Result, error) {
+func (u *Unfurler) UnfurlPage(targetURL string, skipRobots, fullText bool) (*Result, error) {
 	// Parse URL to ensure it's valid
 	parsedURL, err := url.Parse(targetURL)
 	if err != nil {
@@ -80,7 +94,11 @@ func (u *Unfurler) UnfurlWithOptions(targetURL string, skipRobots bool) (*Result
 
 	// Fetch the page with size limit
 	logrus.Debugf("Fetching page content from %s", targetURL)
-	resp, err := u.client.GetLimited(targetURL)
+	client := u.client
+	if fullText {
+		client = u.fullTextClient
+	}
+	resp, err := client.GetLimited(targetURL)
 	if err != nil {
 		logrus.Debugf("Failed to fetch page from %s: %v", targetURL, err)
 		return nil, fmt.Errorf("failed to fetch URL: %w", err)
@@ -121,6 +139,17 @@ func (u *Unfurler) UnfurlWithOptions(targetURL string, skipRobots bool) (*Result
 	// Combine results
 	result := &Result{
 		Metadata: make(map[string]interface{}),
+		FullText: fullText,
+	}
+
+	if fullText {
+		// Resolve the article's links against the page it was found on, after redirects
+		pageURL := parsedURL
+		if resp.Request != nil && resp.Request.URL != nil {
+			pageURL = resp.Request.URL
+		}
+		result.Content = ExtractContent(body, pageURL)
+		logrus.Debugf("Extracted %d bytes of article content from %s", len(result.Content), targetURL)
 	}
 
 	// Prefer OpenGraph data when available
@@ -293,6 +322,10 @@ func (u *Unfurler) ToURLMetadata(targetURL string, result *Result, statusCode in
 			metadata.FaviconURL = sql.NullString{String: result.FaviconURL, Valid: true}
 		}
 
+		if result.FullText {
+			metadata.Content = sql.NullString{String: result.Content, Valid: true}
+		}
+
 		// Store additional metadata as JSON
 		if len(result.Metadata) > 0 {
 			metaJSON, err := json.Marshal(result.Metadata)
@@ -0,0 +1,212 @@
+package urlcanon
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/httpclient"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultStripParams returns the query parameters dropped from links unless
+// the config lists its own. A trailing * matches any parameter starting with
+// the rest.
+func DefaultStripParams() []string {
+	return []string{
+		"utm_*", "fbclid", "gclid", "dclid", "msclkid", "yclid", "igshid",
+		"mc_cid", "mc_eid", "_hsenc", "_hsmi", "mkt_tok", "ref_src",
+	}
+}
+
+// DefaultRedirectors returns the hosts whose links only redirect elsewhere,
+// resolved when the config enables it and lists none of its own.
+func DefaultRedirectors() []string {
+	return []string{
+		"feedproxy.google.com", "feeds.feedburner.com", "rss.feedsportal.com",
+		"t.co", "bit.ly", "buff.ly", "ow.ly", "dlvr.it", "lnkd.in", "tinyurl.com",
+	}
+}
+
+// Canonicalizer rewrites item links to one form per page. A nil
+// Canonicalizer leaves links as they are.
+type Canonicalizer struct {
+	stripParams   map[string]bool
+	stripPrefixes []string
+	redirectors   map[string]bool // Hosts to resolve; empty unless resolving is enabled
+
+	mu       sync.Mutex
+	resolved map[string]string // Redirector link -> canonical final URL
+}
+
+// New creates a canonicalizer from the links config, or returns nil when
+// canonicalization is disabled.
+func New(cfg config.LinksConfig) *Canonicalizer {
+	if !cfg.Canonicalize {
+		return nil
+	}
+
+	stripParams := cfg.StripParams
+	if len(stripParams) == 0 {
+		stripParams = DefaultStripParams()
+	}
+
+	c := &Canonicalizer{
+		stripParams: make(map[string]bool),
+		redirectors: make(map[string]bool),
+		resolved:    make(map[string]string),
+	}
+	for _, param := range stripParams {
+		param = strings.ToLower(strings.TrimSpace(param))
+		if prefix, ok := strings.CutSuffix(param, "*"); ok {
+			c.stripPrefixes = append(c.stripPrefixes, prefix)
+		} else if param != "" {
+			c.stripParams[param] = true
+		}
+	}
+
+	if cfg.ResolveRedirects {
+		redirectors := cfg.Redirectors
+		if len(redirectors) == 0 {
+			redirectors = DefaultRedirectors()
+		}
+		for _, host := range redirectors {
+			c.redirectors[strings.ToLower(strings.TrimSpace(host))] = true
+		}
+	}
+
+	return c
+}
+
+// Canonicalize lowercases the scheme and host of an http(s) link and drops
+// its default port, fragment and stripped query parameters, keeping the other
+// parameters in order. Other links are returned unchanged.
+func (c *Canonicalizer) Canonicalize(link string) string {
+	if c == nil {
+		return link
+	}
+
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return link
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return link
+	}
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port != "" && (u.Scheme != "http" || port != "80") && (u.Scheme != "https" || port != "443") {
+		host += ":" + port
+	}
+	u.Host = host
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.RawQuery = c.stripQuery(u.RawQuery)
+	u.ForceQuery = false
+
+	return u.String()
+}
+
+// stripQuery drops the stripped parameters from a raw query string.
+func (c *Canonicalizer) stripQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	kept := []string{}
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(pair, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if !c.strips(strings.ToLower(name)) {
+			kept = append(kept, pair)
+		}
+	}
+	return strings.Join(kept, "&")
+}
+
+// strips reports whether a lowercased parameter name is stripped.
+func (c *Canonicalizer) strips(name string) bool {
+	if c.stripParams[name] {
+		return true
+	}
+	for _, prefix := range c.stripPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRedirector reports whether the link is on a host that is resolved to its
+// final URL. Always false unless resolving redirects is enabled.
+func (c *Canonicalizer) IsRedirector(link string) bool {
+	if c == nil || len(c.redirectors) == 0 {
+		return false
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return c.redirectors[strings.ToLower(u.Hostname())]
+}
+
+// Resolve follows a redirector link with client to the page it leads to and
+// returns that page's canonical URL. Other links, and links that fail to
+// resolve, are returned unchanged. Results are remembered for the
+// canonicalizer's life.
+func (c *Canonicalizer) Resolve(link string, client *httpclient.Client) string {
+	if client == nil || !c.IsRedirector(link) {
+		return link
+	}
+
+	c.mu.Lock()
+	final, ok := c.resolved[link]
+	c.mu.Unlock()
+	if ok {
+		return final
+	}
+
+	final = link
+	if target, err := followRedirects(client, link); err != nil {
+		logrus.Debugf("Failed to resolve redirect for %s: %v", link, err)
+	} else {
+		final = c.Canonicalize(target)
+		logrus.Debugf("Resolved %s to %s", link, final)
+	}
+
+	c.mu.Lock()
+	c.resolved[link] = final
+	c.mu.Unlock()
+	return final
+}
+
+// followRedirects returns the URL a link finally redirects to. It asks with a
+// HEAD request, falling back to a GET whose body is never read for servers
+// that refuse HEAD.
+func followRedirects(client *httpclient.Client, link string) (string, error) {
+	var err error
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		var resp *httpclient.Response
+		resp, err = client.Do(&httpclient.Request{URL: link, Method: method})
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusBadRequest {
+			err = fmt.Errorf("HTTP %d", resp.StatusCode)
+			continue
+		}
+		return resp.Request.URL.String(), nil
+	}
+	return "", err
+}
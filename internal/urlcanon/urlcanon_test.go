@@ -0,0 +1,115 @@
+package urlcanon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lmorchard/feedspool-go/internal/config"
+	"github.com/lmorchard/feedspool-go/internal/httpclient"
+)
+
+func TestCanonicalize(t *testing.T) {
+	c := New(config.LinksConfig{Canonicalize: true})
+
+	tests := []struct {
+		link     string
+		expected string
+	}{
+		{"https://Example.COM/Story", "https://example.com/Story"},
+		{"HTTP://example.com:80/story#comments", "http://example.com/story"},
+		{"https://example.com:443/story?utm_source=rss&utm_medium=feed", "https://example.com/story"},
+		{"https://example.com/story?id=2&fbclid=abc&a=1", "https://example.com/story?id=2&a=1"},
+		{"https://example.com:8080/story?", "https://example.com:8080/story"},
+		{"mailto:someone@example.com", "mailto:someone@example.com"},
+		{"/relative/path", "/relative/path"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			if got := c.Canonicalize(tt.link); got != tt.expected {
+				t.Errorf("Canonicalize(%q) = %q, want %q", tt.link, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeConfig(t *testing.T) {
+	c := New(config.LinksConfig{Canonicalize: true, StripParams: []string{"ref", "share_*"}})
+	got := c.Canonicalize("https://example.com/a?ref=rss&share_via=x&utm_source=rss")
+	if got != "https://example.com/a?utm_source=rss" {
+		t.Errorf("Canonicalize() with configured params = %q", got)
+	}
+
+	if disabled := New(config.LinksConfig{}); disabled != nil {
+		t.Error("New() should return nil when canonicalization is disabled")
+	}
+	var none *Canonicalizer
+	if got := none.Canonicalize("https://example.com/a?utm_source=rss"); got != "https://example.com/a?utm_source=rss" {
+		t.Errorf("nil Canonicalize() = %q, want the link unchanged", got)
+	}
+	if none.IsRedirector("https://t.co/abc") {
+		t.Error("nil IsRedirector() should be false")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	requests := 0
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+	methods := []string{}
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		methods = append(methods, r.Method)
+		switch {
+		case r.URL.Path == "/missing":
+			http.NotFound(w, r)
+		case r.URL.Path == "/no-head" && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			http.Redirect(w, r, target.URL+"/story?utm_source=short#top", http.StatusFound)
+		}
+	}))
+	defer redirector.Close()
+
+	redirectorHost := strings.TrimPrefix(redirector.URL, "http://")
+	client := httpclient.NewClient(&httpclient.Config{})
+
+	c := New(config.LinksConfig{
+		Canonicalize:     true,
+		ResolveRedirects: true,
+		Redirectors:      []string{strings.Split(redirectorHost, ":")[0]},
+	})
+
+	link := redirector.URL + "/abc"
+	if !c.IsRedirector(link) {
+		t.Fatalf("IsRedirector(%q) = false, want true", link)
+	}
+	for i := 0; i < 2; i++ {
+		if got := c.Resolve(link, client); got != target.URL+"/story" {
+			t.Errorf("Resolve() = %q, want %q", got, target.URL+"/story")
+		}
+	}
+	if requests != 1 || methods[0] != http.MethodHead {
+		t.Errorf("Resolve() made requests %v, want one HEAD with caching", methods)
+	}
+
+	// Servers refusing HEAD are asked with GET
+	if got := c.Resolve(redirector.URL+"/no-head", client); got != target.URL+"/story" {
+		t.Errorf("Resolve() without HEAD = %q, want %q", got, target.URL+"/story")
+	}
+
+	if got := c.Resolve(redirector.URL+"/missing", client); got != redirector.URL+"/missing" {
+		t.Errorf("Resolve() of a failing link = %q, want it unchanged", got)
+	}
+
+	// Without resolving enabled, no host is a redirector
+	plain := New(config.LinksConfig{Canonicalize: true})
+	if got := plain.Resolve(link, client); got != link {
+		t.Errorf("Resolve() without resolving = %q, want %q", got, link)
+	}
+}
@@ -0,0 +1,132 @@
+package websub
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"strings"
+)
+
+// DiscoverHub finds the WebSub hub and self (topic) URLs a feed advertises,
+// from HTTP Link headers first and then from the feed document itself:
+// <link rel="hub"> elements in RSS (atom:link) and Atom, or "hubs" in a JSON
+// Feed. Either result is empty when not advertised.
+func DiscoverHub(header http.Header, body []byte) (hub, self string) {
+	hub, self = discoverFromLinkHeaders(header)
+	if hub != "" && self != "" {
+		return hub, self
+	}
+
+	var docHub, docSelf string
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		docHub, docSelf = discoverFromJSONFeed(trimmed)
+	} else {
+		docHub, docSelf = discoverFromXML(body)
+	}
+
+	if hub == "" {
+		hub = docHub
+	}
+	if self == "" {
+		self = docSelf
+	}
+	return hub, self
+}
+
+// discoverFromLinkHeaders parses RFC 8288 Link headers for rel="hub" and rel="self".
+func discoverFromLinkHeaders(header http.Header) (hub, self string) {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			target, params, found := strings.Cut(link, ";")
+			if !found {
+				continue
+			}
+			target = strings.TrimSpace(target)
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			target = target[1 : len(target)-1]
+
+			for _, param := range strings.Split(params, ";") {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(name, "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+					switch {
+					case strings.EqualFold(rel, "hub") && hub == "":
+						hub = target
+					case strings.EqualFold(rel, "self") && self == "":
+						self = target
+					}
+				}
+			}
+		}
+	}
+	return hub, self
+}
+
+// discoverFromXML scans an RSS or Atom document's channel-level link
+// elements, stopping at the first item or entry.
+func discoverFromXML(body []byte) (hub, self string) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return hub, self
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "item", "entry":
+			return hub, self
+		case "link":
+			var rel, href string
+			for _, attr := range start.Attr {
+				switch attr.Name.Local {
+				case "rel":
+					rel = attr.Value
+				case "href":
+					href = attr.Value
+				}
+			}
+			for _, r := range strings.Fields(rel) {
+				switch {
+				case strings.EqualFold(r, "hub") && hub == "":
+					hub = href
+				case strings.EqualFold(r, "self") && self == "":
+					self = href
+				}
+			}
+		}
+	}
+}
+
+// discoverFromJSONFeed reads the hubs and feed_url of a JSON Feed.
+func discoverFromJSONFeed(body []byte) (hub, self string) {
+	var feed struct {
+		FeedURL string `json:"feed_url"`
+		Hubs    []struct {
+			Type string `json:"type"`
+			URL  string `json:"url"`
+		} `json:"hubs"`
+	}
+	if err := json.Unmarshal(body, &feed); err != nil {
+		return "", ""
+	}
+
+	for _, h := range feed.Hubs {
+		if strings.EqualFold(h.Type, "websub") || strings.EqualFold(h.Type, "pubsubhubbub") {
+			return h.URL, feed.FeedURL
+		}
+	}
+	return "", feed.FeedURL
+}
@@ -0,0 +1,68 @@
+package websub
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDiscoverHub(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   http.Header
+		body     string
+		wantHub  string
+		wantSelf string
+	}{
+		{
+			name: "atom links",
+			body: `<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <link rel="hub" href="https://hub.example.com/"/>
+  <link rel="self" href="https://example.com/atom.xml"/>
+  <entry><link rel="hub" href="https://wrong.example.com/"/></entry>
+</feed>`,
+			wantHub:  "https://hub.example.com/",
+			wantSelf: "https://example.com/atom.xml",
+		},
+		{
+			name: "rss atom:link",
+			body: `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+  <atom:link rel="hub" href="https://hub.example.com/"/>
+  <item><title>One</title></item>
+</channel></rss>`,
+			wantHub: "https://hub.example.com/",
+		},
+		{
+			name: "link headers win",
+			header: http.Header{"Link": {
+				`<https://header-hub.example.com/>; rel="hub", <https://example.com/feed>; rel="self"`,
+			}},
+			body:     `<feed><link rel="hub" href="https://hub.example.com/"/></feed>`,
+			wantHub:  "https://header-hub.example.com/",
+			wantSelf: "https://example.com/feed",
+		},
+		{
+			name:     "json feed",
+			body:     `{"feed_url": "https://example.com/feed.json", "hubs": [{"type": "WebSub", "url": "https://hub.example.com/"}]}`,
+			wantHub:  "https://hub.example.com/",
+			wantSelf: "https://example.com/feed.json",
+		},
+		{
+			name: "no hub",
+			body: `<rss><channel><title>Plain</title></channel></rss>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+			hub, self := DiscoverHub(header, []byte(tt.body))
+			if hub != tt.wantHub || self != tt.wantSelf {
+				t.Errorf("DiscoverHub() = %q, %q; want %q, %q", hub, self, tt.wantHub, tt.wantSelf)
+			}
+		})
+	}
+}
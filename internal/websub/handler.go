@@ -0,0 +1,158 @@
+package websub
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // sha1 is still the most common WebSub signature method
+	"crypto/sha256"
+	"crypto/sha512"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/sirupsen/logrus"
+)
+
+// maxPushSize limits the size of content a hub may push in one request.
+const maxPushSize = 10 * 1024 * 1024
+
+// Handler returns the HTTP handler for subscription callbacks. It must be
+// mounted at CallbackPath.
+func (m *Manager) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callbackID := strings.TrimPrefix(r.URL.Path, m.callback.Path)
+
+		sub, err := m.db.GetWebSubSubscriptionByCallback(callbackID)
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to look up WebSub callback")
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if sub == nil {
+			http.Error(w, "unknown subscription", http.StatusGone)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			m.handleVerification(w, r, sub)
+		case http.MethodPost:
+			m.handleContent(w, r, sub)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// handleVerification answers a hub's intent verification or denial.
+func (m *Manager) handleVerification(w http.ResponseWriter, r *http.Request, sub *database.WebSubSubscription) {
+	query := r.URL.Query()
+
+	switch query.Get("hub.mode") {
+	case "subscribe":
+		if query.Get("hub.topic") != sub.TopicURL ||
+			(sub.State != database.WebSubPending && sub.State != database.WebSubActive) {
+			http.Error(w, "subscription not requested", http.StatusNotFound)
+			return
+		}
+
+		lease := m.lease
+		if seconds, err := strconv.Atoi(query.Get("hub.lease_seconds")); err == nil && seconds > 0 {
+			lease = time.Duration(seconds) * time.Second
+		}
+
+		sub.State = database.WebSubActive
+		sub.LeaseExpiresAt = sql.NullTime{Time: time.Now().Add(lease), Valid: true}
+		sub.LastError = ""
+		if err := m.db.UpdateWebSubSubscription(sub); err != nil {
+			logrus.WithError(err).Warn("Failed to activate WebSub subscription")
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		logrus.Infof("WebSub subscription for %s verified (lease %v)", sub.FeedURL, lease)
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, query.Get("hub.challenge"))
+
+	case "denied":
+		sub.State = database.WebSubDenied
+		sub.LastError = "denied by hub"
+		if reason := query.Get("hub.reason"); reason != "" {
+			sub.LastError += ": " + reason
+		}
+		if err := m.db.UpdateWebSubSubscription(sub); err != nil {
+			logrus.WithError(err).Warn("Failed to record WebSub denial")
+		}
+		logrus.Warnf("WebSub subscription for %s %s", sub.FeedURL, sub.LastError)
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		// We never unsubscribe, so anything else isn't something we asked for
+		http.Error(w, "unsupported hub.mode", http.StatusNotFound)
+	}
+}
+
+// handleContent ingests content a hub pushed. Content with a missing or bad
+// signature is acknowledged but ignored, as the WebSub spec requires.
+func (m *Manager) handleContent(w http.ResponseWriter, r *http.Request, sub *database.WebSubSubscription) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPushSize))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	if !validSignature(r.Header.Get("X-Hub-Signature"), sub.Secret, body) {
+		logrus.Warnf("Ignoring WebSub content for %s with invalid signature", sub.FeedURL)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	itemCount, err := m.ingest(sub.FeedURL, bytes.NewReader(body))
+	if err != nil {
+		logrus.Warnf("Failed to ingest WebSub content for %s: %v", sub.FeedURL, err)
+	} else {
+		logrus.Infof("Received %d items from WebSub hub for %s", itemCount, sub.FeedURL)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// validSignature checks an X-Hub-Signature header ("method=hexdigest") against
+// the HMAC of body with secret.
+func validSignature(signature, secret string, body []byte) bool {
+	method, digest, found := strings.Cut(signature, "=")
+	if !found {
+		return false
+	}
+
+	var newHash func() hash.Hash
+	switch strings.ToLower(method) {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
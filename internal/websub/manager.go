@@ -0,0 +1,236 @@
+// Package websub subscribes to the WebSub (PubSubHubbub) hubs that feeds
+// advertise and receives content the hubs push to a callback endpoint.
+package websub
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/httpclient"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultLeaseDuration is the subscription lease requested from hubs.
+	DefaultLeaseDuration = 7 * 24 * time.Hour
+	// DefaultCheckInterval is how often subscriptions are made and renewed.
+	DefaultCheckInterval = 10 * time.Minute
+
+	// renewBefore is how long before a lease expires it is renewed.
+	renewBefore = 24 * time.Hour
+	// retryAfter is how long to wait before retrying a failed or unverified subscription.
+	retryAfter = time.Hour
+	// deniedRetryAfter is how long to wait before asking a hub that denied us again.
+	deniedRetryAfter = 7 * 24 * time.Hour
+)
+
+// IngestFunc stores feed content pushed by a hub for feedURL, returning the
+// number of items processed.
+type IngestFunc func(feedURL string, body io.Reader) (int, error)
+
+// Config holds configuration for the subscription manager.
+type Config struct {
+	// CallbackURL is the public base URL hubs deliver to. Each subscription
+	// gets its own callback below it.
+	CallbackURL   string
+	LeaseDuration time.Duration
+}
+
+// Manager subscribes to hubs recorded during fetch, renews their leases, and
+// serves the callback endpoint hubs use to verify intent and push content.
+type Manager struct {
+	db       *database.DB
+	client   *httpclient.Client
+	callback *url.URL
+	lease    time.Duration
+	ingest   IngestFunc
+}
+
+// NewManager creates a subscription manager. The callback URL must be an
+// absolute http(s) URL reachable by the hubs.
+func NewManager(db *database.DB, client *httpclient.Client, config *Config, ingest IngestFunc) (*Manager, error) {
+	callback, err := url.Parse(config.CallbackURL)
+	if err != nil || (callback.Scheme != "http" && callback.Scheme != "https") || callback.Host == "" {
+		return nil, fmt.Errorf("websub callback URL must be an absolute http(s) URL: %q", config.CallbackURL)
+	}
+	if !strings.HasSuffix(callback.Path, "/") {
+		callback.Path += "/"
+	}
+
+	lease := config.LeaseDuration
+	if lease <= 0 {
+		lease = DefaultLeaseDuration
+	}
+
+	return &Manager{
+		db:       db,
+		client:   client,
+		callback: callback,
+		lease:    lease,
+		ingest:   ingest,
+	}, nil
+}
+
+// CallbackPath returns the server path the callback handler must be mounted at.
+func (m *Manager) CallbackPath() string {
+	return m.callback.Path
+}
+
+// Run syncs subscriptions immediately and then every interval until ctx is done.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultCheckInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Sync(ctx); err != nil {
+			logrus.WithError(err).Warn("WebSub subscription sync failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync subscribes to newly discovered hubs, retries failed or unverified
+// subscriptions, and renews leases that are about to expire.
+func (m *Manager) Sync(ctx context.Context) error {
+	subs, err := m.db.GetWebSubSubscriptions()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, sub := range subs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !needsSubscribe(sub, now) {
+			continue
+		}
+		if err := m.subscribe(ctx, sub); err != nil {
+			logrus.Warnf("WebSub subscribe to %s for %s failed: %v", sub.HubURL, sub.FeedURL, err)
+		}
+	}
+
+	return nil
+}
+
+// needsSubscribe reports whether a subscription request should be sent for sub.
+func needsSubscribe(sub *database.WebSubSubscription, now time.Time) bool {
+	sinceUpdate := retryAfter
+	if sub.UpdatedAt.Valid {
+		sinceUpdate = now.Sub(sub.UpdatedAt.Time)
+	}
+
+	switch sub.State {
+	case database.WebSubDiscovered:
+		return sub.LastError == "" || sinceUpdate >= retryAfter
+	case database.WebSubPending:
+		return sinceUpdate >= retryAfter
+	case database.WebSubActive:
+		if !sub.LeaseExpiresAt.Valid {
+			return true
+		}
+		// Renew short leases halfway through rather than on every check
+		margin := renewBefore
+		if sub.UpdatedAt.Valid {
+			if half := sub.LeaseExpiresAt.Time.Sub(sub.UpdatedAt.Time) / 2; half < margin {
+				margin = half
+			}
+		}
+		return sub.LeaseExpiresAt.Time.Sub(now) < margin
+	case database.WebSubDenied:
+		return sinceUpdate >= deniedRetryAfter
+	default:
+		return false
+	}
+}
+
+// subscribe sends a subscription request for sub to its hub. The hub then
+// verifies it through the callback, possibly before it has even responded, so
+// the pending subscription is saved first.
+func (m *Manager) subscribe(ctx context.Context, sub *database.WebSubSubscription) error {
+	if sub.CallbackID == "" {
+		sub.CallbackID = randomToken()
+	}
+	if sub.Secret == "" {
+		sub.Secret = randomToken()
+	}
+
+	// Active subscriptions stay active while their lease is renewed
+	wasActive := sub.State == database.WebSubActive
+	if !wasActive {
+		sub.State = database.WebSubPending
+	}
+	sub.LastError = ""
+	if err := m.db.UpdateWebSubSubscription(sub); err != nil {
+		return err
+	}
+
+	form := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {sub.TopicURL},
+		"hub.callback":      {m.callbackURL(sub)},
+		"hub.lease_seconds": {strconv.Itoa(int(m.lease.Seconds()))},
+		"hub.secret":        {sub.Secret},
+	}
+
+	resp, err := m.client.Do(&httpclient.Request{
+		URL:     sub.HubURL,
+		Method:  "POST",
+		Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+		Body:    strings.NewReader(form.Encode()),
+		Context: ctx,
+	})
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			err = fmt.Errorf("hub returned HTTP %d", resp.StatusCode)
+		}
+	}
+
+	if err != nil {
+		if !wasActive {
+			sub.State = database.WebSubDiscovered
+		}
+		sub.LastError = err.Error()
+		if updateErr := m.db.UpdateWebSubSubscription(sub); updateErr != nil {
+			logrus.WithError(updateErr).Warn("Failed to record WebSub subscribe error")
+		}
+		return err
+	}
+
+	logrus.Infof("Requested WebSub subscription for %s from %s", sub.FeedURL, sub.HubURL)
+	return nil
+}
+
+// callbackURL returns the callback URL for sub.
+func (m *Manager) callbackURL(sub *database.WebSubSubscription) string {
+	callback := *m.callback
+	callback.Path += sub.CallbackID
+	return callback.String()
+}
+
+// randomToken returns a random hex token for callback IDs and secrets.
+func randomToken() string {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
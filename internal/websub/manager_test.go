@@ -0,0 +1,241 @@
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lmorchard/feedspool-go/internal/database"
+	"github.com/lmorchard/feedspool-go/internal/database/databasetest"
+	"github.com/lmorchard/feedspool-go/internal/httpclient"
+)
+
+// standInHub is a minimal WebSub hub that accepts a subscription, verifies it
+// against the callback, and then pushes signed content to it.
+func standInHub(t *testing.T, content string, errs chan<- error) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("hub.mode") != "subscribe" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		callback := r.Form.Get("hub.callback")
+		topic := r.Form.Get("hub.topic")
+		secret := r.Form.Get("hub.secret")
+		w.WriteHeader(http.StatusAccepted)
+
+		go func() {
+			errs <- verifyAndPush(callback, topic, secret, content)
+		}()
+	}))
+}
+
+func verifyAndPush(callback, topic, secret, content string) error {
+	query := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {topic},
+		"hub.challenge":     {"challenge-123"},
+		"hub.lease_seconds": {"3600"},
+	}
+	resp, err := http.Get(callback + "?" + query.Encode())
+	if err != nil {
+		return err
+	}
+	echoed, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(echoed) != "challenge-123" {
+		return fmt.Errorf("verification got HTTP %d %q", resp.StatusCode, echoed)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(content))
+
+	req, err := http.NewRequest(http.MethodPost, callback, strings.NewReader(content))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("content delivery got HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func TestManagerSubscribesAndReceivesPush(t *testing.T) {
+	db := databasetest.New(t)
+
+	feedURL := "https://example.com/feed.xml"
+	if err := db.UpsertFeed(&database.Feed{URL: feedURL, FeedJSON: database.JSON(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	content := `<rss version="2.0"><channel><item><guid>1</guid></item></channel></rss>`
+	hubErrs := make(chan error, 1)
+	hub := standInHub(t, content, hubErrs)
+	defer hub.Close()
+
+	if err := db.UpsertWebSubHub(feedURL, hub.URL, feedURL); err != nil {
+		t.Fatal(err)
+	}
+
+	type push struct {
+		feedURL string
+		body    string
+	}
+	pushes := make(chan push, 1)
+	ingest := func(feedURL string, body io.Reader) (int, error) {
+		b, err := io.ReadAll(body)
+		pushes <- push{feedURL, string(b)}
+		return 1, err
+	}
+
+	var handler http.Handler
+	callbackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+	}))
+	defer callbackServer.Close()
+
+	manager, err := NewManager(db, httpclient.NewClient(nil), &Config{
+		CallbackURL: callbackServer.URL + "/websub",
+	}, ingest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manager.CallbackPath() != "/websub/" {
+		t.Errorf("CallbackPath() = %q, want /websub/", manager.CallbackPath())
+	}
+	handler = manager.Handler()
+
+	if err := manager.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	select {
+	case err := <-hubErrs:
+		if err != nil {
+			t.Fatalf("stand-in hub: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the hub to verify and push")
+	}
+
+	select {
+	case p := <-pushes:
+		if p.feedURL != feedURL || p.body != content {
+			t.Errorf("ingested %q for %s, want the pushed content for %s", p.body, p.feedURL, feedURL)
+		}
+	default:
+		t.Fatal("pushed content was not ingested")
+	}
+
+	active, err := db.HasActiveWebSubSubscription(feedURL)
+	if err != nil || !active {
+		t.Errorf("HasActiveWebSubSubscription() = %v, %v; want true", active, err)
+	}
+
+	// An active subscription isn't requested again until its lease runs low
+	subs, err := db.GetWebSubSubscriptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if needsSubscribe(subs[0], time.Now()) {
+		t.Error("needsSubscribe() = true for a freshly verified subscription")
+	}
+}
+
+func TestHandlerIgnoresBadSignature(t *testing.T) {
+	db := databasetest.New(t)
+
+	feedURL := "https://example.com/feed.xml"
+	if err := db.UpsertFeed(&database.Feed{URL: feedURL, FeedJSON: database.JSON(`{}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpsertWebSubHub(feedURL, "https://hub.example.com/", feedURL); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateWebSubSubscription(&database.WebSubSubscription{
+		FeedURL: feedURL, CallbackID: "cb", Secret: "s3cret", State: database.WebSubActive,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ingested := false
+	manager, err := NewManager(db, httpclient.NewClient(nil), &Config{CallbackURL: "https://feeds.example.com/websub/"},
+		func(string, io.Reader) (int, error) {
+			ingested = true
+			return 0, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/websub/cb", strings.NewReader("<rss/>"))
+	req.Header.Set("X-Hub-Signature", "sha256=deadbeef")
+	rec := httptest.NewRecorder()
+	manager.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("status = %d, want 202", rec.Code)
+	}
+	if ingested {
+		t.Error("content with a bad signature should not be ingested")
+	}
+
+	rec = httptest.NewRecorder()
+	manager.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/websub/unknown", nil))
+	if rec.Code != http.StatusGone {
+		t.Errorf("unknown callback status = %d, want 410", rec.Code)
+	}
+}
+
+func TestNeedsSubscribe(t *testing.T) {
+	now := time.Now()
+	at := func(d time.Duration) sql.NullTime { return sql.NullTime{Time: now.Add(d), Valid: true} }
+
+	tests := []struct {
+		name string
+		sub  database.WebSubSubscription
+		want bool
+	}{
+		{"new", database.WebSubSubscription{State: database.WebSubDiscovered, UpdatedAt: at(0)}, true},
+		{"recently failed", database.WebSubSubscription{
+			State: database.WebSubDiscovered, LastError: "boom", UpdatedAt: at(-time.Minute),
+		}, false},
+		{"failed long ago", database.WebSubSubscription{
+			State: database.WebSubDiscovered, LastError: "boom", UpdatedAt: at(-2 * time.Hour),
+		}, true},
+		{"pending", database.WebSubSubscription{State: database.WebSubPending, UpdatedAt: at(-time.Minute)}, false},
+		{"unverified", database.WebSubSubscription{State: database.WebSubPending, UpdatedAt: at(-2 * time.Hour)}, true},
+		{"active", database.WebSubSubscription{State: database.WebSubActive, LeaseExpiresAt: at(72 * time.Hour)}, false},
+		{"expiring", database.WebSubSubscription{State: database.WebSubActive, LeaseExpiresAt: at(time.Hour)}, true},
+		{"short lease", database.WebSubSubscription{
+			State: database.WebSubActive, LeaseExpiresAt: at(50 * time.Minute), UpdatedAt: at(-10 * time.Minute),
+		}, false},
+		{"denied", database.WebSubSubscription{State: database.WebSubDenied, UpdatedAt: at(-time.Hour)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := tt.sub
+			if got := needsSubscribe(&sub, now); got != tt.want {
+				t.Errorf("needsSubscribe() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}